@@ -16,6 +16,17 @@ local kind_map = {
 	redis_command = "Function",
 	jq_operator = "Function",
 	jq_path = "Variable",
+	http_method = "Keyword",
+	search_index = "Class",
+	search_endpoint = "Function",
+	search_field = "Field",
+	graphql_keyword = "Keyword",
+	graphql_type = "Class",
+	graphql_field = "Field",
+	cypher_keyword = "Keyword",
+	cypher_label = "Class",
+	cypher_reltype = "Interface",
+	cypher_property = "Property",
 }
 
 function M.new()
@@ -35,11 +46,13 @@ function M:get_trigger_characters()
 	--       collections / operations / columns.
 	-- '{'  opens a jq placeholder (`{{`) → suggests jq operators / paths.
 	-- '$'  starts a Mongo $-operator (`$match`, `$group`, …).
+	-- '/'  ends a search path segment (`products/`) → suggests endpoints.
+	-- ':'  starts a Cypher label or relationship type (`(u:`, `[:`).
 	-- '>' is intentionally NOT a trigger: by the time the user types '>'
 	--      they've already committed the label, and what comes next is
 	--      body content whose own keystrokes (word characters) will
 	--      auto-trigger via cmp's default behaviour.
-	return { "|", ".", "{", "$" }
+	return { "|", ".", "{", "$", "/", ":" }
 end
 
 function M:get_keyword_pattern()
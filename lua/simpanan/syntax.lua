@@ -6,8 +6,8 @@
 --   * Postgres / MySQL  -> syntax/sql.vim  (oracle flavour + supplement)
 --   * MongoDB           -> syntax/javascript.vim (mongo shell is JS-based)
 --
--- Redis and jq stages have no bundled syntax and keep the generic
--- highlighting from syntax/simpanan.vim.
+-- Redis, Memcached, etcd, Consul, Kafka, Elasticsearch, HTTP, GraphQL, Neo4j and jq stages have no bundled syntax and keep the
+-- generic highlighting from syntax/simpanan.vim.
 --
 -- Called from:
 --   * ftplugin/simpanan.lua on BufRead/BufNewFile and BufEnter
@@ -33,7 +33,13 @@ local includes_installed_by_buf = {}
 local function uri_kind(uri)
 	if uri:match("^postgres://")
 		or uri:match("^postgresql://")
-		or uri:match("^mysql://") then
+		or uri:match("^mysql://")
+		or uri:match("^sqlite://")
+		or uri:match("^sqlserver://")
+		or uri:match("^dynamodb://")
+		or uri:match("^cassandra://")
+		or uri:match("^scylla://")
+		or uri:match("^clickhouse://") then
 		return "sql"
 	end
 	if uri:match("^mongodb://")
@@ -0,0 +1,104 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/common"
+)
+
+// Adapter is the single extension point for a backend. Everything the
+// pipeline, the read-only validator, schema introspection and
+// autocomplete need to know about a connection type is reached through
+// it, so a new backend lives in one place and is wired in with a single
+// RegisterAdapter call.
+type Adapter interface {
+	// ConnType is the connection type the adapter serves.
+	ConnType() common.ConnType
+	// Schemes lists the URI schemes (without "://") that resolve to
+	// this adapter, e.g. "postgres" and "postgresql".
+	Schemes() []string
+	// QueryType classifies a stage body as read, write or admin. It
+	// drives both dispatch and ChainedStagesAreReadOnly.
+	QueryType(query string) common.QueryType
+	// Execute runs one stage. previousResults is the prior stage's
+	// JSON output (empty for the first stage); placeholders have
+	// already been substituted into q by the time Execute is called.
+	Execute(q common.QueryMetadata, previousResults []byte) ([]byte, error)
+	// Catalog is the static completion knowledge for the type.
+	Catalog() BuiltinCatalog
+}
+
+// SchemaIntrospector is implemented by adapters whose backend has a
+// shape worth caching for autocomplete. Adapters that do not implement
+// it (redis, jq) are ineligible for a SchemaCache.
+type SchemaIntrospector interface {
+	Introspect(label, uri string) (*SchemaCache, error)
+}
+
+// PlaceholderStyler is implemented by adapters that want `{{jq}}`
+// placeholders rendered in something other than plain text, e.g. SQL
+// backends that bind them as driver parameters. The style may depend
+// on the stage body (psql meta-commands are not SQL and cannot take
+// bind parameters), and a backend with its own dialect can return its
+// own common.PlaceholderStyle, typically a common.BindStyle. Adapters
+// without it get common.PlaceholderText.
+type PlaceholderStyler interface {
+	PlaceholderStyle(query string) common.PlaceholderStyle
+}
+
+// CompletionDialect is implemented by adapters whose stages complete in
+// a dialect of their own rather than through the SQL, Mongo, command
+// word or jq path their Catalog would pick. ClassifyCompletion places
+// the cursor within the stage body typed so far, returning contexts the
+// dialect defines; Suggest answers them, with ConnectionLabel and
+// StageIndex filled in. Stage labels and jq placeholders are still
+// completed by the core.
+type CompletionDialect interface {
+	ClassifyCompletion(stageContent, prefix string) ContextClassification
+	Suggest(cc ContextClassification) []Suggestion
+}
+
+// placeholderStyle resolves the style PipeData should use for a stage.
+func placeholderStyle(a Adapter, query string) common.PlaceholderStyle {
+	if styler, ok := a.(PlaceholderStyler); ok {
+		return styler.PlaceholderStyle(query)
+	}
+	return common.PlaceholderText
+}
+
+var adapterRegistry = map[common.ConnType]Adapter{}
+
+// RegisterAdapter makes a backend available to the pipeline. It is
+// meant to be called from an init function; like database/sql.Register
+// it panics on a nil adapter, a duplicate connection type or a scheme
+// already claimed by another type.
+func RegisterAdapter(a Adapter) {
+	if a == nil {
+		panic("simpanan: RegisterAdapter adapter is nil")
+	}
+	ct := a.ConnType()
+	if _, dup := adapterRegistry[ct]; dup {
+		panic(fmt.Sprintf("simpanan: RegisterAdapter called twice for %q", ct))
+	}
+	if err := common.RegisterConnType(ct, a.Schemes()...); err != nil {
+		panic(fmt.Sprintf("simpanan: RegisterAdapter %q: %s", ct, err))
+	}
+	adapterRegistry[ct] = a
+}
+
+// LookupAdapter returns the adapter registered for a connection type.
+func LookupAdapter(ct common.ConnType) (Adapter, bool) {
+	a, ok := adapterRegistry[ct]
+	return a, ok
+}
+
+// schemaCacheEligible reports whether connections of the given type get
+// a SchemaCache, i.e. whether their adapter implements
+// SchemaIntrospector.
+func schemaCacheEligible(ct common.ConnType) bool {
+	a, ok := LookupAdapter(ct)
+	if !ok {
+		return false
+	}
+	_, ok = a.(SchemaIntrospector)
+	return ok
+}
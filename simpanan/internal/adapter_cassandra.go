@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// cassandraConnType is the connection type of cassandra:// and scylla:// URIs.
+const cassandraConnType common.ConnType = "cassandra"
+
+type cassandraAdapter struct{}
+
+func init() { RegisterAdapter(cassandraAdapter{}) }
+
+func (cassandraAdapter) ConnType() common.ConnType { return cassandraConnType }
+
+func (cassandraAdapter) Schemes() []string { return []string{"cassandra", "scylla"} }
+
+func (cassandraAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeCassandra(query)
+}
+
+// PlaceholderStyle binds placeholders as ? parameters typed by the
+// statement's columns, except in DESCRIBE, whose names are not bindable.
+func (a cassandraAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	if a.QueryType(query) == common.Admin {
+		return common.PlaceholderText
+	}
+	return adapters.PlaceholderStylePartiQL
+}
+
+func (a cassandraAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteCassandraReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteCassandraWriteQuery(q)
+	case common.Admin:
+		// DESCRIBE KEYSPACES, DESCRIBE TABLES, DESCRIBE TABLE <table>.
+		return adapters.ExecuteCassandraAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+// Introspect caches each keyspace as a database of its tables.
+func (cassandraAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	cols, err := adapters.IntrospectCassandra(uri)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]sqlColumnRow, 0, len(cols))
+	for _, c := range cols {
+		rows = append(rows, sqlColumnRow{Database: c.Keyspace, Table: c.Table, Column: c.Column})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          buildSqlDatabases(rows),
+	}, nil
+}
+
+func (cassandraAdapter) Catalog() BuiltinCatalog { return cassandraCatalog }
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// clickhouseConnType is the connection type of clickhouse:// URIs.
+const clickhouseConnType common.ConnType = "clickhouse"
+
+type clickhouseAdapter struct{}
+
+func init() { RegisterAdapter(clickhouseAdapter{}) }
+
+func (clickhouseAdapter) ConnType() common.ConnType { return clickhouseConnType }
+
+func (clickhouseAdapter) Schemes() []string { return []string{"clickhouse"} }
+
+func (clickhouseAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeClickhouse(query)
+}
+
+// PlaceholderStyle binds placeholders as typed {pN:Type} query
+// parameters, except in admin statements, whose placeholders name
+// tables and are substituted as text.
+func (a clickhouseAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	if a.QueryType(query) == common.Admin {
+		return common.PlaceholderText
+	}
+	return adapters.PlaceholderStyleClickhouse
+}
+
+func (a clickhouseAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteClickhouseReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteClickhouseWriteQuery(q)
+	case common.Admin:
+		// SHOW TABLES, DESCRIBE <table>, EXISTS <table>.
+		return adapters.ExecuteClickhouseAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+func (clickhouseAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	cols, err := adapters.IntrospectClickhouse(uri)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]sqlColumnRow, 0, len(cols))
+	for _, c := range cols {
+		rows = append(rows, sqlColumnRow{Database: c.Database, Table: c.Table, Column: c.Column})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          buildSqlDatabases(rows),
+	}, nil
+}
+
+func (clickhouseAdapter) Catalog() BuiltinCatalog { return clickhouseCatalog }
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// consulConnType is the connection type of consul:// URIs.
+const consulConnType common.ConnType = "consul"
+
+// consulAdapter does not implement SchemaIntrospector: a KV store has
+// keys but no schema to cache.
+type consulAdapter struct{}
+
+func init() { RegisterAdapter(consulAdapter{}) }
+
+func (consulAdapter) ConnType() common.ConnType { return consulConnType }
+
+func (consulAdapter) Schemes() []string { return []string{"consul"} }
+
+func (consulAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeKv(query)
+}
+
+// PlaceholderStyle renders placeholders as JSON: KV stages take keys
+// as bare words or JSON strings, and put stores a JSON string's text.
+func (consulAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return common.PlaceholderJSON
+}
+
+func (consulAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return adapters.ExecuteConsulQuery(q)
+}
+
+func (consulAdapter) Catalog() BuiltinCatalog { return consulCatalog }
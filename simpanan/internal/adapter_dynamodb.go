@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// dynamodbConnType is the connection type of dynamodb:// URIs.
+const dynamodbConnType common.ConnType = "dynamodb"
+
+type dynamodbAdapter struct{}
+
+func init() { RegisterAdapter(dynamodbAdapter{}) }
+
+func (dynamodbAdapter) ConnType() common.ConnType { return dynamodbConnType }
+
+func (dynamodbAdapter) Schemes() []string { return []string{"dynamodb"} }
+
+func (dynamodbAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeDynamodb(query)
+}
+
+// PlaceholderStyle binds placeholders as PartiQL ? parameters and
+// renders them as JSON literals in the shorthand's key objects.
+func (a dynamodbAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	switch {
+	case a.QueryType(query) == common.Admin:
+		return common.PlaceholderText
+	case adapters.IsDynamoShorthand(query):
+		return common.PlaceholderJSON
+	}
+	return adapters.PlaceholderStylePartiQL
+}
+
+func (a dynamodbAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteDynamodbReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteDynamodbWriteQuery(q)
+	case common.Admin:
+		// list-tables, describe-table <table>.
+		return adapters.ExecuteDynamodbAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+// Introspect caches every table with its key attributes as columns,
+// under one database named after the region.
+func (dynamodbAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	region, tables, err := adapters.IntrospectDynamodb(uri)
+	if err != nil {
+		return nil, err
+	}
+	schemas := make([]TableSchema, 0, len(tables))
+	for _, t := range tables {
+		schemas = append(schemas, TableSchema{Name: t.Name, Columns: t.Attributes})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          []DatabaseSchema{{Name: region, Tables: schemas}},
+	}, nil
+}
+
+func (dynamodbAdapter) Catalog() BuiltinCatalog { return dynamodbCatalog }
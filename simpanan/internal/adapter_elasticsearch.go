@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// elasticsearchConnType is the connection type of es:// and opensearch:// URIs.
+const elasticsearchConnType common.ConnType = "elasticsearch"
+
+type elasticsearchAdapter struct{}
+
+func init() { RegisterAdapter(elasticsearchAdapter{}) }
+
+func (elasticsearchAdapter) ConnType() common.ConnType { return elasticsearchConnType }
+
+func (elasticsearchAdapter) Schemes() []string { return []string{"es", "opensearch"} }
+
+func (elasticsearchAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeElasticsearch(query)
+}
+
+// PlaceholderStyle URL-escapes placeholders in the request path and
+// renders them as JSON literals in the request body.
+func (elasticsearchAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return adapters.PlaceholderStyleHttp
+}
+
+func (a elasticsearchAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteElasticsearchReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteElasticsearchWriteQuery(q)
+	case common.Admin:
+		// _cat/indices, GET products/_mapping.
+		return adapters.ExecuteElasticsearchAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+// Introspect caches every index as a collection of its field paths,
+// under one database named after the cluster.
+func (elasticsearchAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	cluster, indices, err := adapters.IntrospectElasticsearch(uri)
+	if err != nil {
+		return nil, err
+	}
+	colls := make([]CollectionSchema, 0, len(indices))
+	for _, idx := range indices {
+		colls = append(colls, CollectionSchema{Name: idx.Name, Fields: idx.Fields})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          []DatabaseSchema{{Name: cluster, Collections: colls}},
+	}, nil
+}
+
+func (elasticsearchAdapter) Catalog() BuiltinCatalog { return BuiltinCatalog{ConnectionType: elasticsearchConnType} }
+
+func (elasticsearchAdapter) ClassifyCompletion(stageContent, prefix string) ContextClassification {
+	return classifySearch(stageContent, prefix)
+}
+
+func (elasticsearchAdapter) Suggest(cc ContextClassification) []Suggestion { return suggestSearch(cc) }
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// etcdConnType is the connection type of etcd:// URIs.
+const etcdConnType common.ConnType = "etcd"
+
+// etcdAdapter does not implement SchemaIntrospector: a KV store has
+// keys but no schema to cache.
+type etcdAdapter struct{}
+
+func init() { RegisterAdapter(etcdAdapter{}) }
+
+func (etcdAdapter) ConnType() common.ConnType { return etcdConnType }
+
+func (etcdAdapter) Schemes() []string { return []string{"etcd"} }
+
+func (etcdAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeKv(query)
+}
+
+// PlaceholderStyle renders placeholders as JSON: KV stages take keys
+// as bare words or JSON strings, and put stores a JSON string's text.
+func (etcdAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return common.PlaceholderJSON
+}
+
+func (etcdAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return adapters.ExecuteEtcdQuery(q)
+}
+
+func (etcdAdapter) Catalog() BuiltinCatalog { return etcdCatalog }
@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// graphqlConnType is the connection type of graphql+https:// and graphql+http:// URIs.
+const graphqlConnType common.ConnType = "graphql"
+
+type graphqlAdapter struct{}
+
+func init() { RegisterAdapter(graphqlAdapter{}) }
+
+func (graphqlAdapter) ConnType() common.ConnType { return graphqlConnType }
+
+func (graphqlAdapter) Schemes() []string { return []string{"graphql+https", "graphql+http"} }
+
+func (graphqlAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeGraphql(query)
+}
+
+// PlaceholderStyle binds placeholders as GraphQL variables.
+func (graphqlAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return adapters.PlaceholderStyleGraphql
+}
+
+func (a graphqlAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read, common.Write:
+		return adapters.ExecuteGraphqlQuery(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+// Introspect caches every object, interface and input type as a
+// collection of its fields, under one database named after the
+// endpoint's host. FieldTypes lets autocomplete follow a selection set
+// down from the root type.
+func (graphqlAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	host, types, err := adapters.IntrospectGraphql(uri)
+	if err != nil {
+		return nil, err
+	}
+	colls := make([]CollectionSchema, 0, len(types))
+	for _, t := range types {
+		coll := CollectionSchema{Name: t.Name, Fields: make([]string, 0, len(t.Fields)), FieldTypes: map[string]string{}}
+		for _, f := range t.Fields {
+			coll.Fields = append(coll.Fields, f.Name)
+			coll.FieldTypes[f.Name] = f.Type
+		}
+		colls = append(colls, coll)
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          []DatabaseSchema{{Name: host, Collections: colls}},
+	}, nil
+}
+
+func (graphqlAdapter) Catalog() BuiltinCatalog { return BuiltinCatalog{ConnectionType: graphqlConnType} }
+
+func (graphqlAdapter) ClassifyCompletion(stageContent, prefix string) ContextClassification {
+	return classifyGraphql(stageContent, prefix)
+}
+
+func (graphqlAdapter) Suggest(cc ContextClassification) []Suggestion { return suggestGraphql(cc) }
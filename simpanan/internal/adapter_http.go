@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// httpConnType is the connection type of http:// and https:// URIs.
+const httpConnType common.ConnType = "http"
+
+type httpAdapter struct{}
+
+func init() { RegisterAdapter(httpAdapter{}) }
+
+func (httpAdapter) ConnType() common.ConnType { return httpConnType }
+
+func (httpAdapter) Schemes() []string { return []string{"http", "https"} }
+
+func (httpAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeHttp(query)
+}
+
+// PlaceholderStyle URL-escapes placeholders in the request path and
+// renders them as JSON literals in the request body.
+func (httpAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return adapters.PlaceholderStyleHttp
+}
+
+// Execute sends the stage's request whichever way it was classified;
+// the method only decides whether it may be chained.
+func (httpAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return adapters.ExecuteHttpRequest(q)
+}
+
+func (httpAdapter) Catalog() BuiltinCatalog { return BuiltinCatalog{ConnectionType: httpConnType} }
+
+func (httpAdapter) ClassifyCompletion(stageContent, prefix string) ContextClassification {
+	return classifyHttp(stageContent, prefix)
+}
+
+func (httpAdapter) Suggest(cc ContextClassification) []Suggestion { return suggestHttp(cc) }
@@ -0,0 +1,25 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// jqAdapter backs the reserved "jq" label. jq stages are pure
+// transformers over the previous stage's output, so they always
+// classify as read and have no SchemaCache.
+type jqAdapter struct{}
+
+func init() { RegisterAdapter(jqAdapter{}) }
+
+func (jqAdapter) ConnType() common.ConnType { return common.Jq }
+
+func (jqAdapter) Schemes() []string { return []string{"jq"} }
+
+func (jqAdapter) QueryType(string) common.QueryType { return common.Read }
+
+func (jqAdapter) Execute(q common.QueryMetadata, previousResults []byte) ([]byte, error) {
+	return adapters.ExecuteJqQuery(q, previousResults)
+}
+
+func (jqAdapter) Catalog() BuiltinCatalog { return jqCatalog }
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// kafkaConnType is the connection type of kafka:// URIs.
+const kafkaConnType common.ConnType = "kafka"
+
+// kafkaAdapter does not implement SchemaIntrospector: topics carry no
+// schema to cache.
+type kafkaAdapter struct{}
+
+func init() { RegisterAdapter(kafkaAdapter{}) }
+
+func (kafkaAdapter) ConnType() common.ConnType { return kafkaConnType }
+
+func (kafkaAdapter) Schemes() []string { return []string{"kafka"} }
+
+func (kafkaAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeKafka(query)
+}
+
+// PlaceholderStyle renders placeholders as JSON literals: produced
+// payloads are usually JSON, and topics, keys and flag values accept
+// JSON strings.
+func (kafkaAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return common.PlaceholderJSON
+}
+
+// Execute runs consume (read), produce (write) and list-topics /
+// list-partitions (admin) stages alike.
+func (kafkaAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return adapters.ExecuteKafkaQuery(q)
+}
+
+func (kafkaAdapter) Catalog() BuiltinCatalog { return kafkaCatalog }
@@ -0,0 +1,35 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// memcachedConnType is the connection type of memcached:// URIs.
+const memcachedConnType common.ConnType = "memcached"
+
+// memcachedAdapter does not implement SchemaIntrospector: like Redis, a
+// cache has keys but no schema to cache.
+type memcachedAdapter struct{}
+
+func init() { RegisterAdapter(memcachedAdapter{}) }
+
+func (memcachedAdapter) ConnType() common.ConnType { return memcachedConnType }
+
+func (memcachedAdapter) Schemes() []string { return []string{"memcached"} }
+
+func (memcachedAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeMemcached(query)
+}
+
+// PlaceholderStyle renders placeholders as quoted command tokens:
+// memcached stages are tokenised like Redis commands.
+func (memcachedAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return common.PlaceholderRedis
+}
+
+func (memcachedAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return adapters.ExecuteMemcachedQuery(q)
+}
+
+func (memcachedAdapter) Catalog() BuiltinCatalog { return memcachedCatalog }
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+type mongoAdapter struct{}
+
+func init() { RegisterAdapter(mongoAdapter{}) }
+
+func (mongoAdapter) ConnType() common.ConnType { return common.Mongo }
+
+func (mongoAdapter) Schemes() []string { return []string{"mongodb", "mongodb+srv"} }
+
+func (mongoAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeMongo(query)
+}
+
+// PlaceholderStyle renders placeholders as Extended JSON literals so a
+// piped string lands quoted and objects nest as documents.
+func (mongoAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return common.PlaceholderJSON
+}
+
+func (a mongoAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteMongoReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteMongoWriteQuery(q)
+	case common.Admin:
+		return adapters.ExecuteMongoAdminQuery(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+func (mongoAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	return introspectMongo(label, uri)
+}
+
+func (mongoAdapter) Catalog() BuiltinCatalog { return mongoCatalog }
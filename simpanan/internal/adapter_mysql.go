@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+type mysqlAdapter struct{}
+
+func init() { RegisterAdapter(mysqlAdapter{}) }
+
+func (mysqlAdapter) ConnType() common.ConnType { return common.Mysql }
+
+func (mysqlAdapter) Schemes() []string { return []string{"mysql"} }
+
+func (mysqlAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeMysql(query)
+}
+
+// PlaceholderStyle binds placeholders as ? parameters, except in admin
+// commands, which are rewritten or sent as SHOW statements that cannot
+// take bind parameters.
+func (a mysqlAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	if a.QueryType(query) == common.Admin {
+		return common.PlaceholderText
+	}
+	return common.PlaceholderQuestion
+}
+
+func (a mysqlAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteMysqlReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteMysqlWriteQuery(q)
+	case common.Admin:
+		// SHOW/DESCRIBE and the psql-style \dt, \d <table> aliases.
+		return adapters.ExecuteMysqlAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+func (mysqlAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	return introspectMysql(label, uri)
+}
+
+func (mysqlAdapter) Catalog() BuiltinCatalog { return mysqlCatalog }
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// neo4jConnType is the connection type of neo4j://, neo4j+s://, bolt:// and bolt+s:// URIs.
+const neo4jConnType common.ConnType = "neo4j"
+
+type neo4jAdapter struct{}
+
+func init() { RegisterAdapter(neo4jAdapter{}) }
+
+func (neo4jAdapter) ConnType() common.ConnType { return neo4jConnType }
+
+func (neo4jAdapter) Schemes() []string { return []string{"neo4j", "neo4j+s", "bolt", "bolt+s"} }
+
+func (neo4jAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeNeo4j(query)
+}
+
+// PlaceholderStyle binds placeholders as $pN Cypher parameters.
+func (neo4jAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return adapters.PlaceholderStyleNeo4j
+}
+
+func (a neo4jAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteNeo4jReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteNeo4jWriteQuery(q)
+	case common.Admin:
+		// SHOW DATABASES, SHOW INDEXES, SHOW CONSTRAINTS, ...
+		return adapters.ExecuteNeo4jAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+// Introspect caches node labels as collections and relationship types
+// as tables, each with its property keys, under one database.
+func (neo4jAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	schema, err := adapters.IntrospectNeo4j(uri)
+	if err != nil {
+		return nil, err
+	}
+	db := DatabaseSchema{Name: schema.Database}
+	for _, l := range schema.Labels {
+		db.Collections = append(db.Collections, CollectionSchema{Name: l.Name, Fields: l.Properties})
+	}
+	for _, t := range schema.RelationshipTypes {
+		db.Tables = append(db.Tables, TableSchema{Name: t.Name, Columns: t.Properties})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          []DatabaseSchema{db},
+	}, nil
+}
+
+func (neo4jAdapter) Catalog() BuiltinCatalog { return BuiltinCatalog{ConnectionType: neo4jConnType} }
+
+func (neo4jAdapter) ClassifyCompletion(stageContent, prefix string) ContextClassification {
+	return classifyCypher(stageContent, prefix)
+}
+
+func (neo4jAdapter) Suggest(cc ContextClassification) []Suggestion { return suggestCypher(cc) }
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+
+	_ "github.com/lib/pq"
+)
+
+type postgresAdapter struct{}
+
+func init() { RegisterAdapter(postgresAdapter{}) }
+
+func (postgresAdapter) ConnType() common.ConnType { return common.Postgres }
+
+func (postgresAdapter) Schemes() []string { return []string{"postgres", "postgresql"} }
+
+func (postgresAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypePostgres(query)
+}
+
+// PlaceholderStyle binds placeholders as $n parameters, except in psql
+// meta-commands, which are not sent to the server as SQL.
+func (a postgresAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	if a.QueryType(query) == common.Admin {
+		return common.PlaceholderText
+	}
+	return common.PlaceholderDollar
+}
+
+func (a postgresAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecutePostgresReadQuery(q)
+	case common.Write:
+		return adapters.ExecutePostgresWriteQuery(q)
+	case common.Admin:
+		// psql meta-commands, e.g. \dt, \d <table>.
+		return adapters.ExecutePostgresAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+func (postgresAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	return introspectPostgres(label, uri)
+}
+
+func (postgresAdapter) Catalog() BuiltinCatalog { return postgresCatalog }
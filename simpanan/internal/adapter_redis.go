@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+)
+
+// redisAdapter does not implement SchemaIntrospector: per spec there is
+// no SchemaCache for redis connections.
+type redisAdapter struct{}
+
+func init() { RegisterAdapter(redisAdapter{}) }
+
+func (redisAdapter) ConnType() common.ConnType { return common.Redis }
+
+func (redisAdapter) Schemes() []string { return []string{"redis", "rediss"} }
+
+func (redisAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeRedis(query)
+}
+
+func (redisAdapter) PlaceholderStyle(string) common.PlaceholderStyle {
+	return common.PlaceholderRedis
+}
+
+func (redisAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return adapters.ExecuteRedisQuery(q)
+}
+
+func (redisAdapter) Catalog() BuiltinCatalog { return redisCatalog }
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// sqliteConnType is the connection type of sqlite:// URIs.
+const sqliteConnType common.ConnType = "sqlite"
+
+type sqliteAdapter struct{}
+
+func init() { RegisterAdapter(sqliteAdapter{}) }
+
+func (sqliteAdapter) ConnType() common.ConnType { return sqliteConnType }
+
+func (sqliteAdapter) Schemes() []string { return []string{"sqlite"} }
+
+func (sqliteAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeSqlite(query)
+}
+
+// PlaceholderStyle binds placeholders as ? parameters, except in
+// dot-commands, which are not SQL.
+func (a sqliteAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	if a.QueryType(query) == common.Admin {
+		return common.PlaceholderText
+	}
+	return adapters.PlaceholderStyleSqlite
+}
+
+func (a sqliteAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteSqliteReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteSqliteWriteQuery(q)
+	case common.Admin:
+		// sqlite3 shell dot-commands, e.g. .tables, .schema <table>.
+		return adapters.ExecuteSqliteAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+func (sqliteAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	cols, err := adapters.IntrospectSqlite(uri)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]sqlColumnRow, 0, len(cols))
+	for _, c := range cols {
+		rows = append(rows, sqlColumnRow{Database: c.Database, Table: c.Table, Column: c.Column})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          buildSqlDatabases(rows),
+	}, nil
+}
+
+func (sqliteAdapter) Catalog() BuiltinCatalog { return sqliteCatalog }
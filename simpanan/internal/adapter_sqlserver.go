@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"simpanan/internal/adapters"
+	"simpanan/internal/common"
+	"time"
+)
+
+// sqlserverConnType is the connection type of sqlserver:// URIs.
+const sqlserverConnType common.ConnType = "sqlserver"
+
+type sqlserverAdapter struct{}
+
+func init() { RegisterAdapter(sqlserverAdapter{}) }
+
+func (sqlserverAdapter) ConnType() common.ConnType { return sqlserverConnType }
+
+func (sqlserverAdapter) Schemes() []string { return []string{"sqlserver"} }
+
+func (sqlserverAdapter) QueryType(query string) common.QueryType {
+	return adapters.QueryTypeSqlserver(query)
+}
+
+// PlaceholderStyle binds placeholders as @pN parameters, except in admin
+// stages, whose object names are not bindable.
+func (a sqlserverAdapter) PlaceholderStyle(query string) common.PlaceholderStyle {
+	if a.QueryType(query) == common.Admin {
+		return common.PlaceholderText
+	}
+	return adapters.PlaceholderStyleSqlserver
+}
+
+func (a sqlserverAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	switch a.QueryType(q.QueryLine) {
+	case common.Read:
+		return adapters.ExecuteSqlserverReadQuery(q)
+	case common.Write:
+		return adapters.ExecuteSqlserverWriteQuery(q)
+	case common.Admin:
+		// sp_help-style commands, e.g. sp_help users, sp_helpindex users.
+		return adapters.ExecuteSqlserverAdminCmd(q)
+	}
+	return nil, fmt.Errorf("Unknown query type: '%s'", q.QueryLine)
+}
+
+func (sqlserverAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	cols, err := adapters.IntrospectSqlserver(uri)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]sqlColumnRow, 0, len(cols))
+	for _, c := range cols {
+		rows = append(rows, sqlColumnRow{Database: c.Database, Table: c.Table, Column: c.Column})
+	}
+	now := time.Now()
+	return &SchemaCache{
+		ConnectionLabel:    label,
+		PopulatedAt:        &now,
+		LastRefreshAttempt: &now,
+		Databases:          buildSqlDatabases(rows),
+	}, nil
+}
+
+func (sqlserverAdapter) Catalog() BuiltinCatalog { return sqlserverCatalog }
@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeAdapter is an in-house style backend used to prove that a single
+// RegisterAdapter call wires a new type through every call site.
+type fakeAdapter struct {
+	ct      common.ConnType
+	schemes []string
+}
+
+func (f fakeAdapter) ConnType() common.ConnType { return f.ct }
+
+func (f fakeAdapter) Schemes() []string { return f.schemes }
+
+func (fakeAdapter) QueryType(query string) common.QueryType {
+	if strings.HasPrefix(query, "put") {
+		return common.Write
+	}
+	return common.Read
+}
+
+func (fakeAdapter) Execute(q common.QueryMetadata, _ []byte) ([]byte, error) {
+	return []byte(`{"echo":"` + q.QueryLine + `"}`), nil
+}
+
+func (f fakeAdapter) Catalog() BuiltinCatalog {
+	return BuiltinCatalog{ConnectionType: f.ct, SqlKeywords: []string{"FAKESELECT"}}
+}
+
+type fakeIntrospectingAdapter struct{ fakeAdapter }
+
+func (f fakeIntrospectingAdapter) Introspect(label, uri string) (*SchemaCache, error) {
+	return &SchemaCache{ConnectionLabel: label, Databases: []DatabaseSchema{{Name: uri}}}, nil
+}
+
+// fakeDialectAdapter completes its stages in a dialect of its own: a
+// verb, then a bucket named in the verb's Scope.
+type fakeDialectAdapter struct{ fakeAdapter }
+
+const ctxFakeBucket CompletionContext = "fake_bucket"
+
+func (fakeDialectAdapter) ClassifyCompletion(stageContent, prefix string) ContextClassification {
+	verb, _, found := strings.Cut(strings.TrimSpace(stageContent), " ")
+	if !found {
+		return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+	}
+	return ContextClassification{Context: ctxFakeBucket, Prefix: prefix, Scope: []string{verb}}
+}
+
+func (fakeDialectAdapter) Suggest(cc ContextClassification) []Suggestion {
+	if cc.Context != ctxFakeBucket {
+		return nil
+	}
+	return []Suggestion{{Text: cc.ConnectionLabel + ":" + cc.Scope[0] + ":" + cc.Prefix, Kind: "fake_bucket"}}
+}
+
+// registerTestAdapter registers a for the duration of the test. The
+// scheme claim in common outlives the test, so each test should use a
+// scheme of its own.
+func registerTestAdapter(t *testing.T, a Adapter) {
+	t.Helper()
+	RegisterAdapter(a)
+	t.Cleanup(func() { delete(adapterRegistry, a.ConnType()) })
+}
+
+func TestBuiltinAdaptersRegistered(t *testing.T) {
+	for _, ct := range []common.ConnType{common.Postgres, common.Mysql, common.Mongo, common.Redis, common.Jq, sqliteConnType, sqlserverConnType, elasticsearchConnType, httpConnType, graphqlConnType, dynamodbConnType, cassandraConnType, clickhouseConnType, neo4jConnType, etcdConnType, consulConnType, memcachedConnType, kafkaConnType} {
+		a, ok := LookupAdapter(ct)
+		if assert.True(t, ok, "ct=%s", ct) {
+			assert.Equal(t, ct, a.ConnType())
+			assert.Equal(t, ct, a.Catalog().ConnectionType)
+		}
+	}
+}
+
+// TestBuiltinSchemesResolve checks the schemes each built-in adapter
+// claims at registration.
+func TestBuiltinSchemesResolve(t *testing.T) {
+	tests := []struct {
+		name string
+		uri  string
+		want common.ConnType
+	}{
+		{"sqlite scheme", "sqlite://data/app.db", sqliteConnType},
+		{"sqlserver scheme", "sqlserver://sa:pw@localhost:1433?database=app", sqlserverConnType},
+		{"es scheme", "es://localhost:9200", elasticsearchConnType},
+		{"opensearch scheme", "opensearch://admin:pw@search:9200", elasticsearchConnType},
+		{"http scheme", "http://localhost:8080/api", httpConnType},
+		{"https scheme", "https://api.example.com/v1", httpConnType},
+		{"graphql+https scheme", "graphql+https://api.example.com/graphql", graphqlConnType},
+		{"graphql+http scheme", "graphql+http://localhost:4000/graphql", graphqlConnType},
+		{"dynamodb scheme", "dynamodb://us-east-1?endpoint=http://localhost:8000", dynamodbConnType},
+		{"cassandra scheme", "cassandra://app:pw@n1,n2:9042/telemetry", cassandraConnType},
+		{"scylla scheme", "scylla://localhost/telemetry", cassandraConnType},
+		{"clickhouse scheme", "clickhouse://app:pw@ch:8123/analytics", clickhouseConnType},
+		{"neo4j scheme", "neo4j://app:pw@graph:7687/perms", neo4jConnType},
+		{"neo4j+s scheme", "neo4j+s://graph.example.com", neo4jConnType},
+		{"bolt scheme", "bolt://localhost:7687", neo4jConnType},
+		{"etcd scheme", "etcd://app:pw@e1:2379,e2:2379", etcdConnType},
+		{"consul scheme", "consul://localhost:8500?token=t", consulConnType},
+		{"memcached scheme", "memcached://cache:11211", memcachedConnType},
+		{"kafka scheme", "kafka://b1:9092,b2:9092", kafkaConnType},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := common.URI(tc.uri).ConnType()
+			if assert.NoError(t, err) {
+				assert.Equal(t, tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestSchemaCacheEligibility(t *testing.T) {
+	assert.True(t, schemaCacheEligible(common.Postgres))
+	assert.True(t, schemaCacheEligible(common.Mysql))
+	assert.True(t, schemaCacheEligible(common.Mongo))
+	assert.True(t, schemaCacheEligible(sqliteConnType))
+	assert.True(t, schemaCacheEligible(sqlserverConnType))
+	assert.True(t, schemaCacheEligible(elasticsearchConnType))
+	assert.True(t, schemaCacheEligible(graphqlConnType))
+	assert.True(t, schemaCacheEligible(dynamodbConnType))
+	assert.True(t, schemaCacheEligible(cassandraConnType))
+	assert.True(t, schemaCacheEligible(clickhouseConnType))
+	assert.True(t, schemaCacheEligible(neo4jConnType))
+	assert.False(t, schemaCacheEligible(common.Redis))
+	assert.False(t, schemaCacheEligible(common.Jq))
+	assert.False(t, schemaCacheEligible(httpConnType))
+	assert.False(t, schemaCacheEligible(etcdConnType))
+	assert.False(t, schemaCacheEligible(consulConnType))
+	assert.False(t, schemaCacheEligible(memcachedConnType))
+	assert.False(t, schemaCacheEligible(kafkaConnType))
+	assert.False(t, schemaCacheEligible(common.ConnType("nope")))
+}
+
+func TestRegisterAdapterPanicsOnDuplicateConnType(t *testing.T) {
+	assert.Panics(t, func() { RegisterAdapter(postgresAdapter{}) })
+}
+
+func TestRegisterAdapterPanicsOnClaimedScheme(t *testing.T) {
+	a := fakeAdapter{ct: "fake-claim", schemes: []string{"postgres"}}
+	assert.Panics(t, func() { RegisterAdapter(a) })
+	_, ok := LookupAdapter("fake-claim")
+	assert.False(t, ok)
+}
+
+func TestRegisteredAdapterWiredThroughCallSites(t *testing.T) {
+	a := fakeIntrospectingAdapter{fakeAdapter{ct: "fake-wired", schemes: []string{"fakewired"}}}
+	registerTestAdapter(t, a)
+
+	ct, err := common.URI("fakewired://h").ConnType()
+	assert.NoError(t, err)
+	assert.Equal(t, common.ConnType("fake-wired"), *ct)
+
+	out, err := execute(common.QueryMetadata{Conn: "fakewired://h", ConnType: *ct, QueryLine: "get x"}, nil)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"echo":"get x"}`, string(out))
+
+	err = validateChainedStagesAreReadOnly([]common.QueryMetadata{
+		{ConnType: *ct, QueryLine: "put x"},
+		{ConnType: *ct, QueryLine: "get x"},
+	})
+	assert.ErrorContains(t, err, "ChainedStagesAreReadOnly")
+
+	cache, err := IntrospectSchema("lbl", "fakewired://h", *ct)
+	assert.NoError(t, err)
+	assert.Equal(t, "fakewired://h", cache.Databases[0].Name)
+
+	assert.Contains(t, GetBuiltinCatalog(*ct).SqlKeywords, "FAKESELECT")
+
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "fk", URI: "fakewired://h"}})
+	got, ok := lookupConnTypeForLabel("fk")
+	assert.True(t, ok)
+	assert.Equal(t, *ct, got)
+	// SqlKeywords in the catalog opt the type into SQL classification.
+	assert.Equal(t, CtxSqlTableExpected, ClassifyContext("|fk> SELECT * FROM ", 20).Context)
+}
+
+func TestCompletionDialectOwnsItsStages(t *testing.T) {
+	registerTestAdapter(t, fakeDialectAdapter{fakeAdapter{ct: "fake-dialect", schemes: []string{"fakedialect"}}})
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "fd", URI: "fakedialect://h"}})
+
+	// The dialect wins over the SqlKeywords its catalog also ships.
+	buf := "|fd> fetch lo"
+	cc := ClassifyContext(buf, len(buf))
+	assert.Equal(t, ctxFakeBucket, cc.Context)
+	assert.Equal(t, []string{"fetch"}, cc.Scope)
+	assert.Equal(t, []Suggestion{{Text: "fd:fetch:lo", Kind: "fake_bucket"}}, SuggestForBuffer(buf, len(buf)))
+
+	// Stage labels and jq placeholders stay with the core.
+	assert.Equal(t, CtxStageStart, ClassifyContext("|f", 2).Context)
+	buf = "|fd> fetch {{.i"
+	assert.Equal(t, CtxJqPlaceholder, ClassifyContext(buf, len(buf)).Context)
+}
+
+// An admin statement's placeholder names a table, which ClickHouse
+// cannot take as a typed query parameter, so it is substituted as text.
+func TestClickhouseAdminPlaceholderIsText(t *testing.T) {
+	var stmt string
+	var params []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		stmt = string(body)
+		for k := range r.URL.Query() {
+			if strings.HasPrefix(k, "param_") {
+				params = append(params, k)
+			}
+		}
+		io.WriteString(w, "{\"name\":\"id\",\"type\":\"UInt64\"}\n")
+	}))
+	t.Cleanup(srv.Close)
+	conn := "clickhouse://" + strings.TrimPrefix(srv.URL, "http://") + "/analytics"
+
+	out, err := execute(common.QueryMetadata{Conn: conn, ConnType: clickhouseConnType, QueryLine: "DESCRIBE {{.table}}"}, []byte(`{"table": "events"}`))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name": "id", "type": "UInt64"}]`, string(out))
+	assert.Equal(t, "DESCRIBE events", stmt)
+	assert.Empty(t, params)
+
+	// Other statements still bind query parameters.
+	q := common.QueryMetadata{QueryLine: "SELECT * FROM events WHERE id = {{.id}}"}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"id": 7}`), placeholderStyle(clickhouseAdapter{}, q.QueryLine)))
+	assert.Equal(t, "SELECT * FROM events WHERE id = {p1:Int64}", q.QueryLine)
+	assert.Equal(t, []any{7}, q.Args)
+}
+
+func TestKvPlaceholdersQuoteSpaces(t *testing.T) {
+	for _, a := range []Adapter{etcdAdapter{}, consulAdapter{}} {
+		t.Run(string(a.ConnType()), func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: "put {{.key}} {{.value}}"}
+			assert.NoError(t, common.PipeData(&q, []byte(`{"key": "motd 1", "value": "hello world"}`), placeholderStyle(a, q.QueryLine)))
+			assert.Equal(t, `put "motd 1" "hello world"`, q.QueryLine)
+
+			q = common.QueryMetadata{QueryLine: "put {{.key}} {{.value}}"}
+			assert.NoError(t, common.PipeData(&q, []byte(`{"key": "flags", "value": {"on": true}}`), placeholderStyle(a, q.QueryLine)))
+			assert.Equal(t, `put "flags" {"on":true}`, q.QueryLine)
+		})
+	}
+}
+
+func TestIntrospectSchema_UnregisteredTypeErrors(t *testing.T) {
+	_, err := IntrospectSchema("x", "nope://", common.ConnType("nope"))
+	assert.Error(t, err)
+}
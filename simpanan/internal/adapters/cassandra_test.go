@@ -0,0 +1,344 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/inf.v0"
+)
+
+func TestQueryTypeCassandra(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`SELECT * FROM ks.events WHERE device = ?`, common.Read},
+		{`select count(*) from events`, common.Read},
+		{`INSERT INTO events (device, at) VALUES (?, ?)`, common.Write},
+		{`UPDATE events SET v = 1 WHERE device = 'a'`, common.Write},
+		{`DELETE FROM events WHERE device = 'a'`, common.Write},
+		{`BEGIN BATCH INSERT INTO a (k) VALUES (1); APPLY BATCH`, common.Write},
+		{`TRUNCATE events`, common.Write},
+		{`DESCRIBE TABLES`, common.Admin},
+		{`desc table ks.events`, common.Admin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeCassandra(tt.query))
+		})
+	}
+}
+
+func TestNewCassandraCluster(t *testing.T) {
+	c, err := newCassandraCluster("cassandra://app:pw@n1,n2:9043/telemetry?consistency=one")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"n1", "n2:9043"}, c.Hosts)
+	assert.Equal(t, "telemetry", c.Keyspace)
+	assert.Equal(t, gocql.One, c.Consistency)
+	assert.Equal(t, gocql.PasswordAuthenticator{Username: "app", Password: "pw"}, c.Authenticator)
+
+	c, err = newCassandraCluster("scylla://localhost")
+	assert.NoError(t, err)
+	assert.Equal(t, "", c.Keyspace)
+	assert.Equal(t, gocql.LocalQuorum, c.Consistency)
+	assert.Nil(t, c.Authenticator)
+
+	for _, uri := range []string{"cassandra:///ks", "cassandra://h?consistency=most", "postgres://h/db"} {
+		_, err := newCassandraCluster(uri)
+		assert.Error(t, err, uri)
+	}
+}
+
+func TestParseCassandraDescribe(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    cassandraDescribeCmd
+		wantErr bool
+	}{
+		{`DESCRIBE KEYSPACES`, cassandraDescribeCmd{what: "KEYSPACES"}, false},
+		{`desc tables;`, cassandraDescribeCmd{what: "TABLES"}, false},
+		{`DESCRIBE TABLE Events`, cassandraDescribeCmd{what: "TABLE", table: "events"}, false},
+		{`DESCRIBE TABLE ks."Events"`, cassandraDescribeCmd{what: "TABLE", keyspace: "ks", table: "Events"}, false},
+		{`DESCRIBE`, cassandraDescribeCmd{}, true},
+		{`DESCRIBE TABLE`, cassandraDescribeCmd{}, true},
+		{`DESCRIBE CLUSTER`, cassandraDescribeCmd{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseCassandraDescribe(tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertCqlValue(t *testing.T) {
+	uuid, _ := gocql.ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		in   any
+		want any
+	}{
+		{"uuid", uuid, "6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{"blob", []byte{0xca, 0xfe}, "0xcafe"},
+		{"inet", net.ParseIP("10.0.0.1"), "10.0.0.1"},
+		{"decimal", inf.NewDec(12345, 2), json.Number("123.45")},
+		{"varint", new(big.Int).SetInt64(-7), json.Number("-7")},
+		{"timestamp", at, at},
+		{"int", 42, 42},
+		{"list", []int{1, 2}, []any{1, 2}},
+		{"set of uuids", []gocql.UUID{uuid}, []any{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}},
+		{"map with int keys", map[int]string{1: "a"}, map[string]any{"1": "a"}},
+		{"nil", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertCqlValue(tt.in))
+		})
+	}
+}
+
+func TestCqlArgMarshal(t *testing.T) {
+	native := func(typ gocql.Type) gocql.TypeInfo { return gocql.NewNativeType(4, typ, "") }
+
+	var i int
+	b, err := cqlArg{float64(42)}.MarshalCQL(native(gocql.TypeInt))
+	assert.NoError(t, err)
+	assert.NoError(t, gocql.Unmarshal(native(gocql.TypeInt), b, &i))
+	assert.Equal(t, 42, i)
+
+	var f float64
+	b, err = cqlArg{float64(2)}.MarshalCQL(native(gocql.TypeDouble))
+	assert.NoError(t, err)
+	assert.NoError(t, gocql.Unmarshal(native(gocql.TypeDouble), b, &f))
+	assert.Equal(t, 2.0, f)
+
+	var ts time.Time
+	b, err = cqlArg{"2024-05-01T12:00:00Z"}.MarshalCQL(native(gocql.TypeTimestamp))
+	assert.NoError(t, err)
+	assert.NoError(t, gocql.Unmarshal(native(gocql.TypeTimestamp), b, &ts))
+	assert.True(t, ts.Equal(time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)))
+
+	var ints []int
+	list := gocql.CollectionType{NativeType: native(gocql.TypeList).(gocql.NativeType), Elem: native(gocql.TypeInt)}
+	b, err = cqlArg{[]any{float64(1), float64(2)}}.MarshalCQL(list)
+	assert.NoError(t, err)
+	assert.NoError(t, gocql.Unmarshal(list, b, &ints))
+	assert.Equal(t, []int{1, 2}, ints)
+
+	var m map[string]int64
+	mapType := gocql.CollectionType{NativeType: native(gocql.TypeMap).(gocql.NativeType), Key: native(gocql.TypeText), Elem: native(gocql.TypeBigInt)}
+	b, err = cqlArg{map[string]any{"a": float64(3)}}.MarshalCQL(mapType)
+	assert.NoError(t, err)
+	assert.NoError(t, gocql.Unmarshal(mapType, b, &m))
+	assert.Equal(t, map[string]int64{"a": 3}, m)
+
+	_, err = cqlArg{1.5}.MarshalCQL(native(gocql.TypeInt))
+	assert.Error(t, err)
+}
+
+// fakeCqlResult is the stand-in's answer to one statement.
+type fakeCqlResult struct {
+	columns []string
+	rows    []map[string]any
+	err     error
+}
+
+// fakeCqlCall is one statement the stand-in was sent.
+type fakeCqlCall struct {
+	stmt     string
+	args     []any
+	pageSize int
+}
+
+type fakeCqlSession struct {
+	keyspace string
+	respond  func(stmt string, args []any) fakeCqlResult
+	calls    []fakeCqlCall
+	// scanned counts rows handed out, to check reads stop at the limit.
+	scanned int
+}
+
+func (s *fakeCqlSession) Iter(_ context.Context, stmt string, args []any, pageSize int) cqlIter {
+	unwrapped := make([]any, 0, len(args))
+	for _, a := range args {
+		unwrapped = append(unwrapped, a.(cqlArg).v)
+	}
+	s.calls = append(s.calls, fakeCqlCall{stmt, unwrapped, pageSize})
+	return &fakeCqlIter{res: s.respond(stmt, unwrapped), session: s}
+}
+
+func (s *fakeCqlSession) Keyspace() string { return s.keyspace }
+func (s *fakeCqlSession) Close()           {}
+
+type fakeCqlIter struct {
+	res     fakeCqlResult
+	session *fakeCqlSession
+	next    int
+}
+
+func (it *fakeCqlIter) Columns() []gocql.ColumnInfo {
+	cols := make([]gocql.ColumnInfo, 0, len(it.res.columns))
+	for _, c := range it.res.columns {
+		cols = append(cols, gocql.ColumnInfo{Name: c})
+	}
+	return cols
+}
+
+func (it *fakeCqlIter) MapScan(m map[string]any) bool {
+	if it.next >= len(it.res.rows) {
+		return false
+	}
+	for k, v := range it.res.rows[it.next] {
+		m[k] = v
+	}
+	it.next++
+	it.session.scanned++
+	return true
+}
+
+func (it *fakeCqlIter) Close() error { return it.res.err }
+
+func useFakeCassandra(t *testing.T, keyspace string, respond func(string, []any) fakeCqlResult) *fakeCqlSession {
+	t.Helper()
+	fake := &fakeCqlSession{keyspace: keyspace, respond: respond}
+	orig := openCassandra
+	openCassandra = func(string) (cqlSession, error) { return fake, nil }
+	t.Cleanup(func() { openCassandra = orig })
+	return fake
+}
+
+func TestCassandraReadStopsAtLimit(t *testing.T) {
+	limit := common.GetConfig().MaxRowLimit
+	var rows []map[string]any
+	for i := 0; i < limit+5; i++ {
+		rows = append(rows, map[string]any{"device": "d1", "seq": i})
+	}
+	fake := useFakeCassandra(t, "telemetry", func(string, []any) fakeCqlResult {
+		return fakeCqlResult{columns: []string{"device", "seq"}, rows: rows}
+	})
+
+	q := common.QueryMetadata{Conn: "cassandra://h/telemetry", QueryLine: `SELECT * FROM events WHERE device = {{.d}}`}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"d": "d1"}`), PlaceholderStylePartiQL))
+	out, err := ExecuteCassandraReadQuery(q)
+	assert.NoError(t, err)
+
+	var got []map[string]any
+	assert.NoError(t, json.Unmarshal(out, &got))
+	assert.Len(t, got, limit)
+	assert.True(t, strings.HasPrefix(string(out), `[{"device":"d1","seq":0}`), "column order is kept")
+	assert.Equal(t, limit, fake.scanned)
+	assert.Equal(t, fakeCqlCall{`SELECT * FROM events WHERE device = ?`, []any{"d1"}, limit}, fake.calls[0])
+}
+
+func TestCassandraWrite(t *testing.T) {
+	useFakeCassandra(t, "", func(stmt string, _ []any) fakeCqlResult {
+		switch {
+		case strings.Contains(stmt, "IF NOT EXISTS"):
+			return fakeCqlResult{columns: []string{"[applied]", "device"}, rows: []map[string]any{{"[applied]": false, "device": "d1"}}}
+		case strings.Contains(stmt, "missing"):
+			return fakeCqlResult{err: errors.New("unconfigured table missing")}
+		}
+		return fakeCqlResult{}
+	})
+
+	out, err := ExecuteCassandraWriteQuery(common.QueryMetadata{QueryLine: `INSERT INTO ks.events (device) VALUES ('d1')`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"applied": true}`, string(out))
+
+	out, err = ExecuteCassandraWriteQuery(common.QueryMetadata{QueryLine: `INSERT INTO ks.events (device) VALUES ('d1') IF NOT EXISTS`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"[applied]": false, "device": "d1"}]`, string(out))
+
+	_, err = ExecuteCassandraWriteQuery(common.QueryMetadata{QueryLine: `TRUNCATE missing`})
+	assert.EqualError(t, err, "unconfigured table missing: TRUNCATE missing")
+}
+
+// systemSchemaColumns answers queries on system_schema.columns.
+func systemSchemaColumns(stmt string, _ []any) fakeCqlResult {
+	rows := []map[string]any{
+		{"keyspace_name": "telemetry", "table_name": "events", "column_name": "value", "type": "double", "kind": "regular", "position": -1},
+		{"keyspace_name": "telemetry", "table_name": "events", "column_name": "at", "type": "timestamp", "kind": "clustering", "position": 0},
+		{"keyspace_name": "telemetry", "table_name": "events", "column_name": "device", "type": "text", "kind": "partition_key", "position": 0},
+		{"keyspace_name": "telemetry", "table_name": "events", "column_name": "bucket", "type": "int", "kind": "partition_key", "position": 1},
+		{"keyspace_name": "system", "table_name": "local", "column_name": "key", "type": "text", "kind": "partition_key", "position": 0},
+		{"keyspace_name": "app", "table_name": "users", "column_name": "id", "type": "uuid", "kind": "partition_key", "position": 0},
+	}
+	if strings.Contains(stmt, "WHERE") {
+		return fakeCqlResult{columns: []string{"column_name", "type", "kind", "position"}, rows: rows[:4]}
+	}
+	return fakeCqlResult{columns: []string{"keyspace_name", "table_name", "column_name", "kind", "position"}, rows: rows}
+}
+
+func TestCassandraDescribe(t *testing.T) {
+	fake := useFakeCassandra(t, "telemetry", func(stmt string, args []any) fakeCqlResult {
+		switch {
+		case strings.Contains(stmt, "system_schema.keyspaces"):
+			return fakeCqlResult{columns: []string{"keyspace_name", "replication"}, rows: []map[string]any{
+				{"keyspace_name": "telemetry", "replication": map[string]string{"class": "SimpleStrategy", "replication_factor": "1"}},
+			}}
+		case strings.Contains(stmt, "system_schema.tables"):
+			return fakeCqlResult{columns: []string{"keyspace_name", "table_name"}, rows: []map[string]any{{"keyspace_name": "telemetry", "table_name": "events"}}}
+		case len(args) == 2 && args[1] == "nope":
+			return fakeCqlResult{}
+		}
+		return systemSchemaColumns(stmt, args)
+	})
+
+	out, err := ExecuteCassandraAdminCmd(common.QueryMetadata{QueryLine: `DESCRIBE KEYSPACES`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"keyspace_name": "telemetry", "replication": {"class": "SimpleStrategy", "replication_factor": "1"}}]`, string(out))
+
+	out, err = ExecuteCassandraAdminCmd(common.QueryMetadata{QueryLine: `DESCRIBE TABLES`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"keyspace_name": "telemetry", "table_name": "events"}]`, string(out))
+	assert.Equal(t, []any{"telemetry"}, fake.calls[1].args, "listing is limited to the uri's keyspace")
+
+	out, err = ExecuteCassandraAdminCmd(common.QueryMetadata{QueryLine: `DESCRIBE TABLE events`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[
+		{"column_name": "device", "type": "text", "kind": "partition_key", "position": 0},
+		{"column_name": "bucket", "type": "int", "kind": "partition_key", "position": 1},
+		{"column_name": "at", "type": "timestamp", "kind": "clustering", "position": 0},
+		{"column_name": "value", "type": "double", "kind": "regular", "position": -1}
+	]`, string(out))
+	assert.Equal(t, []any{"telemetry", "events"}, fake.calls[2].args)
+
+	_, err = ExecuteCassandraAdminCmd(common.QueryMetadata{QueryLine: `DESCRIBE TABLE other.nope`})
+	assert.EqualError(t, err, "DESCRIBE TABLE: other.nope does not exist")
+
+	fake.keyspace = ""
+	_, err = ExecuteCassandraAdminCmd(common.QueryMetadata{QueryLine: `DESCRIBE TABLE events`})
+	assert.Error(t, err, "no keyspace to look in")
+
+	_, err = ExecuteCassandraAdminCmd(common.QueryMetadata{QueryLine: `DESCRIBE CLUSTER`})
+	assert.Error(t, err)
+}
+
+func TestIntrospectCassandra(t *testing.T) {
+	useFakeCassandra(t, "", systemSchemaColumns)
+
+	cols, err := IntrospectCassandra("cassandra://h")
+	assert.NoError(t, err)
+	assert.Equal(t, []CassandraColumn{
+		{Keyspace: "app", Table: "users", Column: "id", Kind: "partition_key", Position: 0},
+		{Keyspace: "telemetry", Table: "events", Column: "device", Kind: "partition_key", Position: 0},
+		{Keyspace: "telemetry", Table: "events", Column: "bucket", Kind: "partition_key", Position: 1},
+		{Keyspace: "telemetry", Table: "events", Column: "at", Kind: "clustering", Position: 0},
+		{Keyspace: "telemetry", Table: "events", Column: "value", Kind: "regular", Position: -1},
+	}, cols)
+}
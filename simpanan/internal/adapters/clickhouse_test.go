@@ -0,0 +1,210 @@
+package adapters
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeClickhouse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`SELECT count() FROM events`, common.Read},
+		{`WITH 1 AS x SELECT x`, common.Read},
+		{`(SELECT 1) UNION ALL (SELECT 2)`, common.Read},
+		{`EXPLAIN SELECT 1`, common.Read},
+		{`INSERT INTO events VALUES (1)`, common.Write},
+		{`ALTER TABLE events DELETE WHERE id = 1`, common.Write},
+		{`CREATE TABLE t (x UInt8) ENGINE = Memory`, common.Write},
+		{`OPTIMIZE TABLE events FINAL`, common.Write},
+		{`SHOW TABLES`, common.Admin},
+		{`describe table events`, common.Admin},
+		{`DESC events`, common.Admin},
+		{`EXISTS TABLE events`, common.Admin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeClickhouse(tt.query))
+		})
+	}
+}
+
+func TestNewClickhouseEndpoint(t *testing.T) {
+	tests := []struct {
+		name     string
+		uri      string
+		base     string
+		settings string
+		user     string
+		wantErr  bool
+	}{
+		{"default port", "clickhouse://localhost", "http://localhost:8123/",
+			"default_format=JSONEachRow&output_format_json_quote_64bit_integers=0", "", false},
+		{"database and settings", "clickhouse://app:pw@ch:9000/analytics?max_execution_time=30", "http://ch:9000/",
+			"database=analytics&default_format=JSONEachRow&max_execution_time=30&output_format_json_quote_64bit_integers=0", "app", false},
+		{"secure", "clickhouse://ch.example.com?secure=true", "https://ch.example.com:8443/",
+			"default_format=JSONEachRow&output_format_json_quote_64bit_integers=0", "", false},
+		{"missing host", "clickhouse:///db", "", "", "", true},
+		{"wrong scheme", "http://ch:8123", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := newClickhouseEndpoint(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.base, e.base.String())
+			assert.Equal(t, tt.settings, e.settings.Encode())
+			assert.Equal(t, tt.user, e.user)
+		})
+	}
+}
+
+func TestClickhouseResult(t *testing.T) {
+	tests := []struct {
+		name  string
+		body  string
+		limit int
+		want  string
+	}{
+		{"rows keep column order", "{\"b\":1,\"a\":\"x\"}\n{\"b\":18446744073709551615,\"a\":null}\n", 0,
+			`[{"b":1,"a":"x"},{"b":18446744073709551615,"a":null}]`},
+		{"limit", "{\"n\":1}\n{\"n\":2}\n{\"n\":3}\n", 2, `[{"n":1},{"n":2}]`},
+		{"empty", "", 0, `[]`},
+		{"nested", "{\"tags\":[\"a\"],\"m\":{\"k\":1}}\n", 0, `[{"tags":["a"],"m":{"k":1}}]`},
+		{"FORMAT JSON", "{\n\t\"data\": [],\n\t\"rows\": 0\n}\n", 0, "{\n\t\"data\": [],\n\t\"rows\": 0\n}\n"},
+		{"FORMAT CSV", "1,\"a\"\n", 0, `"1,\"a\"\n"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := clickhouseResult([]byte(tt.body), tt.limit)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, string(out))
+		})
+	}
+}
+
+// chRecorded is one request the stand-in was sent.
+type chRecorded struct {
+	query  string
+	params url.Values
+	user   string
+}
+
+// newClickhouseStandIn answers statements by their first word the way
+// ClickHouse's HTTP interface does.
+func newClickhouseStandIn(t *testing.T) (string, *[]chRecorded) {
+	t.Helper()
+	srv, seen := newStandIn(t, func(r *http.Request, body []byte) chRecorded {
+		return chRecorded{query: string(body), params: r.URL.Query(), user: r.Header.Get("X-ClickHouse-User")}
+	}, func(w http.ResponseWriter, r *http.Request, rec chRecorded) {
+		switch {
+		case r.Header.Get("X-ClickHouse-Key") != "pw":
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, "Code: 516. DB::Exception: app: Authentication failed. (AUTHENTICATION_FAILED)\n")
+		case strings.Contains(rec.query, "system.columns"):
+			io.WriteString(w, `{"database":"analytics","table":"events","name":"id"}`+"\n"+
+				`{"database":"analytics","table":"events","name":"at"}`+"\n"+
+				`{"database":"default","table":"t","name":"x"}`+"\n")
+		case strings.HasPrefix(rec.query, "SELECT"):
+			for i := 1; i <= 3; i++ {
+				fmt.Fprintf(w, "{\"id\":%d,\"user\":%q}\n", i, rec.params.Get("param_p1"))
+			}
+		case strings.HasPrefix(rec.query, "INSERT"):
+			w.Header().Set("X-ClickHouse-Summary", `{"read_rows":"2","read_bytes":"16","written_rows":"2","written_bytes":"16","total_rows_to_read":"0"}`)
+		case rec.query == "SHOW TABLES":
+			io.WriteString(w, "{\"name\":\"events\"}\n")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "Code: 60. DB::Exception: Table analytics.nope does not exist. (UNKNOWN_TABLE)\n")
+		}
+	})
+	return "clickhouse://app:pw@" + strings.TrimPrefix(srv.URL, "http://") + "/analytics", seen
+}
+
+func TestClickhouseAgainstStandIn(t *testing.T) {
+	conn, seen := newClickhouseStandIn(t)
+
+	q := common.QueryMetadata{Conn: conn, QueryLine: `SELECT * FROM events WHERE user = {{.user}}`}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"user": "ana"}`), PlaceholderStyleClickhouse))
+	out, err := ExecuteClickhouseReadQuery(q)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id": 1, "user": "ana"}, {"id": 2, "user": "ana"}, {"id": 3, "user": "ana"}]`, string(out))
+	got := (*seen)[0]
+	assert.Equal(t, `SELECT * FROM events WHERE user = {p1:String}`, got.query)
+	assert.Equal(t, "analytics", got.params.Get("database"))
+	assert.Equal(t, fmt.Sprint(common.GetConfig().MaxRowLimit), got.params.Get("max_result_rows"))
+	assert.Equal(t, "break", got.params.Get("result_overflow_mode"))
+	assert.Equal(t, "app", got.user)
+
+	out, err = ExecuteClickhouseWriteQuery(common.QueryMetadata{Conn: conn, QueryLine: `INSERT INTO events VALUES (4, 'bo'), (5, 'cy')`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"rows_affected": 2}`, string(out))
+	assert.Empty(t, (*seen)[1].params.Get("max_result_rows"), "writes are not limited")
+
+	out, err = ExecuteClickhouseAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: `SHOW TABLES`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name": "events"}]`, string(out))
+
+	_, err = ExecuteClickhouseAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: `DESCRIBE nope`})
+	assert.EqualError(t, err, "clickhouse: Code: 60. DB::Exception: Table analytics.nope does not exist. (UNKNOWN_TABLE)")
+
+	_, err = ExecuteClickhouseReadQuery(common.QueryMetadata{Conn: strings.Replace(conn, ":pw@", ":bad@", 1), QueryLine: `SELECT 1`})
+	assert.EqualError(t, err, "clickhouse: Code: 516. DB::Exception: app: Authentication failed. (AUTHENTICATION_FAILED)")
+}
+
+func TestIntrospectClickhouse(t *testing.T) {
+	conn, seen := newClickhouseStandIn(t)
+
+	cols, err := IntrospectClickhouse(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, []ClickhouseColumn{
+		{"analytics", "events", "id"},
+		{"analytics", "events", "at"},
+		{"default", "t", "x"},
+	}, cols)
+	assert.Contains(t, (*seen)[0].query, "NOT IN ('system', 'INFORMATION_SCHEMA', 'information_schema')")
+}
+
+func TestPlaceholderStyleClickhouse(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		payload  string
+		wantLine string
+		wantArgs []any
+	}{
+		{
+			name:     "params carry their type",
+			line:     `SELECT * FROM events WHERE user = '{{.user}}' AND id IN ({{.ids}}) AND score > {{.score}} AND ok = {{.ok}} AND meta = {{.meta}}`,
+			payload:  `{"user": "o'neil", "ids": [1, 2], "score": 0.5, "ok": true, "meta": {"v": 1}}`,
+			wantLine: `SELECT * FROM events WHERE user = {p1:String} AND id IN ({p2:Int64}, {p3:Int64}) AND score > {p4:Float64} AND ok = {p5:Bool} AND meta = {p6:String}`,
+			wantArgs: []any{"o'neil", 1, 2, 0.5, true, `{"v":1}`},
+		},
+		{
+			name:     "quoted number is a String",
+			line:     `SELECT * FROM addresses WHERE zip = '{{.zip}}' AND n = {{.zip}}`,
+			payload:  `{"zip": 10115}`,
+			wantLine: `SELECT * FROM addresses WHERE zip = {p1:String} AND n = {p2:Int64}`,
+			wantArgs: []any{10115, 10115},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: tc.line}
+			assert.NoError(t, common.PipeData(&q, []byte(tc.payload), PlaceholderStyleClickhouse))
+			assert.Equal(t, tc.wantLine, q.QueryLine)
+			assert.Equal(t, tc.wantArgs, q.Args)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"simpanan/internal/common"
+	"strconv"
+	"strings"
+)
+
+// consulClient speaks Consul's KV HTTP API (`/v1/kv/...`).
+type consulClient struct {
+	base  url.URL
+	token string
+	dc    string
+}
+
+// newConsulClient reads a `consul://host:8500?token=...&dc=...` URI.
+// The port defaults to 8500; `?secure=true` switches to HTTPS.
+func newConsulClient(uri string) (*consulClient, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid consul uri: %s", err)
+	}
+	if u.Scheme != "consul" {
+		return nil, fmt.Errorf("consul uri must use consul:// scheme, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("consul uri is missing a host: consul://localhost:8500")
+	}
+	params := u.Query()
+	c := &consulClient{base: url.URL{Scheme: "http", Host: u.Host}, token: params.Get("token"), dc: params.Get("dc")}
+	if secure, _ := strconv.ParseBool(params.Get("secure")); secure {
+		c.base.Scheme = "https"
+	}
+	if u.Port() == "" {
+		c.base.Host = u.Hostname() + ":8500"
+	}
+	return c, nil
+}
+
+// ExecuteConsulQuery runs a get, list, put or delete stage against
+// Consul's KV store.
+func ExecuteConsulQuery(q common.QueryMetadata) ([]byte, error) {
+	cmd, err := parseKvCommand(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newConsulClient(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	return executeKvCommand(c, cmd)
+}
+
+// consulKv is an entry as /v1/kv answers it, value base64 or null.
+type consulKv struct {
+	Key   string `json:"Key"`
+	Value []byte `json:"Value"`
+}
+
+func (c *consulClient) get(key string) (*kvPair, error) {
+	var kvs []consulKv
+	if found, err := c.do(http.MethodGet, key, nil, nil, &kvs); err != nil || !found || len(kvs) == 0 {
+		return nil, err
+	}
+	return &kvPair{Key: kvs[0].Key, Value: kvs[0].Value}, nil
+}
+
+// getPrefix and list cap their answer client side: the KV API has no
+// limit parameter.
+func (c *consulClient) getPrefix(prefix string, limit int) ([]kvPair, error) {
+	var kvs []consulKv
+	if _, err := c.do(http.MethodGet, prefix, url.Values{"recurse": {""}}, nil, &kvs); err != nil {
+		return nil, err
+	}
+	pairs := make([]kvPair, 0, len(kvs))
+	for _, kv := range kvs {
+		if len(pairs) == limit {
+			break
+		}
+		pairs = append(pairs, kvPair{Key: kv.Key, Value: kv.Value})
+	}
+	return pairs, nil
+}
+
+func (c *consulClient) list(prefix string, limit int) ([]string, error) {
+	keys := []string{}
+	if _, err := c.do(http.MethodGet, prefix, url.Values{"keys": {""}}, nil, &keys); err != nil {
+		return nil, err
+	}
+	if len(keys) > limit {
+		keys = keys[:limit]
+	}
+	return keys, nil
+}
+
+func (c *consulClient) put(key string, value []byte) error {
+	var ok bool
+	if _, err := c.do(http.MethodPut, key, nil, value, &ok); err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("consul: put %q was not applied", key)
+	}
+	return nil
+}
+
+// delete counts the keys it is about to remove first, since Consul's
+// DELETE only answers true.
+func (c *consulClient) delete(key string, prefix bool) (int64, error) {
+	var params url.Values
+	var n int64
+	if prefix {
+		params = url.Values{"recurse": {""}}
+		keys := []string{}
+		if _, err := c.do(http.MethodGet, key, url.Values{"keys": {""}}, nil, &keys); err != nil {
+			return 0, err
+		}
+		n = int64(len(keys))
+	} else {
+		pair, err := c.get(key)
+		if err != nil {
+			return 0, err
+		}
+		if pair != nil {
+			n = 1
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	if _, err := c.do(http.MethodDelete, key, params, nil, nil); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// do sends one KV request and decodes the answer into out. A 404, which
+// Consul returns for a missing key or an empty prefix, is reported as
+// not found rather than as an error.
+func (c *consulClient) do(method, key string, params url.Values, body []byte, out any) (bool, error) {
+	u := c.base
+	u.Path = "/v1/kv/" + key
+	u.RawPath = "/v1/kv/" + consulEscapeKey(key)
+	if c.dc != "" {
+		if params == nil {
+			params = url.Values{}
+		}
+		params.Set("dc", c.dc)
+	}
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, respBody, err := sendHttp(req)
+	if err != nil {
+		return false, fmt.Errorf("consul: %s", err)
+	}
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return false, nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		if snippet := httpSnippet(respBody); snippet != "" {
+			return false, fmt.Errorf("consul: %s", snippet)
+		}
+		return false, fmt.Errorf("consul: %s", resp.Status)
+	}
+	if out == nil {
+		return true, nil
+	}
+	return true, json.Unmarshal(respBody, out)
+}
+
+// consulEscapeKey escapes each `/`-separated segment of a key so keys
+// with spaces, `?` or `#` reach Consul intact.
+func consulEscapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
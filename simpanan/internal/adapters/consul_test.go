@@ -0,0 +1,145 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simpanan/internal/common"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewConsulClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		base    string
+		token   string
+		dc      string
+		wantErr bool
+	}{
+		{"default port", "consul://localhost", "http://localhost:8500", "", "", false},
+		{"token, dc and tls", "consul://consul.example.com:8501?token=s3cr3t&dc=eu1&secure=true",
+			"https://consul.example.com:8501", "s3cr3t", "eu1", false},
+		{"missing host", "consul:///kv", "", "", "", true},
+		{"wrong scheme", "http://localhost:8500", "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newConsulClient(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.base, got.base.String())
+			assert.Equal(t, tt.token, got.token)
+			assert.Equal(t, tt.dc, got.dc)
+		})
+	}
+}
+
+// fakeConsul is an in-memory stand-in for Consul's KV API.
+func fakeConsul(t *testing.T, store map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "s3cr3t" {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte("Permission denied"))
+			return
+		}
+		assert.Equal(t, "eu1", r.URL.Query().Get("dc"))
+		key := strings.TrimPrefix(r.URL.Path, "/v1/kv/")
+		_, recurse := r.URL.Query()["recurse"]
+		_, keysOnly := r.URL.Query()["keys"]
+		var keys []string
+		for k := range store {
+			if k == key || ((recurse || keysOnly) && strings.HasPrefix(k, key)) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		switch r.Method {
+		case http.MethodGet:
+			if len(keys) == 0 {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			if keysOnly {
+				json.NewEncoder(w).Encode(keys)
+				return
+			}
+			kvs := []consulKv{}
+			for _, k := range keys {
+				kvs = append(kvs, consulKv{Key: k, Value: []byte(store[k])})
+			}
+			json.NewEncoder(w).Encode(kvs)
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			store[key] = string(body)
+			w.Write([]byte("true"))
+		case http.MethodDelete:
+			for _, k := range keys {
+				delete(store, k)
+			}
+			w.Write([]byte("true"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestConsulAgainstFake(t *testing.T) {
+	store := map[string]string{
+		"flags/checkout":  `{"enabled": true}`,
+		"flags/new ui":    "on",
+		"service/db/host": "db.internal",
+	}
+	srv := fakeConsul(t, store)
+	conn := strings.Replace(srv.URL, "http://", "consul://", 1) + "?token=s3cr3t&dc=eu1"
+	run := func(query string) (string, error) {
+		out, err := ExecuteConsulQuery(common.QueryMetadata{Conn: conn, QueryLine: query})
+		return string(out), err
+	}
+
+	out, err := run(`get "flags/new ui"`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "flags/new ui", "value": "on"}`, out)
+
+	out, err = run(`get flags/missing`)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", out)
+
+	out, err = run(`get flags/ --prefix --limit 1`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "flags/checkout", "value": {"enabled": true}}]`, out)
+
+	out, err = run(`list nothing/`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[]`, out)
+
+	out, err = run(`list`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "flags/checkout"}, {"key": "flags/new ui"}, {"key": "service/db/host"}]`, out)
+
+	out, err = run(`put service/db/port 5432`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"applied": true}`, out)
+	assert.Equal(t, "5432", store["service/db/port"])
+
+	out, err = run(`delete flags/missing`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"deleted": 0}`, out)
+
+	out, err = run(`delete service/ --prefix`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"deleted": 2}`, out)
+	assert.Len(t, store, 2)
+
+	_, err = ExecuteConsulQuery(common.QueryMetadata{Conn: strings.Replace(srv.URL, "http://", "consul://", 1), QueryLine: `get a`})
+	assert.EqualError(t, err, "consul: Permission denied")
+}
@@ -0,0 +1,559 @@
+package adapters
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/url"
+	"simpanan/internal/common"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// dynamoTimeout caps one DynamoDB stage, introspection included.
+const dynamoTimeout = 30 * time.Second
+
+// dynamoCommand is a parsed shorthand stage:
+//
+//	scan sessions [--index by_user] [--limit 50]
+//	query sessions {"user_id": "u1"} [--index by_user] [--limit 50] [--desc]
+//	get-item sessions {"session_id": "s1"}
+//	list-tables
+//	describe-table sessions
+type dynamoCommand struct {
+	name  string
+	table string
+	// key holds the attribute values a query matches or get-item reads.
+	key   map[string]any
+	index string
+	limit int32
+	desc  bool
+}
+
+// dynamoCommands maps each shorthand to whether it takes a key object.
+var dynamoCommands = map[string]bool{
+	"scan":           false,
+	"query":          true,
+	"get-item":       true,
+	"list-tables":    false,
+	"describe-table": false,
+}
+
+// IsDynamoShorthand reports whether a stage uses the shorthand form
+// rather than PartiQL.
+func IsDynamoShorthand(query string) bool {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return false
+	}
+	_, ok := dynamoCommands[strings.ToLower(fields[0])]
+	return ok
+}
+
+func parseDynamoCommand(query string) (dynamoCommand, error) {
+	rest := strings.TrimSpace(query)
+	name, rest, _ := strings.Cut(rest, " ")
+	cmd := dynamoCommand{name: strings.ToLower(name)}
+	takesKey, ok := dynamoCommands[cmd.name]
+	if !ok {
+		return cmd, fmt.Errorf("unknown dynamodb command %q", name)
+	}
+	if cmd.name == "list-tables" {
+		if strings.TrimSpace(rest) != "" {
+			return cmd, fmt.Errorf("list-tables takes no arguments")
+		}
+		return cmd, nil
+	}
+
+	rest = strings.TrimSpace(rest)
+	cmd.table, rest, _ = strings.Cut(rest, " ")
+	if cmd.table == "" || strings.HasPrefix(cmd.table, "{") || strings.HasPrefix(cmd.table, "--") {
+		return cmd, fmt.Errorf("%s needs a table name", cmd.name)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if takesKey {
+		dec := json.NewDecoder(strings.NewReader(rest))
+		dec.UseNumber()
+		if err := dec.Decode(&cmd.key); err != nil || len(cmd.key) == 0 {
+			return cmd, fmt.Errorf("%s needs a key object such as {\"id\": \"a1\"}", cmd.name)
+		}
+		rest = rest[dec.InputOffset():]
+	}
+
+	flags := strings.Fields(rest)
+	for i := 0; i < len(flags); i++ {
+		flag := flags[i]
+		switch {
+		case flag == "--desc" && cmd.name == "query":
+			cmd.desc = true
+		case (flag == "--index" || flag == "--limit") && cmd.name != "get-item" && cmd.name != "describe-table":
+			if i+1 == len(flags) {
+				return cmd, fmt.Errorf("%s needs a value", flag)
+			}
+			i++
+			if flag == "--index" {
+				cmd.index = flags[i]
+				continue
+			}
+			n, err := strconv.ParseInt(flags[i], 10, 32)
+			if err != nil || n <= 0 {
+				return cmd, fmt.Errorf("--limit needs a positive number, got %q", flags[i])
+			}
+			cmd.limit = int32(n)
+		default:
+			return cmd, fmt.Errorf("unexpected %q in %s", flag, cmd.name)
+		}
+	}
+	return cmd, nil
+}
+
+// QueryTypeDynamodb classifies a stage by its first word: PartiQL
+// SELECT and the scan/query/get-item shorthand read, list-tables and
+// describe-table are admin, and every other statement writes.
+func QueryTypeDynamodb(query string) common.QueryType {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return common.Read
+	}
+	switch strings.ToLower(fields[0]) {
+	case "select", "scan", "query", "get-item":
+		return common.Read
+	case "list-tables", "describe-table":
+		return common.Admin
+	}
+	return common.Write
+}
+
+// PlaceholderStylePartiQL binds placeholders as `?` parameters,
+// keeping each value's JSON shape so objects and arrays are sent as
+// typed values. DynamoDB PartiQL and CQL share it.
+var PlaceholderStylePartiQL common.PlaceholderStyle = common.BindStyle{
+	Rules:  common.LiteralRules{DoubledEscapes: true, SQLComments: true},
+	Quotes: "'",
+	Bind: func(q *common.QueryMetadata, value any, _ bool) string {
+		return common.ExpandParams(value, func(v any) string {
+			q.Args = append(q.Args, v)
+			return "?"
+		})
+	},
+}
+
+// newDynamoClient reads a `dynamodb://<region>` URI. Optional parts:
+// `?endpoint=http://localhost:8000` for DynamoDB Local, `?profile=` for
+// a shared-config profile and `key:secret@` userinfo for static
+// credentials; otherwise the default AWS credential chain applies.
+func newDynamoClient(ctx context.Context, uri string) (*dynamodb.Client, string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid dynamodb uri: %s", err)
+	}
+	if u.Scheme != "dynamodb" {
+		return nil, "", fmt.Errorf("dynamodb uri must use dynamodb:// scheme, got %q", u.Scheme)
+	}
+	region := u.Hostname()
+	if region == "" {
+		return nil, "", fmt.Errorf("dynamodb uri is missing the region: dynamodb://us-east-1")
+	}
+
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+	if profile := u.Query().Get("profile"); profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(profile))
+	}
+	if u.User != nil {
+		secret, _ := u.User.Password()
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(u.User.Username(), secret, "")))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, "", err
+	}
+	endpoint := u.Query().Get("endpoint")
+	client := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+	return client, region, nil
+}
+
+// ExecuteDynamodbReadQuery runs a PartiQL SELECT or a scan, query or
+// get-item shorthand. Items come back as plain JSON objects, at most
+// MaxRowLimit of them unless the stage sets --limit; get-item answers
+// with the item itself, or null.
+func ExecuteDynamodbReadQuery(q common.QueryMetadata) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoTimeout)
+	defer cancel()
+	client, _, err := newDynamoClient(ctx, q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	if !IsDynamoShorthand(q.QueryLine) {
+		return executeDynamoStatement(ctx, client, q)
+	}
+
+	cmd, err := parseDynamoCommand(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	limit := aws.Int32(int32(common.GetConfig().MaxRowLimit))
+	if cmd.limit > 0 {
+		limit = aws.Int32(cmd.limit)
+	}
+	var index *string
+	if cmd.index != "" {
+		index = aws.String(cmd.index)
+	}
+
+	switch cmd.name {
+	case "scan":
+		out, err := client.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String(cmd.table), IndexName: index, Limit: limit})
+		if err != nil {
+			return nil, dynamoError(err)
+		}
+		return marshalDynamoItems(out.Items)
+
+	case "query":
+		names := map[string]string{}
+		values := map[string]types.AttributeValue{}
+		var conds []string
+		for i, attr := range sortedKeys(cmd.key) {
+			av, err := toAttributeValue(cmd.key[attr])
+			if err != nil {
+				return nil, err
+			}
+			names[fmt.Sprintf("#k%d", i)] = attr
+			values[fmt.Sprintf(":v%d", i)] = av
+			conds = append(conds, fmt.Sprintf("#k%d = :v%d", i, i))
+		}
+		out, err := client.Query(ctx, &dynamodb.QueryInput{
+			TableName:                 aws.String(cmd.table),
+			IndexName:                 index,
+			KeyConditionExpression:    aws.String(strings.Join(conds, " AND ")),
+			ExpressionAttributeNames:  names,
+			ExpressionAttributeValues: values,
+			ScanIndexForward:          aws.Bool(!cmd.desc),
+			Limit:                     limit,
+		})
+		if err != nil {
+			return nil, dynamoError(err)
+		}
+		return marshalDynamoItems(out.Items)
+
+	case "get-item":
+		key := map[string]types.AttributeValue{}
+		for attr, v := range cmd.key {
+			av, err := toAttributeValue(v)
+			if err != nil {
+				return nil, err
+			}
+			key[attr] = av
+		}
+		out, err := client.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String(cmd.table), Key: key})
+		if err != nil {
+			return nil, dynamoError(err)
+		}
+		if out.Item == nil {
+			return []byte("null"), nil
+		}
+		return json.Marshal(fromAttributeMap(out.Item))
+	}
+	return nil, fmt.Errorf("%s is not a read", cmd.name)
+}
+
+// ExecuteDynamodbWriteQuery runs a PartiQL INSERT, UPDATE or DELETE and
+// returns the items the statement gave back (`RETURNING ALL OLD *`),
+// usually none.
+func ExecuteDynamodbWriteQuery(q common.QueryMetadata) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoTimeout)
+	defer cancel()
+	client, _, err := newDynamoClient(ctx, q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	return executeDynamoStatement(ctx, client, q)
+}
+
+// ExecuteDynamodbAdminCmd answers list-tables with one row per table and
+// describe-table with one row per key attribute of the table and of
+// each of its indexes.
+func ExecuteDynamodbAdminCmd(q common.QueryMetadata) ([]byte, error) {
+	cmd, err := parseDynamoCommand(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoTimeout)
+	defer cancel()
+	client, _, err := newDynamoClient(ctx, q.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	switch cmd.name {
+	case "list-tables":
+		names, err := listDynamoTables(ctx, client)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]dynamoTableRow, 0, len(names))
+		for _, n := range names {
+			rows = append(rows, dynamoTableRow{n})
+		}
+		return json.Marshal(rows)
+	case "describe-table":
+		keys, err := describeDynamoKeys(ctx, client, cmd.table)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(keys)
+	}
+	return nil, fmt.Errorf("%s is not an admin command", cmd.name)
+}
+
+// DynamodbTable is a table and its key attributes, table keys first,
+// for the schema cache.
+type DynamodbTable struct {
+	Name       string
+	Attributes []string
+}
+
+// IntrospectDynamodb returns the URI's region and every table with its
+// key attributes. Non-key attributes are schemaless and not listed.
+func IntrospectDynamodb(uri string) (string, []DynamodbTable, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoTimeout)
+	defer cancel()
+	client, region, err := newDynamoClient(ctx, uri)
+	if err != nil {
+		return "", nil, err
+	}
+	names, err := listDynamoTables(ctx, client)
+	if err != nil {
+		return "", nil, err
+	}
+
+	tables := make([]DynamodbTable, 0, len(names))
+	for _, name := range names {
+		keys, err := describeDynamoKeys(ctx, client, name)
+		if err != nil {
+			return "", nil, err
+		}
+		table := DynamodbTable{Name: name}
+		seen := map[string]bool{}
+		for _, k := range keys {
+			if !seen[k.Attribute] {
+				seen[k.Attribute] = true
+				table.Attributes = append(table.Attributes, k.Attribute)
+			}
+		}
+		tables = append(tables, table)
+	}
+	return region, tables, nil
+}
+
+type dynamoTableRow struct {
+	TableName string `json:"table_name"`
+}
+
+// dynamoKeyRow is one key attribute of a table (Index empty) or of one
+// of its secondary indexes.
+type dynamoKeyRow struct {
+	Index     string `json:"index"`
+	Attribute string `json:"attribute"`
+	KeyType   string `json:"key_type"`
+	Type      string `json:"type"`
+}
+
+func executeDynamoStatement(ctx context.Context, client *dynamodb.Client, q common.QueryMetadata) ([]byte, error) {
+	params := make([]types.AttributeValue, 0, len(q.Args))
+	for _, arg := range q.Args {
+		av, err := toAttributeValue(arg)
+		if err != nil {
+			return nil, err
+		}
+		params = append(params, av)
+	}
+	in := &dynamodb.ExecuteStatementInput{Statement: aws.String(q.QueryLine)}
+	if len(params) > 0 {
+		in.Parameters = params
+	}
+	if QueryTypeDynamodb(q.QueryLine) == common.Read {
+		in.Limit = aws.Int32(int32(common.GetConfig().MaxRowLimit))
+	}
+	out, err := client.ExecuteStatement(ctx, in)
+	if err != nil {
+		return nil, dynamoError(err)
+	}
+	return marshalDynamoItems(out.Items)
+}
+
+func listDynamoTables(ctx context.Context, client *dynamodb.Client) ([]string, error) {
+	var names []string
+	pages := dynamodb.NewListTablesPaginator(client, &dynamodb.ListTablesInput{})
+	for pages.HasMorePages() {
+		page, err := pages.NextPage(ctx)
+		if err != nil {
+			return nil, dynamoError(err)
+		}
+		names = append(names, page.TableNames...)
+	}
+	return names, nil
+}
+
+func describeDynamoKeys(ctx context.Context, client *dynamodb.Client, table string) ([]dynamoKeyRow, error) {
+	out, err := client.DescribeTable(ctx, &dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return nil, dynamoError(err)
+	}
+	desc := out.Table
+	attrTypes := map[string]string{}
+	for _, def := range desc.AttributeDefinitions {
+		attrTypes[aws.ToString(def.AttributeName)] = string(def.AttributeType)
+	}
+
+	rows := []dynamoKeyRow{}
+	add := func(index string, schema []types.KeySchemaElement) {
+		for _, k := range schema {
+			name := aws.ToString(k.AttributeName)
+			rows = append(rows, dynamoKeyRow{Index: index, Attribute: name, KeyType: string(k.KeyType), Type: attrTypes[name]})
+		}
+	}
+	add("", desc.KeySchema)
+	for _, idx := range desc.GlobalSecondaryIndexes {
+		add(aws.ToString(idx.IndexName), idx.KeySchema)
+	}
+	for _, idx := range desc.LocalSecondaryIndexes {
+		add(aws.ToString(idx.IndexName), idx.KeySchema)
+	}
+	return rows, nil
+}
+
+// dynamoError trims the SDK's operation wrapping down to the service's
+// own error code and message.
+func dynamoError(err error) error {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return fmt.Errorf("dynamodb: %s: %s", apiErr.ErrorCode(), apiErr.ErrorMessage())
+	}
+	return err
+}
+
+func marshalDynamoItems(items []map[string]types.AttributeValue) ([]byte, error) {
+	out := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		out = append(out, fromAttributeMap(item))
+	}
+	return json.Marshal(out)
+}
+
+func fromAttributeMap(m map[string]types.AttributeValue) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = fromAttributeValue(v)
+	}
+	return out
+}
+
+// fromAttributeValue unwraps DynamoDB's typed values into plain JSON.
+// Numbers keep their exact digits, sets become arrays and binary values
+// base64 strings.
+func fromAttributeValue(av types.AttributeValue) any {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return json.Number(v.Value)
+	case *types.AttributeValueMemberBOOL:
+		return v.Value
+	case *types.AttributeValueMemberNULL:
+		return nil
+	case *types.AttributeValueMemberB:
+		return base64.StdEncoding.EncodeToString(v.Value)
+	case *types.AttributeValueMemberM:
+		return fromAttributeMap(v.Value)
+	case *types.AttributeValueMemberL:
+		out := make([]any, 0, len(v.Value))
+		for _, el := range v.Value {
+			out = append(out, fromAttributeValue(el))
+		}
+		return out
+	case *types.AttributeValueMemberSS:
+		return v.Value
+	case *types.AttributeValueMemberNS:
+		out := make([]json.Number, 0, len(v.Value))
+		for _, n := range v.Value {
+			out = append(out, json.Number(n))
+		}
+		return out
+	case *types.AttributeValueMemberBS:
+		out := make([]string, 0, len(v.Value))
+		for _, b := range v.Value {
+			out = append(out, base64.StdEncoding.EncodeToString(b))
+		}
+		return out
+	}
+	return nil
+}
+
+// toAttributeValue types a JSON value (a piped placeholder or part of a
+// shorthand key object) for DynamoDB. Arrays become lists, not sets.
+func toAttributeValue(v any) (types.AttributeValue, error) {
+	switch val := v.(type) {
+	case nil:
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case string:
+		return &types.AttributeValueMemberS{Value: val}, nil
+	case bool:
+		return &types.AttributeValueMemberBOOL{Value: val}, nil
+	case json.Number:
+		return &types.AttributeValueMemberN{Value: val.String()}, nil
+	case float64:
+		return &types.AttributeValueMemberN{Value: strconv.FormatFloat(val, 'f', -1, 64)}, nil
+	case int:
+		return &types.AttributeValueMemberN{Value: strconv.Itoa(val)}, nil
+	case *big.Int:
+		return &types.AttributeValueMemberN{Value: val.String()}, nil
+	case map[string]any:
+		m := make(map[string]types.AttributeValue, len(val))
+		for k, el := range val {
+			av, err := toAttributeValue(el)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = av
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	case []any:
+		l := make([]types.AttributeValue, 0, len(val))
+		for _, el := range val {
+			av, err := toAttributeValue(el)
+			if err != nil {
+				return nil, err
+			}
+			l = append(l, av)
+		}
+		return &types.AttributeValueMemberL{Value: l}, nil
+	}
+	return nil, fmt.Errorf("dynamodb: cannot send %T as an attribute value", v)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,450 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"simpanan/internal/common"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// esReadEndpoints are the `_`-endpoints that only read even when
+// called with POST, which searches usually are.
+var esReadEndpoints = map[string]bool{
+	"_search": true, "_msearch": true, "_count": true,
+	"_mget": true, "_explain": true, "_validate": true,
+	"_field_caps": true, "_termvectors": true, "_mtermvectors": true,
+	"_analyze": true, "_rank_eval": true, "_sql": true,
+}
+
+// esClient talks to the cluster behind an `es://` or `opensearch://`
+// URI.
+type esClient struct {
+	base   url.URL
+	apiKey string
+}
+
+// newEsClient converts `es://user:pass@host:9200` into the cluster's
+// HTTP base URL. `?tls=true` switches to https and `?api_key=...` sends
+// an ApiKey Authorization header instead of basic auth.
+func newEsClient(uri string) (*esClient, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid elasticsearch uri: %s", err)
+	}
+	if u.Scheme != "es" && u.Scheme != "opensearch" {
+		return nil, fmt.Errorf("elasticsearch uri must use es:// or opensearch:// scheme, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("elasticsearch uri is missing host")
+	}
+
+	params := u.Query()
+	c := &esClient{
+		base:   url.URL{Scheme: "http", Host: u.Host, User: u.User, Path: strings.TrimSuffix(u.Path, "/")},
+		apiKey: params.Get("api_key"),
+	}
+	if tls, _ := strconv.ParseBool(params.Get("tls")); tls {
+		c.base.Scheme = "https"
+	}
+	return c, nil
+}
+
+// esRequest is a parsed Elasticsearch stage.
+type esRequest struct {
+	method   string
+	path     string
+	query    url.Values
+	body     string
+	endpoint string
+}
+
+func parseEsRequest(line string) (esRequest, error) {
+	stage := common.ParseHTTPStage(line)
+	if stage.Target == "" {
+		return esRequest{}, fmt.Errorf("elasticsearch stage is missing a request path: %q", line)
+	}
+	path, rawQuery, _ := strings.Cut(stage.Target, "?")
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return esRequest{}, fmt.Errorf("invalid query string in %q: %s", stage.Target, err)
+	}
+	r := esRequest{
+		method: stage.Method,
+		path:   "/" + strings.TrimPrefix(path, "/"),
+		query:  query,
+		body:   stage.Body,
+	}
+	if r.method == "" {
+		r.method = http.MethodGet
+	}
+	// The endpoint is the first `_`-segment: `_search` for both
+	// `products/_search` and `_search/scroll`.
+	for _, seg := range strings.Split(path, "/") {
+		if strings.HasPrefix(seg, "_") {
+			r.endpoint = seg
+			break
+		}
+	}
+	return r, nil
+}
+
+// QueryTypeElasticsearch classifies a stage by HTTP method and
+// endpoint: `_cat/...` and reading `_mapping` are admin; GET, HEAD and
+// searches sent with POST are reads; everything else writes.
+func QueryTypeElasticsearch(query string) common.QueryType {
+	r, err := parseEsRequest(query)
+	if err != nil {
+		return common.Read
+	}
+	readMethod := r.method == http.MethodGet || r.method == http.MethodHead
+	switch {
+	case r.endpoint == "_cat":
+		return common.Admin
+	case (r.endpoint == "_mapping" || r.endpoint == "_mappings") && readMethod:
+		return common.Admin
+	case readMethod:
+		return common.Read
+	case r.method == http.MethodPost && esReadEndpoints[r.endpoint]:
+		return common.Read
+	}
+	return common.Write
+}
+
+// ExecuteElasticsearchReadQuery runs a read stage. A search answers with
+// its hits, capped at MaxRowLimit unless the stage sets `size` itself;
+// when the search asked for aggregations the result is an object holding
+// both. Other reads return the response body as-is.
+func ExecuteElasticsearchReadQuery(q common.QueryMetadata) ([]byte, error) {
+	c, err := newEsClient(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseEsRequest(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.endpoint != "_search" || strings.Contains(r.path, "/_search/") {
+		return c.do(r)
+	}
+	if !r.query.Has("size") && !r.query.Has("scroll") && !esBodyHasKey(r.body, "size") {
+		r.query.Set("size", strconv.Itoa(common.GetConfig().MaxRowLimit))
+	}
+	resp, err := c.do(r)
+	if err != nil {
+		return nil, err
+	}
+	return esSearchResult(resp)
+}
+
+// ExecuteElasticsearchWriteQuery runs a write stage and returns the
+// cluster's response. `_bulk` bodies may be written as a sequence of
+// JSON objects on any number of lines; they are sent as the newline
+// delimited JSON the endpoint expects.
+func ExecuteElasticsearchWriteQuery(q common.QueryMetadata) ([]byte, error) {
+	c, err := newEsClient(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseEsRequest(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(r)
+}
+
+// ExecuteElasticsearchAdminCmd answers the admin stages:
+//
+//	_cat/<api> [?params]     the cat API's rows as JSON (format=json)
+//	GET [index/]_mapping     [{"index", "field", "type"}] with dotted
+//	                         paths for nested properties and multi-fields
+func ExecuteElasticsearchAdminCmd(q common.QueryMetadata) ([]byte, error) {
+	c, err := newEsClient(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	r, err := parseEsRequest(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+
+	switch r.endpoint {
+	case "_cat":
+		if !r.query.Has("format") {
+			r.query.Set("format", "json")
+		}
+		return c.do(r)
+	case "_mapping", "_mappings":
+		resp, err := c.do(r)
+		if err != nil {
+			return nil, err
+		}
+		indices, err := esMappingFields(resp)
+		if err != nil {
+			return nil, err
+		}
+		type fieldRow struct {
+			Index string `json:"index"`
+			Field string `json:"field"`
+			Type  string `json:"type"`
+		}
+		rows := []fieldRow{}
+		for _, idx := range indices {
+			for _, f := range idx.fields {
+				rows = append(rows, fieldRow{idx.name, f.path, f.typ})
+			}
+		}
+		return json.Marshal(rows)
+	}
+	return nil, fmt.Errorf("ExecuteElasticsearchAdminCmd: unsupported command %s.", q.QueryLine)
+}
+
+// ElasticsearchIndex is one index and its field paths, for schema
+// introspection.
+type ElasticsearchIndex struct {
+	Name   string
+	Fields []string
+}
+
+// IntrospectElasticsearch returns the cluster name and the fields of
+// every index not hidden behind a leading dot.
+func IntrospectElasticsearch(uri string) (string, []ElasticsearchIndex, error) {
+	c, err := newEsClient(uri)
+	if err != nil {
+		return "", nil, err
+	}
+
+	info, err := c.do(esRequest{method: http.MethodGet, path: "/"})
+	if err != nil {
+		return "", nil, err
+	}
+	var cluster struct {
+		ClusterName string `json:"cluster_name"`
+	}
+	if err := json.Unmarshal(info, &cluster); err != nil {
+		return "", nil, err
+	}
+
+	resp, err := c.do(esRequest{method: http.MethodGet, path: "/_mapping"})
+	if err != nil {
+		return "", nil, err
+	}
+	mapped, err := esMappingFields(resp)
+	if err != nil {
+		return "", nil, err
+	}
+	var indices []ElasticsearchIndex
+	for _, idx := range mapped {
+		if strings.HasPrefix(idx.name, ".") {
+			continue
+		}
+		fields := make([]string, 0, len(idx.fields))
+		for _, f := range idx.fields {
+			fields = append(fields, f.path)
+		}
+		indices = append(indices, ElasticsearchIndex{Name: idx.name, Fields: fields})
+	}
+	return cluster.ClusterName, indices, nil
+}
+
+// do sends r through sendHttp and returns the response body, turning a
+// non-2xx status into an error carrying the cluster's own reason.
+func (c *esClient) do(r esRequest) ([]byte, error) {
+	u := c.base
+	u.Path += r.path
+	if r.query == nil {
+		r.query = url.Values{}
+	}
+	u.RawQuery = r.query.Encode()
+
+	var body io.Reader
+	contentType := "application/json"
+	if r.body != "" {
+		payload := []byte(r.body)
+		if r.endpoint == "_bulk" || r.endpoint == "_msearch" {
+			var err error
+			if payload, err = toNDJSON(r.body); err != nil {
+				return nil, fmt.Errorf("%s body: %s", r.endpoint, err)
+			}
+			contentType = "application/x-ndjson"
+		}
+		body = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequest(r.method, u.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.apiKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+c.apiKey)
+	}
+
+	resp, respBody, err := sendHttp(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, esError(resp.StatusCode, respBody)
+	}
+	if r.method == http.MethodHead || len(bytes.TrimSpace(respBody)) == 0 {
+		// HEAD answers with the status alone: the document or index
+		// exists.
+		return json.Marshal(struct {
+			Status int `json:"status"`
+		}{resp.StatusCode})
+	}
+	return respBody, nil
+}
+
+// esError renders an error response, e.g.
+// "elasticsearch: 404 index_not_found_exception: no such index [x]".
+func esError(status int, body []byte) error {
+	var e struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(body, &e) == nil && len(e.Error) > 0 {
+		var cause struct {
+			Type   string `json:"type"`
+			Reason string `json:"reason"`
+		}
+		if json.Unmarshal(e.Error, &cause) == nil && cause.Type != "" {
+			return fmt.Errorf("elasticsearch: %d %s: %s", status, cause.Type, cause.Reason)
+		}
+		var reason string
+		if json.Unmarshal(e.Error, &reason) == nil {
+			return fmt.Errorf("elasticsearch: %d %s", status, reason)
+		}
+	}
+	return fmt.Errorf("elasticsearch: %d %s", status, httpSnippet(body))
+}
+
+// esSearchResult picks the hits out of a search response, together
+// with the aggregations when there are any.
+func esSearchResult(resp []byte) ([]byte, error) {
+	var sr struct {
+		Hits struct {
+			Hits []json.RawMessage `json:"hits"`
+		} `json:"hits"`
+		Aggregations json.RawMessage `json:"aggregations"`
+	}
+	if err := json.Unmarshal(resp, &sr); err != nil {
+		return nil, err
+	}
+	hits := sr.Hits.Hits
+	if hits == nil {
+		hits = []json.RawMessage{}
+	}
+	if len(sr.Aggregations) == 0 {
+		return json.Marshal(hits)
+	}
+	return json.Marshal(struct {
+		Hits         []json.RawMessage `json:"hits"`
+		Aggregations json.RawMessage   `json:"aggregations"`
+	}{hits, sr.Aggregations})
+}
+
+// esBodyHasKey reports whether the body is a JSON object with the
+// given top-level key.
+func esBodyHasKey(body, key string) bool {
+	var obj map[string]json.RawMessage
+	if json.Unmarshal([]byte(body), &obj) != nil {
+		return false
+	}
+	_, ok := obj[key]
+	return ok
+}
+
+// toNDJSON re-encodes a sequence of JSON values, however they are
+// spread over lines, as one compact value per line.
+func toNDJSON(body string) ([]byte, error) {
+	dec := json.NewDecoder(strings.NewReader(body))
+	var out bytes.Buffer
+	for {
+		var v json.RawMessage
+		if err := dec.Decode(&v); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if err := json.Compact(&out, v); err != nil {
+			return nil, err
+		}
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+type esField struct {
+	path string
+	typ  string
+}
+
+type esIndexFields struct {
+	name   string
+	fields []esField
+}
+
+// esMappingFields flattens a `_mapping` response into the field paths
+// of each index, sorted by index and path. Object properties nest with
+// dots (`address.city`), as do multi-fields (`title.keyword`).
+func esMappingFields(resp []byte) ([]esIndexFields, error) {
+	var indices map[string]struct {
+		Mappings json.RawMessage `json:"mappings"`
+	}
+	if err := json.Unmarshal(resp, &indices); err != nil {
+		return nil, fmt.Errorf("unexpected _mapping response: %s", err)
+	}
+
+	names := make([]string, 0, len(indices))
+	for name := range indices {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := make([]esIndexFields, 0, len(names))
+	for _, name := range names {
+		var m esMapping
+		if len(indices[name].Mappings) > 0 {
+			if err := json.Unmarshal(indices[name].Mappings, &m); err != nil {
+				return nil, fmt.Errorf("unexpected mapping of %s: %s", name, err)
+			}
+		}
+		idx := esIndexFields{name: name}
+		m.collect("", &idx.fields)
+		sort.Slice(idx.fields, func(i, j int) bool { return idx.fields[i].path < idx.fields[j].path })
+		out = append(out, idx)
+	}
+	return out, nil
+}
+
+// esMapping is one level of a mapping: the root, an object field or a
+// leaf field with its multi-fields.
+type esMapping struct {
+	Type       string               `json:"type"`
+	Properties map[string]esMapping `json:"properties"`
+	Fields     map[string]esMapping `json:"fields"`
+}
+
+func (m esMapping) collect(prefix string, out *[]esField) {
+	for name, child := range m.Properties {
+		path := prefix + name
+		typ := child.Type
+		if typ == "" {
+			typ = "object"
+		}
+		*out = append(*out, esField{path, typ})
+		child.collect(path+".", out)
+	}
+	for name, sub := range m.Fields {
+		*out = append(*out, esField{prefix + name, sub.Type})
+	}
+}
@@ -0,0 +1,196 @@
+package adapters
+
+import (
+	"io"
+	"net/http"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeElasticsearch(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`GET products/_search {"query": {"match_all": {}}}`, common.Read},
+		{`POST products/_search {"query": {"match_all": {}}}`, common.Read},
+		{`POST products/_count`, common.Read},
+		{`POST _mget {"docs": []}`, common.Read},
+		{`GET products/_doc/1`, common.Read},
+		{`HEAD products`, common.Read},
+		{`products/_search`, common.Read},
+		{`PUT products/_doc/1 {"name": "a"}`, common.Write},
+		{`POST products/_doc {"name": "a"}`, common.Write},
+		{`POST products/_update/1 {"doc": {}}`, common.Write},
+		{`DELETE products`, common.Write},
+		{`POST _bulk {"index": {}}`, common.Write},
+		{`PUT products/_mapping {"properties": {}}`, common.Write},
+		{`_cat/indices`, common.Admin},
+		{`GET _cat/indices?v`, common.Admin},
+		{`GET products/_mapping`, common.Admin},
+		{`GET _mapping`, common.Admin},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeElasticsearch(tt.query))
+		})
+	}
+}
+
+func TestNewEsClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    string
+		apiKey  string
+		wantErr bool
+	}{
+		{"plain", "es://localhost:9200", "http://localhost:9200", "", false},
+		{"opensearch with credentials", "opensearch://admin:pw@search:9200", "http://admin:pw@search:9200", "", false},
+		{"tls", "es://search.example.com:443?tls=true", "https://search.example.com:443", "", false},
+		{"api key", "es://h:9200?api_key=abc", "http://h:9200", "abc", false},
+		{"path prefix", "es://h/proxy/", "http://h/proxy", "", false},
+		{"missing host", "es://", "", "", true},
+		{"wrong scheme", "http://h:9200", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := newEsClient(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, c.base.String())
+			assert.Equal(t, tt.apiKey, c.apiKey)
+		})
+	}
+}
+
+func TestEsMappingFields(t *testing.T) {
+	got, err := esMappingFields([]byte(`{
+		"products": {"mappings": {"properties": {
+			"title": {"type": "text", "fields": {"keyword": {"type": "keyword"}}},
+			"price": {"type": "scaled_float"},
+			"vendor": {"properties": {"name": {"type": "keyword"}}}
+		}}},
+		"empty": {"mappings": {}}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []esIndexFields{
+		{name: "empty"},
+		{name: "products", fields: []esField{
+			{"price", "scaled_float"},
+			{"title", "text"},
+			{"title.keyword", "keyword"},
+			{"vendor", "object"},
+			{"vendor.name", "keyword"},
+		}},
+	}, got)
+}
+
+func TestToNDJSON(t *testing.T) {
+	got, err := toNDJSON("{\"index\": {\"_id\": 1}} {\"name\":\n \"a\"}\n{\"delete\": {\"_id\": 2}}")
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"index\":{\"_id\":1}}\n{\"name\":\"a\"}\n{\"delete\":{\"_id\":2}}\n", string(got))
+
+	_, err = toNDJSON(`{"index": `)
+	assert.Error(t, err)
+}
+
+func TestEsError(t *testing.T) {
+	assert.EqualError(t, esError(400, []byte(`{"error": {"type": "parsing_exception", "reason": "bad query"}}`)), "elasticsearch: 400 parsing_exception: bad query")
+	assert.EqualError(t, esError(401, []byte(`{"error": "no auth"}`)), "elasticsearch: 401 no auth")
+	// A proxy's HTML page is quoted, cut short.
+	page := "<html>" + strings.Repeat("x", httpErrorSnippet) + "</html>"
+	assert.EqualError(t, esError(502, []byte(page)), "elasticsearch: 502 "+page[:httpErrorSnippet]+"...")
+}
+
+// esRecorded is one request seen by the httptest stand-in.
+type esRecorded struct {
+	method, path, query, contentType, auth, body string
+}
+
+func newEsStandIn(t *testing.T, routes map[string]string) (string, *[]esRecorded) {
+	t.Helper()
+	srv, seen := newStandIn(t, func(r *http.Request, body []byte) esRecorded {
+		return esRecorded{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), string(body)}
+	}, func(w http.ResponseWriter, _ *http.Request, rec esRecorded) {
+		resp, ok := routes[rec.method+" "+rec.path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"error": {"type": "index_not_found_exception", "reason": "no such index [missing]"}, "status": 404}`)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, resp)
+	})
+	return "es://" + strings.TrimPrefix(srv.URL, "http://"), seen
+}
+
+func TestElasticsearchAgainstStandIn(t *testing.T) {
+	conn, seen := newEsStandIn(t, map[string]string{
+		"POST /products/_search": `{"took": 1, "hits": {"total": {"value": 1}, "hits": [{"_index": "products", "_id": "1", "_score": 1.0, "_source": {"name": "lamp", "price": 12345678901234567890}}]}}`,
+		"GET /products/_search":  `{"hits": {"hits": []}, "aggregations": {"brands": {"buckets": [{"key": "acme", "doc_count": 3}]}}}`,
+		"POST /_bulk":            `{"errors": false, "items": []}`,
+		"GET /_cat/indices":      `[{"index": "products", "docs.count": "3"}]`,
+		"GET /products/_mapping": `{"products": {"mappings": {"properties": {"name": {"type": "keyword"}}}}}`,
+	})
+
+	out, err := ExecuteElasticsearchReadQuery(common.QueryMetadata{Conn: conn, QueryLine: `POST products/_search {"query": {"match": {"name": "lamp"}}}`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"_index": "products", "_id": "1", "_score": 1.0, "_source": {"name": "lamp", "price": 12345678901234567890}}]`, string(out))
+	assert.Contains(t, string(out), "12345678901234567890")
+	assert.Equal(t, esRecorded{"POST", "/products/_search", "size=20", "application/json", "", `{"query": {"match": {"name": "lamp"}}}`}, (*seen)[0])
+
+	out, err = ExecuteElasticsearchReadQuery(common.QueryMetadata{Conn: conn, QueryLine: `GET products/_search {"size": 0, "aggs": {"brands": {"terms": {"field": "brand"}}}}`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"hits": [], "aggregations": {"brands": {"buckets": [{"key": "acme", "doc_count": 3}]}}}`, string(out))
+	assert.Equal(t, "", (*seen)[1].query, "size in the body is left alone")
+
+	out, err = ExecuteElasticsearchWriteQuery(common.QueryMetadata{Conn: conn, QueryLine: "POST _bulk\n{\"index\": {\"_index\": \"products\"}}\n{\"name\": \"desk\"}"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"errors": false, "items": []}`, string(out))
+	assert.Equal(t, "application/x-ndjson", (*seen)[2].contentType)
+	assert.Equal(t, "{\"index\":{\"_index\":\"products\"}}\n{\"name\":\"desk\"}\n", (*seen)[2].body)
+
+	out, err = ExecuteElasticsearchAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: `_cat/indices`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"index": "products", "docs.count": "3"}]`, string(out))
+	assert.Equal(t, "format=json", (*seen)[3].query)
+
+	out, err = ExecuteElasticsearchAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: `GET products/_mapping`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"index": "products", "field": "name", "type": "keyword"}]`, string(out))
+
+	_, err = ExecuteElasticsearchReadQuery(common.QueryMetadata{Conn: conn, QueryLine: `GET missing/_doc/1`})
+	assert.EqualError(t, err, "elasticsearch: 404 index_not_found_exception: no such index [missing]")
+}
+
+func TestElasticsearchSendsCredentials(t *testing.T) {
+	conn, seen := newEsStandIn(t, map[string]string{"GET /products/_doc/1": `{"found": true}`})
+	host := strings.TrimPrefix(conn, "es://")
+
+	_, err := ExecuteElasticsearchReadQuery(common.QueryMetadata{Conn: "es://elastic:pw@" + host, QueryLine: `GET products/_doc/1`})
+	assert.NoError(t, err)
+	assert.Equal(t, "Basic ZWxhc3RpYzpwdw==", (*seen)[0].auth)
+
+	_, err = ExecuteElasticsearchReadQuery(common.QueryMetadata{Conn: "opensearch://" + host + "?api_key=k3y", QueryLine: `GET products/_doc/1`})
+	assert.NoError(t, err)
+	assert.Equal(t, "ApiKey k3y", (*seen)[1].auth)
+}
+
+func TestIntrospectElasticsearch(t *testing.T) {
+	conn, _ := newEsStandIn(t, map[string]string{
+		"GET /":         `{"cluster_name": "search-dev"}`,
+		"GET /_mapping": `{".kibana": {"mappings": {"properties": {"x": {"type": "keyword"}}}}, "products": {"mappings": {"properties": {"name": {"type": "text"}, "tags": {"type": "keyword"}}}}}`,
+	})
+
+	cluster, indices, err := IntrospectElasticsearch(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, "search-dev", cluster)
+	assert.Equal(t, []ElasticsearchIndex{{Name: "products", Fields: []string{"name", "tags"}}}, indices)
+}
@@ -0,0 +1,163 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"simpanan/internal/common"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEtcdClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    etcdClient
+		wantErr bool
+	}{
+		{"default port", "etcd://localhost", etcdClient{endpoints: []string{"http://localhost:2379"}}, false},
+		{"cluster with auth", "etcd://app:pw@e1:2379,e2?secure=true",
+			etcdClient{endpoints: []string{"https://e1:2379", "https://e2:2379"}, user: "app", password: "pw"}, false},
+		{"missing host", "etcd:///flags", etcdClient{}, true},
+		{"wrong scheme", "http://localhost:2379", etcdClient{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newEtcdClient(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, *got)
+		})
+	}
+}
+
+func TestEtcdPrefixRange(t *testing.T) {
+	key, end := etcdPrefixRange("flags/")
+	assert.Equal(t, []byte("flags/"), key)
+	assert.Equal(t, []byte("flags0"), end)
+
+	key, end = etcdPrefixRange("a\xff")
+	assert.Equal(t, []byte("a\xff"), key)
+	assert.Equal(t, []byte("b"), end)
+
+	key, end = etcdPrefixRange("")
+	assert.Equal(t, []byte{0}, key)
+	assert.Equal(t, []byte{0}, end)
+}
+
+// fakeEtcd is an in-memory stand-in for etcd's v3 JSON gateway.
+func fakeEtcd(t *testing.T, store map[string]string) *httptest.Server {
+	t.Helper()
+	inRange := func(k string, req etcdRangeRequest) bool {
+		switch {
+		case len(req.RangeEnd) == 0:
+			return k == string(req.Key)
+		case bytes.Equal(req.RangeEnd, []byte{0}):
+			return k >= string(req.Key)
+		}
+		return k >= string(req.Key) && k < string(req.RangeEnd)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v3/auth/authenticate" {
+			w.Write([]byte(`{"token": "tok"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "tok" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": "etcdserver: user name is empty", "code": 16, "message": "etcdserver: user name is empty"}`))
+			return
+		}
+		var req struct {
+			etcdRangeRequest
+			Value []byte `json:"value"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		var keys []string
+		for k := range store {
+			if inRange(k, req.etcdRangeRequest) {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		switch r.URL.Path {
+		case "/v3/kv/range":
+			if req.Limit > 0 && len(keys) > req.Limit {
+				keys = keys[:req.Limit]
+			}
+			kvs := []etcdKv{}
+			for _, k := range keys {
+				kv := etcdKv{Key: []byte(k)}
+				if !req.KeysOnly {
+					kv.Value = []byte(store[k])
+				}
+				kvs = append(kvs, kv)
+			}
+			json.NewEncoder(w).Encode(map[string]any{"kvs": kvs, "count": strconv.Itoa(len(kvs))})
+		case "/v3/kv/put":
+			store[string(req.Key)] = string(req.Value)
+			w.Write([]byte(`{"header": {}}`))
+		case "/v3/kv/deleterange":
+			for _, k := range keys {
+				delete(store, k)
+			}
+			w.Write([]byte(`{"deleted": "` + strconv.Itoa(len(keys)) + `"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEtcdAgainstFake(t *testing.T) {
+	store := map[string]string{
+		"flags/checkout": `{"enabled": true, "rollout": 25}`,
+		"flags/search":   "off",
+		"service/db":     "postgres://db:5432/app",
+	}
+	srv := fakeEtcd(t, store)
+	// The first member is down; the client moves on to the second.
+	conn := "etcd://app:pw@127.0.0.1:1," + strings.TrimPrefix(srv.URL, "http://")
+	run := func(query string) (string, error) {
+		out, err := ExecuteEtcdQuery(common.QueryMetadata{Conn: conn, QueryLine: query})
+		return string(out), err
+	}
+
+	out, err := run(`get flags/checkout`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "flags/checkout", "value": {"enabled": true, "rollout": 25}}`, out)
+
+	out, err = run(`get flags/missing`)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", out)
+
+	out, err = run(`get flags/ --prefix`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "flags/checkout", "value": {"enabled": true, "rollout": 25}}, {"key": "flags/search", "value": "off"}]`, out)
+
+	out, err = run(`list --limit 2`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "flags/checkout"}, {"key": "flags/search"}]`, out)
+
+	out, err = run(`put flags/search {"enabled": false}`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"applied": true}`, out)
+	assert.Equal(t, `{"enabled": false}`, store["flags/search"])
+
+	out, err = run(`delete flags/ --prefix`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"deleted": 2}`, out)
+	assert.Equal(t, map[string]string{"service/db": "postgres://db:5432/app"}, store)
+
+	_, err = ExecuteEtcdQuery(common.QueryMetadata{Conn: "etcd://" + strings.TrimPrefix(srv.URL, "http://"), QueryLine: `get a`})
+	assert.EqualError(t, err, "etcd: etcdserver: user name is empty")
+}
@@ -0,0 +1,640 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"net/http"
+	"simpanan/internal/common"
+	"strings"
+	"sync"
+)
+
+// graphqlSchemePrefix marks an http(s) URI as a GraphQL endpoint:
+// `graphql+https://api.internal/graphql`.
+const graphqlSchemePrefix = "graphql+"
+
+// graphqlIntrospectionQuery reads the parts of the schema simpanan
+// uses: root types, every type's fields with their arguments, and input
+// objects' fields. Type references are unwrapped four levels deep,
+// enough for `[[ID!]!]!`.
+const graphqlIntrospectionQuery = `query SimpananIntrospection {
+  __schema {
+    queryType { name }
+    mutationType { name }
+    subscriptionType { name }
+    types {
+      kind
+      name
+      fields(includeDeprecated: true) { name args { name type { ...TypeRef } } type { ...TypeRef } }
+      inputFields { name type { ...TypeRef } }
+    }
+  }
+}
+fragment TypeRef on __Type { kind name ofType { kind name ofType { kind name ofType { kind name ofType { kind name } } } } }`
+
+// graphqlConditionArgs are the arguments of the built-in @include and
+// @skip directives.
+var graphqlConditionArgs = []graphqlInputValue{
+	{Name: "if", Type: graphqlTypeRef{Kind: "NON_NULL", OfType: &graphqlTypeRef{Kind: "SCALAR", Name: "Boolean"}}},
+}
+
+// newGraphqlEndpoint reads a `graphql+https://` or `graphql+http://`
+// URI. Everything after the prefix is configured as for an HTTP
+// connection: `header.<Name>` parameters become request headers.
+func newGraphqlEndpoint(uri string) (*httpEndpoint, error) {
+	rest, ok := strings.CutPrefix(uri, graphqlSchemePrefix)
+	if !ok {
+		return nil, fmt.Errorf("graphql uri must use graphql+https:// or graphql+http:// scheme, got %q", uri)
+	}
+	return newHttpEndpoint(rest)
+}
+
+// QueryTypeGraphql classifies a document by its operations: any
+// mutation makes it a write.
+func QueryTypeGraphql(query string) common.QueryType {
+	for _, op := range common.GraphqlOperations(common.TokenizeGraphql(query)) {
+		if op.Kind == "mutation" {
+			return common.Write
+		}
+	}
+	return common.Read
+}
+
+// PlaceholderStyleGraphql binds placeholders as `$pN` variables
+// appended to QueryMetadata.Args. Arrays stay one variable, and a
+// placeholder may replace a whole "..." string.
+var PlaceholderStyleGraphql common.PlaceholderStyle = common.BindStyle{
+	Rules:  common.LiteralRules{BackslashEscapes: true, DoubleQuotesOnly: true},
+	Quotes: `"`,
+	Bind: func(q *common.QueryMetadata, value any, _ bool) string {
+		q.Args = append(q.Args, value)
+		return fmt.Sprintf("$p%d", len(q.Args))
+	},
+}
+
+// ExecuteGraphqlQuery posts the stage's document and returns the
+// response's `data`. Values piped in through `{{jq}}` placeholders
+// arrive in q.Args and are sent as the variables `$p1`, `$p2`, ...,
+// declared on the operation with the type the schema expects where
+// they are used.
+func ExecuteGraphqlQuery(q common.QueryMetadata) ([]byte, error) {
+	e, err := newGraphqlEndpoint(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := q.QueryLine
+	toks := common.TokenizeGraphql(doc)
+	ops := common.GraphqlOperations(toks)
+	switch {
+	case len(ops) == 0:
+		return nil, fmt.Errorf("graphql stage has no operation: %q", doc)
+	case len(ops) > 1:
+		return nil, fmt.Errorf("graphql stage must hold a single operation, got %d", len(ops))
+	case ops[0].Kind == "subscription":
+		return nil, fmt.Errorf("graphql subscriptions are not supported")
+	}
+
+	variables := map[string]any{}
+	if len(q.Args) > 0 {
+		// Without introspection the values' own types are the best
+		// guess, so a failure here is not fatal.
+		schema, _ := cachedGraphqlSchema(q.Conn, e)
+		expected := graphqlVariableTypes(toks, schema)
+		decls := make([]string, 0, len(q.Args))
+		for i, v := range q.Args {
+			name := fmt.Sprintf("$p%d", i+1)
+			typ, ok := expected[name]
+			if !ok {
+				typ, ok = graphqlValueType(v)
+			}
+			if !ok {
+				return nil, fmt.Errorf("graphql: cannot tell the type of %s; the schema does not describe where it is used", name)
+			}
+			decls = append(decls, name+": "+typ)
+			variables[name[1:]] = v
+		}
+		doc = declareGraphqlVariables(doc, toks, ops[0], decls)
+	}
+
+	return e.graphql(doc, variables)
+}
+
+// GraphqlType is an object, interface or input type, for the schema
+// cache.
+type GraphqlType struct {
+	Name   string
+	Fields []GraphqlField
+}
+
+// GraphqlField is a field and the name of the type it holds, without
+// list or non-null wrappers.
+type GraphqlField struct {
+	Name string
+	Type string
+}
+
+// IntrospectGraphql returns the endpoint's host and the schema's
+// object, interface and input types. Introspection types (`__Type`)
+// are left out.
+func IntrospectGraphql(uri string) (string, []GraphqlType, error) {
+	e, err := newGraphqlEndpoint(uri)
+	if err != nil {
+		return "", nil, err
+	}
+	schema, err := fetchGraphqlSchema(e)
+	if err != nil {
+		return "", nil, err
+	}
+	graphqlSchemas.Lock()
+	graphqlSchemas.byConn[uri] = schema
+	graphqlSchemas.Unlock()
+
+	var types []GraphqlType
+	for _, t := range schema.Types {
+		if strings.HasPrefix(t.Name, "__") {
+			continue
+		}
+		var fields []GraphqlField
+		switch t.Kind {
+		case "OBJECT", "INTERFACE":
+			for _, f := range t.Fields {
+				fields = append(fields, GraphqlField{Name: f.Name, Type: f.Type.named()})
+			}
+		case "INPUT_OBJECT":
+			for _, f := range t.InputFields {
+				fields = append(fields, GraphqlField{Name: f.Name, Type: f.Type.named()})
+			}
+		default:
+			continue
+		}
+		types = append(types, GraphqlType{Name: t.Name, Fields: fields})
+	}
+	return e.base.Host, types, nil
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []graphqlError  `json:"errors"`
+}
+
+type graphqlError struct {
+	Message string `json:"message"`
+	Path    []any  `json:"path"`
+}
+
+// graphql posts one document and returns its `data`. GraphQL errors
+// fail the request even when some data came back with them.
+func (e *httpEndpoint) graphql(doc string, variables map[string]any) ([]byte, error) {
+	payload := map[string]any{"query": doc}
+	if len(variables) > 0 {
+		payload["variables"] = variables
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	u := e.base
+	u.RawQuery = e.params.Encode()
+	req, err := e.newRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	resp, respBody, err := sendHttp(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var out graphqlResponse
+	decodeErr := json.Unmarshal(respBody, &out)
+	if len(out.Errors) > 0 {
+		return nil, graphqlErrors(out.Errors)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("graphql: %s: %s", resp.Status, httpSnippet(respBody))
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("graphql: response is not JSON: %s", httpSnippet(respBody))
+	}
+	if len(out.Data) == 0 {
+		return []byte("null"), nil
+	}
+	return out.Data, nil
+}
+
+// graphqlErrors folds a response's errors into one stage error, each
+// message followed by the path it was raised at.
+func graphqlErrors(errs []graphqlError) error {
+	msgs := make([]string, 0, len(errs))
+	for _, e := range errs {
+		msg := e.Message
+		if len(e.Path) > 0 {
+			parts := make([]string, 0, len(e.Path))
+			for _, p := range e.Path {
+				parts = append(parts, fmt.Sprint(p))
+			}
+			msg += " (at " + strings.Join(parts, ".") + ")"
+		}
+		msgs = append(msgs, msg)
+	}
+	return fmt.Errorf("graphql: %s", strings.Join(msgs, "; "))
+}
+
+type graphqlSchema struct {
+	QueryType        *graphqlNamed `json:"queryType"`
+	MutationType     *graphqlNamed `json:"mutationType"`
+	SubscriptionType *graphqlNamed `json:"subscriptionType"`
+	Types            []graphqlType `json:"types"`
+}
+
+type graphqlNamed struct {
+	Name string `json:"name"`
+}
+
+type graphqlType struct {
+	Kind        string              `json:"kind"`
+	Name        string              `json:"name"`
+	Fields      []graphqlField      `json:"fields"`
+	InputFields []graphqlInputValue `json:"inputFields"`
+}
+
+type graphqlField struct {
+	Name string              `json:"name"`
+	Args []graphqlInputValue `json:"args"`
+	Type graphqlTypeRef      `json:"type"`
+}
+
+type graphqlInputValue struct {
+	Name string         `json:"name"`
+	Type graphqlTypeRef `json:"type"`
+}
+
+type graphqlTypeRef struct {
+	Kind   string          `json:"kind"`
+	Name   string          `json:"name"`
+	OfType *graphqlTypeRef `json:"ofType"`
+}
+
+// String writes the reference in SDL form: `[ID!]!`.
+func (t *graphqlTypeRef) String() string {
+	switch {
+	case t.Kind == "NON_NULL" && t.OfType != nil:
+		return t.OfType.String() + "!"
+	case t.Kind == "LIST" && t.OfType != nil:
+		return "[" + t.OfType.String() + "]"
+	}
+	return t.Name
+}
+
+// named strips list and non-null wrappers.
+func (t *graphqlTypeRef) named() string {
+	for t.OfType != nil {
+		t = t.OfType
+	}
+	return t.Name
+}
+
+// element is the type of a list's items, or nil when t is not a list.
+func (t *graphqlTypeRef) element() *graphqlTypeRef {
+	if t != nil && t.Kind == "NON_NULL" {
+		t = t.OfType
+	}
+	if t == nil || t.Kind != "LIST" {
+		return nil
+	}
+	return t.OfType
+}
+
+// graphqlSchemas holds the schema introspected for each connection, so
+// only the first stage with placeholders pays for introspection.
+// IntrospectGraphql replaces the entry when the schema cache refreshes.
+var graphqlSchemas = struct {
+	sync.Mutex
+	byConn map[string]*graphqlSchema
+}{byConn: map[string]*graphqlSchema{}}
+
+// cachedGraphqlSchema returns the connection's schema, introspecting it
+// the first time. A failed introspection is not cached.
+func cachedGraphqlSchema(conn string, e *httpEndpoint) (*graphqlSchema, error) {
+	graphqlSchemas.Lock()
+	schema, ok := graphqlSchemas.byConn[conn]
+	graphqlSchemas.Unlock()
+	if ok {
+		return schema, nil
+	}
+	schema, err := fetchGraphqlSchema(e)
+	if err != nil {
+		return nil, err
+	}
+	graphqlSchemas.Lock()
+	graphqlSchemas.byConn[conn] = schema
+	graphqlSchemas.Unlock()
+	return schema, nil
+}
+
+func fetchGraphqlSchema(e *httpEndpoint) (*graphqlSchema, error) {
+	data, err := e.graphql(graphqlIntrospectionQuery, nil)
+	if err != nil {
+		return nil, err
+	}
+	var out struct {
+		Schema *graphqlSchema `json:"__schema"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("graphql: unexpected introspection result: %s", err)
+	}
+	if out.Schema == nil {
+		return nil, fmt.Errorf("graphql: introspection returned no schema")
+	}
+	return out.Schema, nil
+}
+
+func (s *graphqlSchema) rootType(op string) string {
+	var root *graphqlNamed
+	switch op {
+	case "query":
+		root = s.QueryType
+	case "mutation":
+		root = s.MutationType
+	case "subscription":
+		root = s.SubscriptionType
+	}
+	if root == nil {
+		return ""
+	}
+	return root.Name
+}
+
+func (s *graphqlSchema) typeNamed(name string) *graphqlType {
+	for i := range s.Types {
+		if s.Types[i].Name == name {
+			return &s.Types[i]
+		}
+	}
+	return nil
+}
+
+func (s *graphqlSchema) field(typeName, name string) *graphqlField {
+	t := s.typeNamed(typeName)
+	if t == nil {
+		return nil
+	}
+	for i := range t.Fields {
+		if t.Fields[i].Name == name {
+			return &t.Fields[i]
+		}
+	}
+	return nil
+}
+
+func inputValueType(values []graphqlInputValue, name string) *graphqlTypeRef {
+	for i := range values {
+		if values[i].Name == name {
+			return &values[i].Type
+		}
+	}
+	return nil
+}
+
+// graphqlVariableTypes walks a document alongside the schema and
+// returns the type expected where each variable is used, e.g.
+// {"$p1": "ID!"}. Uses the schema does not describe are left out.
+func graphqlVariableTypes(toks []common.GraphqlToken, schema *graphqlSchema) map[string]string {
+	types := map[string]string{}
+	if schema == nil {
+		return types
+	}
+	w := &graphqlWalker{toks: toks, schema: schema, types: types}
+	w.document()
+	return types
+}
+
+// graphqlWalker is a forgiving recursive-descent pass over a tokenized
+// document. It only tracks which type each selection set and argument
+// belongs to; anything it does not understand is stepped over.
+type graphqlWalker struct {
+	toks   []common.GraphqlToken
+	pos    int
+	schema *graphqlSchema
+	types  map[string]string
+}
+
+func (w *graphqlWalker) done() bool { return w.pos >= len(w.toks) }
+
+func (w *graphqlWalker) peek() common.GraphqlToken {
+	if w.done() {
+		return common.GraphqlToken{}
+	}
+	return w.toks[w.pos]
+}
+
+func (w *graphqlWalker) is(punct string) bool {
+	t := w.peek()
+	return t.Kind == common.GraphqlPunct && t.Text == punct
+}
+
+func (w *graphqlWalker) isName(name string) bool {
+	t := w.peek()
+	return t.Kind == common.GraphqlName && t.Text == name
+}
+
+func (w *graphqlWalker) document() {
+	for !w.done() {
+		t := w.peek()
+		switch {
+		case w.is("{"):
+			w.selectionSet(w.schema.rootType("query"))
+		case w.isName("query"), w.isName("mutation"), w.isName("subscription"):
+			w.pos++
+			if w.peek().Kind == common.GraphqlName {
+				w.pos++
+			}
+			if w.is("(") {
+				w.skipVariableDefinitions()
+			}
+			w.directives()
+			w.selectionSet(w.schema.rootType(t.Text))
+		case w.isName("fragment"):
+			w.pos += 2
+			typeName := ""
+			if w.isName("on") {
+				typeName = w.toks[min(w.pos+1, len(w.toks)-1)].Text
+				w.pos += 2
+			}
+			w.directives()
+			w.selectionSet(typeName)
+		default:
+			w.pos++
+		}
+	}
+}
+
+func (w *graphqlWalker) skipVariableDefinitions() {
+	for !w.done() && !w.is(")") {
+		w.pos++
+	}
+	w.pos++
+}
+
+func (w *graphqlWalker) selectionSet(typeName string) {
+	if !w.is("{") {
+		return
+	}
+	w.pos++
+	for !w.done() && !w.is("}") {
+		t := w.peek()
+		switch {
+		case w.is("..."):
+			w.pos++
+			inner := typeName
+			if w.isName("on") {
+				w.pos++
+				inner = w.peek().Text
+				w.pos++
+			} else if w.peek().Kind == common.GraphqlName {
+				// A fragment spread; the fragment is walked on its own.
+				w.pos++
+				w.directives()
+				continue
+			}
+			w.directives()
+			w.selectionSet(inner)
+		case t.Kind == common.GraphqlName:
+			w.pos++
+			name := t.Text
+			if w.is(":") {
+				w.pos++
+				name = w.peek().Text
+				w.pos++
+			}
+			var args []graphqlInputValue
+			fieldType := ""
+			if f := w.schema.field(typeName, name); f != nil {
+				args, fieldType = f.Args, f.Type.named()
+			}
+			if w.is("(") {
+				w.arguments(args)
+			}
+			w.directives()
+			w.selectionSet(fieldType)
+		default:
+			w.pos++
+		}
+	}
+	w.pos++
+}
+
+func (w *graphqlWalker) directives() {
+	for w.is("@") {
+		w.pos++
+		name := w.peek().Text
+		w.pos++
+		if w.is("(") {
+			var args []graphqlInputValue
+			if name == "include" || name == "skip" {
+				args = graphqlConditionArgs
+			}
+			w.arguments(args)
+		}
+	}
+}
+
+func (w *graphqlWalker) arguments(args []graphqlInputValue) {
+	w.pos++
+	for !w.done() && !w.is(")") {
+		t := w.peek()
+		w.pos++
+		if t.Kind != common.GraphqlName || !w.is(":") {
+			continue
+		}
+		w.pos++
+		w.value(inputValueType(args, t.Text))
+	}
+	w.pos++
+}
+
+func (w *graphqlWalker) value(typ *graphqlTypeRef) {
+	t := w.peek()
+	switch {
+	case t.Kind == common.GraphqlVariable:
+		w.pos++
+		if typ != nil {
+			w.types[t.Text] = typ.String()
+		}
+	case w.is("["):
+		w.pos++
+		elem := typ.element()
+		for !w.done() && !w.is("]") {
+			before := w.pos
+			w.value(elem)
+			if w.pos == before {
+				w.pos++
+			}
+		}
+		w.pos++
+	case w.is("{"):
+		w.pos++
+		var fields []graphqlInputValue
+		if typ != nil {
+			if it := w.schema.typeNamed(typ.named()); it != nil {
+				fields = it.InputFields
+			}
+		}
+		for !w.done() && !w.is("}") {
+			f := w.peek()
+			w.pos++
+			if f.Kind != common.GraphqlName || !w.is(":") {
+				continue
+			}
+			w.pos++
+			w.value(inputValueType(fields, f.Text))
+		}
+		w.pos++
+	default:
+		w.pos++
+	}
+}
+
+// graphqlValueType guesses a nullable scalar type from a piped value,
+// for variables whose use the schema does not describe. Lists and
+// objects have no such guess.
+func graphqlValueType(v any) (string, bool) {
+	switch val := v.(type) {
+	case string:
+		return "String", true
+	case bool:
+		return "Boolean", true
+	case int, *big.Int:
+		return "Int", true
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) <= math.MaxInt32 {
+			return "Int", true
+		}
+		return "Float", true
+	}
+	return "", false
+}
+
+// declareGraphqlVariables adds variable definitions to op, merging them
+// into a definition list the operation already has. A bare selection
+// set becomes `query(...) { ... }`.
+func declareGraphqlVariables(doc string, toks []common.GraphqlToken, op common.GraphqlOperation, decls []string) string {
+	list := strings.Join(decls, ", ")
+	head := toks[op.At]
+	if head.Kind == common.GraphqlPunct {
+		return doc[:head.Offset] + "query(" + list + ") " + doc[head.Offset:]
+	}
+	after := op.At + 1
+	if after < len(toks) && toks[after].Kind == common.GraphqlName {
+		after++
+	}
+	if after < len(toks) && toks[after].Kind == common.GraphqlPunct && toks[after].Text == "(" {
+		at := toks[after].End()
+		return doc[:at] + list + ", " + doc[at:]
+	}
+	at := toks[after-1].End()
+	return doc[:at] + "(" + list + ")" + doc[at:]
+}
@@ -0,0 +1,260 @@
+package adapters
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func gqlNamed(kind, name string) graphqlTypeRef {
+	return graphqlTypeRef{Kind: kind, Name: name}
+}
+
+func gqlNonNull(t graphqlTypeRef) graphqlTypeRef {
+	return graphqlTypeRef{Kind: "NON_NULL", OfType: &t}
+}
+
+func gqlList(t graphqlTypeRef) graphqlTypeRef {
+	return graphqlTypeRef{Kind: "LIST", OfType: &t}
+}
+
+// testGraphqlSchema is
+//
+//	type Query { user(id: ID!): User  users(filter: UserFilter, ids: [ID!]): [User!]! }
+//	type Mutation { addUser(name: String!, tags: [String!]): User }
+//	type User { id: ID!  name: String  posts(first: Int): [Post] }
+//	type Post { title: String }
+//	input UserFilter { name: String  age: Int }
+func testGraphqlSchema() *graphqlSchema {
+	id, str, integer := gqlNamed("SCALAR", "ID"), gqlNamed("SCALAR", "String"), gqlNamed("SCALAR", "Int")
+	user, post := gqlNamed("OBJECT", "User"), gqlNamed("OBJECT", "Post")
+	return &graphqlSchema{
+		QueryType:    &graphqlNamed{"Query"},
+		MutationType: &graphqlNamed{"Mutation"},
+		Types: []graphqlType{
+			{Kind: "OBJECT", Name: "Query", Fields: []graphqlField{
+				{Name: "user", Args: []graphqlInputValue{{"id", gqlNonNull(id)}}, Type: user},
+				{Name: "users", Args: []graphqlInputValue{{"filter", gqlNamed("INPUT_OBJECT", "UserFilter")}, {"ids", gqlList(gqlNonNull(id))}}, Type: gqlNonNull(gqlList(gqlNonNull(user)))},
+			}},
+			{Kind: "OBJECT", Name: "Mutation", Fields: []graphqlField{
+				{Name: "addUser", Args: []graphqlInputValue{{"name", gqlNonNull(str)}, {"tags", gqlList(gqlNonNull(str))}}, Type: user},
+			}},
+			{Kind: "OBJECT", Name: "User", Fields: []graphqlField{
+				{Name: "id", Type: gqlNonNull(id)},
+				{Name: "name", Type: str},
+				{Name: "posts", Args: []graphqlInputValue{{"first", integer}}, Type: gqlList(post)},
+			}},
+			{Kind: "OBJECT", Name: "Post", Fields: []graphqlField{{Name: "title", Type: str}}},
+			{Kind: "INPUT_OBJECT", Name: "UserFilter", InputFields: []graphqlInputValue{{"name", str}, {"age", integer}}},
+			{Kind: "SCALAR", Name: "ID"},
+			{Kind: "OBJECT", Name: "__Type", Fields: []graphqlField{{Name: "name", Type: str}}},
+		},
+	}
+}
+
+func TestQueryTypeGraphql(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`{ users { id } }`, common.Read},
+		{`query Users { users { id } }`, common.Read},
+		{`mutation { addUser(name: "a") { id } }`, common.Write},
+		{`fragment F on User { id } mutation M { addUser(name: "a") { ...F } }`, common.Write},
+		{`# mutation in a comment
+		query { users { id } }`, common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeGraphql(tt.query))
+		})
+	}
+}
+
+func TestGraphqlVariableTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want map[string]string
+	}{
+		{"argument", `{ user(id: $p1) { name } }`, map[string]string{"$p1": "ID!"}},
+		{"aliased nested field", `query { me: user(id: "1") { posts(first: $p1) { title } } }`, map[string]string{"$p1": "Int"}},
+		{"list and input object", `{ users(ids: [$p1, "x"], filter: {age: $p2}) { id } }`, map[string]string{"$p1": "ID!", "$p2": "Int"}},
+		{"whole list", `{ users(ids: $p1) { id } }`, map[string]string{"$p1": "[ID!]"}},
+		{"mutation", `mutation Add { addUser(name: $p1, tags: $p2) { id } }`, map[string]string{"$p1": "String!", "$p2": "[String!]"}},
+		{"directive", `{ users { name @include(if: $p1) } }`, map[string]string{"$p1": "Boolean!"}},
+		{"fragment", `{ users { ...P } } fragment P on User { posts(first: $p1) { title } }`, map[string]string{"$p1": "Int"}},
+		{"inline fragment", `{ users { ... on User { posts(first: $p1) { title } } } }`, map[string]string{"$p1": "Int"}},
+		{"unknown field", `{ nope(x: $p1) { id } }`, map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, graphqlVariableTypes(common.TokenizeGraphql(tt.doc), testGraphqlSchema()))
+		})
+	}
+}
+
+func TestDeclareGraphqlVariables(t *testing.T) {
+	tests := []struct {
+		doc, want string
+	}{
+		{`{ user(id: $p1) { name } }`, `query($p1: ID!) { user(id: $p1) { name } }`},
+		{`query { user(id: $p1) { name } }`, `query($p1: ID!) { user(id: $p1) { name } }`},
+		{`query One { user(id: $p1) { name } }`, `query One($p1: ID!) { user(id: $p1) { name } }`},
+		{`query One($x: Int) { user(id: $p1) { name } }`, `query One($p1: ID!, $x: Int) { user(id: $p1) { name } }`},
+		{`fragment F on User { id } query { user(id: $p1) { ...F } }`, `fragment F on User { id } query($p1: ID!) { user(id: $p1) { ...F } }`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc, func(t *testing.T) {
+			toks := common.TokenizeGraphql(tt.doc)
+			op := common.GraphqlOperations(toks)[0]
+			assert.Equal(t, tt.want, declareGraphqlVariables(tt.doc, toks, op, []string{"$p1: ID!"}))
+		})
+	}
+}
+
+func TestGraphqlValueType(t *testing.T) {
+	for _, tt := range []struct {
+		v    any
+		want string
+		ok   bool
+	}{
+		{"a", "String", true},
+		{true, "Boolean", true},
+		{float64(7), "Int", true},
+		{7.5, "Float", true},
+		{[]any{1}, "", false},
+		{nil, "", false},
+	} {
+		got, ok := graphqlValueType(tt.v)
+		assert.Equal(t, tt.want, got)
+		assert.Equal(t, tt.ok, ok)
+	}
+}
+
+// gqlRecorded is one document the stand-in was sent.
+type gqlRecorded struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+	auth      string
+}
+
+// newGraphqlStandIn answers introspection with testGraphqlSchema and
+// every other document with respond.
+func newGraphqlStandIn(t *testing.T, respond func(gqlRecorded) (int, string)) (string, *[]gqlRecorded) {
+	t.Helper()
+	schema, err := json.Marshal(map[string]any{"data": map[string]any{"__schema": testGraphqlSchema()}})
+	assert.NoError(t, err)
+	srv, seen := newStandIn(t, func(r *http.Request, body []byte) gqlRecorded {
+		var req gqlRecorded
+		json.Unmarshal(body, &req)
+		req.auth = r.Header.Get("Authorization")
+		return req
+	}, func(w http.ResponseWriter, r *http.Request, req gqlRecorded) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Method != http.MethodPost || r.URL.Path != "/graphql" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if strings.Contains(req.Query, "__schema") {
+			w.Write(schema)
+			return
+		}
+		status, resp := respond(req)
+		w.WriteHeader(status)
+		io.WriteString(w, resp)
+	})
+	return "graphql+" + srv.URL + "/graphql", seen
+}
+
+func TestGraphqlAgainstStandIn(t *testing.T) {
+	conn, seen := newGraphqlStandIn(t, func(req gqlRecorded) (int, string) {
+		switch {
+		case strings.Contains(req.Query, "broken"):
+			return http.StatusOK, `{"data": {"user": null}, "errors": [{"message": "boom", "path": ["user", 0, "name"]}, {"message": "again"}]}`
+		case strings.Contains(req.Query, "addUser"):
+			return http.StatusOK, `{"data": {"addUser": {"id": "9"}}}`
+		case strings.Contains(req.Query, "user("):
+			return http.StatusOK, `{"data": {"user": {"name": "ana"}}}`
+		}
+		return http.StatusBadRequest, `<html>bad request</html>`
+	})
+
+	out, err := ExecuteGraphqlQuery(common.QueryMetadata{Conn: conn + "?header.Authorization=Bearer%20t", QueryLine: `{ user(id: "7") { name } }`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"user": {"name": "ana"}}`, string(out))
+	assert.Equal(t, gqlRecorded{Query: `{ user(id: "7") { name } }`, auth: "Bearer t"}, (*seen)[0], "no variables, no introspection")
+
+	q := common.QueryMetadata{Conn: conn, QueryLine: `mutation { addUser(name: {{.name}}, tags: {{.tags}}) { id } }`}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"name": "bo", "tags": ["a"]}`), PlaceholderStyleGraphql))
+	out, err = ExecuteGraphqlQuery(q)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"addUser": {"id": "9"}}`, string(out))
+	assert.Equal(t, `mutation($p1: String!, $p2: [String!]) { addUser(name: $p1, tags: $p2) { id } }`, (*seen)[2].Query)
+	assert.Equal(t, map[string]any{"p1": "bo", "p2": []any{"a"}}, (*seen)[2].Variables)
+
+	// The schema is introspected once per connection.
+	q = common.QueryMetadata{Conn: conn, QueryLine: `{ user(id: {{.id}}) { name } }`}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"id": 7}`), PlaceholderStyleGraphql))
+	_, err = ExecuteGraphqlQuery(q)
+	assert.NoError(t, err)
+	assert.Len(t, *seen, 4)
+	assert.Equal(t, `query($p1: ID!) { user(id: $p1) { name } }`, (*seen)[3].Query)
+
+	_, err = ExecuteGraphqlQuery(common.QueryMetadata{Conn: conn, QueryLine: `query broken { user(id: "1") { name } }`})
+	assert.EqualError(t, err, "graphql: boom (at user.0.name); again")
+
+	_, err = ExecuteGraphqlQuery(common.QueryMetadata{Conn: conn, QueryLine: `{ other }`})
+	assert.EqualError(t, err, "graphql: 400 Bad Request: <html>bad request</html>")
+
+	_, err = ExecuteGraphqlQuery(common.QueryMetadata{Conn: conn, QueryLine: `query A { a } query B { b }`})
+	assert.Error(t, err)
+	_, err = ExecuteGraphqlQuery(common.QueryMetadata{Conn: conn, QueryLine: `subscription { s }`})
+	assert.Error(t, err)
+}
+
+func TestIntrospectGraphql(t *testing.T) {
+	conn, _ := newGraphqlStandIn(t, nil)
+
+	host, types, err := IntrospectGraphql(conn)
+	assert.NoError(t, err)
+	assert.Equal(t, strings.TrimPrefix(strings.TrimSuffix(conn, "/graphql"), "graphql+http://"), host)
+	assert.Equal(t, []GraphqlType{
+		{Name: "Query", Fields: []GraphqlField{{"user", "User"}, {"users", "User"}}},
+		{Name: "Mutation", Fields: []GraphqlField{{"addUser", "User"}}},
+		{Name: "User", Fields: []GraphqlField{{"id", "ID"}, {"name", "String"}, {"posts", "Post"}}},
+		{Name: "Post", Fields: []GraphqlField{{"title", "String"}}},
+		{Name: "UserFilter", Fields: []GraphqlField{{"name", "String"}, {"age", "Int"}}},
+	}, types)
+}
+
+func TestPlaceholderStyleGraphql(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		payload  string
+		wantLine string
+		wantArgs []any
+	}{
+		{
+			name:     "variables keep arrays whole",
+			line:     `query { users(ids: {{.ids}}, name: "{{.name}}", note: "it's") { id } }`,
+			payload:  `{"ids": [1, 2], "name": "Ann"}`,
+			wantLine: `query { users(ids: $p1, name: $p2, note: "it's") { id } }`,
+			wantArgs: []any{[]any{1, 2}, "Ann"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: tc.line}
+			assert.NoError(t, common.PipeData(&q, []byte(tc.payload), PlaceholderStyleGraphql))
+			assert.Equal(t, tc.wantLine, q.QueryLine)
+			assert.Equal(t, tc.wantArgs, q.Args)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package adapters
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"simpanan/internal/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeHttp(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`GET /users/7`, common.Read},
+		{`get /users`, common.Read},
+		{`/users`, common.Read},
+		{`HEAD /health`, common.Read},
+		{`OPTIONS /users`, common.Read},
+		{`POST /orders {"sku": "a1"}`, common.Write},
+		{`PUT /users/7 {"name": "b"}`, common.Write},
+		{`PATCH /users/7 {"name": "b"}`, common.Write},
+		{`DELETE /users/7`, common.Write},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeHttp(tt.query))
+		})
+	}
+}
+
+func TestNewHttpEndpoint(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		base    string
+		params  string
+		header  http.Header
+		wantErr bool
+	}{
+		{"plain", "http://localhost:8080", "http://localhost:8080", "", http.Header{}, false},
+		{"base path", "https://api.example.com/v1/", "https://api.example.com/v1", "", http.Header{}, false},
+		{"headers and params", "https://api.example.com/v1?header.Authorization=Bearer%20t0k&header.X-Tenant=acme&api-version=2",
+			"https://api.example.com/v1", "api-version=2",
+			http.Header{"Authorization": {"Bearer t0k"}, "X-Tenant": {"acme"}}, false},
+		{"credentials", "http://u:pw@h", "http://u:pw@h", "", http.Header{}, false},
+		{"missing host", "http://", "", "", nil, true},
+		{"wrong scheme", "es://h:9200", "", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e, err := newHttpEndpoint(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.base, e.base.String())
+			assert.Equal(t, tt.params, e.params.Encode())
+			assert.Equal(t, tt.header, e.header)
+		})
+	}
+}
+
+func TestHttpEndpointRequest(t *testing.T) {
+	e, err := newHttpEndpoint("https://api.example.com/v1?header.X-Tenant=acme&api-version=2")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name, line, method, url, body string
+		wantErr                       bool
+	}{
+		{"get", `GET /users/7`, "GET", "https://api.example.com/v1/users/7?api-version=2", "", false},
+		{"default method", `users`, "GET", "https://api.example.com/v1/users?api-version=2", "", false},
+		{"stage params merge", `GET /users?limit=5&api-version=3`, "GET", "https://api.example.com/v1/users?api-version=3&limit=5", "", false},
+		{"escaped placeholder", `GET /users/a%2Fb`, "GET", "https://api.example.com/v1/users/a%2Fb?api-version=2", "", false},
+		{"post body", `POST /orders {"sku": "a1"}`, "POST", "https://api.example.com/v1/orders?api-version=2", `{"sku": "a1"}`, false},
+		{"missing path", `DELETE`, "", "", "", true},
+		{"other host", `GET https://evil.example.com/x`, "", "", "", true},
+		{"dot segments inside the base", `GET /users/./7/../8`, "GET", "https://api.example.com/v1/users/8?api-version=2", "", false},
+		{"trailing slash kept", `GET /users/`, "GET", "https://api.example.com/v1/users/?api-version=2", "", false},
+		{"escapes the base path", `GET ../../admin`, "", "", "", true},
+		{"escapes the base path from a subpath", `GET /users/../../admin`, "", "", "", true},
+		{"escaped dot segments", `GET /%2E%2E/admin`, "", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := e.request(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.method, req.Method)
+			assert.Equal(t, tt.url, req.URL.String())
+			assert.Equal(t, "acme", req.Header.Get("X-Tenant"))
+			if tt.body == "" {
+				assert.Nil(t, req.Body)
+				return
+			}
+			body, _ := io.ReadAll(req.Body)
+			assert.Equal(t, tt.body, string(body))
+			assert.Equal(t, "application/json", req.Header.Get("Content-Type"))
+		})
+	}
+}
+
+func TestHttpAgainstStandIn(t *testing.T) {
+	var auth []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		switch r.Method + " " + r.URL.Path {
+		case "GET /api/users/7":
+			io.WriteString(w, `{"id": 7, "name": "ana"}`)
+		case "GET /api/health":
+			io.WriteString(w, "ok\n")
+		case "DELETE /api/users/7":
+			w.WriteHeader(http.StatusNoContent)
+		case "POST /api/users":
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			io.WriteString(w, `{"error": "name is required"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	conn := srv.URL + "/api?header.Authorization=Bearer%20t0k"
+
+	out, err := ExecuteHttpRequest(common.QueryMetadata{Conn: conn, QueryLine: `GET /users/7`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"id": 7, "name": "ana"}`, string(out))
+	assert.Equal(t, "Bearer t0k", auth[0])
+
+	out, err = ExecuteHttpRequest(common.QueryMetadata{Conn: conn, QueryLine: `GET /health`})
+	assert.NoError(t, err)
+	assert.Equal(t, `"ok\n"`, string(out))
+
+	out, err = ExecuteHttpRequest(common.QueryMetadata{Conn: conn, QueryLine: `DELETE /users/7`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"status": 204}`, string(out))
+
+	_, err = ExecuteHttpRequest(common.QueryMetadata{Conn: conn, QueryLine: `POST /users {}`})
+	assert.EqualError(t, err, `http: POST /api/users: 422 Unprocessable Entity: {"error": "name is required"}`)
+
+	_, err = ExecuteHttpRequest(common.QueryMetadata{Conn: conn, QueryLine: `GET /missing`})
+	assert.EqualError(t, err, "http: GET /api/missing: 404 Not Found")
+}
+
+func TestPlaceholderStyleHttp(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		payload  string
+		wantLine string
+		wantArgs []any
+	}{
+		{
+			name:     "target is url-escaped, body is json",
+			line:     `GET products/_doc/{{ .id }} {"q": {{.q}}}`,
+			payload:  `{"id": "a/b c", "q": "x"}`,
+			wantLine: `GET products/_doc/a%2Fb%20c {"q": "x"}`,
+			wantArgs: nil,
+		},
+		{
+			name:     "query string is query-escaped",
+			line:     `GET products/_search?q={{.q}}`,
+			payload:  `{"q": "name:a&b"}`,
+			wantLine: `GET products/_search?q=name%3Aa%26b`,
+			wantArgs: nil,
+		},
+		{
+			name:     "quoted body placeholder is escaped in place",
+			line:     `POST notes {"text": "re: {{.t}}"}`,
+			payload:  `{"t": "say \"hi\""}`,
+			wantLine: `POST notes {"text": "re: say \"hi\""}`,
+			wantArgs: nil,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: tc.line}
+			assert.NoError(t, common.PipeData(&q, []byte(tc.payload), PlaceholderStyleHttp))
+			assert.Equal(t, tc.wantLine, q.QueryLine)
+			assert.Equal(t, tc.wantArgs, q.Args)
+		})
+	}
+}
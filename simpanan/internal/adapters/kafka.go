@@ -0,0 +1,515 @@
+package adapters
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"simpanan/internal/common"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+	"github.com/twmb/franz-go/pkg/sasl/plain"
+	"github.com/twmb/franz-go/pkg/sasl/scram"
+)
+
+const kafkaTimeout = 15 * time.Second
+
+// kafkaCommand is a parsed Kafka stage:
+//
+//	consume <topic> [--from-offset N] [--partition P] [--limit N]
+//	produce <topic> [--key K] [--partition P] [--header k=v]... <value>
+//	list-topics
+//	list-partitions <topic>
+//
+// A non-negative --from-offset is an absolute offset and a negative one
+// counts back from the end of each partition; without it a consume
+// starts at the earliest offset. Arguments are bare words or JSON
+// strings, and a produce's value is the rest of the line.
+type kafkaCommand struct {
+	name       string
+	topic      string
+	fromOffset *int64
+	partition  *int32
+	limit      int
+	key        *string
+	headers    []kgo.RecordHeader
+	value      []byte
+}
+
+// QueryTypeKafka classifies a Kafka stage: produce writes, the list-*
+// commands are admin, and consume reads.
+func QueryTypeKafka(query string) common.QueryType {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return common.Read
+	}
+	switch strings.ToLower(fields[0]) {
+	case "produce":
+		return common.Write
+	case "list-topics", "list-partitions":
+		return common.Admin
+	}
+	return common.Read
+}
+
+// kafkaFlags names the commands that take each flag.
+var kafkaFlags = map[string]string{
+	"--from-offset": "consume",
+	"--limit":       "consume",
+	"--partition":   "consume produce",
+	"--key":         "produce",
+	"--header":      "produce",
+}
+
+func parseKafkaCommand(query string) (kafkaCommand, error) {
+	name, rest := kvNextWord(strings.TrimSpace(query))
+	cmd := kafkaCommand{name: strings.ToLower(name), limit: common.GetConfig().MaxRowLimit}
+	switch cmd.name {
+	case "consume", "produce", "list-partitions":
+		var err error
+		if cmd.topic, rest, err = kvNextArg(strings.TrimSpace(rest)); err != nil {
+			return kafkaCommand{}, fmt.Errorf("invalid quoted topic: %s", err)
+		}
+		if cmd.topic == "" || strings.HasPrefix(cmd.topic, "--") {
+			return kafkaCommand{}, fmt.Errorf("%s needs a topic", cmd.name)
+		}
+	case "list-topics":
+	case "":
+		return kafkaCommand{}, fmt.Errorf("empty kafka command: use consume, produce, list-topics or list-partitions")
+	default:
+		return kafkaCommand{}, fmt.Errorf("unknown kafka command %q: use consume, produce, list-topics or list-partitions", name)
+	}
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		if !strings.HasPrefix(rest, "--") {
+			if cmd.name != "produce" {
+				return kafkaCommand{}, fmt.Errorf("%s takes no argument %q", cmd.name, rest)
+			}
+			cmd.value = []byte(rest)
+			break
+		}
+		var flag, arg string
+		flag, rest = kvNextWord(rest)
+		takers, known := kafkaFlags[flag]
+		if !known {
+			return kafkaCommand{}, fmt.Errorf("unknown %s flag %q", cmd.name, flag)
+		}
+		if !strings.Contains(takers, cmd.name) {
+			return kafkaCommand{}, fmt.Errorf("%s does not take %s", cmd.name, flag)
+		}
+		var err error
+		if arg, rest, err = kvNextArg(strings.TrimSpace(rest)); err != nil {
+			return kafkaCommand{}, fmt.Errorf("invalid quoted %s value: %s", flag, err)
+		}
+		switch flag {
+		case "--from-offset":
+			n, err := strconv.ParseInt(arg, 10, 64)
+			if err != nil {
+				return kafkaCommand{}, fmt.Errorf("--from-offset needs an offset, got %q", arg)
+			}
+			cmd.fromOffset = &n
+		case "--limit":
+			n, err := strconv.Atoi(arg)
+			if err != nil || n <= 0 {
+				return kafkaCommand{}, fmt.Errorf("--limit needs a positive number, got %q", arg)
+			}
+			cmd.limit = n
+		case "--partition":
+			n, err := strconv.ParseInt(arg, 10, 32)
+			if err != nil || n < 0 {
+				return kafkaCommand{}, fmt.Errorf("--partition needs a partition number, got %q", arg)
+			}
+			p := int32(n)
+			cmd.partition = &p
+		case "--key":
+			cmd.key = &arg
+		case "--header":
+			k, v, ok := strings.Cut(arg, "=")
+			if !ok || k == "" {
+				return kafkaCommand{}, fmt.Errorf("--header needs key=value, got %q", arg)
+			}
+			cmd.headers = append(cmd.headers, kgo.RecordHeader{Key: k, Value: []byte(v)})
+		}
+	}
+	if cmd.name == "produce" && cmd.value == nil {
+		return kafkaCommand{}, fmt.Errorf("produce needs a value: produce <topic> <value>")
+	}
+	return cmd, nil
+}
+
+// kafkaOptions reads a `kafka://user:pass@b1:9092,b2:9092` URI into
+// client options. Brokers without a port use 9092; `?tls=true` dials
+// TLS, and credentials authenticate with SASL/PLAIN unless `?sasl=`
+// names scram-sha-256 or scram-sha-512.
+func kafkaOptions(uri string) ([]kgo.Opt, error) {
+	hosts, uri := splitHostList(uri)
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kafka uri: %s", err)
+	}
+	if u.Scheme != "kafka" {
+		return nil, fmt.Errorf("kafka uri must use kafka:// scheme, got %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("kafka uri is missing a broker: kafka://localhost:9092")
+	}
+	var seeds []string
+	for _, host := range append([]string{u.Host}, hosts...) {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, "9092")
+		}
+		seeds = append(seeds, host)
+	}
+	opts := []kgo.Opt{kgo.SeedBrokers(seeds...), kgo.DialTimeout(kafkaTimeout)}
+
+	params := u.Query()
+	if secure, _ := strconv.ParseBool(params.Get("tls")); secure {
+		opts = append(opts, kgo.DialTLSConfig(&tls.Config{}))
+	}
+	if u.User != nil {
+		user := u.User.Username()
+		pass, _ := u.User.Password()
+		switch mech := strings.ToLower(params.Get("sasl")); mech {
+		case "", "plain":
+			opts = append(opts, kgo.SASL(plain.Auth{User: user, Pass: pass}.AsMechanism()))
+		case "scram-sha-256":
+			opts = append(opts, kgo.SASL(scram.Auth{User: user, Pass: pass}.AsSha256Mechanism()))
+		case "scram-sha-512":
+			opts = append(opts, kgo.SASL(scram.Auth{User: user, Pass: pass}.AsSha512Mechanism()))
+		default:
+			return nil, fmt.Errorf("unknown kafka sasl mechanism %q: use plain, scram-sha-256 or scram-sha-512", mech)
+		}
+	}
+	return opts, nil
+}
+
+// ExecuteKafkaQuery runs a consume, produce, list-topics or
+// list-partitions stage.
+func ExecuteKafkaQuery(q common.QueryMetadata) ([]byte, error) {
+	cmd, err := parseKafkaCommand(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := kafkaOptions(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), kafkaTimeout)
+	defer cancel()
+
+	if cmd.name == "produce" && cmd.partition != nil {
+		opts = append(opts, kgo.RecordPartitioner(kgo.ManualPartitioner()))
+	}
+	client, err := kgo.NewClient(opts...)
+	if err != nil {
+		return nil, kafkaError(err)
+	}
+	defer client.Close()
+
+	switch cmd.name {
+	case "consume":
+		rows, err := consumeKafka(ctx, client, opts, cmd)
+		if err != nil {
+			return nil, err
+		}
+		return marshalRowData(rows)
+	case "produce":
+		return produceKafka(ctx, client, cmd)
+	case "list-topics":
+		topics, err := kafkaMetadata(ctx, client, nil)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]common.RowData, 0, len(topics))
+		for _, t := range topics {
+			rows = append(rows, common.RowData{
+				{Key: "topic", Value: t.name},
+				{Key: "partitions", Value: len(t.partitions)},
+				{Key: "internal", Value: t.internal},
+			})
+		}
+		return marshalRowData(rows)
+	case "list-partitions":
+		topics, err := kafkaMetadata(ctx, client, []string{cmd.topic})
+		if err != nil {
+			return nil, err
+		}
+		earliest, latest, err := kafkaOffsets(ctx, client, cmd.topic, topics[0].partitionIDs())
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]common.RowData, 0, len(topics[0].partitions))
+		for _, p := range topics[0].partitions {
+			rows = append(rows, common.RowData{
+				{Key: "partition", Value: p.Partition},
+				{Key: "leader", Value: p.Leader},
+				{Key: "replicas", Value: p.Replicas},
+				{Key: "isr", Value: p.ISR},
+				{Key: "earliest", Value: earliest[p.Partition]},
+				{Key: "latest", Value: latest[p.Partition]},
+			})
+		}
+		return marshalRowData(rows)
+	}
+	return nil, fmt.Errorf("unknown kafka command %q", cmd.name)
+}
+
+// consumeKafka reads from each partition's start offset up to the end
+// it had when the stage began, stopping early at cmd.limit records or
+// at the deadline. It answers records in partition, then offset, order.
+func consumeKafka(ctx context.Context, client *kgo.Client, opts []kgo.Opt, cmd kafkaCommand) ([]common.RowData, error) {
+	topics, err := kafkaMetadata(ctx, client, []string{cmd.topic})
+	if err != nil {
+		return nil, err
+	}
+	partitions := topics[0].partitionIDs()
+	if cmd.partition != nil {
+		if !containsPartition(partitions, *cmd.partition) {
+			return nil, fmt.Errorf("kafka: topic %q has no partition %d", cmd.topic, *cmd.partition)
+		}
+		partitions = []int32{*cmd.partition}
+	}
+	earliest, latest, err := kafkaOffsets(ctx, client, cmd.topic, partitions)
+	if err != nil {
+		return nil, err
+	}
+
+	// Where each partition starts, the offset it is read up to, and the
+	// offset it has been fetched up to.
+	starts := map[int32]kgo.Offset{}
+	ends := map[int32]int64{}
+	positions := map[int32]int64{}
+	for _, p := range partitions {
+		start := earliest[p]
+		if cmd.fromOffset != nil {
+			if *cmd.fromOffset >= 0 {
+				start = *cmd.fromOffset
+			} else {
+				start = latest[p] + *cmd.fromOffset
+			}
+			if start < earliest[p] {
+				start = earliest[p]
+			}
+		}
+		if start < latest[p] {
+			starts[p] = kgo.NewOffset().At(start)
+			ends[p] = latest[p]
+			positions[p] = start
+		}
+	}
+	rows := []common.RowData{}
+	if len(starts) == 0 {
+		return rows, nil
+	}
+
+	// Transaction markers take up offsets but carry no data, so they are
+	// kept to move the position past them and left out of the answer.
+	consumer, err := kgo.NewClient(append(opts,
+		kgo.ConsumePartitions(map[string]map[int32]kgo.Offset{cmd.topic: starts}),
+		kgo.KeepControlRecords(),
+		kgo.FetchMaxWait(time.Second))...)
+	if err != nil {
+		return nil, kafkaError(err)
+	}
+	defer consumer.Close()
+
+	var records []*kgo.Record
+	for len(ends) > 0 && len(records) < cmd.limit {
+		fetches := consumer.PollRecords(ctx, cmd.limit-len(records))
+		if ctx.Err() != nil {
+			// Answer what was read before the deadline.
+			break
+		}
+		var fetchErr error
+		fetches.EachError(func(_ string, _ int32, err error) { fetchErr = err })
+		if fetchErr != nil {
+			return nil, kafkaError(fetchErr)
+		}
+		fetches.EachPartition(func(fp kgo.FetchTopicPartition) {
+			end, reading := ends[fp.Partition]
+			if !reading {
+				return
+			}
+			for _, r := range fp.Records {
+				if r.Offset >= end || len(records) >= cmd.limit {
+					break
+				}
+				positions[fp.Partition] = r.Offset + 1
+				if !r.Attrs.IsControl() {
+					records = append(records, r)
+				}
+			}
+			// The partition is done once its position reaches the end,
+			// or the high watermark if the log has since been cut short.
+			if fp.HighWatermark < end {
+				end = fp.HighWatermark
+			}
+			if positions[fp.Partition] >= end {
+				delete(ends, fp.Partition)
+			}
+		})
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Partition != records[j].Partition {
+			return records[i].Partition < records[j].Partition
+		}
+		return records[i].Offset < records[j].Offset
+	})
+	for _, r := range records {
+		rows = append(rows, kafkaRecordRow(r))
+	}
+	return rows, nil
+}
+
+// kafkaRecordRow renders a record with its key, header values and
+// payload decoded as JSON when they hold JSON.
+func kafkaRecordRow(r *kgo.Record) common.RowData {
+	// A repeated header keeps its last value.
+	headers := map[string]any{}
+	for _, h := range r.Headers {
+		headers[h.Key] = kafkaValue(h.Value)
+	}
+	return common.RowData{
+		{Key: "topic", Value: r.Topic},
+		{Key: "partition", Value: r.Partition},
+		{Key: "offset", Value: r.Offset},
+		{Key: "timestamp", Value: r.Timestamp.UTC().Format(time.RFC3339Nano)},
+		{Key: "key", Value: kafkaValue(r.Key)},
+		{Key: "headers", Value: headers},
+		{Key: "value", Value: kafkaValue(r.Value)},
+	}
+}
+
+// kafkaValue is kvValue with a missing key or tombstone value as null.
+func kafkaValue(b []byte) any {
+	if b == nil {
+		return nil
+	}
+	return kvValue(b)
+}
+
+func produceKafka(ctx context.Context, client *kgo.Client, cmd kafkaCommand) ([]byte, error) {
+	rec := &kgo.Record{Topic: cmd.topic, Value: cmd.value, Headers: cmd.headers}
+	if cmd.key != nil {
+		rec.Key = []byte(*cmd.key)
+	}
+	if cmd.partition != nil {
+		rec.Partition = *cmd.partition
+	}
+	res, err := client.ProduceSync(ctx, rec).First()
+	if err != nil {
+		return nil, kafkaError(err)
+	}
+	return common.RowData{
+		{Key: "topic", Value: res.Topic},
+		{Key: "partition", Value: res.Partition},
+		{Key: "offset", Value: res.Offset},
+	}.MarshallJSON()
+}
+
+type kafkaTopic struct {
+	name       string
+	internal   bool
+	partitions []kmsg.MetadataResponseTopicPartition
+}
+
+func (t kafkaTopic) partitionIDs() []int32 {
+	ids := make([]int32, 0, len(t.partitions))
+	for _, p := range t.partitions {
+		ids = append(ids, p.Partition)
+	}
+	return ids
+}
+
+func containsPartition(ids []int32, p int32) bool {
+	for _, id := range ids {
+		if id == p {
+			return true
+		}
+	}
+	return false
+}
+
+// kafkaMetadata describes the named topics, or every topic when names
+// is nil, sorted by name with partitions in order.
+func kafkaMetadata(ctx context.Context, client *kgo.Client, names []string) ([]kafkaTopic, error) {
+	req := kmsg.NewPtrMetadataRequest()
+	for _, name := range names {
+		t := kmsg.NewMetadataRequestTopic()
+		t.Topic = kmsg.StringPtr(name)
+		req.Topics = append(req.Topics, t)
+	}
+	resp, err := req.RequestWith(ctx, client)
+	if err != nil {
+		return nil, kafkaError(err)
+	}
+	var topics []kafkaTopic
+	for _, t := range resp.Topics {
+		name := ""
+		if t.Topic != nil {
+			name = *t.Topic
+		}
+		if err := kerr.ErrorForCode(t.ErrorCode); err != nil {
+			if err == kerr.UnknownTopicOrPartition {
+				return nil, fmt.Errorf("kafka: unknown topic %q", name)
+			}
+			return nil, kafkaError(err)
+		}
+		sort.Slice(t.Partitions, func(i, j int) bool { return t.Partitions[i].Partition < t.Partitions[j].Partition })
+		topics = append(topics, kafkaTopic{name: name, internal: t.IsInternal, partitions: t.Partitions})
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].name < topics[j].name })
+	return topics, nil
+}
+
+// kafkaOffsets lists the earliest and latest (next to be written)
+// offset of each partition.
+func kafkaOffsets(ctx context.Context, client *kgo.Client, topic string, partitions []int32) (earliest, latest map[int32]int64, err error) {
+	list := func(timestamp int64) (map[int32]int64, error) {
+		req := kmsg.NewPtrListOffsetsRequest()
+		req.ReplicaID = -1
+		t := kmsg.NewListOffsetsRequestTopic()
+		t.Topic = topic
+		for _, p := range partitions {
+			rp := kmsg.NewListOffsetsRequestTopicPartition()
+			rp.Partition = p
+			rp.Timestamp = timestamp
+			t.Partitions = append(t.Partitions, rp)
+		}
+		req.Topics = append(req.Topics, t)
+		resp, err := req.RequestWith(ctx, client)
+		if err != nil {
+			return nil, kafkaError(err)
+		}
+		offsets := map[int32]int64{}
+		for _, rt := range resp.Topics {
+			for _, rp := range rt.Partitions {
+				if err := kerr.ErrorForCode(rp.ErrorCode); err != nil {
+					return nil, kafkaError(err)
+				}
+				offsets[rp.Partition] = rp.Offset
+			}
+		}
+		return offsets, nil
+	}
+	// Timestamp -2 asks for the earliest offset, -1 for the latest.
+	if earliest, err = list(-2); err != nil {
+		return nil, nil, err
+	}
+	if latest, err = list(-1); err != nil {
+		return nil, nil, err
+	}
+	return earliest, latest, nil
+}
+
+// kafkaError formats a client or broker error as `kafka: <message>`.
+func kafkaError(err error) error {
+	return fmt.Errorf("kafka: %s", err)
+}
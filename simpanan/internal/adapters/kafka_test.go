@@ -0,0 +1,215 @@
+package adapters
+
+import (
+	"encoding/json"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/twmb/franz-go/pkg/kfake"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+func TestQueryTypeKafka(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`consume orders --from-offset -10 --limit 20`, common.Read},
+		{`produce orders {"id": 1}`, common.Write},
+		{`PRODUCE orders x`, common.Write},
+		{`list-topics`, common.Admin},
+		{`list-partitions orders`, common.Admin},
+		{``, common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeKafka(tt.query))
+		})
+	}
+}
+
+func TestParseKafkaCommand(t *testing.T) {
+	max := common.GetConfig().MaxRowLimit
+	offset, partition, key := int64(-10), int32(2), "order-1"
+	tests := []struct {
+		query   string
+		want    kafkaCommand
+		wantErr bool
+	}{
+		{`consume orders --from-offset -10 --limit 20`,
+			kafkaCommand{name: "consume", topic: "orders", fromOffset: &offset, limit: 20}, false},
+		{`consume "orders" --partition 2`, kafkaCommand{name: "consume", topic: "orders", partition: &partition, limit: max}, false},
+		{`produce orders --key order-1 --header "source=simp an" {"id": 1, "total": 9.5}`,
+			kafkaCommand{name: "produce", topic: "orders", limit: max, key: &key,
+				headers: []kgo.RecordHeader{{Key: "source", Value: []byte("simp an")}}, value: []byte(`{"id": 1, "total": 9.5}`)}, false},
+		{`produce orders --key "order-1" plain text`,
+			kafkaCommand{name: "produce", topic: "orders", limit: max, key: &key, value: []byte("plain text")}, false},
+		{`list-topics`, kafkaCommand{name: "list-topics", limit: max}, false},
+		{`list-partitions orders`, kafkaCommand{name: "list-partitions", topic: "orders", limit: max}, false},
+		{``, kafkaCommand{}, true},
+		{`tail orders`, kafkaCommand{}, true},
+		{`consume`, kafkaCommand{}, true},
+		{`consume --limit 5`, kafkaCommand{}, true},
+		{`consume orders extra`, kafkaCommand{}, true},
+		{`consume orders --key k`, kafkaCommand{}, true},
+		{`consume orders --limit 0`, kafkaCommand{}, true},
+		{`consume orders --from-offset latest`, kafkaCommand{}, true},
+		{`produce orders`, kafkaCommand{}, true},
+		{`produce orders --limit 5 x`, kafkaCommand{}, true},
+		{`produce orders --header novalue x`, kafkaCommand{}, true},
+		{`list-topics orders`, kafkaCommand{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseKafkaCommand(tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseKafkaCommandPiped(t *testing.T) {
+	q := common.QueryMetadata{QueryLine: `produce {{.topic}} --key {{.id}} {"id": {{.id}}, "tags": {{.tags}}}`}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"topic": "order events", "id": "o-1", "tags": ["a"]}`), common.PlaceholderJSON))
+	cmd, err := parseKafkaCommand(q.QueryLine)
+	assert.NoError(t, err)
+	assert.Equal(t, "order events", cmd.topic)
+	assert.Equal(t, "o-1", *cmd.key)
+	assert.JSONEq(t, `{"id": "o-1", "tags": ["a"]}`, string(cmd.value))
+}
+
+func TestKafkaOptions(t *testing.T) {
+	opts, err := kafkaOptions("kafka://b1,b2:9093")
+	assert.NoError(t, err)
+	assert.Len(t, opts, 2)
+
+	opts, err = kafkaOptions("kafka://app:pw@b1:9092?tls=true&sasl=scram-sha-512")
+	assert.NoError(t, err)
+	assert.Len(t, opts, 4)
+
+	_, err = kafkaOptions("kafka://app:pw@b1?sasl=gssapi")
+	assert.Error(t, err)
+	_, err = kafkaOptions("kafka://")
+	assert.Error(t, err)
+	_, err = kafkaOptions("http://b1:9092")
+	assert.Error(t, err)
+}
+
+func TestKafkaAgainstFake(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(2, "orders", "audit"))
+	assert.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	conn := "kafka://" + strings.Join(cluster.ListenAddrs(), ",")
+	run := func(query string) (string, error) {
+		out, err := ExecuteKafkaQuery(common.QueryMetadata{Conn: conn, QueryLine: query})
+		return string(out), err
+	}
+
+	for i, value := range []string{`{"id": 1}`, `{"id": 2}`, `{"id": 3}`, `not json`} {
+		out, err := run(`produce orders --partition 0 --key order-` + string(rune('1'+i)) + ` --header trace={"span":7} ` + value)
+		assert.NoError(t, err)
+		var res map[string]any
+		assert.NoError(t, json.Unmarshal([]byte(out), &res))
+		assert.Equal(t, map[string]any{"topic": "orders", "partition": float64(0), "offset": float64(i)}, res)
+	}
+	_, err = run(`produce orders --partition 1 {"id": 9}`)
+	assert.NoError(t, err)
+
+	out, err := run(`consume orders --from-offset -2 --partition 0`)
+	assert.NoError(t, err)
+	var records []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(out), &records))
+	assert.Len(t, records, 2)
+	for _, r := range records {
+		delete(r, "timestamp")
+	}
+	assert.Equal(t, []map[string]any{
+		{"topic": "orders", "partition": float64(0), "offset": float64(2), "key": "order-3",
+			"headers": map[string]any{"trace": map[string]any{"span": float64(7)}}, "value": map[string]any{"id": float64(3)}},
+		{"topic": "orders", "partition": float64(0), "offset": float64(3), "key": "order-4",
+			"headers": map[string]any{"trace": map[string]any{"span": float64(7)}}, "value": "not json"},
+	}, records)
+
+	// Both partitions, from the start, stop at the end or the limit.
+	out, err = run(`consume orders`)
+	assert.NoError(t, err)
+	records = nil
+	assert.NoError(t, json.Unmarshal([]byte(out), &records))
+	assert.Len(t, records, 5)
+	assert.Equal(t, float64(1), records[4]["partition"])
+	assert.Nil(t, records[4]["key"])
+
+	out, err = run(`consume orders --partition 0 --limit 3`)
+	assert.NoError(t, err)
+	records = nil
+	assert.NoError(t, json.Unmarshal([]byte(out), &records))
+	assert.Len(t, records, 3)
+
+	out, err = run(`consume audit`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[]`, out)
+
+	out, err = run(`list-topics`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"topic": "audit", "partitions": 2, "internal": false}, {"topic": "orders", "partitions": 2, "internal": false}]`, out)
+
+	out, err = run(`list-partitions orders`)
+	assert.NoError(t, err)
+	var partitions []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(out), &partitions))
+	assert.Len(t, partitions, 2)
+	assert.Equal(t, float64(0), partitions[0]["earliest"])
+	assert.Equal(t, float64(4), partitions[0]["latest"])
+	assert.Equal(t, float64(1), partitions[1]["latest"])
+
+	_, err = run(`consume missing`)
+	assert.EqualError(t, err, `kafka: unknown topic "missing"`)
+	_, err = run(`consume orders --partition 7`)
+	assert.EqualError(t, err, `kafka: topic "orders" has no partition 7`)
+}
+
+// A partition whose last offsets hold no records, such as a transaction
+// commit marker, is done once the fetch reaches the high watermark.
+func TestKafkaConsumeStopsAtGapAtEnd(t *testing.T) {
+	cluster, err := kfake.NewCluster(kfake.NumBrokers(1), kfake.SeedTopics(1, "ledger"))
+	assert.NoError(t, err)
+	t.Cleanup(cluster.Close)
+	conn := "kafka://" + strings.Join(cluster.ListenAddrs(), ",")
+	for _, value := range []string{`{"id": 1}`, `{"id": 2}`} {
+		_, err := ExecuteKafkaQuery(common.QueryMetadata{Conn: conn, QueryLine: `produce ledger ` + value})
+		assert.NoError(t, err)
+	}
+
+	// The latest offset is listed one past the last record, where a
+	// commit marker would sit.
+	cluster.ControlKey(int16(kmsg.ListOffsets), func(kreq kmsg.Request) (kmsg.Response, error, bool) {
+		req := kreq.(*kmsg.ListOffsetsRequest)
+		if req.Topics[0].Partitions[0].Timestamp != -1 {
+			return nil, nil, false
+		}
+		resp := req.ResponseKind().(*kmsg.ListOffsetsResponse)
+		rt := kmsg.NewListOffsetsResponseTopic()
+		rt.Topic = "ledger"
+		rp := kmsg.NewListOffsetsResponseTopicPartition()
+		rp.Offset = 3
+		rt.Partitions = append(rt.Partitions, rp)
+		resp.Topics = append(resp.Topics, rt)
+		return resp, nil, true
+	})
+
+	start := time.Now()
+	out, err := ExecuteKafkaQuery(common.QueryMetadata{Conn: conn, QueryLine: `consume ledger`})
+	assert.NoError(t, err)
+	var records []map[string]any
+	assert.NoError(t, json.Unmarshal([]byte(out), &records))
+	assert.Len(t, records, 2)
+	assert.Less(t, time.Since(start), kafkaTimeout/2)
+}
@@ -0,0 +1,223 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"simpanan/internal/common"
+	"strconv"
+	"strings"
+)
+
+// kvCommand is a parsed etcd or Consul stage:
+//
+//	get <key>                  {"key", "value"}, or null when missing
+//	get <prefix> --prefix      [{"key", "value"}] under the prefix
+//	list [<prefix>]            [{"key"}] under the prefix, values left out
+//	put <key> <value>          stores the rest of the line
+//	delete <key> [--prefix]    {"deleted": n}
+//
+// `--limit N` caps get --prefix and list, which otherwise stop at
+// MaxRowLimit. Keys are bare words or JSON strings.
+type kvCommand struct {
+	name   string
+	key    string
+	value  []byte
+	prefix bool
+	limit  int
+}
+
+// kvWriteCommands are the commands that change the store.
+var kvWriteCommands = map[string]struct{}{"put": {}, "delete": {}}
+
+// QueryTypeKv classifies a KV stage by its command: put and delete
+// write, everything else reads.
+func QueryTypeKv(query string) common.QueryType {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return common.Read
+	}
+	if _, ok := kvWriteCommands[strings.ToLower(fields[0])]; ok {
+		return common.Write
+	}
+	return common.Read
+}
+
+func parseKvCommand(query string) (kvCommand, error) {
+	rest := strings.TrimSpace(query)
+	name, rest := kvNextWord(rest)
+	cmd := kvCommand{name: strings.ToLower(name)}
+	switch cmd.name {
+	case "get", "list", "put", "delete":
+	case "":
+		return kvCommand{}, fmt.Errorf("empty kv command: use get, list, put or delete")
+	default:
+		return kvCommand{}, fmt.Errorf("unknown kv command %q: use get, list, put or delete", name)
+	}
+
+	haveKey := false
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		if haveKey && cmd.name == "put" {
+			cmd.value = []byte(kvPutValue(rest))
+			rest = ""
+			break
+		}
+		var word string
+		switch {
+		case strings.HasPrefix(rest, "--"):
+			word, rest = kvNextWord(rest)
+			switch word {
+			case "--prefix":
+				if cmd.name != "get" && cmd.name != "delete" {
+					return kvCommand{}, fmt.Errorf("%s does not take --prefix", cmd.name)
+				}
+				cmd.prefix = true
+			case "--limit":
+				var n string
+				n, rest = kvNextWord(strings.TrimSpace(rest))
+				limit, err := strconv.Atoi(n)
+				if err != nil || limit <= 0 {
+					return kvCommand{}, fmt.Errorf("--limit needs a positive number, got %q", n)
+				}
+				cmd.limit = limit
+			default:
+				return kvCommand{}, fmt.Errorf("unknown %s flag %q", cmd.name, word)
+			}
+		case haveKey:
+			return kvCommand{}, fmt.Errorf("%s takes one key, got extra %q", cmd.name, rest)
+		default:
+			var err error
+			if cmd.key, rest, err = kvNextArg(rest); err != nil {
+				return kvCommand{}, fmt.Errorf("invalid quoted key: %s", err)
+			}
+			haveKey = true
+		}
+	}
+
+	switch {
+	case cmd.name != "list" && !haveKey:
+		return kvCommand{}, fmt.Errorf("%s needs a key", cmd.name)
+	case cmd.name == "put" && cmd.value == nil:
+		return kvCommand{}, fmt.Errorf("put needs a value: put <key> <value>")
+	case cmd.limit > 0 && cmd.name != "list" && !(cmd.name == "get" && cmd.prefix):
+		return kvCommand{}, fmt.Errorf("--limit applies to list and get --prefix")
+	}
+	if cmd.limit == 0 {
+		cmd.limit = common.GetConfig().MaxRowLimit
+	}
+	return cmd, nil
+}
+
+func kvNextWord(s string) (word, rest string) {
+	if i := strings.IndexAny(s, " \t\r\n"); i >= 0 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
+
+// kvNextArg reads a bare word or a JSON string from the front of s.
+func kvNextArg(s string) (arg, rest string, err error) {
+	if !strings.HasPrefix(s, `"`) {
+		arg, rest = kvNextWord(s)
+		return arg, rest, nil
+	}
+	dec := json.NewDecoder(strings.NewReader(s))
+	if err := dec.Decode(&arg); err != nil {
+		return "", "", err
+	}
+	return arg, s[dec.InputOffset():], nil
+}
+
+// kvPutValue is the text a put stores: the rest of the line, unquoted
+// when it is a single JSON string so `put greeting "hello world"`
+// stores hello world. Any other JSON is stored as written.
+func kvPutValue(rest string) string {
+	var s string
+	if strings.HasPrefix(rest, `"`) && json.Unmarshal([]byte(rest), &s) == nil {
+		return s
+	}
+	return rest
+}
+
+// kvPair is one key and its raw value.
+type kvPair struct {
+	Key   string
+	Value []byte
+}
+
+// kvStore is what a backend does for the KV commands.
+type kvStore interface {
+	// get returns the pair at key, or nil when there is none.
+	get(key string) (*kvPair, error)
+	// getPrefix returns up to limit pairs under prefix, in key order.
+	getPrefix(prefix string, limit int) ([]kvPair, error)
+	// list returns up to limit keys under prefix, in key order.
+	list(prefix string, limit int) ([]string, error)
+	put(key string, value []byte) error
+	// delete removes key, or every key under it when prefix is set, and
+	// reports how many keys went.
+	delete(key string, prefix bool) (int64, error)
+}
+
+// executeKvCommand runs a parsed stage against store and renders its
+// answer.
+func executeKvCommand(store kvStore, cmd kvCommand) ([]byte, error) {
+	switch cmd.name {
+	case "get":
+		if !cmd.prefix {
+			pair, err := store.get(cmd.key)
+			if err != nil || pair == nil {
+				return []byte("null"), err
+			}
+			return kvRow(*pair).MarshallJSON()
+		}
+		pairs, err := store.getPrefix(cmd.key, cmd.limit)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]common.RowData, 0, len(pairs))
+		for _, p := range pairs {
+			rows = append(rows, kvRow(p))
+		}
+		return marshalRowData(rows)
+	case "list":
+		keys, err := store.list(cmd.key, cmd.limit)
+		if err != nil {
+			return nil, err
+		}
+		rows := make([]common.RowData, 0, len(keys))
+		for _, k := range keys {
+			rows = append(rows, common.RowData{{Key: "key", Value: k}})
+		}
+		return marshalRowData(rows)
+	case "put":
+		if err := store.put(cmd.key, cmd.value); err != nil {
+			return nil, err
+		}
+		return json.Marshal(struct {
+			Applied bool `json:"applied"`
+		}{true})
+	case "delete":
+		n, err := store.delete(cmd.key, cmd.prefix)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(struct {
+			Deleted int64 `json:"deleted"`
+		}{n})
+	}
+	return nil, fmt.Errorf("unknown kv command %q", cmd.name)
+}
+
+func kvRow(p kvPair) common.RowData {
+	return common.RowData{{Key: "key", Value: p.Key}, {Key: "value", Value: kvValue(p.Value)}}
+}
+
+// kvValue decodes a stored value that is JSON, so flags and config
+// documents come back as JSON rather than strings; anything else is
+// returned as a string.
+func kvValue(b []byte) any {
+	if json.Valid(b) {
+		return json.RawMessage(b)
+	}
+	return string(b)
+}
@@ -0,0 +1,79 @@
+package adapters
+
+import (
+	"simpanan/internal/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeKv(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`get flags/checkout`, common.Read},
+		{`get flags/ --prefix`, common.Read},
+		{`list`, common.Read},
+		{`put flags/checkout {"enabled": true}`, common.Write},
+		{`DELETE flags/ --prefix`, common.Write},
+		{``, common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeKv(tt.query))
+		})
+	}
+}
+
+func TestParseKvCommand(t *testing.T) {
+	max := common.GetConfig().MaxRowLimit
+	tests := []struct {
+		query   string
+		want    kvCommand
+		wantErr bool
+	}{
+		{`get flags/checkout`, kvCommand{name: "get", key: "flags/checkout", limit: max}, false},
+		{`GET flags/ --prefix --limit 5`, kvCommand{name: "get", key: "flags/", prefix: true, limit: 5}, false},
+		{`get "service config/db"`, kvCommand{name: "get", key: "service config/db", limit: max}, false},
+		{`list`, kvCommand{name: "list", limit: max}, false},
+		{`list --limit 10 flags/`, kvCommand{name: "list", key: "flags/", limit: 10}, false},
+		{`put flags/checkout {"enabled": true}`,
+			kvCommand{name: "put", key: "flags/checkout", value: []byte(`{"enabled": true}`), limit: max}, false},
+		{`put greeting "hello world"`, kvCommand{name: "put", key: "greeting", value: []byte("hello world"), limit: max}, false},
+		{`put motd --not-a-flag`, kvCommand{name: "put", key: "motd", value: []byte("--not-a-flag"), limit: max}, false},
+		{`delete flags/ --prefix`, kvCommand{name: "delete", key: "flags/", prefix: true, limit: max}, false},
+		{``, kvCommand{}, true},
+		{`watch flags/`, kvCommand{}, true},
+		{`get`, kvCommand{}, true},
+		{`get a b`, kvCommand{}, true},
+		{`put key`, kvCommand{}, true},
+		{`list --prefix`, kvCommand{}, true},
+		{`get a --limit 5`, kvCommand{}, true},
+		{`list --limit 0`, kvCommand{}, true},
+		{`get a --recursive`, kvCommand{}, true},
+		{`get "unterminated`, kvCommand{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, err := parseKvCommand(tt.query)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestKvValue(t *testing.T) {
+	row := kvRow(kvPair{Key: "a", Value: []byte(`{"enabled":true}`)})
+	out, err := row.MarshallJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "a", "value": {"enabled": true}}`, string(out))
+
+	out, err = kvRow(kvPair{Key: "b", Value: []byte("plain text")}).MarshallJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "b", "value": "plain text"}`, string(out))
+}
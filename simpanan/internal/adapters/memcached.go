@@ -0,0 +1,416 @@
+package adapters
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"path"
+	"simpanan/internal/common"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const memcachedTimeout = 10 * time.Second
+
+// memcachedWriteCommands are the commands that change the cache.
+// Anything else is read, as with QueryTypeRedis.
+var memcachedWriteCommands = map[string]struct{}{
+	"set": {}, "add": {}, "replace": {}, "append": {}, "prepend": {}, "cas": {},
+	"delete": {}, "incr": {}, "decr": {}, "touch": {},
+}
+
+// QueryTypeMemcached classifies a memcached command line by its first
+// token, case-insensitively. Unknown commands default to read.
+func QueryTypeMemcached(query string) common.QueryType {
+	fields := strings.Fields(query)
+	if len(fields) == 0 {
+		return common.Read
+	}
+	if _, ok := memcachedWriteCommands[strings.ToLower(fields[0])]; ok {
+		return common.Write
+	}
+	return common.Read
+}
+
+// memcachedAddress reads a `memcached://host:11211` URI. The port
+// defaults to 11211.
+func memcachedAddress(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", fmt.Errorf("invalid memcached uri: %s", err)
+	}
+	if u.Scheme != "memcached" {
+		return "", fmt.Errorf("memcached uri must use memcached:// scheme, got %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", fmt.Errorf("memcached uri is missing a host: memcached://localhost:11211")
+	}
+	if u.Port() == "" {
+		return net.JoinHostPort(u.Hostname(), "11211"), nil
+	}
+	return u.Host, nil
+}
+
+// ExecuteMemcachedQuery runs one memcached command over the text
+// protocol. Arguments are tokenised like Redis commands, so values with
+// spaces are quoted:
+//
+//	get <key>...                   {"key", "value", "flags"}, null, or an array for several keys
+//	gets <key>...                  as get, plus "cas"
+//	set|add|replace|append|prepend <key> <value> [<ttl>]
+//	cas <key> <value> <cas> [<ttl>]
+//	delete <key>                   {"deleted": bool}
+//	incr|decr <key> <n>            {"value": n}, or null when missing
+//	touch <key> <ttl>              {"touched": bool}
+//	stats [items|slabs|settings]   {"name": value}
+//	stats cachedump <slab> <n>     [{"key", "size", "expires"}]
+//	keys [<glob>]                  every slab's cachedump, filtered
+//
+// Values that are JSON come back decoded.
+func ExecuteMemcachedQuery(q common.QueryMetadata) ([]byte, error) {
+	tokens, err := tokenizeRedisCommand(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty memcached command")
+	}
+	addr, err := memcachedAddress(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", addr, memcachedTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("memcached: %s", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(memcachedTimeout))
+	mc := &memcachedConn{r: bufio.NewReader(conn), w: conn}
+
+	name, args := strings.ToLower(tokens[0]), tokens[1:]
+	switch name {
+	case "get", "gets":
+		return mc.retrieve(name, args)
+	case "set", "add", "replace", "append", "prepend", "cas":
+		return mc.store(name, args)
+	case "delete":
+		if err := memcachedArgs(name, args, 1, 1, "<key>"); err != nil {
+			return nil, err
+		}
+		reply, err := mc.call("delete " + args[0])
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(struct {
+			Deleted bool `json:"deleted"`
+		}{reply == "DELETED"})
+	case "incr", "decr":
+		if err := memcachedArgs(name, args, 2, 2, "<key> <n>"); err != nil {
+			return nil, err
+		}
+		if _, err := strconv.ParseUint(args[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("%s needs a non-negative integer, got %q", name, args[1])
+		}
+		reply, err := mc.call(name + " " + args[0] + " " + args[1])
+		if err != nil || reply == "NOT_FOUND" {
+			return []byte("null"), err
+		}
+		n, err := strconv.ParseUint(reply, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("memcached: unexpected %s reply %q", name, reply)
+		}
+		return json.Marshal(struct {
+			Value uint64 `json:"value"`
+		}{n})
+	case "touch":
+		if err := memcachedArgs(name, args, 2, 2, "<key> <ttl>"); err != nil {
+			return nil, err
+		}
+		if _, err := strconv.ParseInt(args[1], 10, 64); err != nil {
+			return nil, fmt.Errorf("touch needs a ttl in seconds, got %q", args[1])
+		}
+		reply, err := mc.call("touch " + args[0] + " " + args[1])
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(struct {
+			Touched bool `json:"touched"`
+		}{reply == "TOUCHED"})
+	case "stats":
+		if len(args) > 0 && strings.ToLower(args[0]) == "cachedump" {
+			if err := memcachedArgs("stats cachedump", args[1:], 2, 2, "<slab> <limit>"); err != nil {
+				return nil, err
+			}
+			items, err := mc.cachedump(args[1], args[2])
+			if err != nil {
+				return nil, err
+			}
+			return json.Marshal(items)
+		}
+		stats, err := mc.stats(args)
+		if err != nil {
+			return nil, err
+		}
+		return stats.MarshallJSON()
+	case "keys":
+		if err := memcachedArgs(name, args, 0, 1, "[<glob>]"); err != nil {
+			return nil, err
+		}
+		pattern := "*"
+		if len(args) == 1 {
+			pattern = args[0]
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid keys pattern %q: %s", pattern, err)
+		}
+		items, err := mc.keys(pattern, common.GetConfig().MaxRowLimit)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(items)
+	}
+	return nil, fmt.Errorf("unknown memcached command %q", tokens[0])
+}
+
+func memcachedArgs(name string, args []string, min, max int, usage string) error {
+	if len(args) < min || len(args) > max {
+		return fmt.Errorf("usage: %s %s", name, usage)
+	}
+	for _, a := range args {
+		if a == "" || strings.ContainsAny(a, " \t\r\n") {
+			return fmt.Errorf("%s: %q is not a valid memcached key or number", name, a)
+		}
+	}
+	return nil
+}
+
+// memcachedItem is one cachedump line: the key, its size in bytes and
+// its expiry as a unix time (0 when it never expires).
+type memcachedItem struct {
+	Key     string `json:"key"`
+	Size    int64  `json:"size"`
+	Expires int64  `json:"expires"`
+}
+
+type memcachedConn struct {
+	r *bufio.Reader
+	w io.Writer
+}
+
+// call sends one command line and returns the one-line reply, turning
+// ERROR, CLIENT_ERROR and SERVER_ERROR into errors.
+func (mc *memcachedConn) call(line string) (string, error) {
+	if _, err := io.WriteString(mc.w, line+"\r\n"); err != nil {
+		return "", fmt.Errorf("memcached: %s", err)
+	}
+	return mc.readLine()
+}
+
+func (mc *memcachedConn) readLine() (string, error) {
+	line, err := mc.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("memcached: %s", err)
+	}
+	line = strings.TrimRight(line, "\r\n")
+	switch {
+	case line == "ERROR":
+		return "", fmt.Errorf("memcached: unknown command")
+	case strings.HasPrefix(line, "CLIENT_ERROR "), strings.HasPrefix(line, "SERVER_ERROR "):
+		_, msg, _ := strings.Cut(line, " ")
+		return "", fmt.Errorf("memcached: %s", msg)
+	}
+	return line, nil
+}
+
+// retrieve runs get or gets. One key answers its item or null; several
+// keys answer an array of the items found.
+func (mc *memcachedConn) retrieve(name string, keys []string) ([]byte, error) {
+	if err := memcachedArgs(name, keys, 1, len(keys)+1, "<key>..."); err != nil {
+		return nil, err
+	}
+	line, err := mc.call(name + " " + strings.Join(keys, " "))
+	if err != nil {
+		return nil, err
+	}
+	rows := []common.RowData{}
+	for ; line != "END"; line, err = mc.readLine() {
+		if err != nil {
+			return nil, err
+		}
+		// VALUE <key> <flags> <bytes> [<cas>]
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != "VALUE" {
+			return nil, fmt.Errorf("memcached: unexpected %s reply %q", name, line)
+		}
+		size, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return nil, fmt.Errorf("memcached: unexpected %s reply %q", name, line)
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(mc.r, data); err != nil {
+			return nil, fmt.Errorf("memcached: %s", err)
+		}
+		flags, _ := strconv.ParseUint(fields[2], 10, 32)
+		row := common.RowData{
+			{Key: "key", Value: fields[1]},
+			{Key: "value", Value: kvValue(data[:size])},
+			{Key: "flags", Value: flags},
+		}
+		if name == "gets" && len(fields) > 4 {
+			cas, _ := strconv.ParseUint(fields[4], 10, 64)
+			row = append(row, common.ColumnValuePair{Key: "cas", Value: cas})
+		}
+		rows = append(rows, row)
+	}
+	if len(keys) > 1 {
+		return marshalRowData(rows)
+	}
+	if len(rows) == 0 {
+		return []byte("null"), nil
+	}
+	return rows[0].MarshallJSON()
+}
+
+// store runs a storage command: `<cmd> <key> <value> [<ttl>]`, or
+// `cas <key> <value> <cas> [<ttl>]`. It answers {"stored": true}, or
+// {"stored": false, "reason": ...} with memcached's reply lower-cased.
+func (mc *memcachedConn) store(name string, args []string) ([]byte, error) {
+	usage, min := "<key> <value> [<ttl>]", 2
+	if name == "cas" {
+		usage, min = "<key> <value> <cas> [<ttl>]", 3
+	}
+	if len(args) < min || len(args) > min+1 {
+		return nil, fmt.Errorf("usage: %s %s", name, usage)
+	}
+	key, value, rest := args[0], args[1], args[2:]
+	if err := memcachedArgs(name, append([]string{key}, rest...), 1, 3, usage); err != nil {
+		return nil, err
+	}
+	cas := ""
+	if name == "cas" {
+		if _, err := strconv.ParseUint(rest[0], 10, 64); err != nil {
+			return nil, fmt.Errorf("cas needs the unique value from gets, got %q", rest[0])
+		}
+		cas, rest = " "+rest[0], rest[1:]
+	}
+	ttl := "0"
+	if len(rest) == 1 {
+		if _, err := strconv.ParseInt(rest[0], 10, 64); err != nil {
+			return nil, fmt.Errorf("%s needs a ttl in seconds, got %q", name, rest[0])
+		}
+		ttl = rest[0]
+	}
+	reply, err := mc.call(fmt.Sprintf("%s %s 0 %s %d%s\r\n%s", name, key, ttl, len(value), cas, value))
+	if err != nil {
+		return nil, err
+	}
+	if reply == "STORED" {
+		return json.Marshal(struct {
+			Stored bool `json:"stored"`
+		}{true})
+	}
+	return json.Marshal(struct {
+		Stored bool   `json:"stored"`
+		Reason string `json:"reason"`
+	}{false, strings.ToLower(reply)})
+}
+
+// stats runs `stats [group]` and answers its STAT lines as one row,
+// numbers as numbers.
+func (mc *memcachedConn) stats(args []string) (common.RowData, error) {
+	if err := memcachedArgs("stats", args, 0, 1, "[items|slabs|settings|sizes|conns]"); err != nil {
+		return nil, err
+	}
+	line, err := mc.call(strings.TrimSpace("stats " + strings.Join(args, " ")))
+	if err != nil {
+		return nil, err
+	}
+	row := common.RowData{}
+	for ; line != "END"; line, err = mc.readLine() {
+		if err != nil {
+			return nil, err
+		}
+		// STAT <name> <value>
+		fields := strings.SplitN(line, " ", 3)
+		if len(fields) < 3 || fields[0] != "STAT" {
+			return nil, fmt.Errorf("memcached: unexpected stats reply %q", line)
+		}
+		row = append(row, common.ColumnValuePair{Key: fields[1], Value: memcachedStatValue(fields[2])})
+	}
+	return row, nil
+}
+
+func memcachedStatValue(s string) any {
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n
+	}
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+	return s
+}
+
+// cachedump lists up to limit keys of one slab class (0 for as many as
+// memcached will return in one reply).
+func (mc *memcachedConn) cachedump(slab, limit string) ([]memcachedItem, error) {
+	line, err := mc.call("stats cachedump " + slab + " " + limit)
+	if err != nil {
+		return nil, err
+	}
+	items := []memcachedItem{}
+	for ; line != "END"; line, err = mc.readLine() {
+		if err != nil {
+			return nil, err
+		}
+		// ITEM <key> [<size> b; <expires> s]
+		var item memcachedItem
+		if _, err := fmt.Sscanf(line, "ITEM %s [%d b; %d s]", &item.Key, &item.Size, &item.Expires); err != nil {
+			return nil, fmt.Errorf("memcached: unexpected cachedump reply %q", line)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// keys walks `stats items` for the slab classes in use and dumps each
+// one, keeping up to limit keys matching pattern in key order.
+// cachedump is a debugging aid: memcached caps each reply, so very
+// large slabs are listed only in part.
+func (mc *memcachedConn) keys(pattern string, limit int) ([]memcachedItem, error) {
+	stats, err := mc.stats([]string{"items"})
+	if err != nil {
+		return nil, err
+	}
+	var slabs []string
+	seen := map[string]bool{}
+	for _, s := range stats {
+		// items:<slab>:number
+		parts := strings.Split(s.Key, ":")
+		if len(parts) == 3 && parts[0] == "items" && !seen[parts[1]] {
+			seen[parts[1]] = true
+			slabs = append(slabs, parts[1])
+		}
+	}
+	items := []memcachedItem{}
+	for _, slab := range slabs {
+		dump, err := mc.cachedump(slab, "0")
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range dump {
+			if ok, _ := path.Match(pattern, item.Key); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Key < items[j].Key })
+	if len(items) > limit {
+		items = items[:limit]
+	}
+	return items, nil
+}
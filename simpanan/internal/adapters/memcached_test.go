@@ -0,0 +1,263 @@
+package adapters
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"simpanan/internal/common"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeMemcached(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`get session:1`, common.Read},
+		{`gets a b`, common.Read},
+		{`stats items`, common.Read},
+		{`keys session:*`, common.Read},
+		{`set session:1 "{}" 60`, common.Write},
+		{`CAS k v 12`, common.Write},
+		{`delete session:1`, common.Write},
+		{`incr hits 1`, common.Write},
+		{`touch k 10`, common.Write},
+		{``, common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeMemcached(tt.query))
+		})
+	}
+}
+
+func TestMemcachedAddress(t *testing.T) {
+	addr, err := memcachedAddress("memcached://cache")
+	assert.NoError(t, err)
+	assert.Equal(t, "cache:11211", addr)
+
+	addr, err = memcachedAddress("memcached://127.0.0.1:22122")
+	assert.NoError(t, err)
+	assert.Equal(t, "127.0.0.1:22122", addr)
+
+	_, err = memcachedAddress("memcached://")
+	assert.Error(t, err)
+	_, err = memcachedAddress("redis://cache:6379")
+	assert.Error(t, err)
+}
+
+type fakeMemcachedItem struct {
+	value []byte
+	flags int
+	cas   int
+	slab  int
+}
+
+// fakeMemcached is an in-process stand-in for memcached's text protocol,
+// serving the commands the adapter sends. Items up to 16 bytes live in
+// slab 1, bigger ones in slab 2.
+func fakeMemcached(t *testing.T, items map[string]*fakeMemcachedItem) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	nextCas := 100
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					if len(fields) == 0 {
+						fmt.Fprint(conn, "ERROR\r\n")
+						continue
+					}
+					switch fields[0] {
+					case "get", "gets":
+						for _, k := range fields[1:] {
+							if it, ok := items[k]; ok {
+								cas := ""
+								if fields[0] == "gets" {
+									cas = " " + strconv.Itoa(it.cas)
+								}
+								fmt.Fprintf(conn, "VALUE %s %d %d%s\r\n%s\r\n", k, it.flags, len(it.value), cas, it.value)
+							}
+						}
+						fmt.Fprint(conn, "END\r\n")
+					case "set", "add", "cas":
+						size, _ := strconv.Atoi(fields[4])
+						data := make([]byte, size+2)
+						io.ReadFull(r, data)
+						it, exists := items[fields[1]]
+						switch {
+						case fields[0] == "add" && exists:
+							fmt.Fprint(conn, "NOT_STORED\r\n")
+							continue
+						case fields[0] == "cas" && !exists:
+							fmt.Fprint(conn, "NOT_FOUND\r\n")
+							continue
+						case fields[0] == "cas" && fields[5] != strconv.Itoa(it.cas):
+							fmt.Fprint(conn, "EXISTS\r\n")
+							continue
+						}
+						flags, _ := strconv.Atoi(fields[2])
+						slab := 1
+						if size > 16 {
+							slab = 2
+						}
+						nextCas++
+						items[fields[1]] = &fakeMemcachedItem{value: data[:size], flags: flags, cas: nextCas, slab: slab}
+						fmt.Fprint(conn, "STORED\r\n")
+					case "delete":
+						if _, ok := items[fields[1]]; !ok {
+							fmt.Fprint(conn, "NOT_FOUND\r\n")
+							continue
+						}
+						delete(items, fields[1])
+						fmt.Fprint(conn, "DELETED\r\n")
+					case "incr":
+						it, ok := items[fields[1]]
+						if !ok {
+							fmt.Fprint(conn, "NOT_FOUND\r\n")
+							continue
+						}
+						n, err := strconv.Atoi(string(it.value))
+						if err != nil {
+							fmt.Fprint(conn, "CLIENT_ERROR cannot increment or decrement non-numeric value\r\n")
+							continue
+						}
+						by, _ := strconv.Atoi(fields[2])
+						it.value = []byte(strconv.Itoa(n + by))
+						fmt.Fprintf(conn, "%d\r\n", n+by)
+					case "stats":
+						switch {
+						case len(fields) == 1:
+							fmt.Fprint(conn, "STAT pid 42\r\nSTAT version 1.6.21\r\nSTAT rusage_user 0.125\r\nEND\r\n")
+						case fields[1] == "items":
+							counts := map[int]int{}
+							for _, it := range items {
+								counts[it.slab]++
+							}
+							for _, slab := range []int{1, 2} {
+								if counts[slab] > 0 {
+									fmt.Fprintf(conn, "STAT items:%d:number %d\r\nSTAT items:%d:age 10\r\n", slab, counts[slab], slab)
+								}
+							}
+							fmt.Fprint(conn, "END\r\n")
+						case fields[1] == "cachedump":
+							var keys []string
+							for k, it := range items {
+								if strconv.Itoa(it.slab) == fields[2] {
+									keys = append(keys, k)
+								}
+							}
+							sort.Strings(keys)
+							for _, k := range keys {
+								fmt.Fprintf(conn, "ITEM %s [%d b; 0 s]\r\n", k, len(items[k].value))
+							}
+							fmt.Fprint(conn, "END\r\n")
+						}
+					default:
+						fmt.Fprint(conn, "ERROR\r\n")
+					}
+				}
+			}()
+		}
+	}()
+	return "memcached://" + ln.Addr().String()
+}
+
+func TestMemcachedAgainstFake(t *testing.T) {
+	items := map[string]*fakeMemcachedItem{
+		"session:1": {value: []byte(`{"user":"alice","roles":["admin"]}`), flags: 0, cas: 7, slab: 2},
+		"session:2": {value: []byte("opaque"), flags: 3, cas: 8, slab: 1},
+		"hits":      {value: []byte("41"), cas: 9, slab: 1},
+	}
+	conn := fakeMemcached(t, items)
+	run := func(query string) (string, error) {
+		out, err := ExecuteMemcachedQuery(common.QueryMetadata{Conn: conn, QueryLine: query})
+		return string(out), err
+	}
+
+	out, err := run(`get session:1`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"key": "session:1", "value": {"user": "alice", "roles": ["admin"]}, "flags": 0}`, out)
+
+	out, err = run(`get missing`)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", out)
+
+	out, err = run(`gets session:2 missing hits`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "session:2", "value": "opaque", "flags": 3, "cas": 8}, {"key": "hits", "value": 41, "flags": 0, "cas": 9}]`, out)
+
+	out, err = run(`set greeting "hello world" 60`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"stored": true}`, out)
+	assert.Equal(t, "hello world", string(items["greeting"].value))
+
+	out, err = run(`add greeting again`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"stored": false, "reason": "not_stored"}`, out)
+
+	out, err = run(`cas session:2 fresh 1`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"stored": false, "reason": "exists"}`, out)
+
+	out, err = run(`cas session:2 fresh 8`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"stored": true}`, out)
+
+	out, err = run(`incr hits 1`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"value": 42}`, out)
+
+	out, err = run(`incr missing 1`)
+	assert.NoError(t, err)
+	assert.Equal(t, "null", out)
+
+	_, err = run(`incr greeting 1`)
+	assert.EqualError(t, err, "memcached: cannot increment or decrement non-numeric value")
+
+	out, err = run(`stats`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"pid": 42, "version": "1.6.21", "rusage_user": 0.125}`, out)
+
+	out, err = run(`stats cachedump 2 0`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "session:1", "size": 34, "expires": 0}]`, out)
+
+	out, err = run(`keys session:*`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"key": "session:1", "size": 34, "expires": 0}, {"key": "session:2", "size": 5, "expires": 0}]`, out)
+
+	out, err = run(`delete session:2`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"deleted": true}`, out)
+
+	out, err = run(`delete session:2`)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"deleted": false}`, out)
+
+	_, err = run(`flush_all`)
+	assert.EqualError(t, err, `unknown memcached command "flush_all"`)
+	_, err = run(`set "two words" v`)
+	assert.Error(t, err)
+	_, err = run(`set k v soon`)
+	assert.Error(t, err)
+}
@@ -3,6 +3,7 @@ package adapters
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/url"
 	"regexp"
@@ -36,8 +37,9 @@ func mongoDBName(connURI string) (string, error) {
 
 type (
 	method              string
-	queryHandlerFn      func(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error)
+	queryHandlerFn      func(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error)
 	adminQueryHandlerFn func(ctx context.Context, db *mongo.Database, paramStrs ...*string) ([]byte, error)
+	dbQueryHandlerFn    func(ctx context.Context, db *mongo.Database, mq *mongoQuery) ([]byte, error)
 )
 
 var (
@@ -47,18 +49,33 @@ var (
 	aggregate              method = "aggregate"
 	distinct               method = "distinct"
 	count                  method = "count"
+	countDocuments         method = "countDocuments"
 	estimatedDocumentCount method = "estimatedDocumentCount"
+	getIndexes             method = "getIndexes"
+	stats                  method = "stats"
 
 	// read methods, admin
 	showCollections method = "collections"
 
 	// write methods
-	insertOne  method = "insertOne"
-	insertMany method = "insertMany"
-	updateOne  method = "updateOne"
-	updateMany method = "updateMany"
-	deleteOne  method = "deleteOne"
-	deleteMany method = "deleteMany"
+	insertOne         method = "insertOne"
+	insertMany        method = "insertMany"
+	updateOne         method = "updateOne"
+	updateMany        method = "updateMany"
+	replaceOne        method = "replaceOne"
+	deleteOne         method = "deleteOne"
+	deleteMany        method = "deleteMany"
+	findOneAndUpdate  method = "findOneAndUpdate"
+	findOneAndReplace method = "findOneAndReplace"
+	findOneAndDelete  method = "findOneAndDelete"
+	bulkWrite         method = "bulkWrite"
+
+	// admin methods
+	createIndex method = "createIndex"
+	dropIndex   method = "dropIndex"
+
+	// database methods: db.<method>(...) without a collection
+	runCommand method = "runCommand"
 
 	readActions = map[method]queryHandlerFn{
 		find:                   handleFind,
@@ -66,7 +83,10 @@ var (
 		aggregate:              handleAggregate,
 		distinct:               handleDistinct,
 		count:                  handleCount,
+		countDocuments:         handleCount,
 		estimatedDocumentCount: handleEstimatedDocCount,
+		getIndexes:             handleGetIndexes,
+		stats:                  handleStats,
 	}
 
 	adminReadActions = map[method]adminQueryHandlerFn{
@@ -74,16 +94,34 @@ var (
 	}
 
 	writeActions = map[method]queryHandlerFn{
-		insertOne:  handleInsertOne,
-		insertMany: handleInsertMany,
-		updateOne:  handleUpdateOne,
-		updateMany: handleUpdateMany,
-		deleteOne:  handleDeleteOne,
-		deleteMany: handleDeleteMany,
+		insertOne:         handleInsertOne,
+		insertMany:        handleInsertMany,
+		updateOne:         handleUpdateOne,
+		updateMany:        handleUpdateMany,
+		replaceOne:        handleReplaceOne,
+		deleteOne:         handleDeleteOne,
+		deleteMany:        handleDeleteMany,
+		findOneAndUpdate:  handleFindOneAndUpdate,
+		findOneAndReplace: handleFindOneAndReplace,
+		findOneAndDelete:  handleFindOneAndDelete,
+		bulkWrite:         handleBulkWrite,
+	}
+
+	adminActions = map[method]queryHandlerFn{
+		createIndex: handleCreateIndex,
+		dropIndex:   handleDropIndex,
+	}
+
+	dbActions = map[method]dbQueryHandlerFn{
+		runCommand: handleRunCommand,
 	}
 )
 
 func ExecuteMongoReadQuery(q common.QueryMetadata) ([]byte, error) {
+	if !strings.HasPrefix(q.QueryLine, "show") {
+		return executeMongoQuery(q, readActions, "Read")
+	}
+
 	opt := options.Client().ApplyURI(q.Conn)
 
 	ctx := context.Background()
@@ -104,50 +142,117 @@ func ExecuteMongoReadQuery(q common.QueryMetadata) ([]byte, error) {
 	}
 	db := client.Database(dbName)
 
-	if strings.HasPrefix(q.QueryLine, "show") {
-		matches := regexp.MustCompile(`^show (.*)$`).FindAllStringSubmatch(q.QueryLine, -1)
-		if len(matches) < 1 || len(matches[0]) < 2 {
-			return nil, fmt.Errorf("Invalid read query type and data: '%+v'", matches)
-		}
+	matches := regexp.MustCompile(`^show (.*)$`).FindAllStringSubmatch(q.QueryLine, -1)
+	if len(matches) < 1 || len(matches[0]) < 2 {
+		return nil, fmt.Errorf("Invalid read query type and data: '%+v'", matches)
+	}
 
-		handler, ok := adminReadActions[method(matches[0][1])]
-		if !ok {
-			return nil, fmt.Errorf("Admin read handler not found: %v.", matches[0][1])
-		}
+	handler, ok := adminReadActions[method(matches[0][1])]
+	if !ok {
+		return nil, fmt.Errorf("Admin read handler not found: %v.", matches[0][1])
+	}
 
-		return handler(ctx, db, &matches[0][1])
-	} else {
-		matches := regexp.MustCompile(`db\.(.*?)\.(.*?)\((.*?)\)(\..*)?$`).FindAllStringSubmatch(q.QueryLine, -1)
+	return handler(ctx, db, &matches[0][1])
+}
 
-		if len(matches) < 1 || len(matches[0]) < 4 {
-			return nil, fmt.Errorf("Invalid read query type and data: '%+v'", matches)
-		}
+func ExecuteMongoWriteQuery(q common.QueryMetadata) ([]byte, error) {
+	return executeMongoQuery(q, writeActions, "Write")
+}
 
-		coll := db.Collection(matches[0][1])
-		handler, ok := readActions[method(matches[0][2])]
-		if !ok {
-			return nil, fmt.Errorf("Read handler not found: %v.", matches[0][2])
+// ExecuteMongoAdminQuery runs index management and db.runCommand()
+// stages.
+func ExecuteMongoAdminQuery(q common.QueryMetadata) ([]byte, error) {
+	return executeMongoQuery(q, adminActions, "Admin")
+}
+
+// executeMongoQuery parses a call chain and hands it to its collection
+// method in actions, or to a database method such as db.runCommand().
+// The query is parsed before connecting so syntax errors come back
+// without a round-trip.
+func executeMongoQuery(q common.QueryMetadata, actions map[method]queryHandlerFn, kind string) ([]byte, error) {
+	mq, err := parseMongoQuery(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+	if mq.canonical, err = mongoCanonicalOutput(q.Conn); err != nil {
+		return nil, err
+	}
+	var dbHandler dbQueryHandlerFn
+	ok := false
+	if mq.coll == "" {
+		dbHandler, ok = dbActions[method(mq.method.name)]
+	}
+	handler, found := actions[method(mq.method.name)]
+	if !ok && !found {
+		return nil, fmt.Errorf("%s handler not found: %v.", kind, mq.method.name)
+	}
+	if !ok && mq.coll == "" {
+		return nil, &mongoSyntaxError{pos: mq.method.pos, msg: fmt.Sprintf("db.%s() needs a collection: db.<collection>.%s(...)", mq.method.name, mq.method.name)}
+	}
+	// Only find takes cursor options.
+	if len(mq.opts) > 0 && mq.method.name != string(find) {
+		return nil, &mongoSyntaxError{pos: mq.opts[0].pos, msg: fmt.Sprintf("%s() does not take .%s()", mq.method.name, mq.opts[0].name)}
+	}
+
+	opt := options.Client().ApplyURI(q.Conn)
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Disconnect(ctx)
+
+	err = client.Ping(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if dbHandler != nil {
+		db, err := mongoDatabase(client, q.Conn, mq)
+		if err != nil {
+			return nil, err
 		}
+		return dbHandler(ctx, db, mq)
+	}
+	coll, err := mongoCollection(client, q.Conn, mq)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, coll, mq)
+}
 
-		cursorOptStr := matches[0][4]
-		return handler(ctx, coll, &matches[0][3], &cursorOptStr)
+// mongoDatabase resolves the database a parsed query targets: the
+// URI's, unless the query picked another with db.getSiblingDB().
+func mongoDatabase(client *mongo.Client, connURI string, mq *mongoQuery) (*mongo.Database, error) {
+	dbName := mq.db
+	if dbName == "" {
+		var err error
+		if dbName, err = mongoDBName(connURI); err != nil {
+			return nil, err
+		}
 	}
+	return client.Database(dbName), nil
 }
 
-func handleFind(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
+// mongoCollection resolves the collection a parsed query targets.
+func mongoCollection(client *mongo.Client, connURI string, mq *mongoQuery) (*mongo.Collection, error) {
+	db, err := mongoDatabase(client, connURI, mq)
 	if err != nil {
 		return nil, err
 	}
+	return db.Collection(mq.coll), nil
+}
 
-	f, err := constructBsonObject(params[0])
+func handleFind(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	f, err := constructBsonDoc(mq.method.arg(0, "{}"))
 	if err != nil {
 		return nil, err
 	}
 
 	opts := options.Find()
-	if len(params) > 1 {
-		o, err := constructBsonObject(params[1])
+	if len(mq.method.args) > 1 {
+		o, err := constructBsonDoc(mq.method.args[1])
 		if err != nil {
 			return nil, err
 		}
@@ -155,10 +260,11 @@ func handleFind(ctx context.Context, coll *mongo.Collection, paramStrs ...*strin
 		opts.SetProjection(o)
 	}
 
-	cursorOpts, err := parseCursorOpts(find, *paramStrs[1])
+	cursorOpts, err := parseCursorOpts(find, mq.opts)
 	if err != nil {
 		return nil, err
 	}
+	var explain *findExplain
 	for _, co := range cursorOpts {
 		newOpt, err := co.Apply(opts)
 		if err != nil {
@@ -169,6 +275,18 @@ func handleFind(ctx context.Context, coll *mongo.Collection, paramStrs ...*strin
 			return nil, fmt.Errorf("Failed parsing FindOptions for %v", co)
 		}
 		opts = tmp
+		if e, ok := co.(findExplain); ok {
+			explain = &e
+		}
+	}
+
+	if explain != nil {
+		var result bson.D
+		cmd := bson.D{{Key: "explain", Value: findExplainCommand(coll.Name(), f, opts)}, {Key: "verbosity", Value: explain.verbosity}}
+		if err := coll.Database().RunCommand(ctx, cmd).Decode(&result); err != nil {
+			return nil, fmt.Errorf("%s: %v", err, f)
+		}
+		return mongoExtJSON(result, mq.canonical)
 	}
 
 	cursor, err := coll.Find(ctx, f, opts)
@@ -179,13 +297,13 @@ func handleFind(ctx context.Context, coll *mongo.Collection, paramStrs ...*strin
 
 	rowCount := 0
 
-	tmpRes := []map[string]any{}
+	tmpRes := bson.A{}
 	for cursor.Next(ctx) {
 		if rowCount == common.GetConfig().MaxRowLimit {
 			break
 		}
 
-		var result map[string]any
+		var result bson.D
 		if err := cursor.Decode(&result); err != nil {
 			return nil, err
 		}
@@ -197,27 +315,18 @@ func handleFind(ctx context.Context, coll *mongo.Collection, paramStrs ...*strin
 		return nil, err
 	}
 
-	res, err := json.Marshal(tmpRes)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %v.", err.Error(), tmpRes)
-	}
-	return res, nil
+	return mongoExtJSON(tmpRes, mq.canonical)
 }
 
-func handleFindOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
-		return nil, err
-	}
-
-	f, err := constructBsonObject(params[0])
+func handleFindOne(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	f, err := constructBsonDoc(mq.method.arg(0, "{}"))
 	if err != nil {
 		return nil, err
 	}
 
 	opts := options.FindOne()
-	if len(params) > 1 {
-		o, err := constructBsonObject(params[1])
+	if len(mq.method.args) > 1 {
+		o, err := constructBsonDoc(mq.method.args[1])
 		if err != nil {
 			return nil, err
 		}
@@ -225,30 +334,25 @@ func handleFindOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*st
 		opts.SetProjection(o)
 	}
 
-	var result bson.M
+	var result bson.D
 	err = coll.FindOne(ctx, f, opts).Decode(&result)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", err, f)
 	}
-	res, err := json.Marshal(result)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %v.", err.Error(), result)
-	}
-	return res, nil
+	return mongoExtJSON(result, mq.canonical)
 }
 
-func handleAggregate(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
+func handleAggregate(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	f, err := constructBsonArray(mq.method.arg(0, "[]"))
 	if err != nil {
 		return nil, err
 	}
-	f, err := constructBsonArray(params[0])
+	opts, err := newAggregateOpts(mq.method.arg(1, "{}"))
 	if err != nil {
 		return nil, err
 	}
 
-	// TODO: handle opts
-	cursor, err := coll.Aggregate(ctx, f)
+	cursor, err := coll.Aggregate(ctx, f, opts)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", err, f)
 	}
@@ -256,13 +360,13 @@ func handleAggregate(ctx context.Context, coll *mongo.Collection, paramStrs ...*
 
 	rowCount := 0
 
-	tmpRes := []map[string]any{}
+	tmpRes := bson.A{}
 	for cursor.Next(ctx) {
 		if rowCount == common.GetConfig().MaxRowLimit {
 			break
 		}
 
-		var result map[string]any
+		var result bson.D
 		if err := cursor.Decode(&result); err != nil {
 			return nil, err
 		}
@@ -274,24 +378,19 @@ func handleAggregate(ctx context.Context, coll *mongo.Collection, paramStrs ...*
 		return nil, err
 	}
 
-	res, err := json.Marshal(tmpRes)
-	if err != nil {
-		return nil, fmt.Errorf("%s: %v.", err.Error(), tmpRes)
-	}
-	return res, nil
+	return mongoExtJSON(tmpRes, mq.canonical)
 }
 
-func handleCount(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
+func handleCount(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	f, err := constructBsonDoc(mq.method.arg(0, "{}"))
 	if err != nil {
 		return nil, err
 	}
-
-	f, err := constructBsonObject(params[0])
+	opts, err := newCountOpts(mq.method.name, mq.method.arg(1, "{}"))
 	if err != nil {
 		return nil, err
 	}
-	count, err := coll.CountDocuments(ctx, f)
+	count, err := coll.CountDocuments(ctx, f, opts)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v", err, f)
 	}
@@ -302,7 +401,7 @@ func handleCount(ctx context.Context, coll *mongo.Collection, paramStrs ...*stri
 	return res, nil
 }
 
-func handleEstimatedDocCount(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
+func handleEstimatedDocCount(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
 	count, err := coll.EstimatedDocumentCount(ctx)
 	if err != nil {
 		return nil, err
@@ -314,90 +413,99 @@ func handleEstimatedDocCount(ctx context.Context, coll *mongo.Collection, paramS
 	return res, nil
 }
 
-func handleDistinct(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
+func handleDistinct(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
+		return nil, err
+	}
+	fieldName, ok := shellString(mq.method.args[0])
+	if !ok {
+		return nil, fmt.Errorf("distinct() takes a field name string first, got %s", mq.method.args[0])
+	}
+	f, err := constructBsonDoc(mq.method.arg(1, "{}"))
 	if err != nil {
 		return nil, err
 	}
-
-	fieldAndFilter := strings.Split(params[0], ",")
-	fieldName := strings.ReplaceAll(fieldAndFilter[0], "\"", "")
-	var f any
-	if len(fieldAndFilter) > 1 {
-		f2, err := constructBsonObject(fieldAndFilter[1])
-		if err != nil {
-			return nil, err
-		}
-		f = f2
-	} else {
-		f = bson.D{}
+	opts, err := newDistinctOpts(mq.method.arg(2, "{}"))
+	if err != nil {
+		return nil, err
 	}
-	values, err := coll.Distinct(ctx, fieldName, f)
+	values, err := coll.Distinct(ctx, fieldName, f, opts)
 	if err != nil {
 		return nil, fmt.Errorf("%s: %s - %v", err.Error(), fieldName, f)
 	}
-	res, err := json.Marshal(values)
+	return mongoExtJSON(bson.A(values), mq.canonical)
+}
+
+// constructBsonDoc decodes a document argument written the way mongosh
+// accepts it (unquoted keys, single quotes, ObjectId(...), /regex/i, ...)
+// into a bson.D, so filters, updates and pipeline stages reach the
+// server with their keys in the order they were written.
+func constructBsonDoc(objStr string) (bson.D, error) {
+	extJSON, err := shellToExtJSON(objStr)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %v.", err.Error(), values)
+		return nil, fmt.Errorf("%s: %s.", err.Error(), objStr)
 	}
-	return res, nil
-}
 
-func constructBsonObject(objStr string) (any, error) {
-	if objStr == "{}" {
-		return bson.D{}, nil
+	doc := bson.D{}
+	if err := bson.UnmarshalExtJSON([]byte(extJSON), false, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %s.", err.Error(), objStr)
 	}
+	if err := convertLegacyObjectId(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
 
-	var resultMap bson.M
-	err := bson.UnmarshalExtJSON([]byte(objStr), false, &resultMap)
-	if err != nil {
-		if strings.Contains(err.Error(), "invalid JSON input") {
-			return bson.D{}, fmt.Errorf("%s: %s.\nHint: are all the key-value string fields enclosed by quotes?", err.Error(), objStr)
+// convertLegacyObjectId turns an `_id` written as the string
+// "ObjectId('<hex>')", which older queries use, into an ObjectID.
+func convertLegacyObjectId(doc bson.D) error {
+	for i, e := range doc {
+		v, ok := e.Value.(string)
+		if e.Key != "_id" || !ok || !strings.HasPrefix(v, "ObjectId") {
+			continue
 		}
-		return bson.D{}, fmt.Errorf("%s: %s.", err.Error(), objStr)
-	}
-
-	v, ok := resultMap["_id"]
-	if ok {
-		switch v.(type) {
-		case string:
-			if strings.HasPrefix(v.(string), "ObjectId") {
-				// special handling for ObjectId type
-				replacer := strings.NewReplacer("'", "", "(", "", ")", "")
-				hex := replacer.Replace(strings.Split(fmt.Sprintf("%v", v), "ObjectId")[1])
-				id, err := primitive.ObjectIDFromHex(hex)
-				if err != nil {
-					return bson.D{}, fmt.Errorf("%s: %s.", err.Error(), hex)
-				}
-				resultMap["_id"] = id
-			}
+		replacer := strings.NewReplacer("'", "", "(", "", ")", "")
+		hex := replacer.Replace(strings.TrimPrefix(v, "ObjectId"))
+		id, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			return fmt.Errorf("%s: %s.", err.Error(), hex)
 		}
+		doc[i].Value = id
 	}
-
-	return resultMap, nil
+	return nil
 }
 
-func constructBsonArray(objArr string) ([]any, error) {
-	objArr = strings.TrimPrefix(objArr, "[")
-	objArr = strings.TrimSuffix(objArr, "]")
+// constructBsonArray decodes an array argument, such as a pipeline or
+// the documents of insertMany, into a bson.A whose elements are all
+// documents.
+func constructBsonArray(objArr string) (bson.A, error) {
+	if strings.TrimSpace(objArr) == "" {
+		return bson.A{}, nil
+	}
 
-	objs, err := splitCommaSeparatedObjStr(objArr)
+	extJSON, err := shellToExtJSON(objArr)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("%s: %s.", err.Error(), objArr)
 	}
 
-	var resArr bson.A
-	for _, o := range objs {
-		// not calling bson.UnmarshalExtJSON for special field handling
-		bsonObj, err := constructBsonObject(o)
-		if err != nil {
+	var wrapper bson.D
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+extJSON+`}`), false, &wrapper); err != nil {
+		return nil, fmt.Errorf("%s: %s.", err.Error(), objArr)
+	}
+	arr, ok := wrapper[0].Value.(bson.A)
+	if !ok {
+		return nil, fmt.Errorf("expected an array: %s.", objArr)
+	}
+	for i, v := range arr {
+		doc, ok := v.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("element %d is not a document: %s.", i, objArr)
+		}
+		if err := convertLegacyObjectId(doc); err != nil {
 			return nil, err
 		}
-
-		resArr = append(resArr, bsonObj)
 	}
-
-	return resArr, nil
+	return arr, nil
 }
 
 func QueryTypeMongo(query string) common.QueryType {
@@ -405,87 +513,65 @@ func QueryTypeMongo(query string) common.QueryType {
 		return common.Read
 	}
 
-	matches := regexp.MustCompile(`db\..*?\.(.*?)\(.*\)`).FindStringSubmatch(query)
-	if len(matches) < 2 {
+	// Only the head is parsed: the arguments may still hold {{...}}
+	// placeholders when a pipeline is validated.
+	mq, err := parseMongoHead(query)
+	if err != nil {
 		return common.QueryType("")
 	}
-	if _, ok := readActions[method(matches[1])]; ok {
+	if mq.coll == "" {
+		if method(mq.method.name) == runCommand {
+			return queryTypeRunCommand(query)
+		}
+		return common.QueryType("")
+	}
+	if _, ok := readActions[method(mq.method.name)]; ok {
 		return common.Read
 	}
 
-	if _, ok := writeActions[method(matches[1])]; ok {
+	if _, ok := writeActions[method(mq.method.name)]; ok {
 		return common.Write
 	}
 
+	if _, ok := adminActions[method(mq.method.name)]; ok {
+		return common.Admin
+	}
+
 	return common.QueryType("")
 }
 
-func ExecuteMongoWriteQuery(q common.QueryMetadata) ([]byte, error) {
-	opt := options.Client().ApplyURI(q.Conn)
-
-	ctx := context.Background()
-	client, err := mongo.Connect(ctx, opt)
-	if err != nil {
-		return nil, err
-	}
-	defer client.Disconnect(ctx)
-
-	err = client.Ping(ctx, nil)
-	if err != nil {
+func handleInsertOne(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
 		return nil, err
 	}
 
-	dbName, err := mongoDBName(q.Conn)
+	obj, err := constructBsonDoc(mq.method.args[0])
 	if err != nil {
 		return nil, err
 	}
-	db := client.Database(dbName)
-
-	matches := regexp.MustCompile(`db\.(.*?)\.(.*?)\((.*?)\)`).FindAllStringSubmatch(q.QueryLine, -1)
-
-	if len(matches) < 1 || len(matches[0]) < 3 {
-		return nil, fmt.Errorf("Invalid write query type and data: '%+v'", matches)
-	}
-
-	coll := db.Collection(matches[0][1])
-	handler, ok := writeActions[method(matches[0][2])]
-	if !ok {
-		return nil, fmt.Errorf("Write handler not found: %v.", matches[0][2])
-	}
-
-	return handler(ctx, coll, &matches[0][3])
-}
-
-func handleInsertOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
-		return nil, err
-	}
-
-	obj, err := constructBsonObject(params[0])
+	insertResult, err := coll.InsertOne(ctx, obj)
 	if err != nil {
 		return nil, err
 	}
-	insertResult, err := coll.InsertOne(ctx, obj)
+	id, err := mongoExtJSON(insertResult.InsertedID, mq.canonical)
 	if err != nil {
 		return nil, err
 	}
 	res, err := json.Marshal(struct {
-		InsertedID any `json:"inserted_id"`
-	}{insertResult.InsertedID})
+		InsertedID json.RawMessage `json:"inserted_id"`
+	}{id})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v.", err.Error(), insertResult)
 	}
 	return res, nil
 }
 
-func handleInsertMany(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
+func handleInsertMany(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
 		return nil, err
 	}
 
-	obj, err := constructBsonArray(params[0])
+	obj, err := constructBsonArray(mq.method.args[0])
 	if err != nil {
 		return nil, err
 	}
@@ -493,43 +579,54 @@ func handleInsertMany(ctx context.Context, coll *mongo.Collection, paramStrs ...
 	if err != nil {
 		return nil, err
 	}
+	ids, err := mongoExtJSON(bson.A(insertResult.InsertedIDs), mq.canonical)
+	if err != nil {
+		return nil, err
+	}
 	res, err := json.Marshal(struct {
-		InsertedIDs any `json:"inserted_ids"`
-	}{insertResult.InsertedIDs})
+		InsertedIDs json.RawMessage `json:"inserted_ids"`
+	}{ids})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v.", err.Error(), insertResult)
 	}
 	return res, nil
 }
 
-func handleUpdateOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
+func handleUpdateOne(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(2); err != nil {
 		return nil, err
 	}
 
-	f, err := constructBsonObject(params[0])
+	f, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	updateObj, err := constructBsonDoc(mq.method.args[1])
 	if err != nil {
 		return nil, err
 	}
-	updateObj, err := constructBsonObject(params[1])
+	opts, err := newUpdateOpts(updateOne, mq.method.arg(2, "{}"))
 	if err != nil {
 		return nil, err
 	}
-	updateResult, err := coll.UpdateOne(ctx, f, updateObj)
+	updateResult, err := coll.UpdateOne(ctx, f, updateObj, opts)
+	if err != nil {
+		return nil, err
+	}
+	upsertedID, err := mongoExtJSON(updateResult.UpsertedID, mq.canonical)
 	if err != nil {
 		return nil, err
 	}
 	res, err := json.Marshal(struct {
-		MatchedCount  int64 `json:"matched_count"`
-		ModifiedCount int64 `json:"modified_count"`
-		UpsertedCount int64 `json:"upserted_count"`
-		UpsertedID    any   `json:"upserted_id"`
+		MatchedCount  int64           `json:"matched_count"`
+		ModifiedCount int64           `json:"modified_count"`
+		UpsertedCount int64           `json:"upserted_count"`
+		UpsertedID    json.RawMessage `json:"upserted_id"`
 	}{
 		MatchedCount:  updateResult.MatchedCount,
 		ModifiedCount: updateResult.ModifiedCount,
 		UpsertedCount: updateResult.UpsertedCount,
-		UpsertedID:    updateResult.UpsertedID,
+		UpsertedID:    upsertedID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v.", err.Error(), updateObj)
@@ -537,34 +634,41 @@ func handleUpdateOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*
 	return res, nil
 }
 
-func handleUpdateMany(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
+func handleUpdateMany(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(2); err != nil {
 		return nil, err
 	}
 
-	f, err := constructBsonObject(params[0])
+	f, err := constructBsonDoc(mq.method.args[0])
 	if err != nil {
 		return nil, err
 	}
-	updateObj, err := constructBsonObject(params[1])
+	updateObj, err := constructBsonDoc(mq.method.args[1])
 	if err != nil {
 		return nil, err
 	}
-	updateResult, err := coll.UpdateMany(ctx, f, updateObj)
+	opts, err := newUpdateOpts(updateMany, mq.method.arg(2, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	updateResult, err := coll.UpdateMany(ctx, f, updateObj, opts)
+	if err != nil {
+		return nil, err
+	}
+	upsertedID, err := mongoExtJSON(updateResult.UpsertedID, mq.canonical)
 	if err != nil {
 		return nil, err
 	}
 	res, err := json.Marshal(struct {
-		MatchedCount  int64 `json:"matched_count"`
-		ModifiedCount int64 `json:"modified_count"`
-		UpsertedCount int64 `json:"upserted_count"`
-		UpsertedID    any   `json:"upserted_id"`
+		MatchedCount  int64           `json:"matched_count"`
+		ModifiedCount int64           `json:"modified_count"`
+		UpsertedCount int64           `json:"upserted_count"`
+		UpsertedID    json.RawMessage `json:"upserted_id"`
 	}{
 		MatchedCount:  updateResult.MatchedCount,
 		ModifiedCount: updateResult.ModifiedCount,
 		UpsertedCount: updateResult.UpsertedCount,
-		UpsertedID:    updateResult.UpsertedID,
+		UpsertedID:    upsertedID,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%s: %v.", err.Error(), updateObj)
@@ -572,13 +676,12 @@ func handleUpdateMany(ctx context.Context, coll *mongo.Collection, paramStrs ...
 	return res, nil
 }
 
-func handleDeleteOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
+func handleDeleteOne(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
 		return nil, err
 	}
 
-	f, err := constructBsonObject(params[0])
+	f, err := constructBsonDoc(mq.method.args[0])
 	if err != nil {
 		return nil, err
 	}
@@ -597,13 +700,12 @@ func handleDeleteOne(ctx context.Context, coll *mongo.Collection, paramStrs ...*
 	return res, nil
 }
 
-func handleDeleteMany(ctx context.Context, coll *mongo.Collection, paramStrs ...*string) ([]byte, error) {
-	params, err := splitCommaSeparatedObjStr(*paramStrs[0])
-	if err != nil {
+func handleDeleteMany(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
 		return nil, err
 	}
 
-	f, err := constructBsonObject(params[0])
+	f, err := constructBsonDoc(mq.method.args[0])
 	if err != nil {
 		return nil, err
 	}
@@ -622,76 +724,303 @@ func handleDeleteMany(ctx context.Context, coll *mongo.Collection, paramStrs ...
 	return res, nil
 }
 
-func splitCommaSeparatedObjStr(input string) (result []string, err error) {
-	if len(input) == 0 {
-		err = fmt.Errorf("splitMethodParamStr: missing input string")
-		return
-	}
-
-	pairMap := map[rune]rune{
-		'}': '{',
-		')': '(',
-		']': '[',
-	}
-	stack := []rune{}
-	acc := []rune{}
-	inString := false
-	var stringDelim rune
-	escaped := false
-	for i, c := range input {
-		// Inside a JSON string literal: preserve every rune verbatim
-		// (including spaces) and do not treat structural characters
-		// as syntax.
-		if inString {
-			acc = append(acc, c)
-			if escaped {
-				escaped = false
-				continue
-			}
-			if c == '\\' {
-				escaped = true
-				continue
-			}
-			if c == stringDelim {
-				inString = false
-			}
-			continue
+func handleReplaceOne(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(2); err != nil {
+		return nil, err
+	}
+
+	f, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := constructBsonDoc(mq.method.args[1])
+	if err != nil {
+		return nil, err
+	}
+	opts, err := newReplaceOpts(mq.method.arg(2, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	updateResult, err := coll.ReplaceOne(ctx, f, replacement, opts)
+	if err != nil {
+		return nil, err
+	}
+	upsertedID, err := mongoExtJSON(updateResult.UpsertedID, mq.canonical)
+	if err != nil {
+		return nil, err
+	}
+	res, err := json.Marshal(struct {
+		MatchedCount  int64           `json:"matched_count"`
+		ModifiedCount int64           `json:"modified_count"`
+		UpsertedCount int64           `json:"upserted_count"`
+		UpsertedID    json.RawMessage `json:"upserted_id"`
+	}{
+		MatchedCount:  updateResult.MatchedCount,
+		ModifiedCount: updateResult.ModifiedCount,
+		UpsertedCount: updateResult.UpsertedCount,
+		UpsertedID:    upsertedID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v.", err.Error(), replacement)
+	}
+	return res, nil
+}
+
+func handleFindOneAndUpdate(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(2); err != nil {
+		return nil, err
+	}
+
+	f, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	update, err := constructUpdate(mq.method.args[1])
+	if err != nil {
+		return nil, err
+	}
+	fm, err := newFindAndModifyOpts(findOneAndUpdate, mq.method.arg(2, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	opts := options.FindOneAndUpdate().SetProjection(fm.projection).SetSort(fm.sort).SetHint(fm.hint).SetCollation(fm.collation)
+	if fm.upsert != nil {
+		opts.SetUpsert(*fm.upsert)
+	}
+	if fm.returnAfter {
+		opts.SetReturnDocument(options.After)
+	}
+	if fm.arrayFilters != nil {
+		opts.SetArrayFilters(options.ArrayFilters{Filters: fm.arrayFilters})
+	}
+	if fm.maxTime != nil {
+		opts.SetMaxTime(*fm.maxTime)
+	}
+	return mongoSingleResult(coll.FindOneAndUpdate(ctx, f, update, opts), mq.canonical)
+}
+
+func handleFindOneAndReplace(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(2); err != nil {
+		return nil, err
+	}
+
+	f, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	replacement, err := constructBsonDoc(mq.method.args[1])
+	if err != nil {
+		return nil, err
+	}
+	fm, err := newFindAndModifyOpts(findOneAndReplace, mq.method.arg(2, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	opts := options.FindOneAndReplace().SetProjection(fm.projection).SetSort(fm.sort).SetHint(fm.hint).SetCollation(fm.collation)
+	if fm.upsert != nil {
+		opts.SetUpsert(*fm.upsert)
+	}
+	if fm.returnAfter {
+		opts.SetReturnDocument(options.After)
+	}
+	if fm.maxTime != nil {
+		opts.SetMaxTime(*fm.maxTime)
+	}
+	return mongoSingleResult(coll.FindOneAndReplace(ctx, f, replacement, opts), mq.canonical)
+}
+
+func handleFindOneAndDelete(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
+		return nil, err
+	}
+
+	f, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	fm, err := newFindAndModifyOpts(findOneAndDelete, mq.method.arg(1, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	opts := options.FindOneAndDelete().SetProjection(fm.projection).SetSort(fm.sort).SetHint(fm.hint).SetCollation(fm.collation)
+	if fm.maxTime != nil {
+		opts.SetMaxTime(*fm.maxTime)
+	}
+	return mongoSingleResult(coll.FindOneAndDelete(ctx, f, opts), mq.canonical)
+}
+
+// mongoSingleResult renders the document a findOneAnd* call returned,
+// or null when nothing matched.
+func mongoSingleResult(sr *mongo.SingleResult, canonical bool) ([]byte, error) {
+	var result bson.D
+	if err := sr.Decode(&result); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return []byte("null"), nil
 		}
+		return nil, err
+	}
+	return mongoExtJSON(result, canonical)
+}
+
+// constructUpdate reads an update argument: an update document or, for
+// an update with an aggregation pipeline, an array of stages.
+func constructUpdate(objStr string) (any, error) {
+	if strings.HasPrefix(strings.TrimSpace(objStr), "[") {
+		return constructBsonArray(objStr)
+	}
+	return constructBsonDoc(objStr)
+}
+
+func handleBulkWrite(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
+		return nil, err
+	}
 
-		// Outside a string: historical behaviour was to strip only
-		// the space character (newlines and tabs kept). Preserve that
-		// so existing callers and tests stay stable.
-		if c != ' ' {
-			acc = append(acc, c)
+	ops, err := shellValue(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	models, err := bulkWriteModels(ops)
+	if err != nil {
+		return nil, err
+	}
+	opts, err := newBulkWriteOpts(mq.method.arg(1, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	bulkResult, err := coll.BulkWrite(ctx, models, opts)
+	if err != nil {
+		return nil, err
+	}
+	upsertedIDs := map[int64]json.RawMessage{}
+	for i, id := range bulkResult.UpsertedIDs {
+		if upsertedIDs[i], err = mongoExtJSON(id, mq.canonical); err != nil {
+			return nil, err
 		}
+	}
+	res, err := json.Marshal(struct {
+		InsertedCount int64                     `json:"inserted_count"`
+		MatchedCount  int64                     `json:"matched_count"`
+		ModifiedCount int64                     `json:"modified_count"`
+		DeletedCount  int64                     `json:"deleted_count"`
+		UpsertedCount int64                     `json:"upserted_count"`
+		UpsertedIDs   map[int64]json.RawMessage `json:"upserted_ids"`
+	}{
+		InsertedCount: bulkResult.InsertedCount,
+		MatchedCount:  bulkResult.MatchedCount,
+		ModifiedCount: bulkResult.ModifiedCount,
+		DeletedCount:  bulkResult.DeletedCount,
+		UpsertedCount: bulkResult.UpsertedCount,
+		UpsertedIDs:   upsertedIDs,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v.", err.Error(), bulkResult)
+	}
+	return res, nil
+}
 
-		switch c {
-		case '"', '\'':
-			inString = true
-			stringDelim = c
-		case '{', '(', '[':
-			stack = append(stack, c)
-		case '}', ')', ']':
-			if len(stack) == 0 {
-				err = fmt.Errorf("invalid character at index %d", i)
-				return
+// bulkWriteModels reads bulkWrite's operations the way mongosh writes
+// them: `[{insertOne: {document: {...}}}, {updateOne: {filter: {...},
+// update: {...}, upsert: true}}, {deleteMany: {filter: {...}}}, ...]`.
+func bulkWriteModels(ops any) ([]mongo.WriteModel, error) {
+	arr, ok := ops.(bson.A)
+	if !ok || len(arr) == 0 {
+		return nil, fmt.Errorf("bulkWrite() takes a non-empty array of operations")
+	}
+	models := make([]mongo.WriteModel, 0, len(arr))
+	for i, op := range arr {
+		doc, ok := op.(bson.D)
+		if !ok || len(doc) != 1 {
+			return nil, fmt.Errorf("bulkWrite operation %d must be a document with one operation name", i)
+		}
+		name := doc[0].Key
+		spec, ok := doc[0].Value.(bson.D)
+		if !ok {
+			return nil, fmt.Errorf("bulkWrite operation %d: %s must be a document", i, name)
+		}
+		var (
+			filter, update, document any
+			upsert                   *bool
+			arrayFilters             []any
+			collation                *options.Collation
+			hint                     any
+		)
+		for _, e := range spec {
+			var err error
+			switch e.Key {
+			case "filter":
+				filter, err = optDoc(name, e.Key, e.Value)
+			case "update":
+				update = e.Value
+			case "replacement", "document":
+				document, err = optDoc(name, e.Key, e.Value)
+			case "upsert":
+				var b bool
+				b, err = optBool(name, e.Key, e.Value)
+				upsert = &b
+			case "arrayFilters":
+				arrayFilters, err = optDocArray(name, e.Key, e.Value)
+			case "collation":
+				collation, err = optCollation(name, e.Value)
+			case "hint":
+				hint, err = optHint(name, e.Value)
+			default:
+				err = unknownOptionError{name, e.Key}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("bulkWrite operation %d: %s", i, err)
 			}
-			if stack[len(stack)-1] != pairMap[c] {
-				err = fmt.Errorf("invalid parentheses at index %d", i)
-				return
+		}
+		if filter == nil && name != string(insertOne) {
+			return nil, fmt.Errorf("bulkWrite operation %d: %s needs a filter", i, name)
+		}
+		switch method(name) {
+		case insertOne:
+			if document == nil {
+				return nil, fmt.Errorf("bulkWrite operation %d: insertOne needs a document", i)
 			}
-			stack = stack[:len(stack)-1]
-		case ',':
-			if len(stack) == 0 {
-				result = append(result, string(acc))
-				acc = []rune{}
+			models = append(models, mongo.NewInsertOneModel().SetDocument(document))
+		case updateOne, updateMany:
+			if update == nil {
+				return nil, fmt.Errorf("bulkWrite operation %d: %s needs an update", i, name)
 			}
+			if method(name) == updateOne {
+				m := mongo.NewUpdateOneModel().SetFilter(filter).SetUpdate(update).SetCollation(collation).SetHint(hint)
+				if upsert != nil {
+					m.SetUpsert(*upsert)
+				}
+				if arrayFilters != nil {
+					m.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+				}
+				models = append(models, m)
+			} else {
+				m := mongo.NewUpdateManyModel().SetFilter(filter).SetUpdate(update).SetCollation(collation).SetHint(hint)
+				if upsert != nil {
+					m.SetUpsert(*upsert)
+				}
+				if arrayFilters != nil {
+					m.SetArrayFilters(options.ArrayFilters{Filters: arrayFilters})
+				}
+				models = append(models, m)
+			}
+		case replaceOne:
+			if document == nil {
+				return nil, fmt.Errorf("bulkWrite operation %d: replaceOne needs a replacement", i)
+			}
+			m := mongo.NewReplaceOneModel().SetFilter(filter).SetReplacement(document).SetCollation(collation).SetHint(hint)
+			if upsert != nil {
+				m.SetUpsert(*upsert)
+			}
+			models = append(models, m)
+		case deleteOne:
+			models = append(models, mongo.NewDeleteOneModel().SetFilter(filter).SetCollation(collation).SetHint(hint))
+		case deleteMany:
+			models = append(models, mongo.NewDeleteManyModel().SetFilter(filter).SetCollation(collation).SetHint(hint))
+		default:
+			return nil, fmt.Errorf("bulkWrite operation %d: unknown operation %s", i, name)
 		}
 	}
-	if len(acc) > 0 {
-		result = append(result, string(acc))
-	}
-	return
+	return models, nil
 }
 
 func handleShowCollections(ctx context.Context, db *mongo.Database, paramStrs ...*string) ([]byte, error) {
@@ -0,0 +1,152 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"simpanan/internal/common"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// readOnlyCommands are the db.runCommand() commands that classify as
+// reads; every other command is admin, so it may only end a pipeline.
+var readOnlyCommands = map[string]bool{
+	"ping": true, "hello": true, "isMaster": true, "ismaster": true,
+	"buildInfo": true, "buildinfo": true, "serverStatus": true, "hostInfo": true,
+	"connectionStatus": true, "listDatabases": true, "listCollections": true,
+	"listIndexes": true, "dbStats": true, "collStats": true, "dataSize": true,
+	"count": true, "distinct": true, "find": true, "explain": true,
+}
+
+// queryTypeRunCommand classifies db.runCommand({<command>: ...}) by its
+// command name, the first key of the document. Only that key is read,
+// so the rest of the document may still hold placeholders.
+func queryTypeRunCommand(query string) common.QueryType {
+	p := &shellParser{src: query}
+	if _, err := p.mongoHead(); err != nil {
+		return common.QueryType("")
+	}
+	p.pos++
+	if err := p.expect('{'); err != nil {
+		return common.Admin
+	}
+	p.skipSpace()
+	name, err := p.key()
+	if err == nil && readOnlyCommands[name] {
+		return common.Read
+	}
+	return common.Admin
+}
+
+func handleRunCommand(ctx context.Context, db *mongo.Database, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
+		return nil, err
+	}
+
+	cmd, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(cmd) == 0 {
+		return nil, fmt.Errorf("runCommand() takes a command document such as {ping: 1}")
+	}
+	return mongoCommandResult(db.RunCommand(ctx, cmd), mq.canonical)
+}
+
+// handleStats runs collStats: `stats()`, `stats(1024)` or
+// `stats({scale: 1024})`.
+func handleStats(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	cmd := bson.D{{Key: "collStats", Value: coll.Name()}}
+	if len(mq.method.args) > 0 {
+		v, err := shellValue(mq.method.args[0])
+		if err != nil {
+			return nil, err
+		}
+		if doc, ok := v.(bson.D); ok {
+			for _, e := range doc {
+				if e.Key != "scale" {
+					return nil, unknownOptionError{"stats", e.Key}
+				}
+				v = e.Value
+			}
+		}
+		scale, err := optInt64("stats", "scale", v)
+		if err != nil {
+			return nil, err
+		}
+		cmd = append(cmd, bson.E{Key: "scale", Value: scale})
+	}
+	return mongoCommandResult(coll.Database().RunCommand(ctx, cmd), mq.canonical)
+}
+
+func handleGetIndexes(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	cursor, err := coll.Indexes().List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexes := []bson.D{}
+	if err := cursor.All(ctx, &indexes); err != nil {
+		return nil, err
+	}
+	return mongoExtJSON(indexes, mq.canonical)
+}
+
+// handleCreateIndex creates one index: `createIndex({email: 1},
+// {unique: true})`. It answers with the index name.
+func handleCreateIndex(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
+		return nil, err
+	}
+
+	keys, err := constructBsonDoc(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("createIndex() needs at least one key")
+	}
+	opts, err := newIndexOpts(mq.method.arg(1, "{}"))
+	if err != nil {
+		return nil, err
+	}
+	name, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys, Options: opts})
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]string{"name": name})
+}
+
+// handleDropIndex drops one index, named or by its key document:
+// `dropIndex('email_1')` or `dropIndex({email: 1})`.
+func handleDropIndex(ctx context.Context, coll *mongo.Collection, mq *mongoQuery) ([]byte, error) {
+	if err := mq.method.requireArgs(1); err != nil {
+		return nil, err
+	}
+
+	index, err := shellValue(mq.method.args[0])
+	if err != nil {
+		return nil, err
+	}
+	switch index.(type) {
+	case string:
+		if index == "*" {
+			return nil, fmt.Errorf("dropIndex() drops a single index, not '*'")
+		}
+	case bson.D:
+	default:
+		return nil, fmt.Errorf("dropIndex() takes an index name or key document, got %s", mq.method.args[0])
+	}
+	cmd := bson.D{{Key: "dropIndexes", Value: coll.Name()}, {Key: "index", Value: index}}
+	return mongoCommandResult(coll.Database().RunCommand(ctx, cmd), mq.canonical)
+}
+
+// mongoCommandResult renders a command's reply document.
+func mongoCommandResult(sr *mongo.SingleResult, canonical bool) ([]byte, error) {
+	var result bson.D
+	if err := sr.Decode(&result); err != nil {
+		return nil, err
+	}
+	return mongoExtJSON(result, canonical)
+}
@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"simpanan/internal/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeRunCommand(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`db.runCommand({ping: 1})`, common.Read},
+		{`db.getSiblingDB('admin').runCommand({ "listDatabases": 1 })`, common.Read},
+		{`db.runCommand({count: 'users', query: {name: {{.name}}}})`, common.Read},
+		{`db.runCommand({drop: 'users'})`, common.Admin},
+		{`db.runCommand({aggregate: 'users', pipeline: [{$out: 'copy'}]})`, common.Admin},
+		{`db.runCommand({{.cmd}})`, common.Admin},
+		{`db.runCommand()`, common.Admin},
+		{`db.users.runCommand({ping: 1})`, common.QueryType("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeMongo(tt.query))
+		})
+	}
+}
+
+func TestExecuteMongoAdminQueryErrors(t *testing.T) {
+	conn := "mongodb://127.0.0.1:1/app"
+	_, err := ExecuteMongoAdminQuery(common.QueryMetadata{Conn: conn, QueryLine: `db.users.find()`})
+	assert.EqualError(t, err, `Admin handler not found: find.`)
+	_, err = ExecuteMongoAdminQuery(common.QueryMetadata{Conn: conn, QueryLine: `db.createIndex({a: 1})`})
+	assert.EqualError(t, err, `syntax error at offset 3: db.createIndex() needs a collection: db.<collection>.createIndex(...)`)
+	_, err = ExecuteMongoReadQuery(common.QueryMetadata{Conn: conn, QueryLine: `db.users.getIndexes().limit(1)`})
+	assert.EqualError(t, err, `syntax error at offset 22: getIndexes() does not take .limit()`)
+	_, err = ExecuteMongoReadQuery(common.QueryMetadata{Conn: conn, QueryLine: `db.users.getIndexes(`})
+	assert.EqualError(t, err, `syntax error at offset 20: expected a value, got end of input`)
+}
@@ -2,9 +2,11 @@ package adapters
 
 import (
 	"fmt"
-	"regexp"
+	"math"
 	"strconv"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
@@ -12,22 +14,54 @@ type cursorOpt interface {
 	Apply(any) (any, error)
 }
 
-func newFindCursorOpts(opts [][]string) (cOpts []cursorOpt, err error) {
+func newFindCursorOpts(opts []mongoCall) (cOpts []cursorOpt, err error) {
 	for _, o := range opts {
-		switch o[1] {
+		switch o.name {
 		case "sort":
-			param, err := constructBsonObject(o[2])
+			param, err := constructBsonDoc(o.arg(0, ""))
 			if err != nil {
 				return nil, err
 			}
 
 			cOpts = append(cOpts, findSort{param})
 		case "limit":
-			intParam, err := strconv.Atoi(o[2])
+			intParam, err := strconv.Atoi(o.arg(0, ""))
 			if err != nil {
-				return nil, fmt.Errorf("Failed to parse param %v to int: %s", o[2], err.Error())
+				return nil, fmt.Errorf("Failed to parse param %v to int: %s", o.arg(0, ""), err.Error())
 			}
 			cOpts = append(cOpts, findLimit{int64(intParam)})
+		case "explain":
+			verbosity, err := explainVerbosity(o.arg(0, `"queryPlanner"`))
+			if err != nil {
+				return nil, err
+			}
+			cOpts = append(cOpts, findExplain{verbosity})
+		default:
+			// Every other cursor method takes the same value as the
+			// find option of the same name.
+			name := o.name
+			if name == "project" {
+				name = "projection"
+			}
+			if !findCursorMethods[name] {
+				return nil, fmt.Errorf("unknown find cursor method .%s()", o.name)
+			}
+			if len(o.args) > 1 {
+				return nil, fmt.Errorf(".%s() takes one argument, got %d", o.name, len(o.args))
+			}
+			var v any = true // .allowDiskUse() alone turns it on
+			if len(o.args) == 1 {
+				if v, err = shellValue(o.args[0]); err != nil {
+					return nil, err
+				}
+			} else if name != "allowDiskUse" {
+				return nil, fmt.Errorf(".%s() takes one argument, got 0", o.name)
+			}
+			set, err := findOption(name, v)
+			if err != nil {
+				return nil, err
+			}
+			cOpts = append(cOpts, set)
 		}
 	}
 	return
@@ -54,19 +88,625 @@ func (flimit findLimit) Apply(opts any) (any, error) {
 	return fo.SetLimit(flimit.param), nil
 }
 
-func parseCursorOpts(method method, cursorOptStr string) ([]cursorOpt, error) {
-	if cursorOptStr == "" {
-		return []cursorOpt{}, nil
+// findSetter is a cursor option that calls one FindOptions setter.
+type findSetter func(*options.FindOptions) *options.FindOptions
+
+func (set findSetter) Apply(opts any) (any, error) {
+	fo, ok := opts.(*options.FindOptions)
+	if !ok {
+		return nil, fmt.Errorf("findSetter Apply: failed to cast opts %v.", &opts)
 	}
-	matches := regexp.MustCompile(`\.(.*?)\((.*?)\)`).FindAllStringSubmatch(cursorOptStr, -1)
-	if len(matches) == 0 {
-		return nil, fmt.Errorf("parseCursorOpts: invalid option length. method: %s, cursorOptStr: %s, matches: %v", string(method), cursorOptStr, matches)
+	return set(fo), nil
+}
+
+// findExplain turns the find into an explain of it; the options are
+// left as they are.
+type findExplain struct{ verbosity string }
+
+func (findExplain) Apply(opts any) (any, error) { return opts, nil }
+
+// findCursorMethods are the cursor methods findOption handles.
+var findCursorMethods = map[string]bool{
+	"skip": true, "projection": true, "hint": true, "collation": true,
+	"maxTimeMS": true, "batchSize": true, "comment": true, "allowDiskUse": true,
+}
+
+// findOption maps a find option, named as in mongosh, to its setter.
+func findOption(name string, v any) (findSetter, error) {
+	switch name {
+	case "skip":
+		n, err := optInt64("find", name, v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetSkip(n) }, nil
+	case "projection":
+		doc, err := optDoc("find", name, v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetProjection(doc) }, nil
+	case "hint":
+		hint, err := optHint("find", v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetHint(hint) }, nil
+	case "collation":
+		c, err := optCollation("find", v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetCollation(c) }, nil
+	case "maxTimeMS":
+		d, err := optMaxTime("find", v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetMaxTime(d) }, nil
+	case "batchSize":
+		n, err := optInt32("find", name, v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetBatchSize(n) }, nil
+	case "comment":
+		s, err := optString("find", name, v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetComment(s) }, nil
+	case "allowDiskUse":
+		b, err := optBool("find", name, v)
+		if err != nil {
+			return nil, err
+		}
+		return func(fo *options.FindOptions) *options.FindOptions { return fo.SetAllowDiskUse(b) }, nil
+	}
+	return nil, unknownOptionError{"find", name}
+}
+
+func parseCursorOpts(method method, opts []mongoCall) ([]cursorOpt, error) {
+	if len(opts) == 0 {
+		return []cursorOpt{}, nil
 	}
 	switch method {
 	case find:
-		return newFindCursorOpts(matches)
+		return newFindCursorOpts(opts)
 	default:
 		return nil, fmt.Errorf("parseCursorOpts: method %s not implemented.", string(method))
 	}
+}
 
+// explainVerbosity reads the .explain() argument: one of the three
+// verbosity names, or true/false as mongosh takes them.
+func explainVerbosity(arg string) (string, error) {
+	v, err := shellValue(arg)
+	if err != nil {
+		return "", err
+	}
+	switch v {
+	case "queryPlanner", "executionStats", "allPlansExecution":
+		return v.(string), nil
+	case true:
+		return "allPlansExecution", nil
+	case false:
+		return "queryPlanner", nil
+	}
+	return "", fmt.Errorf(".explain() takes queryPlanner, executionStats or allPlansExecution, got %s", arg)
+}
+
+// findExplainCommand is the find command that opts describe, for the
+// explain command to wrap.
+func findExplainCommand(coll string, filter any, opts *options.FindOptions) bson.D {
+	cmd := bson.D{{Key: "find", Value: coll}, {Key: "filter", Value: filter}}
+	add := func(key string, set bool, v any) {
+		if set {
+			cmd = append(cmd, bson.E{Key: key, Value: v})
+		}
+	}
+	add("projection", opts.Projection != nil, opts.Projection)
+	add("sort", opts.Sort != nil, opts.Sort)
+	add("hint", opts.Hint != nil, opts.Hint)
+	if opts.Skip != nil {
+		add("skip", true, *opts.Skip)
+	}
+	if opts.Limit != nil {
+		add("limit", true, *opts.Limit)
+	}
+	if opts.BatchSize != nil {
+		add("batchSize", true, *opts.BatchSize)
+	}
+	if opts.Collation != nil {
+		add("collation", true, opts.Collation.ToDocument())
+	}
+	if opts.MaxTime != nil {
+		add("maxTimeMS", true, opts.MaxTime.Milliseconds())
+	}
+	if opts.Comment != nil {
+		add("comment", true, *opts.Comment)
+	}
+	if opts.AllowDiskUse != nil {
+		add("allowDiskUse", true, *opts.AllowDiskUse)
+	}
+	return cmd
+}
+
+// newAggregateOpts reads aggregate's options object, e.g.
+// `{allowDiskUse: true, hint: 'status_1', let: {min: 5}}`.
+func newAggregateOpts(objStr string) (*options.AggregateOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Aggregate()
+	for _, e := range doc {
+		switch e.Key {
+		case "allowDiskUse":
+			b, err := optBool("aggregate", e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetAllowDiskUse(b)
+		case "hint":
+			hint, err := optHint("aggregate", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetHint(hint)
+		case "let":
+			let, err := optDoc("aggregate", e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetLet(let)
+		case "collation":
+			c, err := optCollation("aggregate", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetCollation(c)
+		case "maxTimeMS":
+			d, err := optMaxTime("aggregate", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetMaxTime(d)
+		case "batchSize":
+			n, err := optInt32("aggregate", e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetBatchSize(n)
+		case "comment":
+			s, err := optString("aggregate", e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetComment(s)
+		default:
+			return nil, unknownOptionError{"aggregate", e.Key}
+		}
+	}
+	return opts, nil
+}
+
+// newCountOpts reads the options object of count or countDocuments,
+// e.g. `{limit: 100, skip: 10}`.
+func newCountOpts(method, objStr string) (*options.CountOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Count()
+	for _, e := range doc {
+		switch e.Key {
+		case "limit", "skip":
+			n, err := optInt64(method, e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			if e.Key == "limit" {
+				opts.SetLimit(n)
+			} else {
+				opts.SetSkip(n)
+			}
+		case "hint":
+			hint, err := optHint(method, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetHint(hint)
+		case "collation":
+			c, err := optCollation(method, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetCollation(c)
+		case "maxTimeMS":
+			d, err := optMaxTime(method, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetMaxTime(d)
+		case "comment":
+			s, err := optString(method, e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetComment(s)
+		default:
+			return nil, unknownOptionError{method, e.Key}
+		}
+	}
+	return opts, nil
+}
+
+// newDistinctOpts reads distinct's options object, e.g.
+// `{collation: {locale: 'en', strength: 2}}`.
+func newDistinctOpts(objStr string) (*options.DistinctOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Distinct()
+	for _, e := range doc {
+		switch e.Key {
+		case "collation":
+			c, err := optCollation("distinct", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetCollation(c)
+		case "maxTimeMS":
+			d, err := optMaxTime("distinct", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetMaxTime(d)
+		case "comment":
+			opts.SetComment(e.Value)
+		default:
+			return nil, unknownOptionError{"distinct", e.Key}
+		}
+	}
+	return opts, nil
+}
+
+type unknownOptionError struct{ method, name string }
+
+func (e unknownOptionError) Error() string {
+	return fmt.Sprintf("unknown %s option %q", e.method, e.name)
+}
+
+func optBool(method, name string, v any) (bool, error) {
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s option %s must be true or false, got %v", method, name, v)
+	}
+	return b, nil
+}
+
+func optInt64(method, name string, v any) (int64, error) {
+	switch n := v.(type) {
+	case int32:
+		return int64(n), nil
+	case int64:
+		return n, nil
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<53 {
+			return int64(n), nil
+		}
+	}
+	return 0, fmt.Errorf("%s option %s must be an integer, got %v", method, name, v)
+}
+
+func optInt32(method, name string, v any) (int32, error) {
+	n, err := optInt64(method, name, v)
+	if err != nil || n < math.MinInt32 || n > math.MaxInt32 {
+		return 0, fmt.Errorf("%s option %s must be a 32-bit integer, got %v", method, name, v)
+	}
+	return int32(n), nil
+}
+
+func optMaxTime(method string, v any) (time.Duration, error) {
+	ms, err := optInt64(method, "maxTimeMS", v)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+func optString(method, name string, v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s option %s must be a string, got %v", method, name, v)
+	}
+	return s, nil
+}
+
+func optDoc(method, name string, v any) (bson.D, error) {
+	d, ok := v.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("%s option %s must be a document, got %v", method, name, v)
+	}
+	return d, nil
+}
+
+// optHint takes an index name or an index key document.
+func optHint(method string, v any) (any, error) {
+	switch v.(type) {
+	case string, bson.D:
+		return v, nil
+	}
+	return nil, fmt.Errorf("%s option hint must be an index name or key document, got %v", method, v)
+}
+
+// optCollation reads a collation document such as
+// `{locale: 'fr', strength: 1}`.
+func optCollation(method string, v any) (*options.Collation, error) {
+	doc, err := optDoc(method, "collation", v)
+	if err != nil {
+		return nil, err
+	}
+	c := &options.Collation{}
+	for _, e := range doc {
+		var err error
+		switch e.Key {
+		case "locale":
+			c.Locale, err = optString(method, "collation.locale", e.Value)
+		case "caseLevel":
+			c.CaseLevel, err = optBool(method, "collation.caseLevel", e.Value)
+		case "caseFirst":
+			c.CaseFirst, err = optString(method, "collation.caseFirst", e.Value)
+		case "strength":
+			var n int64
+			n, err = optInt64(method, "collation.strength", e.Value)
+			c.Strength = int(n)
+		case "numericOrdering":
+			c.NumericOrdering, err = optBool(method, "collation.numericOrdering", e.Value)
+		case "alternate":
+			c.Alternate, err = optString(method, "collation.alternate", e.Value)
+		case "maxVariable":
+			c.MaxVariable, err = optString(method, "collation.maxVariable", e.Value)
+		case "normalization":
+			c.Normalization, err = optBool(method, "collation.normalization", e.Value)
+		case "backwards":
+			c.Backwards, err = optBool(method, "collation.backwards", e.Value)
+		default:
+			err = unknownOptionError{method, "collation." + e.Key}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if c.Locale == "" {
+		return nil, fmt.Errorf("%s option collation needs a locale", method)
+	}
+	return c, nil
+}
+
+// findAndModifyOpts holds the options the findOneAnd* methods share;
+// unset fields stay nil.
+type findAndModifyOpts struct {
+	projection   any
+	sort         any
+	upsert       *bool
+	returnAfter  bool
+	arrayFilters []any
+	collation    *options.Collation
+	hint         any
+	maxTime      *time.Duration
+}
+
+// newFindAndModifyOpts reads the options object of findOneAndUpdate,
+// findOneAndReplace or findOneAndDelete, e.g. `{sort: {at: -1},
+// returnDocument: 'after', upsert: true}`.
+func newFindAndModifyOpts(m method, objStr string) (findAndModifyOpts, error) {
+	var fm findAndModifyOpts
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return fm, err
+	}
+	name := string(m)
+	for _, e := range doc {
+		var err error
+		switch {
+		case e.Key == "projection":
+			fm.projection, err = optDoc(name, e.Key, e.Value)
+		case e.Key == "sort":
+			fm.sort, err = optDoc(name, e.Key, e.Value)
+		case e.Key == "collation":
+			fm.collation, err = optCollation(name, e.Value)
+		case e.Key == "hint":
+			fm.hint, err = optHint(name, e.Value)
+		case e.Key == "maxTimeMS":
+			var d time.Duration
+			d, err = optMaxTime(name, e.Value)
+			fm.maxTime = &d
+		case e.Key == "upsert" && m != findOneAndDelete:
+			var b bool
+			b, err = optBool(name, e.Key, e.Value)
+			fm.upsert = &b
+		case e.Key == "returnDocument" && m != findOneAndDelete:
+			var s string
+			if s, err = optString(name, e.Key, e.Value); err == nil && s != "before" && s != "after" {
+				err = fmt.Errorf("%s option returnDocument must be 'before' or 'after', got %s", name, s)
+			}
+			fm.returnAfter = s == "after"
+		case e.Key == "returnNewDocument" && m != findOneAndDelete:
+			fm.returnAfter, err = optBool(name, e.Key, e.Value)
+		case e.Key == "arrayFilters" && m == findOneAndUpdate:
+			fm.arrayFilters, err = optDocArray(name, e.Key, e.Value)
+		default:
+			err = unknownOptionError{name, e.Key}
+		}
+		if err != nil {
+			return fm, err
+		}
+	}
+	return fm, nil
+}
+
+// newReplaceOpts reads replaceOne's options object, e.g. `{upsert: true}`.
+func newReplaceOpts(objStr string) (*options.ReplaceOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Replace()
+	for _, e := range doc {
+		switch e.Key {
+		case "upsert":
+			b, err := optBool("replaceOne", e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetUpsert(b)
+		case "collation":
+			c, err := optCollation("replaceOne", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetCollation(c)
+		case "hint":
+			hint, err := optHint("replaceOne", e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetHint(hint)
+		default:
+			return nil, unknownOptionError{"replaceOne", e.Key}
+		}
+	}
+	return opts, nil
+}
+
+// newUpdateOpts reads the options object of updateOne or updateMany,
+// e.g. `{upsert: true, arrayFilters: [{'e.n': {$gt: 1}}]}`.
+func newUpdateOpts(m method, objStr string) (*options.UpdateOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	name := string(m)
+	opts := options.Update()
+	for _, e := range doc {
+		switch e.Key {
+		case "upsert":
+			b, err := optBool(name, e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetUpsert(b)
+		case "arrayFilters":
+			filters, err := optDocArray(name, e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetArrayFilters(options.ArrayFilters{Filters: filters})
+		case "collation":
+			c, err := optCollation(name, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetCollation(c)
+		case "hint":
+			hint, err := optHint(name, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetHint(hint)
+		default:
+			return nil, unknownOptionError{name, e.Key}
+		}
+	}
+	return opts, nil
+}
+
+// newBulkWriteOpts reads bulkWrite's options object, e.g. `{ordered: false}`.
+func newBulkWriteOpts(objStr string) (*options.BulkWriteOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.BulkWrite()
+	for _, e := range doc {
+		switch e.Key {
+		case "ordered":
+			b, err := optBool("bulkWrite", e.Key, e.Value)
+			if err != nil {
+				return nil, err
+			}
+			opts.SetOrdered(b)
+		default:
+			return nil, unknownOptionError{"bulkWrite", e.Key}
+		}
+	}
+	return opts, nil
+}
+
+// newIndexOpts reads createIndex's options object, e.g. `{name:
+// 'email_1', unique: true, partialFilterExpression: {email: {$exists:
+// true}}}`.
+func newIndexOpts(objStr string) (*options.IndexOptions, error) {
+	doc, err := constructBsonDoc(objStr)
+	if err != nil {
+		return nil, err
+	}
+	opts := options.Index()
+	for _, e := range doc {
+		var err error
+		switch e.Key {
+		case "name":
+			var s string
+			s, err = optString("createIndex", e.Key, e.Value)
+			opts.SetName(s)
+		case "unique", "sparse", "hidden":
+			var b bool
+			b, err = optBool("createIndex", e.Key, e.Value)
+			switch e.Key {
+			case "unique":
+				opts.SetUnique(b)
+			case "sparse":
+				opts.SetSparse(b)
+			default:
+				opts.SetHidden(b)
+			}
+		case "expireAfterSeconds":
+			var n int32
+			n, err = optInt32("createIndex", e.Key, e.Value)
+			opts.SetExpireAfterSeconds(n)
+		case "partialFilterExpression":
+			var d bson.D
+			d, err = optDoc("createIndex", e.Key, e.Value)
+			opts.SetPartialFilterExpression(d)
+		case "collation":
+			var c *options.Collation
+			c, err = optCollation("createIndex", e.Value)
+			opts.SetCollation(c)
+		default:
+			err = unknownOptionError{"createIndex", e.Key}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return opts, nil
+}
+
+// optDocArray takes an array of documents, such as arrayFilters.
+func optDocArray(method, name string, v any) ([]any, error) {
+	arr, ok := v.(bson.A)
+	if ok {
+		for _, el := range arr {
+			if _, isDoc := el.(bson.D); !isDoc {
+				ok = false
+			}
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s option %s must be an array of documents, got %v", method, name, v)
+	}
+	return []any(arr), nil
 }
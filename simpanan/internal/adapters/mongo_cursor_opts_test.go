@@ -0,0 +1,183 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// applyFindOpts parses a find chain and applies its cursor options the
+// way handleFind does.
+func applyFindOpts(t *testing.T, query string) (*options.FindOptions, *findExplain, error) {
+	t.Helper()
+	mq, err := parseMongoQuery(query)
+	assert.NoError(t, err)
+	cOpts, err := parseCursorOpts(find, mq.opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts := options.Find()
+	var explain *findExplain
+	for _, co := range cOpts {
+		o, err := co.Apply(opts)
+		assert.NoError(t, err)
+		opts = o.(*options.FindOptions)
+		if e, ok := co.(findExplain); ok {
+			explain = &e
+		}
+	}
+	return opts, explain, nil
+}
+
+func TestFindCursorOpts(t *testing.T) {
+	opts, explain, err := applyFindOpts(t, `db.users.find({}).sort({age: -1, name: 1}).skip(20).limit(10)
+		.project({name: 1, _id: 0}).hint({age: -1}).collation({locale: 'en', strength: 2, numericOrdering: true})
+		.maxTimeMS(1500).batchSize(50).comment('report').allowDiskUse()`)
+	assert.NoError(t, err)
+	assert.Nil(t, explain)
+	assert.Equal(t, bson.D{{Key: "age", Value: int32(-1)}, {Key: "name", Value: int32(1)}}, opts.Sort)
+	assert.Equal(t, int64(20), *opts.Skip)
+	assert.Equal(t, int64(10), *opts.Limit)
+	assert.Equal(t, bson.D{{Key: "name", Value: int32(1)}, {Key: "_id", Value: int32(0)}}, opts.Projection)
+	assert.Equal(t, bson.D{{Key: "age", Value: int32(-1)}}, opts.Hint)
+	assert.Equal(t, &options.Collation{Locale: "en", Strength: 2, NumericOrdering: true}, opts.Collation)
+	assert.Equal(t, 1500*time.Millisecond, *opts.MaxTime)
+	assert.Equal(t, int32(50), *opts.BatchSize)
+	assert.Equal(t, "report", *opts.Comment)
+	assert.True(t, *opts.AllowDiskUse)
+
+	opts, explain, err = applyFindOpts(t, `db.users.find({a: 1}).hint('a_1').allowDiskUse(false).explain('executionStats')`)
+	assert.NoError(t, err)
+	assert.Equal(t, "a_1", opts.Hint)
+	assert.False(t, *opts.AllowDiskUse)
+	assert.Equal(t, &findExplain{"executionStats"}, explain)
+	assert.Equal(t, bson.D{
+		{Key: "find", Value: "users"}, {Key: "filter", Value: bson.M{"a": int32(1)}},
+		{Key: "hint", Value: "a_1"}, {Key: "allowDiskUse", Value: false},
+	}, findExplainCommand("users", bson.M{"a": int32(1)}, opts))
+
+	_, explain, err = applyFindOpts(t, `db.users.find().explain()`)
+	assert.NoError(t, err)
+	assert.Equal(t, &findExplain{"queryPlanner"}, explain)
+	_, explain, err = applyFindOpts(t, `db.users.find().explain(true)`)
+	assert.NoError(t, err)
+	assert.Equal(t, &findExplain{"allPlansExecution"}, explain)
+}
+
+func TestFindCursorOptsErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`db.users.find().pretty()`, `unknown find cursor method .pretty()`},
+		{`db.users.find().skip('ten')`, `find option skip must be an integer, got ten`},
+		{`db.users.find().skip(1.5)`, `find option skip must be an integer, got 1.5`},
+		{`db.users.find().skip()`, `.skip() takes one argument, got 0`},
+		{`db.users.find().skip(1, 2)`, `.skip() takes one argument, got 2`},
+		{`db.users.find().batchSize(3000000000)`, `find option batchSize must be a 32-bit integer, got 3000000000`},
+		{`db.users.find().hint(1)`, `find option hint must be an index name or key document, got 1`},
+		{`db.users.find().collation({strength: 2})`, `find option collation needs a locale`},
+		{`db.users.find().collation({locale: 'en', foo: 1})`, `unknown find option "collation.foo"`},
+		{`db.users.find().comment(5)`, `find option comment must be a string, got 5`},
+		{`db.users.find().allowDiskUse('yes')`, `find option allowDiskUse must be true or false, got yes`},
+		{`db.users.find().explain('verbose')`, `.explain() takes queryPlanner, executionStats or allPlansExecution, got 'verbose'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, _, err := applyFindOpts(t, tt.query)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestMethodOptionObjects(t *testing.T) {
+	agg, err := newAggregateOpts(`{allowDiskUse: true, hint: {status: 1}, let: {min: 5}, collation: {locale: 'fr'}, maxTimeMS: 200, batchSize: 10, comment: 'x'}`)
+	assert.NoError(t, err)
+	assert.True(t, *agg.AllowDiskUse)
+	assert.Equal(t, bson.D{{Key: "status", Value: int32(1)}}, agg.Hint)
+	assert.Equal(t, bson.D{{Key: "min", Value: int32(5)}}, agg.Let)
+	assert.Equal(t, "fr", agg.Collation.Locale)
+	assert.Equal(t, 200*time.Millisecond, *agg.MaxTime)
+	assert.Equal(t, int32(10), *agg.BatchSize)
+	assert.Equal(t, "x", *agg.Comment)
+
+	count, err := newCountOpts("count", `{limit: 100, skip: 10, hint: 'status_1', maxTimeMS: 50}`)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), *count.Limit)
+	assert.Equal(t, int64(10), *count.Skip)
+	assert.Equal(t, "status_1", count.Hint)
+	assert.Equal(t, 50*time.Millisecond, *count.MaxTime)
+
+	distinct, err := newDistinctOpts(`{collation: {locale: 'en', strength: 1}, comment: {by: 'me'}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, &options.Collation{Locale: "en", Strength: 1}, distinct.Collation)
+	assert.Equal(t, bson.D{{Key: "by", Value: "me"}}, distinct.Comment)
+
+	_, err = newAggregateOpts(`{cursor: {batchSize: 1}}`)
+	assert.EqualError(t, err, `unknown aggregate option "cursor"`)
+	_, err = newAggregateOpts(`{let: 5}`)
+	assert.EqualError(t, err, `aggregate option let must be a document, got 5`)
+	_, err = newCountOpts("count", `{sort: {a: 1}}`)
+	assert.EqualError(t, err, `unknown count option "sort"`)
+	_, err = newDistinctOpts(`{limit: 1}`)
+	assert.EqualError(t, err, `unknown distinct option "limit"`)
+}
+
+func TestWriteOptionObjects(t *testing.T) {
+	fm, err := newFindAndModifyOpts(findOneAndUpdate, `{sort: {at: -1}, projection: {_id: 0}, upsert: true, returnDocument: 'after', arrayFilters: [{'e.n': {$gt: 1}}], maxTimeMS: 10}`)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "at", Value: int32(-1)}}, fm.sort)
+	assert.Equal(t, bson.D{{Key: "_id", Value: int32(0)}}, fm.projection)
+	assert.True(t, *fm.upsert)
+	assert.True(t, fm.returnAfter)
+	assert.Equal(t, []any{bson.D{{Key: "e.n", Value: bson.D{{Key: "$gt", Value: int32(1)}}}}}, fm.arrayFilters)
+	assert.Equal(t, 10*time.Millisecond, *fm.maxTime)
+
+	fm, err = newFindAndModifyOpts(findOneAndReplace, `{returnNewDocument: true}`)
+	assert.NoError(t, err)
+	assert.True(t, fm.returnAfter)
+
+	_, err = newFindAndModifyOpts(findOneAndReplace, `{arrayFilters: []}`)
+	assert.EqualError(t, err, `unknown findOneAndReplace option "arrayFilters"`)
+	_, err = newFindAndModifyOpts(findOneAndDelete, `{upsert: true}`)
+	assert.EqualError(t, err, `unknown findOneAndDelete option "upsert"`)
+	_, err = newFindAndModifyOpts(findOneAndUpdate, `{returnDocument: 'new'}`)
+	assert.EqualError(t, err, `findOneAndUpdate option returnDocument must be 'before' or 'after', got new`)
+
+	replace, err := newReplaceOpts(`{upsert: true, hint: 'a_1'}`)
+	assert.NoError(t, err)
+	assert.True(t, *replace.Upsert)
+	assert.Equal(t, "a_1", replace.Hint)
+
+	update, err := newUpdateOpts(updateOne, `{upsert: true, arrayFilters: [{'e.n': {$gt: 1}}], hint: {a: 1}, collation: {locale: 'fr'}}`)
+	assert.NoError(t, err)
+	assert.True(t, *update.Upsert)
+	assert.Equal(t, []any{bson.D{{Key: "e.n", Value: bson.D{{Key: "$gt", Value: int32(1)}}}}}, update.ArrayFilters.Filters)
+	assert.Equal(t, bson.D{{Key: "a", Value: int32(1)}}, update.Hint)
+	assert.Equal(t, "fr", update.Collation.Locale)
+	update, err = newUpdateOpts(updateMany, `{}`)
+	assert.NoError(t, err)
+	assert.Nil(t, update.Upsert)
+	_, err = newUpdateOpts(updateMany, `{multi: true}`)
+	assert.EqualError(t, err, `unknown updateMany option "multi"`)
+	_, err = newUpdateOpts(updateOne, `{arrayFilters: {'e.n': 1}}`)
+	assert.EqualError(t, err, `updateOne option arrayFilters must be an array of documents, got [{e.n 1}]`)
+
+	bulk, err := newBulkWriteOpts(`{ordered: false}`)
+	assert.NoError(t, err)
+	assert.False(t, *bulk.Ordered)
+	_, err = newBulkWriteOpts(`{writeConcern: {w: 1}}`)
+	assert.EqualError(t, err, `unknown bulkWrite option "writeConcern"`)
+
+	index, err := newIndexOpts(`{name: 'email_1', unique: true, expireAfterSeconds: 3600, partialFilterExpression: {email: {$exists: true}}}`)
+	assert.NoError(t, err)
+	assert.Equal(t, "email_1", *index.Name)
+	assert.True(t, *index.Unique)
+	assert.Equal(t, int32(3600), *index.ExpireAfterSeconds)
+	assert.Equal(t, bson.D{{Key: "email", Value: bson.D{{Key: "$exists", Value: true}}}}, index.PartialFilterExpression)
+	_, err = newIndexOpts(`{background: true}`)
+	assert.EqualError(t, err, `unknown createIndex option "background"`)
+}
@@ -0,0 +1,44 @@
+package adapters
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mongoCanonicalOutput reads the connection URI's extendedJSON option.
+// Results render as relaxed Extended JSON unless the URI asks for
+// `extendedJSON=canonical`, which keeps every BSON type explicit
+// (`{"$numberLong": "42"}`, `{"$numberDouble": "1.0"}`, ...). The driver
+// ignores options it does not know, so the URI is passed on unchanged.
+func mongoCanonicalOutput(connURI string) (bool, error) {
+	u, err := url.Parse(connURI)
+	if err != nil {
+		return false, fmt.Errorf("invalid mongo uri: %s", err)
+	}
+	switch mode := u.Query().Get("extendedJSON"); mode {
+	case "", "relaxed":
+		return false, nil
+	case "canonical":
+		return true, nil
+	default:
+		return false, fmt.Errorf("invalid mongo extendedJSON option %q: want relaxed or canonical", mode)
+	}
+}
+
+// mongoExtJSON renders v, a document, array or single value decoded
+// from BSON, as Extended JSON. Documents should be bson.D so their
+// fields keep the order the server sent them in.
+func mongoExtJSON(v any, canonical bool) (json.RawMessage, error) {
+	// The driver only marshals documents, so wrap v in one and cut the
+	// value back out: `{"v":<value>}`.
+	doc, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: v}}, canonical, false)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v.", err.Error(), v)
+	}
+	doc = bytes.TrimPrefix(doc, []byte(`{"v":`))
+	return json.RawMessage(bytes.TrimSuffix(doc, []byte("}"))), nil
+}
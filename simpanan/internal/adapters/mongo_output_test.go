@@ -0,0 +1,128 @@
+package adapters
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoCanonicalOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    bool
+		wantErr string
+	}{
+		{"default is relaxed", "mongodb://localhost:27017/mydb", false, ""},
+		{"relaxed", "mongodb://localhost:27017/mydb?extendedJSON=relaxed", false, ""},
+		{"canonical", "mongodb://localhost:27017/mydb?authSource=admin&extendedJSON=canonical", true, ""},
+		{"unknown mode", "mongodb://localhost:27017/mydb?extendedJSON=shell", false, `invalid mongo extendedJSON option "shell": want relaxed or canonical`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mongoCanonicalOutput(tc.uri)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+			// The option is passed on to the driver untouched.
+			assert.NoError(t, options.Client().ApplyURI(tc.uri).Validate())
+		})
+	}
+}
+
+func TestMongoExtJSON(t *testing.T) {
+	oid, _ := primitive.ObjectIDFromHex("65f1c2a9e4b0a1b2c3d4e5f6")
+	dec, _ := primitive.ParseDecimal128("12.50")
+	created := primitive.NewDateTimeFromTime(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	doc := bson.D{
+		{Key: "_id", Value: oid},
+		{Key: "zeta", Value: int32(1)},
+		{Key: "alpha", Value: int64(42)},
+		{Key: "price", Value: dec},
+		{Key: "ratio", Value: 1.0},
+		{Key: "blob", Value: primitive.Binary{Subtype: 0, Data: []byte("hi")}},
+		{Key: "ts", Value: primitive.Timestamp{T: 1700000000, I: 1}},
+		{Key: "created", Value: created},
+		{Key: "tags", Value: bson.A{"a", bson.D{{Key: "y", Value: int32(2)}, {Key: "x", Value: int32(1)}}}},
+		{Key: "gone", Value: nil},
+	}
+
+	tests := []struct {
+		name      string
+		value     any
+		canonical bool
+		want      string
+	}{
+		{
+			"relaxed keeps field order and lossy types",
+			doc, false,
+			`{"_id":{"$oid":"65f1c2a9e4b0a1b2c3d4e5f6"},"zeta":1,"alpha":42,"price":{"$numberDecimal":"12.50"},"ratio":1.0,` +
+				`"blob":{"$binary":{"base64":"aGk=","subType":"00"}},"ts":{"$timestamp":{"t":1700000000,"i":1}},` +
+				`"created":{"$date":"2024-03-01T12:00:00Z"},"tags":["a",{"y":2,"x":1}],"gone":null}`,
+		},
+		{
+			"canonical spells out every number",
+			bson.D{{Key: "zeta", Value: int32(1)}, {Key: "alpha", Value: int64(42)}, {Key: "ratio", Value: 1.0}, {Key: "created", Value: created}},
+			true,
+			`{"zeta":{"$numberInt":"1"},"alpha":{"$numberLong":"42"},"ratio":{"$numberDouble":"1.0"},"created":{"$date":{"$numberLong":"1709294400000"}}}`,
+		},
+		{"array of documents", bson.A{bson.D{{Key: "b", Value: 1}}, bson.D{{Key: "a", Value: 2}}}, false, `[{"b":1},{"a":2}]`},
+		{"empty array", bson.A{}, false, `[]`},
+		{"single id", oid, false, `{"$oid":"65f1c2a9e4b0a1b2c3d4e5f6"}`},
+		{"nil", nil, false, `null`},
+		{"string with braces", "a}", false, `"a}"`},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := mongoExtJSON(tc.value, tc.canonical)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+// A result rendered as Extended JSON can be pasted, or piped, into a
+// later stage's arguments. Canonical output keeps every BSON type;
+// relaxed output keeps all but the integer width, so an int64 that fits
+// in 32 bits comes back as an int32.
+func TestMongoExtJSONRoundTrip(t *testing.T) {
+	oid, _ := primitive.ObjectIDFromHex("65f1c2a9e4b0a1b2c3d4e5f6")
+	dec, _ := primitive.ParseDecimal128("12.50")
+	doc := func(small any) bson.D {
+		return bson.D{
+			{Key: "_id", Value: oid},
+			{Key: "price", Value: dec},
+			{Key: "blob", Value: primitive.Binary{Subtype: 4, Data: []byte("0123456789abcdef")}},
+			{Key: "ts", Value: primitive.Timestamp{T: 1700000000, I: 1}},
+			{Key: "created", Value: primitive.NewDateTimeFromTime(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))},
+			{Key: "count", Value: int32(7)},
+			{Key: "small", Value: small},
+			{Key: "big", Value: int64(1) << 40},
+			{Key: "ratio", Value: 2.0},
+		}
+	}
+	tests := []struct {
+		name      string
+		canonical bool
+		want      bson.D
+	}{
+		{"relaxed narrows small int64", false, doc(int32(42))},
+		{"canonical keeps every type", true, doc(int64(42))},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := mongoExtJSON(doc(int64(42)), tc.canonical)
+			assert.NoError(t, err)
+			got, err := constructBsonDoc(string(out))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package adapters
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mongoCall is one `name(args...)` link of a Mongo call chain. Each
+// argument is kept as the source text mongosh would see, for
+// constructBsonDoc and friends to decode.
+type mongoCall struct {
+	name string
+	args []string
+	pos  int
+}
+
+// mongoQuery is a parsed `db.<coll>.<method>(args).<opt>(args)...`
+// chain. db is empty unless the query switched databases with
+// db.getSiblingDB("other"). canonical is not part of the query text; it
+// carries the connection's extendedJSON output mode to the handlers.
+type mongoQuery struct {
+	db        string
+	coll      string
+	method    mongoCall
+	opts      []mongoCall
+	canonical bool
+}
+
+// arg returns the text of the i-th argument, or def when the call has
+// fewer arguments.
+func (c mongoCall) arg(i int, def string) string {
+	if i < len(c.args) {
+		return c.args[i]
+	}
+	return def
+}
+
+// requireArgs reports a call made with fewer than n arguments.
+func (c mongoCall) requireArgs(n int) error {
+	if len(c.args) < n {
+		return &mongoSyntaxError{pos: c.pos, msg: fmt.Sprintf("%s() takes %d argument(s), got %d", c.name, n, len(c.args))}
+	}
+	return nil
+}
+
+// parseMongoQuery parses a whole call chain:
+//
+//	db.users.find({status: 'active'}).sort({age: -1}).limit(10)
+//	db.getCollection("audit.log").find()
+//	db.getSiblingDB("reporting").daily.aggregate([...])
+//
+// Collection names may contain dots (`db.audit.log.find()`); the last
+// name before the first `(` is the method. Errors carry the byte offset
+// into q where parsing stopped.
+func parseMongoQuery(q string) (*mongoQuery, error) {
+	p := &shellParser{src: q}
+	mq, err := p.mongoHead()
+	if err != nil {
+		return nil, err
+	}
+	if mq.method.args, err = p.callArgs(); err != nil {
+		return nil, err
+	}
+	for {
+		p.skipSpace()
+		if p.peek() != '.' {
+			break
+		}
+		p.pos++
+		p.skipSpace()
+		pos := p.pos
+		name := p.ident()
+		if name == "" {
+			return nil, p.errorf("expected a method name after '.'")
+		}
+		p.skipSpace()
+		if p.peek() != '(' {
+			return nil, p.errorf("expected '(' after %s", name)
+		}
+		args, err := p.callArgs()
+		if err != nil {
+			return nil, err
+		}
+		mq.opts = append(mq.opts, mongoCall{name: name, args: args, pos: pos})
+	}
+	if p.peek() == ';' {
+		p.pos++
+		p.skipSpace()
+	}
+	if p.pos < len(p.src) {
+		return nil, p.errorf("unexpected %q after the query", p.src[p.pos])
+	}
+	return mq, nil
+}
+
+// parseMongoHead parses a query up to its method name, leaving the
+// arguments alone so a query still holding `{{...}}` placeholders can
+// be classified.
+func parseMongoHead(q string) (*mongoQuery, error) {
+	return (&shellParser{src: q}).mongoHead()
+}
+
+// mongoHead reads `db[.getSiblingDB("x")].<coll>.<method>` and stops in
+// front of the method's `(`.
+func (p *shellParser) mongoHead() (*mongoQuery, error) {
+	mq := &mongoQuery{}
+	p.skipSpace()
+	if p.ident() != "db" {
+		p.pos = 0
+		p.skipSpace()
+		return nil, p.errorf("expected a query starting with db.")
+	}
+	var names []string
+	namesPos := 0
+	for {
+		p.skipSpace()
+		if p.peek() == '(' {
+			break
+		}
+		if p.peek() != '.' {
+			if p.pos >= len(p.src) {
+				return nil, p.errorf("expected a method call, got end of input")
+			}
+			return nil, p.errorf("expected '.' or '(', got %q", p.src[p.pos])
+		}
+		p.pos++
+		p.skipSpace()
+		pos := p.pos
+		name := p.ident()
+		if name == "" {
+			return nil, p.errorf("expected a name after '.'")
+		}
+		p.skipSpace()
+		if len(names) > 0 || p.peek() != '(' || (name != "getCollection" && name != "getSiblingDB") {
+			if len(names) == 0 {
+				namesPos = pos
+			}
+			names = append(names, name)
+			mq.method.pos = pos
+			continue
+		}
+		if (name == "getSiblingDB" && (mq.db != "" || mq.coll != "")) || (name == "getCollection" && mq.coll != "") {
+			p.pos = pos
+			return nil, p.errorf("unexpected %s()", name)
+		}
+		argPos := p.pos + 1
+		args, err := p.callArgs()
+		if err != nil {
+			return nil, err
+		}
+		arg, ok := "", len(args) == 1
+		if ok {
+			arg, ok = shellString(args[0])
+		}
+		if !ok || arg == "" {
+			p.pos = argPos
+			return nil, p.errorf("%s takes one name string", name)
+		}
+		if name == "getSiblingDB" {
+			mq.db = arg
+		} else {
+			mq.coll = arg
+		}
+	}
+	if len(names) == 0 {
+		return nil, p.errorf("expected a method name before '('")
+	}
+	if mq.coll != "" && len(names) > 1 {
+		p.pos = namesPos
+		return nil, p.errorf("unexpected %q after getCollection()", strings.Join(names[:len(names)-1], "."))
+	}
+	mq.method.name = names[len(names)-1]
+	if mq.coll == "" {
+		mq.coll = strings.Join(names[:len(names)-1], ".")
+	}
+	return mq, nil
+}
+
+// callArgs reads a parenthesised, comma-separated argument list of
+// shell values and returns each argument's source text.
+func (p *shellParser) callArgs() ([]string, error) {
+	p.pos++
+	args := []string{}
+	for {
+		p.skipSpace()
+		if p.peek() == ')' {
+			p.pos++
+			return args, nil
+		}
+		if len(args) > 0 {
+			if err := p.expect(','); err != nil {
+				if p.pos < len(p.src) {
+					return nil, p.errorf("expected ',' or ')', got %q", p.src[p.pos])
+				}
+				return nil, p.errorf("expected ')', got end of input")
+			}
+			p.skipSpace()
+			if p.peek() == ')' {
+				continue
+			}
+		}
+		p.skipSpace()
+		start := p.pos
+		if err := p.value(); err != nil {
+			return nil, err
+		}
+		p.out.Reset()
+		args = append(args, p.src[start:p.pos])
+	}
+}
+
+// shellString decodes a quoted string argument.
+func shellString(arg string) (string, bool) {
+	if arg == "" || (arg[0] != '"' && arg[0] != '\'') {
+		return "", false
+	}
+	p := &shellParser{src: arg}
+	s, err := p.str()
+	return s, err == nil && p.pos == len(arg)
+}
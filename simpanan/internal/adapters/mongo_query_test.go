@@ -0,0 +1,99 @@
+package adapters
+
+import (
+	"simpanan/internal/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMongoQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  *mongoQuery
+	}{
+		{"no arguments", `db.users.find()`,
+			&mongoQuery{coll: "users", method: mongoCall{name: "find", args: []string{}, pos: 9}}},
+		{"cursor chain", `db.users.find({status: 'active'}, {name: 1}).sort({age: -1}).limit(10);`,
+			&mongoQuery{coll: "users", method: mongoCall{name: "find", args: []string{`{status: 'active'}`, `{name: 1}`}, pos: 9},
+				opts: []mongoCall{{name: "sort", args: []string{`{age: -1}`}, pos: 45}, {name: "limit", args: []string{`10`}, pos: 61}}}},
+		{"nested parentheses", `db.orders.find({$expr: {$eq: [ObjectId("65a1f0c2e4b0a1b2c3d4e5f6"), "$ref"]}, at: ISODate('2024-01-01')})`,
+			&mongoQuery{coll: "orders", method: mongoCall{name: "find", pos: 10,
+				args: []string{`{$expr: {$eq: [ObjectId("65a1f0c2e4b0a1b2c3d4e5f6"), "$ref"]}, at: ISODate('2024-01-01')}`}}}},
+		{"parenthesis inside strings and regexes", `db.logs.find({msg: /\)$/, tag: "a)b"}).comment("x)")`,
+			&mongoQuery{coll: "logs", method: mongoCall{name: "find", args: []string{`{msg: /\)$/, tag: "a)b"}`}, pos: 8},
+				opts: []mongoCall{{name: "comment", args: []string{`"x)"`}, pos: 39}}}},
+		{"dotted collection", `db.audit.log.findOne({})`,
+			&mongoQuery{coll: "audit.log", method: mongoCall{name: "findOne", args: []string{`{}`}, pos: 13}}},
+		{"getCollection", `db.getCollection("audit.log").count({level: 'warn'})`,
+			&mongoQuery{coll: "audit.log", method: mongoCall{name: "count", args: []string{`{level: 'warn'}`}, pos: 30}}},
+		{"getSiblingDB", ` db.getSiblingDB('reporting').daily.aggregate([{$match: {}}], ) `,
+			&mongoQuery{db: "reporting", coll: "daily", method: mongoCall{name: "aggregate", args: []string{`[{$match: {}}]`}, pos: 36}}},
+		{"getSiblingDB and getCollection", "db.getSiblingDB(\"other\")\n  .getCollection('a-b').distinct('x')",
+			&mongoQuery{db: "other", coll: "a-b", method: mongoCall{name: "distinct", args: []string{`'x'`}, pos: 49}}},
+		{"database-level call", `db.runCommand({ping: 1})`,
+			&mongoQuery{method: mongoCall{name: "runCommand", args: []string{`{ping: 1}`}, pos: 3}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseMongoQuery(tt.query)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseMongoQueryErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`users.find()`, `syntax error at offset 0: expected a query starting with db.`},
+		{`db.users.find`, `syntax error at offset 13: expected a method call, got end of input`},
+		{`db.users.find({a: 1}`, `syntax error at offset 20: expected ')', got end of input`},
+		{`db.users.find({a: 1} {b: 2})`, `syntax error at offset 21: expected ',' or ')', got '{'`},
+		{`db.users.find({a: active})`, `syntax error at offset 18: unknown identifier "active" (quote it if it is a string)`},
+		{`db.users.find().limit`, `syntax error at offset 21: expected '(' after limit`},
+		{`db.users.find().`, `syntax error at offset 16: expected a method name after '.'`},
+		{`db.users.find() x`, `syntax error at offset 16: unexpected 'x' after the query`},
+		{`db.getCollection(users).find()`, `syntax error at offset 17: unknown identifier "users" (quote it if it is a string)`},
+		{`db.getCollection(1).find()`, `syntax error at offset 17: getCollection takes one name string`},
+		{`db.getCollection("a").b.find()`, `syntax error at offset 22: unexpected "b" after getCollection()`},
+		{`db.getCollection("a").getSiblingDB("b").find()`, `syntax error at offset 22: unexpected getSiblingDB()`},
+		{`db..find()`, `syntax error at offset 3: expected a name after '.'`},
+		{`db.(1)`, `syntax error at offset 3: expected a name after '.'`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			_, err := parseMongoQuery(tt.query)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
+
+func TestQueryTypeMongoHeadOnly(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`db.users.find({name: {{.name}}}).limit({{.n}})`, common.Read},
+		{`db.getSiblingDB("other").users.deleteMany({_id: {$in: {{.ids}}}})`, common.Write},
+		{`db.getCollection("a.b").insertOne({{.}})`, common.Write},
+		{`db.audit.log.aggregate([])`, common.Read},
+		{`db.users.drop()`, common.QueryType("")},
+		{`users.find()`, common.QueryType("")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeMongo(tt.query))
+		})
+	}
+}
+
+func TestExecuteMongoQuerySyntaxErrorBeforeConnecting(t *testing.T) {
+	_, err := ExecuteMongoReadQuery(common.QueryMetadata{Conn: "mongodb://127.0.0.1:1/app", QueryLine: `db.users.find({a: 1`})
+	assert.EqualError(t, err, `syntax error at offset 19: expected ',' or '}' after the value of "a"`)
+	_, err = ExecuteMongoWriteQuery(common.QueryMetadata{Conn: "mongodb://127.0.0.1:1/app", QueryLine: `db.users.dropIndexes()`})
+	assert.EqualError(t, err, `Write handler not found: dropIndexes.`)
+}
@@ -0,0 +1,623 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// mongoSyntaxError is a parse error at a byte offset of the text being
+// parsed.
+type mongoSyntaxError struct {
+	pos int
+	msg string
+}
+
+func (e *mongoSyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at offset %d: %s", e.pos, e.msg)
+}
+
+// shellToExtJSON rewrites a value written the way mongosh accepts it
+// into canonical Extended JSON for bson.UnmarshalExtJSON. On top of
+// plain JSON it takes unquoted keys, single-quoted strings, trailing
+// commas, `/pattern/flags` regex literals and the shell helpers
+// ObjectId, ISODate, Date, NumberInt, NumberLong, NumberDecimal,
+// Decimal128, Timestamp, UUID, BinData, MinKey and MaxKey (with or
+// without `new`).
+func shellToExtJSON(src string) (string, error) {
+	p := &shellParser{src: src}
+	if err := p.value(); err != nil {
+		return "", err
+	}
+	p.skipSpace()
+	if p.pos < len(p.src) {
+		return "", p.errorf("unexpected %q after value", p.src[p.pos])
+	}
+	return p.out.String(), nil
+}
+
+// shellValue decodes a single shell value, such as a cursor method's
+// argument, into its BSON Go value.
+func shellValue(src string) (any, error) {
+	extJSON, err := shellToExtJSON(src)
+	if err != nil {
+		return nil, err
+	}
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(`{"v":`+extJSON+`}`), false, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %s", err, src)
+	}
+	return doc[0].Value, nil
+}
+
+type shellParser struct {
+	src string
+	pos int
+	out strings.Builder
+}
+
+func (p *shellParser) errorf(format string, args ...any) error {
+	return &mongoSyntaxError{pos: p.pos, msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *shellParser) peek() byte {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return 0
+}
+
+func (p *shellParser) skipSpace() {
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// expect consumes c after any whitespace.
+func (p *shellParser) expect(c byte) error {
+	p.skipSpace()
+	if p.peek() != c {
+		if p.pos >= len(p.src) {
+			return p.errorf("expected %q, got end of input", c)
+		}
+		return p.errorf("expected %q, got %q", c, p.src[p.pos])
+	}
+	p.pos++
+	return nil
+}
+
+func (p *shellParser) writeString(s string) {
+	b, _ := json.Marshal(s)
+	p.out.Write(b)
+}
+
+func (p *shellParser) value() error {
+	p.skipSpace()
+	switch c := p.peek(); {
+	case p.pos >= len(p.src):
+		return p.errorf("expected a value, got end of input")
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		s, err := p.str()
+		if err != nil {
+			return err
+		}
+		p.writeString(s)
+		return nil
+	case c == '/':
+		return p.regex()
+	case c == '-' || c == '+' || c == '.' || isDigit(c):
+		num, err := p.number()
+		if err != nil {
+			return err
+		}
+		p.out.WriteString(num)
+		return nil
+	case isIdentStart(c):
+		return p.word()
+	default:
+		return p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *shellParser) object() error {
+	p.pos++
+	p.out.WriteByte('{')
+	for first := true; ; first = false {
+		p.skipSpace()
+		if p.peek() == '}' {
+			p.pos++
+			p.out.WriteByte('}')
+			return nil
+		}
+		if !first {
+			p.out.WriteByte(',')
+		}
+		key, err := p.key()
+		if err != nil {
+			return err
+		}
+		p.writeString(key)
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		p.out.WriteByte(':')
+		if err := p.value(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+		default:
+			return p.errorf("expected ',' or '}' after the value of %q", key)
+		}
+	}
+}
+
+// key reads an object key: a quoted string or a bare identifier such as
+// `status` or `$gt`.
+func (p *shellParser) key() (string, error) {
+	if c := p.peek(); c == '"' || c == '\'' {
+		return p.str()
+	}
+	start := p.pos
+	for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		if p.pos >= len(p.src) {
+			return "", p.errorf("expected a key, got end of input")
+		}
+		return "", p.errorf("expected a key, got %q", p.src[p.pos])
+	}
+	return p.src[start:p.pos], nil
+}
+
+func (p *shellParser) array() error {
+	p.pos++
+	p.out.WriteByte('[')
+	for first := true; ; first = false {
+		p.skipSpace()
+		if p.peek() == ']' {
+			p.pos++
+			p.out.WriteByte(']')
+			return nil
+		}
+		if !first {
+			p.out.WriteByte(',')
+		}
+		if err := p.value(); err != nil {
+			return err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return p.errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+// str reads a single- or double-quoted string, decoding JavaScript
+// escapes.
+func (p *shellParser) str() (string, error) {
+	start := p.pos
+	quote := p.src[p.pos]
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		p.pos++
+		switch c {
+		case quote:
+			return b.String(), nil
+		case '\\':
+			if p.pos >= len(p.src) {
+				break
+			}
+			e := p.src[p.pos]
+			p.pos++
+			switch e {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case 'r':
+				b.WriteByte('\r')
+			case 'b':
+				b.WriteByte('\b')
+			case 'f':
+				b.WriteByte('\f')
+			case 'v':
+				b.WriteByte('\v')
+			case '0':
+				b.WriteByte(0)
+			case 'x':
+				r, err := p.hexEscape(2)
+				if err != nil {
+					return "", err
+				}
+				b.WriteRune(r)
+			case 'u':
+				r, err := p.hexEscape(4)
+				if err != nil {
+					return "", err
+				}
+				if utf16.IsSurrogate(r) && strings.HasPrefix(p.src[p.pos:], `\u`) {
+					p.pos += 2
+					r2, err := p.hexEscape(4)
+					if err != nil {
+						return "", err
+					}
+					r = utf16.DecodeRune(r, r2)
+				}
+				b.WriteRune(r)
+			case '\n':
+				// Line continuation.
+			default:
+				b.WriteByte(e)
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	p.pos = start
+	return "", p.errorf("unterminated string")
+}
+
+func (p *shellParser) hexEscape(n int) (rune, error) {
+	if p.pos+n > len(p.src) {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	v, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+	if err != nil {
+		return 0, p.errorf("invalid escape sequence")
+	}
+	p.pos += n
+	return rune(v), nil
+}
+
+// number reads a numeric literal and returns it as JSON number text,
+// so `+5`, `.5` and `5.` become `5`, `0.5` and `5.0`. Integers stay
+// integers and fractions stay doubles when the result is decoded.
+func (p *shellParser) number() (string, error) {
+	start := p.pos
+	if c := p.peek(); c == '-' || c == '+' {
+		p.pos++
+	}
+	if strings.HasPrefix(p.src[p.pos:], "Infinity") {
+		p.pos += len("Infinity")
+		if p.src[start] == '-' {
+			return `{"$numberDouble":"-Infinity"}`, nil
+		}
+		return `{"$numberDouble":"Infinity"}`, nil
+	}
+	for p.pos < len(p.src) && strings.IndexByte("0123456789.eE+-", p.src[p.pos]) >= 0 {
+		// A sign only belongs to the number right after an exponent.
+		if c := p.src[p.pos]; (c == '+' || c == '-') && p.src[p.pos-1] != 'e' && p.src[p.pos-1] != 'E' {
+			break
+		}
+		p.pos++
+	}
+	text := p.src[start:p.pos]
+	if _, err := strconv.ParseFloat(text, 64); err != nil {
+		p.pos = start
+		return "", p.errorf("invalid number %q", text)
+	}
+	text = strings.TrimPrefix(text, "+")
+	neg := strings.HasPrefix(text, "-")
+	digits := strings.TrimPrefix(text, "-")
+	if strings.HasPrefix(digits, ".") {
+		digits = "0" + digits
+	}
+	if i := strings.IndexByte(digits, '.'); i == len(digits)-1 || (i >= 0 && !isDigit(digits[i+1])) {
+		digits = digits[:i+1] + "0" + digits[i+1:]
+	}
+	if neg {
+		return "-" + digits, nil
+	}
+	return digits, nil
+}
+
+// regex reads a `/pattern/flags` literal into a $regularExpression.
+func (p *shellParser) regex() error {
+	start := p.pos
+	p.pos++
+	var pattern strings.Builder
+	inClass := false
+	for {
+		if p.pos >= len(p.src) || p.src[p.pos] == '\n' {
+			p.pos = start
+			return p.errorf("unterminated regular expression")
+		}
+		c := p.src[p.pos]
+		p.pos++
+		if c == '/' && !inClass {
+			break
+		}
+		pattern.WriteByte(c)
+		switch c {
+		case '\\':
+			if p.pos < len(p.src) {
+				pattern.WriteByte(p.src[p.pos])
+				p.pos++
+			}
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		}
+	}
+	flagStart := p.pos
+	for p.pos < len(p.src) && isIdentStart(p.src[p.pos]) {
+		p.pos++
+	}
+	flags := []byte(p.src[flagStart:p.pos])
+	for _, f := range flags {
+		if strings.IndexByte("imsxu", f) < 0 {
+			p.pos = flagStart
+			return p.errorf("unsupported regular expression flag %q", f)
+		}
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i] < flags[j] })
+	p.out.WriteString(`{"$regularExpression":{"pattern":`)
+	p.writeString(pattern.String())
+	p.out.WriteString(`,"options":`)
+	p.writeString(string(flags))
+	p.out.WriteString(`}}`)
+	return nil
+}
+
+// shellArg is a constructor argument: a string or a number literal.
+type shellArg struct {
+	text   string
+	quoted bool
+}
+
+// word reads a bare literal (true, false, null, ...) or a shell helper
+// call.
+func (p *shellParser) word() error {
+	start := p.pos
+	name := p.ident()
+	if name == "new" {
+		p.skipSpace()
+		start = p.pos
+		name = p.ident()
+		if name == "" {
+			return p.errorf("expected a constructor after new")
+		}
+	}
+	switch name {
+	case "true", "false", "null":
+		p.out.WriteString(name)
+		return nil
+	case "undefined":
+		p.out.WriteString("null")
+		return nil
+	case "NaN", "Infinity":
+		p.out.WriteString(`{"$numberDouble":"` + name + `"}`)
+		return nil
+	case "MinKey", "MaxKey":
+		// mongosh accepts both MinKey and MinKey().
+		save := p.pos
+		p.skipSpace()
+		if p.peek() == '(' {
+			if _, err := p.args(); err != nil {
+				return err
+			}
+		} else {
+			p.pos = save
+		}
+		p.out.WriteString(`{"$` + strings.ToLower(name[:1]) + name[1:] + `":1}`)
+		return nil
+	}
+
+	p.skipSpace()
+	if p.peek() != '(' {
+		p.pos = start
+		return p.errorf("unknown identifier %q (quote it if it is a string)", name)
+	}
+	args, err := p.args()
+	if err != nil {
+		return err
+	}
+	ext, err := shellHelper(name, args)
+	if err != nil {
+		return &mongoSyntaxError{pos: start, msg: err.Error()}
+	}
+	p.out.WriteString(ext)
+	return nil
+}
+
+func (p *shellParser) ident() string {
+	start := p.pos
+	for p.pos < len(p.src) && (isIdentStart(p.src[p.pos]) || isDigit(p.src[p.pos])) {
+		p.pos++
+	}
+	return p.src[start:p.pos]
+}
+
+// args reads a parenthesised list of string and number literals.
+func (p *shellParser) args() ([]shellArg, error) {
+	p.pos++
+	var args []shellArg
+	for {
+		p.skipSpace()
+		switch c := p.peek(); {
+		case c == ')':
+			p.pos++
+			return args, nil
+		case len(args) > 0:
+			if err := p.expect(','); err != nil {
+				return nil, err
+			}
+			p.skipSpace()
+			if p.peek() == ')' {
+				continue
+			}
+		}
+		switch c := p.peek(); {
+		case c == '"' || c == '\'':
+			s, err := p.str()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, shellArg{text: s, quoted: true})
+		case c == '-' || c == '+' || c == '.' || isDigit(c):
+			num, err := p.number()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, shellArg{text: num})
+		case p.pos >= len(p.src):
+			return nil, p.errorf("expected ')', got end of input")
+		default:
+			return nil, p.errorf("expected a string or number argument, got %q", c)
+		}
+	}
+}
+
+// shellHelper turns a shell helper call into its Extended JSON form.
+func shellHelper(name string, args []shellArg) (string, error) {
+	str := func(s string) string {
+		b, _ := json.Marshal(s)
+		return string(b)
+	}
+	switch name {
+	case "ObjectId":
+		if len(args) == 0 {
+			return `{"$oid":"` + primitive.NewObjectID().Hex() + `"}`, nil
+		}
+		if len(args) > 1 || !args[0].quoted {
+			return "", fmt.Errorf("ObjectId takes one hex string")
+		}
+		if _, err := primitive.ObjectIDFromHex(args[0].text); err != nil {
+			return "", fmt.Errorf("ObjectId(%q): %s", args[0].text, err)
+		}
+		return `{"$oid":` + str(args[0].text) + `}`, nil
+	case "ISODate", "Date":
+		t := time.Now()
+		if len(args) > 1 {
+			return "", fmt.Errorf("%s takes one date string or milliseconds", name)
+		}
+		if len(args) == 1 {
+			var err error
+			if t, err = shellDate(args[0]); err != nil {
+				return "", fmt.Errorf("%s(%q): %s", name, args[0].text, err)
+			}
+		}
+		return `{"$date":{"$numberLong":"` + strconv.FormatInt(t.UnixMilli(), 10) + `"}}`, nil
+	case "NumberInt", "NumberLong":
+		bits, key := 32, "$numberInt"
+		if name == "NumberLong" {
+			bits, key = 64, "$numberLong"
+		}
+		if len(args) != 1 {
+			return "", fmt.Errorf("%s takes one integer", name)
+		}
+		n, err := strconv.ParseInt(args[0].text, 10, bits)
+		if err != nil {
+			return "", fmt.Errorf("%s(%s): not a %d-bit integer", name, args[0].text, bits)
+		}
+		return `{"` + key + `":"` + strconv.FormatInt(n, 10) + `"}`, nil
+	case "NumberDecimal", "Decimal128":
+		if len(args) != 1 {
+			return "", fmt.Errorf("%s takes one decimal string", name)
+		}
+		if _, err := primitive.ParseDecimal128(args[0].text); err != nil {
+			return "", fmt.Errorf("%s(%q): %s", name, args[0].text, err)
+		}
+		return `{"$numberDecimal":` + str(args[0].text) + `}`, nil
+	case "Timestamp":
+		if len(args) != 2 || args[0].quoted || args[1].quoted {
+			return "", fmt.Errorf("Timestamp takes seconds and an increment")
+		}
+		var parts [2]uint64
+		for i, a := range args {
+			n, err := strconv.ParseUint(a.text, 10, 32)
+			if err != nil {
+				return "", fmt.Errorf("Timestamp(%s, %s): not a 32-bit unsigned integer", args[0].text, args[1].text)
+			}
+			parts[i] = n
+		}
+		return fmt.Sprintf(`{"$timestamp":{"t":%d,"i":%d}}`, parts[0], parts[1]), nil
+	case "UUID":
+		if len(args) != 1 || !args[0].quoted {
+			return "", fmt.Errorf("UUID takes one hex string")
+		}
+		b, err := hex.DecodeString(strings.ReplaceAll(args[0].text, "-", ""))
+		if err != nil || len(b) != 16 {
+			return "", fmt.Errorf("UUID(%q): not a 32-digit hex string", args[0].text)
+		}
+		return `{"$binary":{"base64":"` + base64.StdEncoding.EncodeToString(b) + `","subType":"04"}}`, nil
+	case "BinData":
+		if len(args) != 2 || args[0].quoted || !args[1].quoted {
+			return "", fmt.Errorf("BinData takes a subtype and a base64 string")
+		}
+		sub, err := strconv.ParseUint(args[0].text, 10, 8)
+		if err != nil {
+			return "", fmt.Errorf("BinData(%s, ...): subtype must be 0-255", args[0].text)
+		}
+		if _, err := base64.StdEncoding.DecodeString(args[1].text); err != nil {
+			return "", fmt.Errorf("BinData(%s, %q): %s", args[0].text, args[1].text, err)
+		}
+		return fmt.Sprintf(`{"$binary":{"base64":%s,"subType":"%02x"}}`, str(args[1].text), sub), nil
+	}
+	return "", fmt.Errorf("unknown shell helper %s()", name)
+}
+
+// shellDateLayouts are the date strings ISODate accepts; without a zone
+// the time is UTC.
+var shellDateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+func shellDate(arg shellArg) (time.Time, error) {
+	if !arg.quoted {
+		ms, err := strconv.ParseFloat(arg.text, 64)
+		if err != nil || math.IsInf(ms, 0) {
+			return time.Time{}, fmt.Errorf("not milliseconds since the epoch")
+		}
+		return time.UnixMilli(int64(ms)).UTC(), nil
+	}
+	for _, layout := range shellDateLayouts {
+		if t, err := time.Parse(layout, arg.text); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("not an ISO-8601 date")
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || c == '$' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -1,55 +1,180 @@
 package adapters
 
 import (
-	"fmt"
+	"simpanan/internal/common"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
-func TestParseQuery(t *testing.T) {
+func TestConstructBsonArray(t *testing.T) {
+	oid, _ := primitive.ObjectIDFromHex("65a1f0c2e4b0a1b2c3d4e5f6")
 	tests := []struct {
-		name           string
-		input          string
-		expectedResult []string
-		expectedError  error
+		name    string
+		input   string
+		want    any
+		wantErr string
 	}{
-		{
-			name:           "valid empty input",
-			input:          "",
-			expectedResult: []string(nil),
-			expectedError:  fmt.Errorf("splitMethodParamStr: missing input string"),
-		},
-		{
-			name:           "valid empty object",
-			input:          "{}",
-			expectedResult: []string{"{}"},
-			expectedError:  nil,
-		},
-		{
-			name:           "parse simple object",
-			input:          `{"name": {"$regex": "abc"}}`,
-			expectedResult: []string{"{\"name\":{\"$regex\":\"abc\"}}"},
-			expectedError:  nil,
-		},
-		{
-			name: "parse nested object",
-			input: `{
-    "$or": [
-        { "field_1": {"$regex": "abc"} },
-        { "field_2": {"$regex": "abc"} }
-        ]
-}, {"_id": 1}`,
-			expectedResult: []string{"{\n\"$or\":[\n{\"field_1\":{\"$regex\":\"abc\"}},\n{\"field_2\":{\"$regex\":\"abc\"}}\n]\n},", `{"_id":1}`},
-			expectedError:  nil,
-		},
-	}
-
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			res, err := splitCommaSeparatedObjStr(test.input)
-			assert.Equal(t, test.expectedResult, res)
-			assert.Equal(t, test.expectedError, err)
+		{"empty input", "", bson.A{}, ""},
+		{"empty array", "[]", bson.A{}, ""},
+		{"ordered stages", `[{$match: {a: 1}}, {$sort: {z: 1, a: -1}}]`,
+			bson.A{
+				bson.D{{Key: "$match", Value: bson.D{{Key: "a", Value: int32(1)}}}},
+				bson.D{{Key: "$sort", Value: bson.D{{Key: "z", Value: int32(1)}, {Key: "a", Value: int32(-1)}}}},
+			}, ""},
+		{"commas inside values", `[{name: /a, (b/i, at: new Date('2024-01-01')}, {name: 'x, y'},]`,
+			bson.A{
+				bson.D{{Key: "name", Value: primitive.Regex{Pattern: "a, (b", Options: "i"}}, {Key: "at", Value: primitive.NewDateTimeFromTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))}},
+				bson.D{{Key: "name", Value: "x, y"}},
+			}, ""},
+		{"nested arrays", `[{$or: [{n: 1}, {n: 2}]}]`,
+			bson.A{bson.D{{Key: "$or", Value: bson.A{bson.D{{Key: "n", Value: int32(1)}}, bson.D{{Key: "n", Value: int32(2)}}}}}}, ""},
+		{"legacy quoted ObjectId", `[{"_id": "ObjectId('65a1f0c2e4b0a1b2c3d4e5f6')"}]`, bson.A{bson.D{{Key: "_id", Value: oid}}}, ""},
+		{"not an array", `{a: 1}`, nil, "expected an array: {a: 1}."},
+		{"element is not a document", `[{a: 1}, 2]`, nil, "element 1 is not a document: [{a: 1}, 2]."},
+		{"syntax error", `[{a: 1} {b: 2}]`, nil, `syntax error at offset 8: expected ',' or ']' in array: [{a: 1} {b: 2}].`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := constructBsonArray(tt.input)
+			if tt.wantErr != "" {
+				assert.EqualError(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestMongoPlaceholdersProduceValidExtendedJSON(t *testing.T) {
+	q := common.QueryMetadata{QueryLine: `{"name": {{.name}}, "meta": {{.meta}}}`}
+	err := common.PipeData(&q, []byte(`{"name": "O\"Neil", "meta": {"tier": 2}}`), common.PlaceholderJSON)
+	assert.NoError(t, err)
+
+	got, err := constructBsonDoc(q.QueryLine)
+	assert.NoError(t, err)
+	assert.Equal(t, bson.D{{Key: "name", Value: `O"Neil`}, {Key: "meta", Value: bson.D{{Key: "tier", Value: int32(2)}}}}, got)
+}
+
+func TestConstructBsonObjectShellSyntax(t *testing.T) {
+	oid, _ := primitive.ObjectIDFromHex("65a1f0c2e4b0a1b2c3d4e5f6")
+	tests := []struct {
+		name  string
+		input string
+		want  bson.D
+	}{
+		{"empty", `{}`, bson.D{}},
+		{"unquoted keys and single quotes", `{status: 'active', $or: [{n: 1}, {n: 2.5}]}`,
+			bson.D{{Key: "status", Value: "active"}, {Key: "$or", Value: bson.A{bson.D{{Key: "n", Value: int32(1)}}, bson.D{{Key: "n", Value: 2.5}}}}}},
+		{"trailing commas", `{tags: ['a', 'b',], n: -3,}`, bson.D{{Key: "tags", Value: bson.A{"a", "b"}}, {Key: "n", Value: int32(-3)}}},
+		{"escapes", `{q: 'it\'s "x"\n', u: "é"}`, bson.D{{Key: "q", Value: "it's \"x\"\n"}, {Key: "u", Value: "é"}}},
+		{"ObjectId", `{_id: ObjectId("65a1f0c2e4b0a1b2c3d4e5f6")}`, bson.D{{Key: "_id", Value: oid}}},
+		{"new ObjectId", `{ref: new ObjectId('65a1f0c2e4b0a1b2c3d4e5f6')}`, bson.D{{Key: "ref", Value: oid}}},
+		{"legacy quoted ObjectId", `{"_id": "ObjectId('65a1f0c2e4b0a1b2c3d4e5f6')"}`, bson.D{{Key: "_id", Value: oid}}},
+		{"ISODate", `{at: {$gte: ISODate("2024-01-02T03:04:05.006Z"), $lt: new Date('2024-02-01')}}`,
+			bson.D{{Key: "at", Value: bson.D{
+				{Key: "$gte", Value: primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 6e6, time.UTC))},
+				{Key: "$lt", Value: primitive.NewDateTimeFromTime(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))},
+			}}}},
+		{"numbers", `{l: NumberLong("9007199254740993"), i: NumberInt(7), d: NumberDecimal('1.10'), f: .5, big: 3000000000}`,
+			bson.D{{Key: "l", Value: int64(9007199254740993)}, {Key: "i", Value: int32(7)}, {Key: "d", Value: mustDecimal(t, "1.10")}, {Key: "f", Value: 0.5}, {Key: "big", Value: int64(3000000000)}}},
+		{"regex", `{name: /^al[/]ice\/x/mi}`, bson.D{{Key: "name", Value: primitive.Regex{Pattern: `^al[/]ice\/x`, Options: "im"}}}},
+		{"timestamp and binary", `{ts: Timestamp(1700000000, 2), u: UUID("0e0f5c2a-4b5d-4c1e-9f0a-1b2c3d4e5f60"), b: BinData(0, "AQI=")}`,
+			bson.D{
+				{Key: "ts", Value: primitive.Timestamp{T: 1700000000, I: 2}},
+				{Key: "u", Value: primitive.Binary{Subtype: 4, Data: []byte{0x0e, 0x0f, 0x5c, 0x2a, 0x4b, 0x5d, 0x4c, 0x1e, 0x9f, 0x0a, 0x1b, 0x2c, 0x3d, 0x4e, 0x5f, 0x60}}},
+				{Key: "b", Value: primitive.Binary{Subtype: 0, Data: []byte{1, 2}}},
+			}},
+		{"keywords", `{a: true, b: null, c: undefined, lo: MinKey, hi: MaxKey()}`,
+			bson.D{{Key: "a", Value: true}, {Key: "b", Value: nil}, {Key: "c", Value: nil}, {Key: "lo", Value: primitive.MinKey{}}, {Key: "hi", Value: primitive.MaxKey{}}}},
+		{"extended json still works", `{"_id": {"$oid": "65a1f0c2e4b0a1b2c3d4e5f6"}, "n": {"$numberLong": "5"}}`,
+			bson.D{{Key: "_id", Value: oid}, {Key: "n", Value: int64(5)}}},
+		{"keys keep their order", `{$set: {z: 1, a: 2}, $unset: {m: ''}}`,
+			bson.D{{Key: "$set", Value: bson.D{{Key: "z", Value: int32(1)}, {Key: "a", Value: int32(2)}}}, {Key: "$unset", Value: bson.D{{Key: "m", Value: ""}}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := constructBsonDoc(tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func mustDecimal(t *testing.T, s string) primitive.Decimal128 {
+	d, err := primitive.ParseDecimal128(s)
+	assert.NoError(t, err)
+	return d
+}
+
+func TestConstructBsonObjectShellSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{`{status: active}`, `syntax error at offset 9: unknown identifier "active" (quote it if it is a string)`},
+		{`{a: 1 b: 2}`, `syntax error at offset 6: expected ',' or '}' after the value of "a"`},
+		{`{a: 'open}`, `syntax error at offset 4: unterminated string`},
+		{`{_id: ObjectId("xyz")}`, `syntax error at offset 6: ObjectId("xyz"): the provided hex string is not a valid ObjectID`},
+		{`{at: ISODate("yesterday")}`, `syntax error at offset 5: ISODate("yesterday"): not an ISO-8601 date`},
+		{`{n: NumberInt(3000000000)}`, `syntax error at offset 4: NumberInt(3000000000): not a 32-bit integer`},
+		{`{name: /abc/g}`, `syntax error at offset 12: unsupported regular expression flag 'g'`},
+		{`{a: 1} {b: 2}`, `syntax error at offset 7: unexpected '{' after value`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := constructBsonDoc(tt.input)
+			assert.EqualError(t, err, tt.want+": "+tt.input+".")
+		})
+	}
+}
+
+func TestBulkWriteModels(t *testing.T) {
+	ops, err := shellValue(`[
+		{insertOne: {document: {_id: 1, name: 'a'}}},
+		{updateOne: {filter: {_id: 1}, update: {$set: {name: 'b'}}, upsert: true}},
+		{updateMany: {filter: {}, update: [{$set: {seen: true}}]}},
+		{replaceOne: {filter: {_id: 2}, replacement: {name: 'c'}}},
+		{deleteOne: {filter: {_id: 3}}},
+		{deleteMany: {filter: {old: true}}},
+	]`)
+	assert.NoError(t, err)
+	models, err := bulkWriteModels(ops)
+	assert.NoError(t, err)
+	assert.Len(t, models, 6)
+	assert.IsType(t, &mongo.InsertOneModel{}, models[0])
+	update := models[1].(*mongo.UpdateOneModel)
+	assert.True(t, *update.Upsert)
+	assert.Equal(t, bson.D{{Key: "$set", Value: bson.D{{Key: "name", Value: "b"}}}}, update.Update)
+	assert.IsType(t, &mongo.UpdateManyModel{}, models[2])
+	assert.IsType(t, &mongo.ReplaceOneModel{}, models[3])
+	assert.IsType(t, &mongo.DeleteOneModel{}, models[4])
+	assert.IsType(t, &mongo.DeleteManyModel{}, models[5])
+
+	tests := []struct {
+		ops  string
+		want string
+	}{
+		{`[]`, `bulkWrite() takes a non-empty array of operations`},
+		{`{insertOne: {}}`, `bulkWrite() takes a non-empty array of operations`},
+		{`[{insertOne: {}, deleteOne: {}}]`, `bulkWrite operation 0 must be a document with one operation name`},
+		{`[{insertOne: {document: {}}}, {upsertOne: {filter: {}}}]`, `bulkWrite operation 1: unknown operation upsertOne`},
+		{`[{deleteOne: {}}]`, `bulkWrite operation 0: deleteOne needs a filter`},
+		{`[{updateOne: {filter: {}}}]`, `bulkWrite operation 0: updateOne needs an update`},
+		{`[{deleteOne: {filter: {}, limit: 1}}]`, `bulkWrite operation 0: unknown deleteOne option "limit"`},
+		{`[{insertOne: {document: 5}}]`, `bulkWrite operation 0: insertOne option document must be a document, got 5`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ops, func(t *testing.T) {
+			ops, err := shellValue(tt.ops)
+			assert.NoError(t, err)
+			_, err = bulkWriteModels(ops)
+			assert.EqualError(t, err, tt.want)
 		})
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 func TestMongoDBName(t *testing.T) {
@@ -34,28 +35,10 @@ func TestMongoDBName(t *testing.T) {
 	}
 }
 
-func TestSplitCommaSeparatedObjStrPreservesSpacesInStrings(t *testing.T) {
+func TestConstructBsonArrayPreservesSpacesInStrings(t *testing.T) {
 	// Regression: spaces inside string literals were previously stripped,
 	// mangling values like "John Doe".
-	input := `{"name": "John Doe"}`
-	got, err := splitCommaSeparatedObjStr(input)
+	got, err := constructBsonArray(`[{"name": "John Doe"}, {"name": "a \"quoted b\" c"}]`)
 	assert.NoError(t, err)
-	assert.Len(t, got, 1)
-	assert.Contains(t, got[0], `"John Doe"`)
-}
-
-func TestSplitCommaSeparatedObjStrPreservesSpacesInEscapedString(t *testing.T) {
-	input := `{"name": "a \"quoted b\" c"}`
-	got, err := splitCommaSeparatedObjStr(input)
-	assert.NoError(t, err)
-	assert.Len(t, got, 1)
-	assert.Contains(t, got[0], `a \"quoted b\" c`)
-}
-
-func TestSplitCommaSeparatedObjStrSplitsTopLevelCommas(t *testing.T) {
-	input := `{"a": 1}, {"b": "x y"}`
-	got, err := splitCommaSeparatedObjStr(input)
-	assert.NoError(t, err)
-	assert.Len(t, got, 2)
-	assert.Contains(t, got[1], `"x y"`)
+	assert.Equal(t, bson.A{bson.D{{Key: "name", Value: "John Doe"}}, bson.D{{Key: "name", Value: `a "quoted b" c`}}}, got)
 }
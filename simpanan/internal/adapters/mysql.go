@@ -55,6 +55,9 @@ func mysqlDSN(uri string) (string, error) {
 }
 
 func QueryTypeMysql(query string) common.QueryType {
+	if mysqlAdminKind(query) != "" {
+		return common.Admin
+	}
 	fields := strings.Fields(query)
 	if len(fields) == 0 {
 		return common.Read
@@ -79,9 +82,27 @@ func ExecuteMysqlReadQuery(q common.QueryMetadata) ([]byte, error) {
 	}
 	defer db.Close()
 
-	rows, err := db.Query(q.QueryLine)
+	return queryMysqlRows(db, common.GetConfig().MaxRowLimit, q.QueryLine, q.Args...)
+}
+
+// queryMysqlRows runs a row-returning statement on an open connection
+// and renders up to limit rows as a JSON array of objects that keep the
+// column order.
+func queryMysqlRows(db *sql.DB, limit int, query string, args ...any) ([]byte, error) {
+	rows, err := scanMysqlRows(db, limit, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %s", err.Error(), q.QueryLine)
+		return nil, err
+	}
+	return marshalRowData(rows)
+}
+
+// scanMysqlRows runs a row-returning statement and converts up to limit
+// rows, or all of them when limit is 0, by column type; see
+// convertMysqlValue.
+func scanMysqlRows(db *sql.DB, limit int, query string, args ...any) ([]common.RowData, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err.Error(), query)
 	}
 	defer rows.Close()
 
@@ -90,16 +111,20 @@ func ExecuteMysqlReadQuery(q common.QueryMetadata) ([]byte, error) {
 		return nil, err
 	}
 
-	values := make([]sql.RawBytes, len(columns))
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
 	dest := make([]any, len(values))
 	for i := range values {
 		dest[i] = &values[i]
 	}
 
-	rowCount := 0
-	var results [][]byte
+	var results []common.RowData
 	for rows.Next() {
-		if rowCount == common.GetConfig().MaxRowLimit {
+		if limit > 0 && len(results) == limit {
 			break
 		}
 		if err := rows.Scan(dest...); err != nil {
@@ -108,28 +133,30 @@ func ExecuteMysqlReadQuery(q common.QueryMetadata) ([]byte, error) {
 
 		rowResults := common.RowData{}
 		for i, col := range values {
-			if col == nil {
-				rowResults = append(rowResults, common.ColumnValuePair{Key: columns[i], Value: nil})
-			} else {
-				rowResults = append(rowResults, common.ColumnValuePair{Key: columns[i], Value: convertToType(col)})
-			}
-		}
-
-		resBytes, err := rowResults.MarshallJSON()
-		if err != nil {
-			return nil, err
+			rowResults = append(rowResults, common.ColumnValuePair{
+				Key:   columns[i],
+				Value: convertMysqlValue(colTypes[i].DatabaseTypeName(), col),
+			})
 		}
-		results = append(results, resBytes)
-		rowCount++
+		results = append(results, rowResults)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
+	return results, nil
+}
 
+// marshalRowData renders rows as a JSON array of objects that keep the
+// column order.
+func marshalRowData(rows []common.RowData) ([]byte, error) {
 	jsonArrB := []byte{'['}
-	for i, r := range results {
-		jsonArrB = append(jsonArrB, r...)
-		if i != len(results)-1 {
+	for i, r := range rows {
+		resBytes, err := r.MarshallJSON()
+		if err != nil {
+			return nil, err
+		}
+		jsonArrB = append(jsonArrB, resBytes...)
+		if i != len(rows)-1 {
 			jsonArrB = append(jsonArrB, ',')
 		}
 	}
@@ -137,6 +164,20 @@ func ExecuteMysqlReadQuery(q common.QueryMetadata) ([]byte, error) {
 	return jsonArrB, nil
 }
 
+// rowDataObjects marshals each row as its own JSON object, for
+// embedding in a larger result.
+func rowDataObjects(rows []common.RowData) ([]json.RawMessage, error) {
+	objects := make([]json.RawMessage, 0, len(rows))
+	for _, r := range rows {
+		b, err := r.MarshallJSON()
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, b)
+	}
+	return objects, nil
+}
+
 func ExecuteMysqlWriteQuery(q common.QueryMetadata) ([]byte, error) {
 	dsn, err := mysqlDSN(q.Conn)
 	if err != nil {
@@ -148,7 +189,7 @@ func ExecuteMysqlWriteQuery(q common.QueryMetadata) ([]byte, error) {
 	}
 	defer db.Close()
 
-	res, err := db.Exec(q.QueryLine)
+	res, err := db.Exec(q.QueryLine, q.Args...)
 	if err != nil {
 		return nil, err
 	}
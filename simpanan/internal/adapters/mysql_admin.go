@@ -0,0 +1,435 @@
+package adapters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpanan/internal/common"
+	"strings"
+)
+
+// mysqlAdminKind names the admin statement a MySQL stage is, or returns
+// "" for ordinary SQL. DESCRIBE followed by a statement is EXPLAIN and
+// stays ordinary SQL.
+func mysqlAdminKind(query string) string {
+	trimmed := strings.TrimSpace(query)
+	if strings.HasPrefix(trimmed, "\\") {
+		return "meta"
+	}
+	words := strings.Fields(strings.ToLower(trimmed))
+	if len(words) < 2 {
+		return ""
+	}
+	switch words[0] {
+	case "describe", "desc":
+		switch strings.TrimLeft(words[1], "(") {
+		case "select", "insert", "update", "delete", "replace", "table", "with", "analyze", "for":
+			return ""
+		}
+		if strings.HasPrefix(words[1], "(") || strings.HasPrefix(words[1], "format") {
+			return ""
+		}
+		return "describe"
+	case "show":
+		rest := words[1:]
+		for len(rest) > 0 && (rest[0] == "full" || rest[0] == "extended") {
+			rest = rest[1:]
+		}
+		if len(rest) == 0 {
+			return ""
+		}
+		switch rest[0] {
+		case "tables":
+			return "show tables"
+		case "create":
+			if len(rest) > 1 && rest[1] == "table" {
+				return "show create table"
+			}
+		case "index", "indexes", "keys":
+			return "show index"
+		case "processlist":
+			return "show processlist"
+		}
+	}
+	return ""
+}
+
+// ExecuteMysqlAdminCmd runs a MySQL admin statement and returns
+// structured JSON:
+//
+//	SHOW [FULL] TABLES ...      [{"name", "type"}]
+//	SHOW CREATE TABLE t         {"name", "type", "create_statement"}
+//	DESCRIBE t                  same shape as \d t
+//	SHOW INDEX FROM t ...       [{"indexname", "indexdef"}]
+//	SHOW [FULL] PROCESSLIST     the server's rows as-is
+//	\dt [pattern]               [{"schema", "name", "type"}]
+//	\d [pattern]                tables and views; with a pattern, the
+//	                            column_definitions/indices/constraints
+//	                            shape of the Postgres admin command
+//
+// psql-style patterns take `*` and `?` wildcards and an optional
+// `database.` prefix. A trailing `+` adds sizes and comments. Admin
+// results are not capped at MaxRowLimit.
+func ExecuteMysqlAdminCmd(q common.QueryMetadata) ([]byte, error) {
+	dsn, err := mysqlDSN(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	line := strings.TrimSpace(q.QueryLine)
+	switch mysqlAdminKind(line) {
+	case "meta":
+		cmd, err := parsePsqlMetaCommand(line)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case cmd.name == "dt":
+			return listMysqlTables(db, cmd, true)
+		case cmd.name == "d" && cmd.pattern == "":
+			return listMysqlTables(db, cmd, false)
+		case cmd.name == "d":
+			return describeMysqlRelations(db, cmd)
+		}
+		return nil, fmt.Errorf("ExecuteMysqlAdminCmd: unsupported meta-command \\%s.", cmd.name)
+
+	case "describe":
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			// DESCRIBE t col: the server's own per-column output.
+			return queryMysqlRows(db, 0, line)
+		}
+		return describeMysqlRelations(db, psqlMetaCommand{name: "d", pattern: fields[1]})
+
+	case "show tables":
+		return showMysqlTables(db, line)
+
+	case "show create table":
+		return showMysqlCreateTable(db, line)
+
+	case "show index":
+		rows, err := scanMysqlRows(db, 0, line)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(mysqlIndexDefs(rows))
+
+	case "show processlist":
+		return queryMysqlRows(db, 0, line)
+	}
+	return nil, fmt.Errorf("ExecuteMysqlAdminCmd: invalid query format %s.", q.QueryLine)
+}
+
+// mysqlTableType maps information_schema TABLE_TYPE values to the
+// names the Postgres admin command uses.
+func mysqlTableType(t any) any {
+	switch t {
+	case "BASE TABLE":
+		return "table"
+	case "VIEW":
+		return "view"
+	case "SYSTEM VIEW":
+		return "system view"
+	}
+	return t
+}
+
+// mysqlPattern turns a psql-style `[database.]name` pattern into LIKE
+// patterns. An empty schema means the connection's current database.
+func mysqlPattern(pattern string) (schema, name string, err error) {
+	parts := strings.Split(pattern, ".")
+	if len(parts) > 2 {
+		return "", "", fmt.Errorf("ExecuteMysqlAdminCmd: improper qualified name (too many dotted names): %s", pattern)
+	}
+	like := func(p string) string {
+		p = strings.Trim(p, "`")
+		p = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`, `?`, `_`).Replace(p)
+		return p
+	}
+	if len(parts) == 2 {
+		return like(parts[0]), like(parts[1]), nil
+	}
+	return "", like(parts[0]), nil
+}
+
+// mysqlTableFilter builds the WHERE clause matching information_schema
+// TABLES rows against a pattern.
+func mysqlTableFilter(pattern string) (string, []any, error) {
+	schema, name, err := mysqlPattern(pattern)
+	if err != nil {
+		return "", nil, err
+	}
+	where := "t.TABLE_SCHEMA = DATABASE()"
+	var args []any
+	if schema != "" {
+		where = "t.TABLE_SCHEMA LIKE ?"
+		args = append(args, schema)
+	}
+	if name != "" && name != "%" {
+		where += " AND t.TABLE_NAME LIKE ?"
+		args = append(args, name)
+	}
+	return where, args, nil
+}
+
+func listMysqlTables(db *sql.DB, cmd psqlMetaCommand, baseOnly bool) ([]byte, error) {
+	where, args, err := mysqlTableFilter(cmd.pattern)
+	if err != nil {
+		return nil, err
+	}
+	if baseOnly {
+		where += " AND t.TABLE_TYPE = 'BASE TABLE'"
+	}
+	cols := []string{
+		"t.TABLE_SCHEMA AS `schema`",
+		"t.TABLE_NAME AS name",
+		"t.TABLE_TYPE AS type",
+	}
+	if cmd.verbose {
+		cols = append(cols,
+			"t.ENGINE AS engine",
+			"t.DATA_LENGTH + t.INDEX_LENGTH AS size",
+			"t.TABLE_COMMENT AS description",
+		)
+	}
+	rows, err := scanMysqlRows(db, 0, fmt.Sprintf(`SELECT %s
+FROM information_schema.TABLES t
+WHERE %s
+ORDER BY 1, 2`, strings.Join(cols, ", "), where), args...)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range rows {
+		r[2].Value = mysqlTableType(r[2].Value)
+	}
+	return marshalRowData(rows)
+}
+
+// describeMysqlRelations answers `\d <pattern>` and `DESCRIBE t` in the
+// relationDescription shape: one object for a single match, an array
+// otherwise.
+func describeMysqlRelations(db *sql.DB, cmd psqlMetaCommand) ([]byte, error) {
+	where, args, err := mysqlTableFilter(cmd.pattern)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Query(fmt.Sprintf(`SELECT t.TABLE_SCHEMA, t.TABLE_NAME, t.TABLE_TYPE,
+  IFNULL(t.DATA_LENGTH + t.INDEX_LENGTH, 0), IFNULL(t.TABLE_COMMENT, '')
+FROM information_schema.TABLES t
+WHERE %s
+ORDER BY 1, 2`, where), args...)
+	if err != nil {
+		return nil, err
+	}
+	var descs []relationDescription
+	for rows.Next() {
+		var d relationDescription
+		var size int64
+		var comment string
+		if err := rows.Scan(&d.Schema, &d.Name, &d.Type, &size, &comment); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		d.Type = fmt.Sprint(mysqlTableType(d.Type))
+		if cmd.verbose {
+			d.Size = fmt.Sprintf("%d bytes", size)
+			d.Description = &comment
+		}
+		descs = append(descs, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(descs) == 0 {
+		return nil, fmt.Errorf("ExecuteMysqlAdminCmd: did not find any relation named %q.", cmd.pattern)
+	}
+
+	for i := range descs {
+		d := &descs[i]
+		if d.ColumnDefinitions, err = queryMysqlRawObjects(db, mysqlColumnsQuery(cmd.verbose), d.Schema, d.Name); err != nil {
+			return nil, err
+		}
+
+		indexRows, err := scanMysqlRows(db, 0, fmt.Sprintf("SHOW INDEX FROM %s FROM %s", quoteMysqlIdent(d.Name), quoteMysqlIdent(d.Schema)))
+		if err != nil {
+			return nil, err
+		}
+		d.Indices = []json.RawMessage{}
+		for _, idx := range mysqlIndexDefs(indexRows) {
+			b, err := json.Marshal(idx)
+			if err != nil {
+				return nil, err
+			}
+			d.Indices = append(d.Indices, b)
+		}
+
+		if d.Constraints, err = queryMysqlRawObjects(db, `SELECT tc.CONSTRAINT_NAME AS constraint_name,
+  tc.CONSTRAINT_TYPE AS constraint_type,
+  kcu.COLUMN_NAME AS column_name
+FROM information_schema.TABLE_CONSTRAINTS tc
+LEFT JOIN information_schema.KEY_COLUMN_USAGE kcu
+  ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+  AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+  AND kcu.TABLE_NAME = tc.TABLE_NAME
+WHERE tc.TABLE_SCHEMA = ? AND tc.TABLE_NAME = ?
+ORDER BY 1, kcu.ORDINAL_POSITION`, d.Schema, d.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(descs) == 1 {
+		return json.Marshal(descs[0])
+	}
+	return json.Marshal(descs)
+}
+
+func mysqlColumnsQuery(verbose bool) string {
+	cols := []string{
+		"c.COLUMN_NAME AS column_name",
+		"c.COLUMN_TYPE AS data_type",
+		"c.IS_NULLABLE AS is_nullable",
+		"c.COLUMN_DEFAULT AS column_default",
+	}
+	if verbose {
+		cols = append(cols,
+			"c.EXTRA AS extra",
+			"c.COLUMN_COMMENT AS description",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM information_schema.COLUMNS c
+WHERE c.TABLE_SCHEMA = ? AND c.TABLE_NAME = ?
+ORDER BY c.ORDINAL_POSITION`, strings.Join(cols, ", "))
+}
+
+// showMysqlTables runs SHOW [FULL] TABLES as written, so FROM, LIKE and
+// WHERE keep working, and renames the `Tables_in_<db>` column.
+func showMysqlTables(db *sql.DB, line string) ([]byte, error) {
+	rows, err := scanMysqlRows(db, 0, line)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]common.RowData, 0, len(rows))
+	for _, r := range rows {
+		var row common.RowData
+		for _, kv := range r {
+			switch {
+			case strings.HasPrefix(kv.Key, "Tables_in_"):
+				row = append(row, common.ColumnValuePair{Key: "name", Value: kv.Value})
+			case kv.Key == "Table_type":
+				row = append(row, common.ColumnValuePair{Key: "type", Value: mysqlTableType(kv.Value)})
+			}
+		}
+		out = append(out, row)
+	}
+	return marshalRowData(out)
+}
+
+func showMysqlCreateTable(db *sql.DB, line string) ([]byte, error) {
+	rows, err := scanMysqlRows(db, 0, line)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("ExecuteMysqlAdminCmd: no result for %s", line)
+	}
+	result := struct {
+		Name            any    `json:"name"`
+		Type            string `json:"type"`
+		CreateStatement any    `json:"create_statement"`
+	}{Type: "table"}
+	if v := rowValue(rows[0], "View"); v != nil {
+		result.Name = v
+		result.Type = "view"
+		result.CreateStatement = rowValue(rows[0], "Create View")
+	} else {
+		result.Name = rowValue(rows[0], "Table")
+		result.CreateStatement = rowValue(rows[0], "Create Table")
+	}
+	return json.Marshal(result)
+}
+
+// mysqlIndexDef is one entry of the indices list, named like the
+// Postgres pg_indexes columns.
+type mysqlIndexDef struct {
+	IndexName string `json:"indexname"`
+	IndexDef  string `json:"indexdef"`
+}
+
+// mysqlIndexDefs folds SHOW INDEX rows (one per indexed column) into one
+// definition per index, written as the key clause SHOW CREATE TABLE
+// would print, e.g. "UNIQUE KEY `email` (`email`) USING BTREE".
+func mysqlIndexDefs(rows []common.RowData) []mysqlIndexDef {
+	defs := []mysqlIndexDef{}
+	var parts []string
+	var name, indexType string
+	var unique bool
+	flush := func() {
+		if name == "" {
+			return
+		}
+		cols := strings.Join(parts, ",")
+		var def string
+		switch {
+		case name == "PRIMARY":
+			def = fmt.Sprintf("PRIMARY KEY (%s) USING %s", cols, indexType)
+		case indexType == "FULLTEXT" || indexType == "SPATIAL":
+			def = fmt.Sprintf("%s KEY %s (%s)", indexType, quoteMysqlIdent(name), cols)
+		case unique:
+			def = fmt.Sprintf("UNIQUE KEY %s (%s) USING %s", quoteMysqlIdent(name), cols, indexType)
+		default:
+			def = fmt.Sprintf("KEY %s (%s) USING %s", quoteMysqlIdent(name), cols, indexType)
+		}
+		defs = append(defs, mysqlIndexDef{IndexName: name, IndexDef: def})
+	}
+
+	for _, r := range rows {
+		key := fmt.Sprint(rowValue(r, "Key_name"))
+		if key != name {
+			flush()
+			name, parts = key, nil
+			indexType = fmt.Sprint(rowValue(r, "Index_type"))
+			unique = fmt.Sprint(rowValue(r, "Non_unique")) == "0"
+		}
+		part := "(" + fmt.Sprint(rowValue(r, "Expression")) + ")"
+		if col := rowValue(r, "Column_name"); col != nil {
+			part = quoteMysqlIdent(fmt.Sprint(col))
+			if sub := rowValue(r, "Sub_part"); sub != nil {
+				part += fmt.Sprintf("(%v)", sub)
+			}
+		}
+		parts = append(parts, part)
+	}
+	flush()
+	return defs
+}
+
+func quoteMysqlIdent(s string) string {
+	return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+}
+
+// rowValue returns the value of column key in row, or nil.
+func rowValue(row common.RowData, key string) any {
+	for _, kv := range row {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return nil
+}
+
+// queryMysqlRawObjects runs query and returns its rows as separate JSON
+// objects, for embedding in a larger result.
+func queryMysqlRawObjects(db *sql.DB, query string, args ...any) ([]json.RawMessage, error) {
+	rows, err := scanMysqlRows(db, 0, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return rowDataObjects(rows)
+}
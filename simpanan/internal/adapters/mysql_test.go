@@ -1,8 +1,11 @@
 package adapters
 
 import (
+	"encoding/json"
+	"math"
 	"simpanan/internal/common"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -75,13 +78,24 @@ func TestQueryTypeMysql(t *testing.T) {
 		query string
 		want  common.QueryType
 	}{
-		// Reads — including metadata-style queries, which are ordinary
-		// SQL on MySQL and classified as read (no separate admin class).
+		// Reads — including SHOW variants without an admin rendering.
 		{"SELECT * FROM t", common.Read},
-		{"show tables", common.Read},
 		{"SHOW DATABASES", common.Read},
-		{"DESCRIBE t", common.Read},
+		{"SHOW STATUS", common.Read},
 		{"EXPLAIN SELECT * FROM t", common.Read},
+		{"DESCRIBE SELECT * FROM t", common.Read},
+		{"desc format=json select 1", common.Read},
+		// Admin — structured SHOW/DESCRIBE and psql-style aliases
+		{"show tables", common.Admin},
+		{"SHOW FULL TABLES FROM db", common.Admin},
+		{"SHOW CREATE TABLE t", common.Admin},
+		{"SHOW INDEX FROM t", common.Admin},
+		{"show keys in t", common.Admin},
+		{"SHOW FULL PROCESSLIST", common.Admin},
+		{"DESCRIBE t", common.Admin},
+		{"desc db.t", common.Admin},
+		{"\\dt", common.Admin},
+		{"  \\d users", common.Admin},
 		// Writes — DML
 		{"INSERT INTO t VALUES (1)", common.Write},
 		{"update t set x=1", common.Write},
@@ -106,3 +120,102 @@ func TestQueryTypeMysql(t *testing.T) {
 		})
 	}
 }
+
+func TestConvertMysqlValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 10, 30, 0, 5000, time.UTC)
+	tests := []struct {
+		name     string
+		typeName string
+		in       any
+		want     any
+	}{
+		{"null", "VARCHAR", nil, nil},
+		{"varchar keeps leading zeros", "VARCHAR", []byte("01234"), "01234"},
+		{"text stays text", "TEXT", []byte("123"), "123"},
+		{"enum is string", "ENUM", []byte("active"), "active"},
+		{"int from text protocol", "INT", []byte("-42"), int64(-42)},
+		{"int from binary protocol", "INT", int64(7), int64(7)},
+		{"year", "YEAR", []byte("2024"), int64(2024)},
+		{"unsigned bigint keeps full range", "UNSIGNED BIGINT", uint64(math.MaxUint64), uint64(math.MaxUint64)},
+		{"unsigned bigint from binary string", "UNSIGNED BIGINT", "18446744073709551615", uint64(math.MaxUint64)},
+		{"unsigned bigint from bytes", "UNSIGNED BIGINT", []byte("18446744073709551615"), uint64(math.MaxUint64)},
+		{"float32 without widening noise", "FLOAT", float32(0.1), 0.1},
+		{"double", "DOUBLE", 1.5, 1.5},
+		{"decimal keeps precision as string", "DECIMAL", []byte("12345678901234567890.0001"), "12345678901234567890.0001"},
+		{"json nests", "JSON", []byte(`{"a": [1, 2]}`), json.RawMessage(`{"a": [1, 2]}`)},
+		{"bit is unsigned integer", "BIT", []byte{0x01, 0x02}, uint64(258)},
+		{"blob is base64", "BLOB", []byte{0xde, 0xad, 0xbe, 0xef}, "3q2+7w=="},
+		{"varbinary is base64", "VARBINARY", []byte("hi"), "aGk="},
+		{"datetime text has no offset", "DATETIME", []byte("2024-03-01 10:30:00.000005"), "2024-03-01T10:30:00.000005"},
+		{"timestamp text has no offset", "TIMESTAMP", []byte("2024-03-01 10:30:00"), "2024-03-01T10:30:00"},
+		{"zero datetime stays verbatim", "DATETIME", []byte("0000-00-00 00:00:00"), "0000-00-00 00:00:00"},
+		{"datetime with parseTime", "DATETIME", ts, "2024-03-01T10:30:00.000005"},
+		{"timestamp with parseTime", "TIMESTAMP", ts, "2024-03-01T10:30:00.000005"},
+		{"date with parseTime", "DATE", ts, "2024-03-01"},
+		{"date text", "DATE", []byte("2024-03-01"), "2024-03-01"},
+		{"time is string", "TIME", []byte("-12:00:01"), "-12:00:01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertMysqlValue(tt.typeName, tt.in))
+		})
+	}
+}
+
+func TestMysqlPattern(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		wantSchema string
+		wantName   string
+		wantErr    bool
+	}{
+		{"users", "", "users", false},
+		{"user_roles", "", `user\_roles`, false},
+		{"billing.*", "billing", "%", false},
+		{"`billing`.`inv?`", "billing", "inv_", false},
+		{"50%", "", `50\%`, false},
+		{"a.b.c", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			schema, name, err := mysqlPattern(tt.pattern)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSchema, schema)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
+}
+
+func TestMysqlIndexDefs(t *testing.T) {
+	row := func(key string, nonUnique int, col any, subPart any, indexType string, expr any) common.RowData {
+		return common.RowData{
+			{Key: "Table", Value: "t"},
+			{Key: "Non_unique", Value: int64(nonUnique)},
+			{Key: "Key_name", Value: key},
+			{Key: "Column_name", Value: col},
+			{Key: "Sub_part", Value: subPart},
+			{Key: "Index_type", Value: indexType},
+			{Key: "Expression", Value: expr},
+		}
+	}
+	got := mysqlIndexDefs([]common.RowData{
+		row("PRIMARY", 0, "id", nil, "BTREE", nil),
+		row("uq_email", 0, "email", nil, "BTREE", nil),
+		row("idx_name", 1, "last", nil, "BTREE", nil),
+		row("idx_name", 1, "first", int64(10), "BTREE", nil),
+		row("ft_body", 1, "body", nil, "FULLTEXT", nil),
+		row("idx_lower", 1, nil, nil, "BTREE", "lower(`email`)"),
+	})
+	assert.Equal(t, []mysqlIndexDef{
+		{"PRIMARY", "PRIMARY KEY (`id`) USING BTREE"},
+		{"uq_email", "UNIQUE KEY `uq_email` (`email`) USING BTREE"},
+		{"idx_name", "KEY `idx_name` (`last`,`first`(10)) USING BTREE"},
+		{"ft_body", "FULLTEXT KEY `ft_body` (`body`)"},
+		{"idx_lower", "KEY `idx_lower` ((lower(`email`))) USING BTREE"},
+	}, got)
+	assert.Equal(t, []mysqlIndexDef{}, mysqlIndexDefs(nil))
+}
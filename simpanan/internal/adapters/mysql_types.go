@@ -0,0 +1,110 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"strconv"
+	"time"
+)
+
+// mysqlDateTimeLayout is the text form MySQL uses for DATETIME and
+// TIMESTAMP values when the DSN does not set parseTime=true.
+const mysqlDateTimeLayout = "2006-01-02 15:04:05.999999"
+
+// convertMysqlValue turns one scanned driver value into its JSON
+// representation, driven by the column's DatabaseTypeName. Depending on
+// the protocol (plain query vs. prepared statement with bind
+// parameters) and on parseTime, go-sql-driver/mysql hands back the
+// same column as []byte, a Go number or a time.Time, so every branch
+// accepts each of those.
+//
+//   - JSON nests as real JSON
+//   - DECIMAL keeps its exact digits as a string
+//   - BIT becomes an unsigned integer
+//   - BLOB/BINARY/VARBINARY (and GEOMETRY) are base64
+//   - DATETIME/TIMESTAMP render as RFC3339 without an offset: MySQL
+//     sends TIMESTAMP in the session time zone, which the value does
+//     not carry; zero dates stay verbatim
+//   - unsigned BIGINT keeps its full range as uint64
+//   - CHAR/VARCHAR/TEXT/ENUM/SET stay strings, so "01234" survives
+func convertMysqlValue(typeName string, v any) any {
+	if v == nil {
+		return nil
+	}
+	switch val := v.(type) {
+	case time.Time:
+		return formatMysqlTime(typeName, val)
+	case float32:
+		// Round-trip through the 32-bit text form so FLOAT 0.1 does not
+		// widen to 0.10000000149011612.
+		f, _ := strconv.ParseFloat(strconv.FormatFloat(float64(val), 'g', -1, 32), 64)
+		return jsonSafeFloat(f)
+	case float64:
+		return jsonSafeFloat(val)
+	case int64, uint64:
+		return val
+	case string:
+		return convertMysqlText(typeName, []byte(val))
+	case []byte:
+		return convertMysqlText(typeName, val)
+	}
+	return v
+}
+
+func convertMysqlText(typeName string, b []byte) any {
+	switch typeName {
+	case "JSON":
+		if json.Valid(b) {
+			return json.RawMessage(append([]byte(nil), b...))
+		}
+	case "DECIMAL":
+		return string(b)
+	case "BIT":
+		var n uint64
+		for _, c := range b {
+			n = n<<8 | uint64(c)
+		}
+		return n
+	case "BLOB", "TINYBLOB", "MEDIUMBLOB", "LONGBLOB",
+		"BINARY", "VARBINARY", "GEOMETRY":
+		return base64.StdEncoding.EncodeToString(b)
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "BIGINT", "YEAR",
+		"UNSIGNED TINYINT", "UNSIGNED SMALLINT", "UNSIGNED MEDIUMINT", "UNSIGNED INT":
+		if i, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return i
+		}
+	case "UNSIGNED BIGINT":
+		if u, err := strconv.ParseUint(string(b), 10, 64); err == nil {
+			return u
+		}
+	case "FLOAT", "DOUBLE":
+		if f, err := strconv.ParseFloat(string(b), 64); err == nil {
+			return jsonSafeFloat(f)
+		}
+	case "DATETIME", "TIMESTAMP":
+		if t, err := time.Parse(mysqlDateTimeLayout, string(b)); err == nil {
+			return t.Format("2006-01-02T15:04:05.999999")
+		}
+	}
+	return string(b)
+}
+
+func formatMysqlTime(typeName string, t time.Time) string {
+	switch typeName {
+	case "DATE":
+		return t.Format(time.DateOnly)
+	case "DATETIME", "TIMESTAMP":
+		return t.Format("2006-01-02T15:04:05.999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// jsonSafeFloat returns f unchanged unless JSON cannot represent it,
+// in which case its text spelling is returned instead.
+func jsonSafeFloat(f float64) any {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return f
+}
@@ -0,0 +1,271 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+	"simpanan/internal/common"
+	"testing"
+	"time"
+
+	"github.com/neo4j/neo4j-go-driver/v5/neo4j/dbtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeNeo4j(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{`MATCH (u:User)-[:MEMBER_OF]->(g:Group) RETURN u, g`, common.Read},
+		{`MATCH p = shortestPath((a)-[*]-(b)) RETURN p`, common.Read},
+		{`CALL db.labels()`, common.Read},
+		{`CREATE (u:User {name: $p1})`, common.Write},
+		{`MERGE (g:Group {name: "ops"}) ON CREATE SET g.at = timestamp()`, common.Write},
+		{`MATCH (u:User) WHERE u.name = 'x' SET u.admin = true`, common.Write},
+		{`MATCH (u:User) DETACH DELETE u`, common.Write},
+		{`match (u) remove u:Admin`, common.Write},
+		{`DROP INDEX user_name`, common.Write},
+		{`SHOW INDEXES`, common.Admin},
+		{`show databases yield name`, common.Admin},
+		// Clause names as labels, types, properties, parameters, map
+		// keys, strings, backtick names and comments.
+		{`MATCH (n:Create)-[:DELETE]->(m) RETURN n.set, $merge`, common.Read},
+		{`MATCH (n {remove: 1}) RETURN "CREATE", 'it\'s SET', n.` + "`delete`", common.Read},
+		{"MATCH (n) // SET n.x = 1\nRETURN n /* DELETE n */", common.Read},
+		{`MATCH (n) RETURN n.x AS set`, common.Read},
+		// A CALL reads only when its procedure is known to.
+		{`CALL db.schema.visualization()`, common.Read},
+		{"CALL `db`.`propertyKeys`() YIELD propertyKey", common.Read},
+		{`CALL db.index.fulltext.queryNodes('people', 'ali*') YIELD node RETURN node`, common.Read},
+		{`CALL apoc.create.node(['User'], {name: 'x'})`, common.Write},
+		{`call APOC.refactor.mergeNodes($p1)`, common.Write},
+		{`MATCH (n) CALL /* tidy */ apoc.nodes.delete(n, 10) YIELD value RETURN value`, common.Write},
+		{`CALL dbms.killQuery('query-1')`, common.Write},
+		{`CALL custom.sync()`, common.Write},
+		{`MATCH (n) CALL { WITH n MATCH (n)--(m) RETURN count(m) AS c } RETURN n, c`, common.Read},
+		{`MATCH (n) CALL (n) { SET n.seen = true } RETURN n`, common.Write},
+		{`MATCH (n:Call) RETURN n.call, 'CALL apoc.create.node()'`, common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeNeo4j(tt.query))
+		})
+	}
+}
+
+func TestNewNeo4jTarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    neo4jTarget
+		wantErr bool
+	}{
+		{"routing with database", "neo4j://app:pw@graph:7687/perms",
+			neo4jTarget{address: "neo4j://graph:7687", database: "perms", user: "app", password: "pw"}, false},
+		{"bolt home database", "bolt://localhost", neo4jTarget{address: "bolt://localhost"}, false},
+		{"tls keeps routing context", "neo4j+s://graph.example.com?region=eu",
+			neo4jTarget{address: "neo4j+s://graph.example.com?region=eu"}, false},
+		{"missing host", "neo4j:///perms", neo4jTarget{}, true},
+		{"wrong scheme", "http://graph:7474", neo4jTarget{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newNeo4jTarget(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestConvertCypherValue(t *testing.T) {
+	alice := dbtype.Node{ElementId: "4:a:1", Labels: []string{"User"}, Props: map[string]any{"name": "alice", "since": dbtype.Date(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC))}}
+	ops := dbtype.Node{ElementId: "4:a:2", Labels: []string{"Group"}}
+	member := dbtype.Relationship{ElementId: "5:a:9", Type: "MEMBER_OF", StartElementId: "4:a:1", EndElementId: "4:a:2",
+		Props: map[string]any{"role": "admin"}}
+
+	assert.Equal(t, map[string]any{
+		"id": "4:a:1", "labels": []string{"User"},
+		"properties": map[string]any{"name": "alice", "since": "2024-03-01"},
+	}, convertCypherValue(alice))
+	assert.Equal(t, map[string]any{"id": "4:a:2", "labels": []string{}, "properties": map[string]any{}},
+		convertCypherValue(dbtype.Node{ElementId: "4:a:2"}))
+	assert.Equal(t, map[string]any{
+		"id": "5:a:9", "type": "MEMBER_OF", "start": "4:a:1", "end": "4:a:2",
+		"properties": map[string]any{"role": "admin"},
+	}, convertCypherValue(member))
+
+	path := convertCypherValue(dbtype.Path{Nodes: []dbtype.Node{alice, ops}, Relationships: []dbtype.Relationship{member}})
+	assert.Equal(t, []any{convertCypherValue(alice), convertCypherValue(ops)}, path.(map[string]any)["nodes"])
+	assert.Equal(t, []any{convertCypherValue(member)}, path.(map[string]any)["relationships"])
+
+	assert.Equal(t, map[string]any{"srid": uint32(4326), "x": 1.5, "y": 2.5},
+		convertCypherValue(dbtype.Point2D{X: 1.5, Y: 2.5, SpatialRefId: 4326}))
+	assert.Equal(t, "P1M2DT3.000000004S", convertCypherValue(dbtype.Duration{Months: 1, Days: 2, Seconds: 3, Nanos: 4}))
+	assert.Equal(t, []any{map[string]any{"n": []any{int64(1)}}}, convertCypherValue([]any{map[string]any{"n": []any{int64(1)}}}))
+}
+
+func TestCypherParams(t *testing.T) {
+	assert.Equal(t, map[string]any{
+		"p1": int64(10),
+		"p2": 0.5,
+		"p3": []any{int64(1), "a"},
+		"p4": map[string]any{"n": int64(2), "ok": true},
+		"p5": nil,
+	}, cypherParams([]any{float64(10), 0.5, []any{float64(1), "a"}, map[string]any{"n": float64(2), "ok": true}, nil}))
+}
+
+// fakeCypherCall is one statement the stand-in was sent.
+type fakeCypherCall struct {
+	stmt   string
+	params map[string]any
+	write  bool
+	limit  int
+}
+
+type fakeCypherSession struct {
+	database string
+	respond  func(stmt string) (*cypherResult, error)
+	calls    []fakeCypherCall
+}
+
+func (s *fakeCypherSession) Run(_ context.Context, stmt string, params map[string]any, write bool, limit int) (*cypherResult, error) {
+	s.calls = append(s.calls, fakeCypherCall{stmt, params, write, limit})
+	res, err := s.respond(stmt)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(res.Records) > limit {
+		res.Records = res.Records[:limit]
+	}
+	return res, nil
+}
+
+func (s *fakeCypherSession) Database() string { return s.database }
+func (s *fakeCypherSession) Close()           {}
+
+// useFakeNeo4j answers every connection with one fake session.
+func useFakeNeo4j(t *testing.T, database string, respond func(string) (*cypherResult, error)) *fakeCypherSession {
+	t.Helper()
+	fake := &fakeCypherSession{database: database, respond: respond}
+	orig := openNeo4j
+	openNeo4j = func(string) (cypherSession, error) { return fake, nil }
+	t.Cleanup(func() { openNeo4j = orig })
+	return fake
+}
+
+func TestNeo4jAgainstFake(t *testing.T) {
+	fake := useFakeNeo4j(t, "perms", func(stmt string) (*cypherResult, error) {
+		switch stmt {
+		case `MATCH (u:User {name: $p1})-[m:MEMBER_OF]->(g) RETURN u.name AS user, m, g LIMIT $p2`:
+			return &cypherResult{Keys: []string{"user", "m", "g"}, Records: [][]any{{
+				"alice",
+				dbtype.Relationship{ElementId: "5:a:9", Type: "MEMBER_OF", StartElementId: "4:a:1", EndElementId: "4:a:2"},
+				dbtype.Node{ElementId: "4:a:2", Labels: []string{"Group"}, Props: map[string]any{"name": "ops"}},
+			}}}, nil
+		case `CREATE (:User {name: "bob"})`:
+			return &cypherResult{Counters: cypherCounters{NodesCreated: 1, PropertiesSet: 1, LabelsAdded: 1}}, nil
+		case `MERGE (u:User {name: "bob"}) RETURN u.name`:
+			return &cypherResult{Keys: []string{"u.name"}, Records: [][]any{{"bob"}}}, nil
+		case `SHOW INDEXES YIELD name`:
+			return &cypherResult{Keys: []string{"name"}, Records: [][]any{{"user_name"}}}, nil
+		}
+		return nil, neo4jError(fmt.Errorf("Invalid input"))
+	})
+
+	q := common.QueryMetadata{Conn: "neo4j://graph/perms", QueryLine: `MATCH (u:User {name: "{{.user}}"})-[m:MEMBER_OF]->(g) RETURN u.name AS user, m, g LIMIT {{.n}}`}
+	assert.NoError(t, common.PipeData(&q, []byte(`{"user": "alice", "n": 5}`), PlaceholderStyleNeo4j))
+	out, err := ExecuteNeo4jReadQuery(q)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{
+		"user": "alice",
+		"m": {"id": "5:a:9", "type": "MEMBER_OF", "start": "4:a:1", "end": "4:a:2", "properties": {}},
+		"g": {"id": "4:a:2", "labels": ["Group"], "properties": {"name": "ops"}}
+	}]`, string(out))
+	assert.Equal(t, fakeCypherCall{
+		stmt:   `MATCH (u:User {name: $p1})-[m:MEMBER_OF]->(g) RETURN u.name AS user, m, g LIMIT $p2`,
+		params: map[string]any{"p1": "alice", "p2": int64(5)},
+		limit:  common.GetConfig().MaxRowLimit,
+	}, fake.calls[0])
+
+	out, err = ExecuteNeo4jWriteQuery(common.QueryMetadata{Conn: "neo4j://graph/perms", QueryLine: `CREATE (:User {name: "bob"})`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"nodes_created": 1, "nodes_deleted": 0, "relationships_created": 0, "relationships_deleted": 0,
+		"properties_set": 1, "labels_added": 1, "labels_removed": 0, "indexes_added": 0, "indexes_removed": 0,
+		"constraints_added": 0, "constraints_removed": 0}`, string(out))
+	assert.True(t, fake.calls[1].write)
+
+	out, err = ExecuteNeo4jWriteQuery(common.QueryMetadata{Conn: "neo4j://graph/perms", QueryLine: `MERGE (u:User {name: "bob"}) RETURN u.name`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"u.name": "bob"}]`, string(out))
+
+	out, err = ExecuteNeo4jAdminCmd(common.QueryMetadata{Conn: "neo4j://graph/perms", QueryLine: `SHOW INDEXES YIELD name`})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name": "user_name"}]`, string(out))
+	assert.Equal(t, 0, fake.calls[3].limit, "admin commands are not limited")
+	assert.False(t, fake.calls[3].write)
+
+	_, err = ExecuteNeo4jReadQuery(common.QueryMetadata{Conn: "neo4j://graph/perms", QueryLine: `MATCH (`})
+	assert.EqualError(t, err, "neo4j: Invalid input")
+}
+
+func TestIntrospectNeo4j(t *testing.T) {
+	useFakeNeo4j(t, "", func(stmt string) (*cypherResult, error) {
+		if stmt == "CALL db.schema.nodeTypeProperties() YIELD nodeLabels, propertyName RETURN nodeLabels, propertyName" {
+			return &cypherResult{Keys: []string{"nodeLabels", "propertyName"}, Records: [][]any{
+				{[]any{"User"}, "name"},
+				{[]any{"User", "Admin"}, "since"},
+				{[]any{"User"}, "email"},
+				{[]any{"Group"}, nil},
+			}}, nil
+		}
+		return &cypherResult{Keys: []string{"relType", "propertyName"}, Records: [][]any{
+			{":`MEMBER_OF`", "role"},
+			{":`OWNS`", nil},
+		}}, nil
+	})
+
+	schema, err := IntrospectNeo4j("neo4j://graph")
+	assert.NoError(t, err)
+	assert.Equal(t, Neo4jSchema{
+		Database: "neo4j",
+		Labels: []Neo4jType{
+			{Name: "Admin", Properties: []string{"since"}},
+			{Name: "Group", Properties: []string{}},
+			{Name: "User", Properties: []string{"email", "name", "since"}},
+		},
+		RelationshipTypes: []Neo4jType{
+			{Name: "MEMBER_OF", Properties: []string{"role"}},
+			{Name: "OWNS", Properties: []string{}},
+		},
+	}, schema)
+}
+
+func TestPlaceholderStyleNeo4j(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		payload  string
+		wantLine string
+		wantArgs []any
+	}{
+		{
+			name:     "parameters keep lists whole",
+			line:     `MATCH (u:User {name: "{{.name}}"}) WHERE u.role IN {{.roles}} AND u.note <> 'it\'s' RETURN u`,
+			payload:  `{"name": "Ann", "roles": ["admin", "ops"]}`,
+			wantLine: `MATCH (u:User {name: $p1}) WHERE u.role IN $p2 AND u.note <> 'it\'s' RETURN u`,
+			wantArgs: []any{"Ann", []any{"admin", "ops"}},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: tc.line}
+			assert.NoError(t, common.PipeData(&q, []byte(tc.payload), PlaceholderStyleNeo4j))
+			assert.Equal(t, tc.wantLine, q.QueryLine)
+			assert.Equal(t, tc.wantArgs, q.Args)
+		})
+	}
+}
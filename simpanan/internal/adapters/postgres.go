@@ -4,9 +4,7 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"simpanan/internal/common"
-	"strconv"
 	"strings"
 )
 
@@ -17,10 +15,16 @@ func ExecutePostgresReadQuery(q common.QueryMetadata) ([]byte, error) {
 	}
 	defer db.Close()
 
-	var rows *sql.Rows
-	rows, err = db.Query(q.QueryLine)
+	return queryPostgresRows(db, common.GetConfig().MaxRowLimit, q.QueryLine, q.Args...)
+}
+
+// queryPostgresRows runs a row-returning statement on an open
+// connection and renders up to limit rows, or all of them when limit is
+// 0, as a JSON array of objects that keep the column order.
+func queryPostgresRows(db *sql.DB, limit int, query string, args ...any) ([]byte, error) {
+	rows, err := db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("%s: %s", err.Error(), q.QueryLine)
+		return nil, fmt.Errorf("%s: %s", err.Error(), query)
 	}
 
 	defer rows.Close()
@@ -31,8 +35,14 @@ func ExecutePostgresReadQuery(q common.QueryMetadata) ([]byte, error) {
 		return nil, err
 	}
 
+	// Column types drive value conversion; see convertPostgresValue.
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
 	// Make a slice to hold the values
-	values := make([]sql.RawBytes, len(columns))
+	values := make([]any, len(columns))
 	dest := make([]any, len(values))
 	for i := range values {
 		dest[i] = &values[i]
@@ -42,7 +52,7 @@ func ExecutePostgresReadQuery(q common.QueryMetadata) ([]byte, error) {
 
 	var results [][]byte
 	for rows.Next() {
-		if rowCount == common.GetConfig().MaxRowLimit {
+		if limit > 0 && rowCount == limit {
 			break
 		}
 
@@ -52,11 +62,10 @@ func ExecutePostgresReadQuery(q common.QueryMetadata) ([]byte, error) {
 
 		rowResults := common.RowData{}
 		for i, col := range values {
-			if col == nil {
-				rowResults = append(rowResults, common.ColumnValuePair{Key: columns[i], Value: nil})
-			} else {
-				rowResults = append(rowResults, common.ColumnValuePair{Key: columns[i], Value: convertToType(col)})
-			}
+			rowResults = append(rowResults, common.ColumnValuePair{
+				Key:   columns[i],
+				Value: convertPostgresValue(colTypes[i].DatabaseTypeName(), col),
+			})
 		}
 
 		resBytes, err := rowResults.MarshallJSON()
@@ -83,37 +92,6 @@ func ExecutePostgresReadQuery(q common.QueryMetadata) ([]byte, error) {
 	return jsonArrB, nil
 }
 
-func convertToType(col sql.RawBytes) any {
-	colB := []byte(col)
-	s := string(colB)
-	// Try int before float so that integer-valued columns don't silently
-	// become float64 (which loses precision for bigint values above 2^53).
-	// A value like "1.5" will fail int parsing and fall through to float.
-	if intValue, err := bytesToInt64(colB); err == nil {
-		return intValue
-	}
-	if floatValue, err := bytesToFloat64(colB); err == nil {
-		return floatValue
-	}
-	// Only treat "true"/"false" as booleans. "0"/"1" are ambiguous
-	// with integer columns and are already handled above.
-	switch strings.ToLower(s) {
-	case "true":
-		return true
-	case "false":
-		return false
-	}
-	return s
-}
-
-func bytesToInt64(b []byte) (int64, error) {
-	return strconv.ParseInt(string(b), 10, 64)
-}
-
-func bytesToFloat64(b []byte) (float64, error) {
-	return strconv.ParseFloat(string(b), 64)
-}
-
 func QueryTypePostgres(query string) common.QueryType {
 	trimmed := strings.TrimSpace(query)
 	if len(trimmed) == 0 {
@@ -133,84 +111,29 @@ func QueryTypePostgres(query string) common.QueryType {
 	return common.Read
 }
 
-func ExecutePostgresAdminCmd(q common.QueryMetadata) ([]byte, error) {
-	switch q.QueryLine {
-	case "\\dt":
-		q.QueryLine = "SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' AND table_type = 'BASE TABLE'"
+func ExecutePostgresWriteQuery(q common.QueryMetadata) ([]byte, error) {
+	// A RETURNING clause turns the write into a row source; run it as a
+	// query so the returned rows reach the next stage.
+	if hasReturningClause(q.QueryLine) {
 		return ExecutePostgresReadQuery(q)
-	default:
-		matches := regexp.MustCompile(`\\d (.*)`).FindAllStringSubmatch(q.QueryLine, -1)
-		if len(matches) != 1 {
-			return nil, fmt.Errorf("ExecutePostgresAdminCmd: invalid query format %s.", q.QueryLine)
-		}
-
-		tableName := strings.TrimSpace(matches[0][1])
-		if tableName == "" {
-			return nil, fmt.Errorf("ExecutePostgresAdminCmd: empty table name.")
-		}
-		escapedTable := strings.ReplaceAll(tableName, "'", "''")
-
-		q.QueryLine = fmt.Sprintf("SELECT column_name, data_type, is_nullable, column_default FROM information_schema.columns WHERE table_name = '%s'", escapedTable)
-		colDef, err := ExecutePostgresReadQuery(q)
-		if err != nil {
-			return nil, err
-		}
-		var colDefMap []map[string]any
-		if err := json.Unmarshal(colDef, &colDefMap); err != nil {
-			return nil, err
-		}
-
-		q.QueryLine = fmt.Sprintf(`SELECT indexname, indexdef
-FROM pg_indexes
-WHERE tablename = '%s';`, escapedTable)
-		indices, err := ExecutePostgresReadQuery(q)
-		if err != nil {
-			return nil, err
-		}
-		var indicesMap []map[string]any
-		if err := json.Unmarshal(indices, &indicesMap); err != nil {
-			return nil, err
-		}
-
-		q.QueryLine = fmt.Sprintf(`SELECT tc.constraint_name, tc.constraint_type, ccu.column_name
-FROM information_schema.table_constraints tc
-JOIN information_schema.constraint_column_usage ccu
-ON tc.constraint_name = ccu.constraint_name
-WHERE tc.table_name = '%s';`, escapedTable)
-		constraints, err := ExecutePostgresReadQuery(q)
-		if err != nil {
-			return nil, err
-		}
-		var constraintMap []map[string]any
-		if err := json.Unmarshal(constraints, &constraintMap); err != nil {
-			return nil, err
-		}
-
-		result := struct {
-			ColumnDefinitions []map[string]any `json:"column_definitions"`
-			Indices           []map[string]any `json:"indices"`
-			Constraints       []map[string]any `json:"constraints"`
-		}{
-			ColumnDefinitions: colDefMap,
-			Indices:           indicesMap,
-			Constraints:       constraintMap,
-		}
-
-		return json.Marshal(result)
 	}
-}
 
-func ExecutePostgresWriteQuery(q common.QueryMetadata) ([]byte, error) {
 	db, err := sql.Open("postgres", q.Conn)
 	if err != nil {
 		return nil, err
 	}
 	defer db.Close()
 
-	res, err := db.Exec(q.QueryLine)
+	res, err := db.Exec(q.QueryLine, q.Args...)
 	if err != nil {
 		return nil, err
 	}
 
-	return json.Marshal(res)
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(struct {
+		RowsAffected int64 `json:"rows_affected"`
+	}{rowsAffected})
 }
@@ -0,0 +1,564 @@
+package adapters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"simpanan/internal/common"
+	"strings"
+)
+
+// psqlMetaCommand is a parsed backslash command such as `\dt+ billing.*`.
+type psqlMetaCommand struct {
+	// name is the command without the backslash and the `+`, e.g. "dt".
+	name string
+	// verbose is set by a trailing `+` and adds sizes, descriptions and
+	// similar detail to the output.
+	verbose bool
+	// pattern is the optional argument, e.g. "billing.*".
+	pattern string
+}
+
+func parsePsqlMetaCommand(line string) (psqlMetaCommand, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "\\") {
+		return psqlMetaCommand{}, fmt.Errorf("ExecutePostgresAdminCmd: invalid query format %s.", line)
+	}
+	if len(fields) > 2 {
+		return psqlMetaCommand{}, fmt.Errorf("ExecutePostgresAdminCmd: expected at most one pattern, got %q.", strings.Join(fields[1:], " "))
+	}
+
+	cmd := psqlMetaCommand{name: strings.TrimPrefix(fields[0], "\\")}
+	cmd.name, cmd.verbose = strings.CutSuffix(cmd.name, "+")
+	if len(fields) == 2 {
+		cmd.pattern = fields[1]
+	}
+	return cmd, nil
+}
+
+// psqlPattern splits a psql object pattern into anchored POSIX regexes
+// for the schema and the object name, following psql's rules: `*` and
+// `?` are wildcards, `.` separates the schema, unquoted letters are
+// folded to lower case, and double quotes make their content literal.
+// An empty part yields an empty regex, meaning "no filter".
+func psqlPattern(pattern string) (schemaRe, nameRe string, err error) {
+	var parts []string
+	var cur strings.Builder
+	inQuotes := false
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == '"':
+			if inQuotes && i+1 < len(runes) && runes[i+1] == '"' {
+				cur.WriteRune('"')
+				i++
+				continue
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			cur.WriteString(regexp.QuoteMeta(string(c)))
+		case c == '.':
+			parts = append(parts, cur.String())
+			cur.Reset()
+		case c == '*':
+			cur.WriteString(".*")
+		case c == '?':
+			cur.WriteString(".")
+		case c == '$':
+			cur.WriteString(`\$`)
+		default:
+			cur.WriteString(strings.ToLower(string(c)))
+		}
+	}
+	parts = append(parts, cur.String())
+
+	anchor := func(re string) string {
+		if re == "" {
+			return ""
+		}
+		return "^(" + re + ")$"
+	}
+	// A bare `*` name matches everything; drop it rather than send a
+	// no-op regex. The schema part is kept even then, because its mere
+	// presence lifts the search_path restriction.
+	name := parts[len(parts)-1]
+	if name == ".*" {
+		name = ""
+	}
+	switch len(parts) {
+	case 1:
+		return "", anchor(name), nil
+	case 2:
+		return anchor(parts[0]), anchor(name), nil
+	}
+	return "", "", fmt.Errorf("ExecutePostgresAdminCmd: improper qualified name (too many dotted names): %s", pattern)
+}
+
+// psqlFilter accumulates WHERE clauses and their bind parameters for a
+// catalog query.
+type psqlFilter struct {
+	clauses []string
+	args    []any
+}
+
+func (f *psqlFilter) add(clause string) {
+	f.clauses = append(f.clauses, clause)
+}
+
+// match filters column against an anchored regex; an empty regex
+// matches everything.
+func (f *psqlFilter) match(column, re string) {
+	if re == "" {
+		return
+	}
+	f.args = append(f.args, re)
+	f.add(fmt.Sprintf("%s OPERATOR(pg_catalog.~) $%d COLLATE pg_catalog.default", column, len(f.args)))
+}
+
+// matchQualified applies a `schema.name` pattern. As in psql, a
+// pattern without a schema part only matches objects visible on the
+// search_path (visibleFn is the pg_*_is_visible check for the object),
+// and the system schemas are hidden unless a pattern is given.
+func (f *psqlFilter) matchQualified(pattern, schemaCol, nameCol, visibleFn string) error {
+	schemaRe, nameRe, err := psqlPattern(pattern)
+	if err != nil {
+		return err
+	}
+	f.add(fmt.Sprintf("%s !~ '^pg_toast'", schemaCol))
+	if pattern == "" {
+		f.add(fmt.Sprintf("%s <> 'pg_catalog' AND %s <> 'information_schema'", schemaCol, schemaCol))
+	}
+	if schemaRe == "" {
+		f.add("pg_catalog." + visibleFn)
+	}
+	f.match(schemaCol, schemaRe)
+	f.match(nameCol, nameRe)
+	return nil
+}
+
+// matchName applies a pattern for an object that is not schema
+// qualified (schemas, roles, databases, extensions).
+func (f *psqlFilter) matchName(pattern, nameCol string) error {
+	schemaRe, nameRe, err := psqlPattern(pattern)
+	if err != nil {
+		return err
+	}
+	if schemaRe != "" {
+		return fmt.Errorf("ExecutePostgresAdminCmd: improper qualified name (too many dotted names): %s", pattern)
+	}
+	f.match(nameCol, nameRe)
+	return nil
+}
+
+func (f *psqlFilter) where() string {
+	if len(f.clauses) == 0 {
+		return ""
+	}
+	return "\nWHERE " + strings.Join(f.clauses, "\n  AND ")
+}
+
+// psqlRelkinds maps the relation-listing commands to the pg_class
+// relkinds they show. Plain `\d` without a pattern lists every kind a
+// user would call a relation.
+var psqlRelkinds = map[string]string{
+	"d":  "'r','p','v','m','S','f'",
+	"dt": "'r','p'",
+	"dv": "'v'",
+	"dm": "'m'",
+	"di": "'i','I'",
+	"ds": "'S'",
+}
+
+// ExecutePostgresAdminCmd runs a psql meta-command against the system
+// catalogs and returns structured JSON:
+//
+//	\d, \dt, \dv, \dm, \di, \ds  relations (optionally `schema.name` patterns)
+//	\d <pattern>                 columns, indices and constraints per relation
+//	\dn                          schemas
+//	\df                          functions and procedures
+//	\du, \dg                     roles
+//	\l                           databases
+//	\dx                          extensions
+//	\dT                          data types
+//
+// A trailing `+` (e.g. `\dt+`, `\d+ billing.invoices`) adds sizes,
+// descriptions and similar detail, as in psql. Results are not capped
+// at MaxRowLimit.
+func ExecutePostgresAdminCmd(q common.QueryMetadata) ([]byte, error) {
+	cmd, err := parsePsqlMetaCommand(q.QueryLine)
+	if err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("postgres", q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if cmd.name == "d" && cmd.pattern != "" {
+		return describePostgresRelations(db, cmd)
+	}
+
+	var query string
+	var f psqlFilter
+	switch cmd.name {
+	case "d", "dt", "dv", "dm", "di", "ds":
+		query, err = psqlListRelations(cmd, &f)
+	case "dn":
+		query, err = psqlListSchemas(cmd, &f)
+	case "df":
+		query, err = psqlListFunctions(cmd, &f)
+	case "du", "dg":
+		query, err = psqlListRoles(cmd, &f)
+	case "l":
+		query, err = psqlListDatabases(cmd, &f)
+	case "dx":
+		query, err = psqlListExtensions(cmd, &f)
+	case "dT":
+		query, err = psqlListTypes(cmd, &f)
+	default:
+		return nil, fmt.Errorf("ExecutePostgresAdminCmd: unsupported meta-command \\%s.", cmd.name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return queryPostgresRows(db, 0, query, f.args...)
+}
+
+const psqlRelkindName = `CASE c.relkind
+    WHEN 'r' THEN 'table' WHEN 'p' THEN 'partitioned table'
+    WHEN 'v' THEN 'view' WHEN 'm' THEN 'materialized view'
+    WHEN 'i' THEN 'index' WHEN 'I' THEN 'partitioned index'
+    WHEN 'S' THEN 'sequence' WHEN 'f' THEN 'foreign table'
+  END`
+
+func psqlListRelations(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	f.add(fmt.Sprintf("c.relkind IN (%s)", psqlRelkinds[cmd.name]))
+	if err := f.matchQualified(cmd.pattern, "n.nspname", "c.relname", "pg_table_is_visible(c.oid)"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"n.nspname AS schema",
+		"c.relname AS name",
+		psqlRelkindName + " AS type",
+		"pg_catalog.pg_get_userbyid(c.relowner) AS owner",
+	}
+	joins := ""
+	if cmd.name == "di" {
+		cols = append(cols, "t.relname AS table")
+		joins = `
+LEFT JOIN pg_catalog.pg_index x ON x.indexrelid = c.oid
+LEFT JOIN pg_catalog.pg_class t ON t.oid = x.indrelid`
+	}
+	if cmd.verbose {
+		cols = append(cols,
+			"pg_catalog.pg_size_pretty(pg_catalog.pg_table_size(c.oid)) AS size",
+			"pg_catalog.obj_description(c.oid, 'pg_class') AS description",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_class c
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace%s%s
+ORDER BY 1, 2`, strings.Join(cols, ", "), joins, f.where()), nil
+}
+
+func psqlListSchemas(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	if cmd.pattern == "" {
+		f.add("n.nspname !~ '^pg_' AND n.nspname <> 'information_schema'")
+	}
+	if err := f.matchName(cmd.pattern, "n.nspname"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"n.nspname AS name",
+		"pg_catalog.pg_get_userbyid(n.nspowner) AS owner",
+	}
+	if cmd.verbose {
+		cols = append(cols,
+			"n.nspacl::text AS access_privileges",
+			"pg_catalog.obj_description(n.oid, 'pg_namespace') AS description",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_namespace n%s
+ORDER BY 1`, strings.Join(cols, ", "), f.where()), nil
+}
+
+func psqlListFunctions(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	if err := f.matchQualified(cmd.pattern, "n.nspname", "p.proname", "pg_function_is_visible(p.oid)"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"n.nspname AS schema",
+		"p.proname AS name",
+		"pg_catalog.pg_get_function_result(p.oid) AS result_type",
+		"pg_catalog.pg_get_function_arguments(p.oid) AS argument_types",
+		"CASE p.prokind WHEN 'a' THEN 'agg' WHEN 'w' THEN 'window' WHEN 'p' THEN 'proc' ELSE 'func' END AS type",
+	}
+	if cmd.verbose {
+		cols = append(cols,
+			"CASE p.provolatile WHEN 'i' THEN 'immutable' WHEN 's' THEN 'stable' ELSE 'volatile' END AS volatility",
+			"pg_catalog.pg_get_userbyid(p.proowner) AS owner",
+			"l.lanname AS language",
+			"pg_catalog.obj_description(p.oid, 'pg_proc') AS description",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_proc p
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = p.pronamespace
+LEFT JOIN pg_catalog.pg_language l ON l.oid = p.prolang%s
+ORDER BY 1, 2, 4`, strings.Join(cols, ", "), f.where()), nil
+}
+
+func psqlListRoles(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	if cmd.pattern == "" {
+		f.add("r.rolname !~ '^pg_'")
+	}
+	if err := f.matchName(cmd.pattern, "r.rolname"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"r.rolname AS role_name",
+		"r.rolsuper AS superuser",
+		"r.rolinherit AS inherit",
+		"r.rolcreaterole AS create_role",
+		"r.rolcreatedb AS create_db",
+		"r.rolcanlogin AS can_login",
+		"r.rolreplication AS replication",
+		"r.rolconnlimit AS connection_limit",
+		"r.rolvaliduntil AS valid_until",
+		`ARRAY(SELECT b.rolname FROM pg_catalog.pg_auth_members m
+    JOIN pg_catalog.pg_roles b ON m.roleid = b.oid
+    WHERE m.member = r.oid ORDER BY 1) AS member_of`,
+	}
+	if cmd.verbose {
+		cols = append(cols, "pg_catalog.shobj_description(r.oid, 'pg_authid') AS description")
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_roles r%s
+ORDER BY 1`, strings.Join(cols, ", "), f.where()), nil
+}
+
+func psqlListDatabases(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	if err := f.matchName(cmd.pattern, "d.datname"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"d.datname AS name",
+		"pg_catalog.pg_get_userbyid(d.datdba) AS owner",
+		"pg_catalog.pg_encoding_to_char(d.encoding) AS encoding",
+		"d.datcollate AS collate",
+		"d.datctype AS ctype",
+		"d.datacl::text AS access_privileges",
+	}
+	if cmd.verbose {
+		cols = append(cols,
+			`CASE WHEN pg_catalog.has_database_privilege(d.datname, 'CONNECT')
+    THEN pg_catalog.pg_size_pretty(pg_catalog.pg_database_size(d.datname))
+    ELSE 'No Access' END AS size`,
+			"t.spcname AS tablespace",
+			"pg_catalog.shobj_description(d.oid, 'pg_database') AS description",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_database d
+LEFT JOIN pg_catalog.pg_tablespace t ON t.oid = d.dattablespace%s
+ORDER BY 1`, strings.Join(cols, ", "), f.where()), nil
+}
+
+func psqlListExtensions(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	if err := f.matchName(cmd.pattern, "e.extname"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"e.extname AS name",
+		"e.extversion AS version",
+		"n.nspname AS schema",
+		"c.description AS description",
+	}
+	if cmd.verbose {
+		// \dx+ lists the objects that belong to each extension.
+		cols = append(cols, `ARRAY(SELECT pg_catalog.pg_describe_object(dep.classid, dep.objid, 0)
+    FROM pg_catalog.pg_depend dep
+    WHERE dep.refclassid = 'pg_catalog.pg_extension'::pg_catalog.regclass
+      AND dep.refobjid = e.oid AND dep.deptype = 'e'
+    ORDER BY 1) AS objects`)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_extension e
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = e.extnamespace
+LEFT JOIN pg_catalog.pg_description c ON c.objoid = e.oid
+  AND c.classoid = 'pg_catalog.pg_extension'::pg_catalog.regclass%s
+ORDER BY 1`, strings.Join(cols, ", "), f.where()), nil
+}
+
+func psqlListTypes(cmd psqlMetaCommand, f *psqlFilter) (string, error) {
+	// Skip the row types of tables and the implicit array types, as
+	// psql does.
+	f.add("(t.typrelid = 0 OR (SELECT c.relkind = 'c' FROM pg_catalog.pg_class c WHERE c.oid = t.typrelid))")
+	f.add("NOT EXISTS (SELECT 1 FROM pg_catalog.pg_type el WHERE el.oid = t.typelem AND el.typarray = t.oid)")
+	if err := f.matchQualified(cmd.pattern, "n.nspname", "t.typname", "pg_type_is_visible(t.oid)"); err != nil {
+		return "", err
+	}
+
+	cols := []string{
+		"n.nspname AS schema",
+		"pg_catalog.format_type(t.oid, NULL) AS name",
+		`CASE t.typtype
+    WHEN 'b' THEN 'base' WHEN 'c' THEN 'composite' WHEN 'd' THEN 'domain'
+    WHEN 'e' THEN 'enum' WHEN 'p' THEN 'pseudo' WHEN 'r' THEN 'range'
+    WHEN 'm' THEN 'multirange'
+  END AS type`,
+		"pg_catalog.obj_description(t.oid, 'pg_type') AS description",
+	}
+	if cmd.verbose {
+		cols = append(cols,
+			"ARRAY(SELECT e.enumlabel FROM pg_catalog.pg_enum e WHERE e.enumtypid = t.oid ORDER BY e.enumsortorder) AS elements",
+			"pg_catalog.pg_get_userbyid(t.typowner) AS owner",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_type t
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = t.typnamespace%s
+ORDER BY 1, 2`, strings.Join(cols, ", "), f.where()), nil
+}
+
+// relationDescription is the `\d <table>` result. The MySQL admin path
+// produces the same shape so both dialects answer `\d` alike.
+type relationDescription struct {
+	Schema            string            `json:"schema"`
+	Name              string            `json:"name"`
+	Type              string            `json:"type"`
+	Size              string            `json:"size,omitempty"`
+	Description       *string           `json:"description,omitempty"`
+	ColumnDefinitions []json.RawMessage `json:"column_definitions"`
+	Indices           []json.RawMessage `json:"indices"`
+	Constraints       []json.RawMessage `json:"constraints"`
+}
+
+// describePostgresRelations answers `\d <pattern>`: one description
+// object when the pattern matches a single relation, an array of them
+// otherwise.
+func describePostgresRelations(db *sql.DB, cmd psqlMetaCommand) ([]byte, error) {
+	var f psqlFilter
+	f.add(fmt.Sprintf("c.relkind IN (%s)", psqlRelkinds["d"]))
+	if err := f.matchQualified(cmd.pattern, "n.nspname", "c.relname", "pg_table_is_visible(c.oid)"); err != nil {
+		return nil, err
+	}
+	query := fmt.Sprintf(`SELECT c.oid, n.nspname, c.relname, %s,
+  pg_catalog.pg_size_pretty(pg_catalog.pg_table_size(c.oid)),
+  pg_catalog.obj_description(c.oid, 'pg_class')
+FROM pg_catalog.pg_class c
+LEFT JOIN pg_catalog.pg_namespace n ON n.oid = c.relnamespace%s
+ORDER BY 2, 3`, psqlRelkindName, f.where())
+
+	rows, err := db.Query(query, f.args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err.Error(), query)
+	}
+	type relation struct {
+		oid  int64
+		desc relationDescription
+	}
+	var relations []relation
+	for rows.Next() {
+		var r relation
+		var size string
+		if err := rows.Scan(&r.oid, &r.desc.Schema, &r.desc.Name, &r.desc.Type, &size, &r.desc.Description); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if cmd.verbose {
+			r.desc.Size = size
+		} else {
+			r.desc.Description = nil
+		}
+		relations = append(relations, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(relations) == 0 {
+		return nil, fmt.Errorf("ExecutePostgresAdminCmd: did not find any relation named %q.", cmd.pattern)
+	}
+
+	descs := make([]relationDescription, 0, len(relations))
+	for _, r := range relations {
+		d := r.desc
+		if d.ColumnDefinitions, err = queryPostgresObjects(db, psqlColumnsQuery(cmd.verbose), r.oid); err != nil {
+			return nil, err
+		}
+		if d.Indices, err = queryPostgresObjects(db, `SELECT i.relname AS indexname, pg_catalog.pg_get_indexdef(i.oid) AS indexdef
+FROM pg_catalog.pg_index x
+JOIN pg_catalog.pg_class i ON i.oid = x.indexrelid
+WHERE x.indrelid = $1
+ORDER BY 1`, r.oid); err != nil {
+			return nil, err
+		}
+		if d.Constraints, err = queryPostgresObjects(db, `SELECT con.conname AS constraint_name,
+  CASE con.contype
+    WHEN 'p' THEN 'PRIMARY KEY' WHEN 'u' THEN 'UNIQUE' WHEN 'f' THEN 'FOREIGN KEY'
+    WHEN 'c' THEN 'CHECK' WHEN 'x' THEN 'EXCLUDE' WHEN 'n' THEN 'NOT NULL'
+  END AS constraint_type,
+  a.attname AS column_name
+FROM pg_catalog.pg_constraint con
+LEFT JOIN LATERAL unnest(con.conkey) AS k(attnum) ON true
+LEFT JOIN pg_catalog.pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum
+WHERE con.conrelid = $1
+ORDER BY 1, 3`, r.oid); err != nil {
+			return nil, err
+		}
+		descs = append(descs, d)
+	}
+
+	if len(descs) == 1 {
+		return json.Marshal(descs[0])
+	}
+	return json.Marshal(descs)
+}
+
+func psqlColumnsQuery(verbose bool) string {
+	cols := []string{
+		"a.attname AS column_name",
+		"pg_catalog.format_type(a.atttypid, a.atttypmod) AS data_type",
+		"CASE WHEN a.attnotnull THEN 'NO' ELSE 'YES' END AS is_nullable",
+		"pg_catalog.pg_get_expr(d.adbin, d.adrelid) AS column_default",
+	}
+	if verbose {
+		cols = append(cols,
+			`CASE a.attstorage
+    WHEN 'p' THEN 'plain' WHEN 'e' THEN 'external' WHEN 'm' THEN 'main' WHEN 'x' THEN 'extended'
+  END AS storage`,
+			"pg_catalog.col_description(a.attrelid, a.attnum) AS description",
+		)
+	}
+	return fmt.Sprintf(`SELECT %s
+FROM pg_catalog.pg_attribute a
+LEFT JOIN pg_catalog.pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+WHERE a.attrelid = $1 AND a.attnum > 0 AND NOT a.attisdropped
+ORDER BY a.attnum`, strings.Join(cols, ", "))
+}
+
+// queryPostgresObjects runs query and returns its rows as separate JSON
+// objects, for embedding in a larger result.
+func queryPostgresObjects(db *sql.DB, query string, args ...any) ([]json.RawMessage, error) {
+	out, err := queryPostgresRows(db, 0, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	objects := []json.RawMessage{}
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
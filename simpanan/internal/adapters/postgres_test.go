@@ -1,8 +1,14 @@
 package adapters
 
 import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
 	"simpanan/internal/common"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -42,40 +48,194 @@ func TestQueryTypePostgres(t *testing.T) {
 	}
 }
 
-func TestConvertToTypePreservesIntegerPrecision(t *testing.T) {
-	// bigint values beyond 2^53 must not be coerced to float64.
-	b := []byte("9007199254740993") // 2^53 + 1
-	got := convertToType(b)
-	asInt, ok := got.(int64)
-	assert.True(t, ok, "expected int64, got %T", got)
-	assert.Equal(t, int64(9007199254740993), asInt)
+func TestConvertPostgresValue(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 10, 30, 0, 500, time.FixedZone("", 7*3600))
+	tests := []struct {
+		name     string
+		typeName string
+		in       any
+		want     any
+	}{
+		{"null", "TEXT", nil, nil},
+		{"numeric-looking text stays text", "TEXT", []byte("123"), "123"},
+		{"varchar keeps leading zeros", "VARCHAR", "01234", "01234"},
+		{"int from driver", "INT8", int64(42), int64(42)},
+		{"float from driver", "FLOAT8", 1.5, 1.5},
+		{"NaN float becomes string", "FLOAT8", math.NaN(), "NaN"},
+		{"bool from driver", "BOOL", true, true},
+		{"numeric keeps precision as string", "NUMERIC", []byte("12345678901234567890.0001"), "12345678901234567890.0001"},
+		{"uuid is string", "UUID", []byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"), "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{"jsonb nests", "JSONB", []byte(`{"a": [1, 2]}`), json.RawMessage(`{"a": [1, 2]}`)},
+		{"json nests", "JSON", []byte(`"x"`), json.RawMessage(`"x"`)},
+		{"bytea is base64", "BYTEA", []byte{0xde, 0xad, 0xbe, 0xef}, "3q2+7w=="},
+		{"timestamptz is RFC3339", "TIMESTAMPTZ", ts, "2024-03-01T10:30:00.0000005+07:00"},
+		{"timestamp has no offset", "TIMESTAMP", ts.UTC(), "2024-03-01T03:30:00.0000005"},
+		{"date", "DATE", ts, "2024-03-01"},
+		{"int array", "_INT4", []byte("{1,2,NULL}"), []any{int64(1), int64(2), nil}},
+		{"text array with quoting", "_TEXT", []byte(`{plain,"with space","q\"uote",NULL,"NULL"}`), []any{"plain", "with space", `q"uote`, nil, "NULL"}},
+		{"nested array", "_INT4", []byte("{{1,2},{3,4}}"), []any{[]any{int64(1), int64(2)}, []any{int64(3), int64(4)}}},
+		{"empty array", "_TEXT", []byte("{}"), []any{}},
+		{"bool array", "_BOOL", []byte("{t,f}"), []any{true, false}},
+		{"numeric array", "_NUMERIC", []byte("{1.10,2}"), []any{"1.10", "2"}},
+		{"jsonb array", "_JSONB", []byte(`{"{\"a\": 1}"}`), []any{json.RawMessage(`{"a": 1}`)}},
+		{"bytea array", "_BYTEA", []byte(`{"\\xdeadbeef"}`), []any{"3q2+7w=="}},
+		{"array with bounds", "_INT4", []byte("[0:1]={7,8}"), []any{int64(7), int64(8)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, convertPostgresValue(tc.typeName, tc.in))
+		})
+	}
+}
+
+func TestConvertPostgresValueRowMarshalsAsNestedJSON(t *testing.T) {
+	row := common.RowData{
+		{Key: "doc", Value: convertPostgresValue("JSONB", []byte(`{"a":1}`))},
+		{Key: "tags", Value: convertPostgresValue("_TEXT", []byte(`{x,y}`))},
+	}
+	got, err := row.MarshallJSON()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"doc":{"a":1},"tags":["x","y"]}`, string(got))
+}
+
+func TestHasReturningClause(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"INSERT INTO t (a) VALUES (1) RETURNING id", true},
+		{"update t set a = 1 returning *", true},
+		{"DELETE FROM t WHERE id = $1\nRETURNING id, a", true},
+		{"UPDATE t SET a = 1", false},
+		{"INSERT INTO t (note) VALUES ('returning soon')", false},
+		{"INSERT INTO t (note) VALUES ('it''s returning')", false},
+		{"INSERT INTO t (note) VALUES (E'\\' returning')", false},
+		{`UPDATE "returning" SET a = 1`, false},
+		{"UPDATE t SET a = 1 -- returning id", false},
+		{"UPDATE t SET a = 1 /* returning id */", false},
+		{"INSERT INTO t (body) VALUES ($$ returning $$)", false},
+		{"INSERT INTO t (body) VALUES ($x$ returning $x$) RETURNING id", true},
+		{"UPDATE t SET returning_at = now()", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, hasReturningClause(tt.query))
+		})
+	}
 }
 
-func TestConvertToTypeIntegerStaysInteger(t *testing.T) {
-	got := convertToType([]byte("42"))
-	asInt, ok := got.(int64)
-	assert.True(t, ok, "expected int64, got %T", got)
-	assert.Equal(t, int64(42), asInt)
+func TestParsePsqlMetaCommand(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    psqlMetaCommand
+		wantErr bool
+	}{
+		{`\dt`, psqlMetaCommand{name: "dt"}, false},
+		{`  \dt+ billing.*`, psqlMetaCommand{name: "dt", verbose: true, pattern: "billing.*"}, false},
+		{`\d+ billing.invoices`, psqlMetaCommand{name: "d", verbose: true, pattern: "billing.invoices"}, false},
+		{`\dT`, psqlMetaCommand{name: "dT"}, false},
+		{`\l`, psqlMetaCommand{name: "l"}, false},
+		{`\dt a b`, psqlMetaCommand{}, true},
+		{`dt`, psqlMetaCommand{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			got, err := parsePsqlMetaCommand(tt.line)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
 }
 
-func TestConvertToTypeFloatStaysFloat(t *testing.T) {
-	got := convertToType([]byte("1.5"))
-	asFloat, ok := got.(float64)
-	assert.True(t, ok, "expected float64, got %T", got)
-	assert.Equal(t, 1.5, asFloat)
+func TestPsqlPattern(t *testing.T) {
+	tests := []struct {
+		pattern    string
+		wantSchema string
+		wantName   string
+		wantErr    bool
+	}{
+		{"", "", "", false},
+		{"users", "", "^(users)$", false},
+		{"Users", "", "^(users)$", false},
+		{`"Users"`, "", "^(Users)$", false},
+		{"billing.*", "^(billing)$", "", false},
+		{"billing.inv*", "^(billing)$", "^(inv.*)$", false},
+		{"*.users", "^(.*)$", "^(users)$", false},
+		{"user?", "", "^(user.)$", false},
+		{`"a.b".c`, `^(a\.b)$`, "^(c)$", false},
+		{`"say ""hi"""`, "", `^(say "hi")$`, false},
+		{"price$", "", `^(price\$)$`, false},
+		{"db.billing.users", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			schema, name, err := psqlPattern(tt.pattern)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantSchema, schema)
+			assert.Equal(t, tt.wantName, name)
+		})
+	}
 }
 
-func TestConvertToTypeBooleanLiteralsOnly(t *testing.T) {
-	// "true"/"false" map to boolean.
-	assert.Equal(t, true, convertToType([]byte("true")))
-	assert.Equal(t, false, convertToType([]byte("false")))
+func TestPsqlListRelationsFilters(t *testing.T) {
+	var f psqlFilter
+	query, err := psqlListRelations(psqlMetaCommand{name: "dt"}, &f)
+	assert.NoError(t, err)
+	assert.Contains(t, query, "c.relkind IN ('r','p')")
+	assert.Contains(t, query, "pg_catalog.pg_table_is_visible(c.oid)")
+	assert.Contains(t, query, "n.nspname <> 'pg_catalog'")
+	assert.Empty(t, f.args)
+
+	// A schema pattern lifts the search_path restriction and binds the
+	// regexes as parameters.
+	f = psqlFilter{}
+	query, err = psqlListRelations(psqlMetaCommand{name: "dv", verbose: true, pattern: "billing.v_*"}, &f)
+	assert.NoError(t, err)
+	assert.Contains(t, query, "c.relkind IN ('v')")
+	assert.NotContains(t, query, "pg_table_is_visible")
+	assert.NotContains(t, query, "n.nspname <> 'pg_catalog'")
+	assert.Contains(t, query, "n.nspname OPERATOR(pg_catalog.~) $1")
+	assert.Contains(t, query, "c.relname OPERATOR(pg_catalog.~) $2")
+	assert.Contains(t, query, "AS description")
+	assert.Equal(t, []any{"^(billing)$", "^(v_.*)$"}, f.args)
+}
 
-	// "1" and "0" are integers, not booleans — matches the pg driver's
-	// raw bytes for integer columns.
-	assert.Equal(t, int64(1), convertToType([]byte("1")))
-	assert.Equal(t, int64(0), convertToType([]byte("0")))
+func TestPsqlListSchemasRejectsQualifiedPattern(t *testing.T) {
+	var f psqlFilter
+	_, err := psqlListSchemas(psqlMetaCommand{name: "dn", pattern: "a.b"}, &f)
+	assert.Error(t, err)
 }
 
-func TestConvertToTypeFallsBackToString(t *testing.T) {
-	assert.Equal(t, "hello", convertToType([]byte("hello")))
+func TestQueryPostgresRowsLimit(t *testing.T) {
+	// The SQL Server test driver answers any database/sql query.
+	rows := make([][]driver.Value, common.GetConfig().MaxRowLimit+5)
+	for i := range rows {
+		rows[i] = []driver.Value{fmt.Sprintf("col_%d", i)}
+	}
+	useFakeSqlserver(t, func(string, []driver.Value) *fakeSqlserverResult {
+		return &fakeSqlserverResult{columns: []string{"column_name"}, types: []string{"TEXT"}, rows: rows}
+	})
+	db, err := sql.Open(sqlserverDriver, "")
+	assert.NoError(t, err)
+	defer db.Close()
+
+	out, err := queryPostgresRows(db, common.GetConfig().MaxRowLimit, "SELECT column_name FROM wide")
+	assert.NoError(t, err)
+	var capped []map[string]any
+	assert.NoError(t, json.Unmarshal(out, &capped))
+	assert.Len(t, capped, common.GetConfig().MaxRowLimit)
+
+	// Admin and describe queries read every row.
+	objects, err := queryPostgresObjects(db, "SELECT column_name FROM wide")
+	assert.NoError(t, err)
+	assert.Len(t, objects, len(rows))
+	assert.JSONEq(t, fmt.Sprintf(`{"column_name": "col_%d"}`, len(rows)-1), string(objects[len(rows)-1]))
 }
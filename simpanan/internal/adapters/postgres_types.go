@@ -0,0 +1,235 @@
+package adapters
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// convertPostgresValue turns one scanned driver value into its JSON
+// representation, driven by the column's DatabaseTypeName rather than
+// by guessing from the text. lib/pq already decodes integers, floats,
+// booleans, bytea and date/time types; everything else arrives as the
+// server's text form in a []byte.
+//
+//   - json/jsonb nest as real JSON
+//   - arrays (type names prefixed with "_") become JSON arrays
+//   - numeric and money keep their exact digits as strings
+//   - timestamps render as RFC3339 (without an offset for the
+//     timezone-less types)
+//   - bytea is base64
+//   - text, uuid and anything unrecognised stay strings
+func convertPostgresValue(typeName string, v any) any {
+	if v == nil {
+		return nil
+	}
+	typeName = strings.ToUpper(typeName)
+	if elem, ok := strings.CutPrefix(typeName, "_"); ok {
+		if b, ok := v.([]byte); ok {
+			arr, err := parsePostgresArray(string(b), elem)
+			if err == nil {
+				return arr
+			}
+		}
+	}
+
+	switch val := v.(type) {
+	case time.Time:
+		return formatPostgresTime(typeName, val)
+	case float64:
+		if math.IsNaN(val) || math.IsInf(val, 0) {
+			// JSON has no NaN/Infinity; keep Postgres' spelling.
+			return strconv.FormatFloat(val, 'g', -1, 64)
+		}
+		return val
+	case int64, bool:
+		return val
+	case string:
+		return val
+	case []byte:
+		return convertPostgresText(typeName, val)
+	}
+	return v
+}
+
+// convertPostgresText converts the text form of a value whose type
+// lib/pq left undecoded.
+func convertPostgresText(typeName string, b []byte) any {
+	switch typeName {
+	case "BYTEA":
+		return base64.StdEncoding.EncodeToString(b)
+	case "JSON", "JSONB":
+		if json.Valid(b) {
+			return json.RawMessage(append([]byte(nil), b...))
+		}
+	case "INT2", "INT4", "INT8", "OID":
+		if i, err := strconv.ParseInt(string(b), 10, 64); err == nil {
+			return i
+		}
+	case "FLOAT4", "FLOAT8":
+		if f, err := strconv.ParseFloat(string(b), 64); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	case "BOOL":
+		return string(b) == "t"
+	}
+	return string(b)
+}
+
+func formatPostgresTime(typeName string, t time.Time) string {
+	switch typeName {
+	case "DATE":
+		return t.Format(time.DateOnly)
+	case "TIME":
+		return t.Format("15:04:05.999999999")
+	case "TIMETZ":
+		return t.Format("15:04:05.999999999Z07:00")
+	case "TIMESTAMP":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	}
+	return t.Format(time.RFC3339Nano)
+}
+
+// parsePostgresArray parses the text form of a Postgres array, e.g.
+// `{1,2,NULL}` or `{{"a b",c},{d,"e\"f"}}`, converting each element
+// according to the array's element type. Arrays with explicit bounds
+// (`[0:1]={1,2}`) have the bounds dropped.
+func parsePostgresArray(s, elemType string) ([]any, error) {
+	if strings.HasPrefix(s, "[") {
+		if eq := strings.Index(s, "="); eq >= 0 {
+			s = s[eq+1:]
+		}
+	}
+	p := pgArrayParser{s: s, elemType: elemType}
+	arr, err := p.parseArray()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.s) {
+		return nil, fmt.Errorf("postgres array: trailing data at %d in %q", p.pos, s)
+	}
+	return arr, nil
+}
+
+type pgArrayParser struct {
+	s        string
+	pos      int
+	elemType string
+}
+
+func (p *pgArrayParser) parseArray() ([]any, error) {
+	if p.pos >= len(p.s) || p.s[p.pos] != '{' {
+		return nil, fmt.Errorf("postgres array: expected '{' at %d in %q", p.pos, p.s)
+	}
+	p.pos++
+	out := []any{}
+	if p.pos < len(p.s) && p.s[p.pos] == '}' {
+		p.pos++
+		return out, nil
+	}
+	for {
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("postgres array: unterminated %q", p.s)
+		}
+		switch p.s[p.pos] {
+		case '{':
+			sub, err := p.parseArray()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, sub)
+		case '"':
+			el, err := p.parseQuoted()
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, convertPostgresArrayElement(p.elemType, el))
+		default:
+			el := p.parseBare()
+			if el == "NULL" {
+				out = append(out, nil)
+			} else {
+				out = append(out, convertPostgresArrayElement(p.elemType, el))
+			}
+		}
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("postgres array: unterminated %q", p.s)
+		}
+		switch p.s[p.pos] {
+		case ',', ';': // ';' is the delimiter for the box type
+			p.pos++
+		case '}':
+			p.pos++
+			return out, nil
+		default:
+			return nil, fmt.Errorf("postgres array: unexpected %q at %d in %q", p.s[p.pos], p.pos, p.s)
+		}
+	}
+}
+
+func (p *pgArrayParser) parseQuoted() (string, error) {
+	p.pos++ // opening quote
+	var sb strings.Builder
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		switch c {
+		case '\\':
+			if p.pos+1 < len(p.s) {
+				sb.WriteByte(p.s[p.pos+1])
+			}
+			p.pos += 2
+		case '"':
+			p.pos++
+			return sb.String(), nil
+		default:
+			sb.WriteByte(c)
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("postgres array: unterminated quoted element in %q", p.s)
+}
+
+func (p *pgArrayParser) parseBare() string {
+	start := p.pos
+	for p.pos < len(p.s) {
+		c := p.s[p.pos]
+		if c == ',' || c == ';' || c == '}' {
+			break
+		}
+		p.pos++
+	}
+	return strings.TrimSpace(p.s[start:p.pos])
+}
+
+// convertPostgresArrayElement converts one element of an array's text
+// form. Elements arrive as text regardless of type, so integer, float,
+// bool, bytea and date/time elements are decoded here.
+func convertPostgresArrayElement(elemType, el string) any {
+	switch elemType {
+	case "BYTEA":
+		if h, ok := strings.CutPrefix(el, `\x`); ok {
+			if b, err := hex.DecodeString(h); err == nil {
+				return base64.StdEncoding.EncodeToString(b)
+			}
+		}
+		return el
+	case "TIMESTAMPTZ":
+		for _, layout := range []string{"2006-01-02 15:04:05.999999999Z07:00:00", "2006-01-02 15:04:05.999999999Z07:00", "2006-01-02 15:04:05.999999999Z07"} {
+			if t, err := time.Parse(layout, el); err == nil {
+				return t.Format(time.RFC3339Nano)
+			}
+		}
+		return el
+	case "TIMESTAMP":
+		if t, err := time.Parse("2006-01-02 15:04:05.999999999", el); err == nil {
+			return formatPostgresTime(elemType, t)
+		}
+		return el
+	}
+	return convertPostgresText(elemType, []byte(el))
+}
@@ -110,3 +110,13 @@ func TestQueryTypeRedis(t *testing.T) {
 		})
 	}
 }
+
+func TestRedisPlaceholdersRoundTripThroughTokenizer(t *testing.T) {
+	q := common.QueryMetadata{QueryLine: `HSET {{.key}} payload {{.doc}} tags {{.tags}}`}
+	err := common.PipeData(&q, []byte(`{"key": "user:1 x", "doc": {"a": "b\"c"}, "tags": ["x y", "z\\"]}`), common.PlaceholderRedis)
+	assert.NoError(t, err)
+
+	got, err := tokenizeRedisCommand(q.QueryLine)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"HSET", "user:1 x", "payload", `{"a":"b\"c"}`, "tags", "x y", `z\`}, got)
+}
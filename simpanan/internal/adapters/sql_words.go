@@ -0,0 +1,119 @@
+package adapters
+
+import (
+	"regexp"
+	"strings"
+)
+
+// hasReturningClause reports whether the statement contains the
+// RETURNING keyword outside string literals, quoted identifiers,
+// dollar-quoted bodies and comments.
+func hasReturningClause(query string) bool {
+	return hasSQLKeyword(query, "returning")
+}
+
+// hasSQLKeyword reports whether keyword appears as a bare word in
+// query; see eachSQLWord for what is skipped.
+func hasSQLKeyword(query, keyword string) bool {
+	found := false
+	eachSQLWord(query, func(word string, _ int) bool {
+		found = strings.EqualFold(word, keyword)
+		return !found
+	})
+	return found
+}
+
+// eachSQLWord calls visit with every bare word of query and its byte
+// offset, until visit returns false. String literals ('...' and
+// E'...'), quoted identifiers ("..." and T-SQL [...]), dollar-quoted
+// bodies and comments are skipped, so a keyword only counts where the
+// server would read it as one.
+func eachSQLWord(query string, visit func(word string, start int) bool) {
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			i = skipSQLQuoted(query, i, c)
+		case c == '[':
+			i = skipSQLQuoted(query, i, ']')
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				return
+			}
+			i += end + 1
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return
+			}
+			i += 2 + end + 2
+		case c == '$':
+			tag := sqlDollarTagRe.FindString(query[i:])
+			if tag == "" {
+				i++
+				continue
+			}
+			end := strings.Index(query[i+len(tag):], tag)
+			if end < 0 {
+				return
+			}
+			i += len(tag) + end + len(tag)
+		case isSQLIdentChar(c):
+			start := i
+			for i < len(query) && isSQLIdentChar(query[i]) {
+				i++
+			}
+			word := query[start:i]
+			// E'...' strings honour backslash escapes.
+			if (word == "E" || word == "e") && i < len(query) && query[i] == '\'' {
+				i = skipSQLEscapeString(query, i)
+				continue
+			}
+			if !visit(word, start) {
+				return
+			}
+		default:
+			i++
+		}
+	}
+}
+
+var sqlDollarTagRe = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func isSQLIdentChar(c byte) bool {
+	return c == '_' || c == '$' || c == '@' || c == '#' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// skipSQLQuoted returns the index just past the literal or quoted
+// identifier opening at query[start], where a doubled closing delimiter
+// is an escaped one.
+func skipSQLQuoted(query string, start int, close byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != close {
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == close {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+func skipSQLEscapeString(query string, start int) int {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '\'':
+			if i+1 < len(query) && query[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	}
+	return len(query)
+}
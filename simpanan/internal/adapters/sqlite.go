@@ -0,0 +1,297 @@
+package adapters
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"simpanan/internal/common"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteDSN converts a `sqlite://path/to/file.db` URI into a SQLite
+// `file:` URI for the driver. `sqlite:///abs/file.db` is absolute,
+// `sqlite://~/file.db` is under the home directory and anything else is
+// relative to the working directory. The file is opened read-write
+// without being created, so a typo in the path is an error rather than a
+// new empty database; pass `?mode=rwc` to opt into creation.
+func sqliteDSN(uri string) (string, error) {
+	rest, ok := strings.CutPrefix(uri, "sqlite://")
+	if !ok {
+		return "", fmt.Errorf("sqlite uri must use sqlite:// scheme, got %q", uri)
+	}
+	path, rawQuery, _ := strings.Cut(rest, "?")
+	if path == "" {
+		return "", fmt.Errorf("sqlite uri is missing a file path")
+	}
+	if home, ok := strings.CutPrefix(path, "~/"); ok {
+		dir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		path = filepath.Join(dir, home)
+	}
+
+	params, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return "", fmt.Errorf("invalid sqlite uri: %s", err)
+	}
+	if !params.Has("mode") {
+		params.Set("mode", "rw")
+	}
+	return "file:" + (&url.URL{Path: path}).EscapedPath() + "?" + params.Encode(), nil
+}
+
+func QueryTypeSqlite(query string) common.QueryType {
+	trimmed := strings.TrimSpace(query)
+	// Leading dot marks a sqlite3 shell dot-command (e.g. .tables).
+	if strings.HasPrefix(trimmed, ".") {
+		return common.Admin
+	}
+	fields := strings.Fields(trimmed)
+	if len(fields) == 0 {
+		return common.Read
+	}
+	switch strings.ToLower(fields[0]) {
+	case "insert", "update", "delete", "replace",
+		"create", "drop", "alter", "reindex", "vacuum",
+		"attach", "detach", "analyze":
+		return common.Write
+	case "pragma":
+		// `PRAGMA journal_mode = WAL` changes state; `PRAGMA table_info(t)`
+		// only reads.
+		if strings.Contains(trimmed, "=") {
+			return common.Write
+		}
+	}
+	return common.Read
+}
+
+// PlaceholderStyleSqlite binds placeholders as `?` parameters. SQLite
+// strings escape a quote only by doubling it, and double quotes wrap
+// identifiers, so only a whole '...' literal is replaced; a placeholder
+// naming a column must be spliced with {{raw: ...}}.
+var PlaceholderStyleSqlite common.PlaceholderStyle = common.BindStyle{
+	Rules:  common.LiteralRules{DoubledEscapes: true, SQLComments: true},
+	Quotes: "'",
+	Bind: func(q *common.QueryMetadata, value any, _ bool) string {
+		return common.ExpandParams(value, func(v any) string {
+			q.Args = append(q.Args, common.BindValue(v))
+			return "?"
+		})
+	},
+}
+
+func ExecuteSqliteReadQuery(q common.QueryMetadata) ([]byte, error) {
+	db, err := openSqlite(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	return querySqliteRows(db, q.QueryLine, q.Args...)
+}
+
+func ExecuteSqliteWriteQuery(q common.QueryMetadata) ([]byte, error) {
+	// A RETURNING clause turns the write into a row source; run it as a
+	// query so the returned rows reach the next stage.
+	if hasReturningClause(q.QueryLine) {
+		return ExecuteSqliteReadQuery(q)
+	}
+
+	db, err := openSqlite(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	res, err := db.Exec(q.QueryLine, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+
+	lastInsertID, _ := res.LastInsertId()
+	rowsAffected, _ := res.RowsAffected()
+	return json.Marshal(struct {
+		LastInsertID int64 `json:"last_insert_id"`
+		RowsAffected int64 `json:"rows_affected"`
+	}{lastInsertID, rowsAffected})
+}
+
+// ExecuteSqliteAdminCmd answers the sqlite3 shell's dot-commands:
+//
+//	.tables [pattern]  [{"name", "type"}] for tables and views
+//	.schema [pattern]  [{"name", "type", "tbl_name", "sql"}]
+//
+// As in the shell, the optional pattern is a LIKE pattern.
+func ExecuteSqliteAdminCmd(q common.QueryMetadata) ([]byte, error) {
+	fields := strings.Fields(q.QueryLine)
+	if len(fields) == 0 || len(fields) > 2 {
+		return nil, fmt.Errorf("ExecuteSqliteAdminCmd: invalid query format %s.", q.QueryLine)
+	}
+	pattern := "%"
+	if len(fields) == 2 {
+		pattern = fields[1]
+	}
+
+	db, err := openSqlite(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	switch fields[0] {
+	case ".tables":
+		return querySqliteRows(db, `SELECT name, type FROM sqlite_master
+WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite\_%' ESCAPE '\' AND name LIKE ?
+ORDER BY name`, pattern)
+	case ".schema":
+		return querySqliteRows(db, `SELECT name, type, tbl_name, sql FROM sqlite_master
+WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite\_%' ESCAPE '\' AND tbl_name LIKE ?
+ORDER BY tbl_name, type DESC, name`, pattern)
+	}
+	return nil, fmt.Errorf("ExecuteSqliteAdminCmd: unsupported dot-command %s.", fields[0])
+}
+
+// sqliteIntrospectQuery reports every table and view of the main
+// database; SQLite has no information_schema, so columns come from
+// pragma_table_info.
+const sqliteIntrospectQuery = `
+SELECT 'main', m.name, p.name
+FROM sqlite_master m
+JOIN pragma_table_info(m.name) p
+WHERE m.type IN ('table', 'view') AND m.name NOT LIKE 'sqlite\_%' ESCAPE '\'
+ORDER BY m.name, p.cid
+`
+
+// SqliteColumn is one column of a table or view.
+type SqliteColumn struct {
+	Database string
+	Table    string
+	Column   string
+}
+
+// IntrospectSqlite lists the columns of every table and view of the
+// main database, in table order.
+func IntrospectSqlite(uri string) ([]SqliteColumn, error) {
+	db, err := openSqlite(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query(sqliteIntrospectQuery)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []SqliteColumn
+	for rows.Next() {
+		var c SqliteColumn
+		if err := rows.Scan(&c.Database, &c.Table, &c.Column); err != nil {
+			return nil, err
+		}
+		cols = append(cols, c)
+	}
+	return cols, rows.Err()
+}
+
+func openSqlite(uri string) (*sql.DB, error) {
+	dsn, err := sqliteDSN(uri)
+	if err != nil {
+		return nil, err
+	}
+	return sql.Open("sqlite", dsn)
+}
+
+// querySqliteRows runs a row-returning statement on an open connection
+// and renders up to MaxRowLimit rows as a JSON array of objects that
+// keep the column order.
+func querySqliteRows(db *sql.DB, query string, args ...any) ([]byte, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", err.Error(), query)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	colTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(values))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var results []common.RowData
+	for rows.Next() {
+		if len(results) == common.GetConfig().MaxRowLimit {
+			break
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		rowResults := common.RowData{}
+		for i, col := range values {
+			rowResults = append(rowResults, common.ColumnValuePair{
+				Key:   columns[i],
+				Value: convertSqliteValue(colTypes[i].DatabaseTypeName(), col),
+			})
+		}
+		results = append(results, rowResults)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return marshalRowData(results)
+}
+
+// convertSqliteValue turns one scanned value into its JSON
+// representation. SQLite values carry their own storage class, so the
+// declared column type only refines it: BLOBs are base64, JSON columns
+// nest, BOOLEAN columns holding 0/1 become booleans and date columns
+// the driver parsed render as RFC3339.
+func convertSqliteValue(declType string, v any) any {
+	switch val := v.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		if declType == "DATE" {
+			return val.Format(time.DateOnly)
+		}
+		return val.Format(time.RFC3339Nano)
+	case []byte:
+		if strings.Contains(declType, "CHAR") || strings.Contains(declType, "TEXT") || strings.Contains(declType, "CLOB") {
+			return string(val)
+		}
+		return base64.StdEncoding.EncodeToString(val)
+	case string:
+		if declType == "JSON" && json.Valid([]byte(val)) {
+			return json.RawMessage(val)
+		}
+		return val
+	case int64:
+		if (declType == "BOOLEAN" || declType == "BOOL") && (val == 0 || val == 1) {
+			return val == 1
+		}
+		return val
+	case float64:
+		return jsonSafeFloat(val)
+	}
+	return v
+}
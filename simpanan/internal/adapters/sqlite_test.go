@@ -0,0 +1,191 @@
+package adapters
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"simpanan/internal/common"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSqliteDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		uri     string
+		want    string
+		wantErr bool
+	}{
+		{"relative path", "sqlite://data/app.db", "file:data/app.db?mode=rw", false},
+		{"absolute path", "sqlite:///var/lib/app.db", "file:/var/lib/app.db?mode=rw", false},
+		{"explicit mode kept", "sqlite://app.db?mode=ro", "file:app.db?mode=ro", false},
+		{"params passed through", "sqlite://app.db?_pragma=foreign_keys(1)", "file:app.db?_pragma=foreign_keys%281%29&mode=rw", false},
+		{"path is escaped", "sqlite://my file#1.db", "file:my%20file%231.db?mode=rw", false},
+		{"missing path", "sqlite://", "", true},
+		{"wrong scheme", "mysql://h/db", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sqliteDSN(tt.uri)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestQueryTypeSqlite(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{"SELECT * FROM t", common.Read},
+		{"with x as (select 1) select * from x", common.Read},
+		{"PRAGMA table_info(t)", common.Read},
+		{"INSERT INTO t VALUES (1)", common.Write},
+		{"replace into t values (1)", common.Write},
+		{"UPDATE t SET a = 1", common.Write},
+		{"DELETE FROM t", common.Write},
+		{"CREATE TABLE t (id INTEGER)", common.Write},
+		{"DROP TABLE t", common.Write},
+		{"VACUUM", common.Write},
+		{"PRAGMA journal_mode = WAL", common.Write},
+		{".tables", common.Admin},
+		{"  .schema users", common.Admin},
+		{"", common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeSqlite(tt.query))
+		})
+	}
+}
+
+func TestPlaceholderStyleSqlite(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		payload  string
+		wantLine string
+		wantArgs []any
+		wantErr  bool
+	}{
+		{
+			name:     "backslash does not escape a quote",
+			line:     `SELECT * FROM files WHERE dir = 'C:\' AND id = {{.id}}`,
+			payload:  `{"id": 1}`,
+			wantLine: `SELECT * FROM files WHERE dir = 'C:\' AND id = ?`,
+			wantArgs: []any{1},
+		},
+		{
+			name:     "single-quoted placeholder absorbs its quotes",
+			line:     `SELECT * FROM t WHERE name = 'it''s' OR name = '{{.name}}'`,
+			payload:  `{"name": "x"}`,
+			wantLine: `SELECT * FROM t WHERE name = 'it''s' OR name = ?`,
+			wantArgs: []any{"x"},
+		},
+		{
+			name:    "double quotes are an identifier, not a literal to absorb",
+			line:    `SELECT "{{.col}}" FROM t`,
+			payload: `{"col": "name"}`,
+			wantErr: true,
+		},
+		{
+			name:     "identifiers are spliced with raw",
+			line:     `SELECT "{{raw: .col}}" FROM t WHERE id = {{.id}}`,
+			payload:  `{"col": "name", "id": 2}`,
+			wantLine: `SELECT "name" FROM t WHERE id = ?`,
+			wantArgs: []any{2},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: tc.line}
+			err := common.PipeData(&q, []byte(tc.payload), PlaceholderStyleSqlite)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantLine, q.QueryLine)
+			assert.Equal(t, tc.wantArgs, q.Args)
+		})
+	}
+}
+
+func TestConvertSqliteValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		declType string
+		in       any
+		want     any
+	}{
+		{"null", "TEXT", nil, nil},
+		{"text keeps leading zeros", "TEXT", "01234", "01234"},
+		{"integer", "INTEGER", int64(42), int64(42)},
+		{"real", "REAL", 1.5, 1.5},
+		{"blob is base64", "BLOB", []byte{0xde, 0xad, 0xbe, 0xef}, "3q2+7w=="},
+		{"bytes in text column stay text", "VARCHAR(10)", []byte("hi"), "hi"},
+		{"json nests", "JSON", `{"a":1}`, json.RawMessage(`{"a":1}`)},
+		{"invalid json stays text", "JSON", `{a`, `{a`},
+		{"boolean", "BOOLEAN", int64(1), true},
+		{"boolean out of range stays int", "BOOLEAN", int64(2), int64(2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertSqliteValue(tt.declType, tt.in))
+		})
+	}
+}
+
+func TestSqliteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+	create := common.QueryMetadata{Conn: "sqlite://" + path + "?mode=rwc"}
+	conn := "sqlite://" + path
+
+	create.QueryLine = `CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, active BOOLEAN, meta JSON, avatar BLOB, born DATE)`
+	_, err := ExecuteSqliteWriteQuery(create)
+	assert.NoError(t, err)
+
+	out, err := ExecuteSqliteWriteQuery(common.QueryMetadata{
+		Conn:      conn,
+		QueryLine: `INSERT INTO users (name, active, meta, avatar, born) VALUES (?, 1, '{"tags":["a"]}', x'CAFE', '2024-03-01')`,
+		Args:      []any{"0042"},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"last_insert_id":1,"rows_affected":1}`, string(out))
+
+	out, err = ExecuteSqliteWriteQuery(common.QueryMetadata{
+		Conn:      conn,
+		QueryLine: `INSERT INTO users (name) VALUES ('bob') RETURNING id, name`,
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":2,"name":"bob"}]`, string(out))
+
+	out, err = ExecuteSqliteReadQuery(common.QueryMetadata{Conn: conn, QueryLine: `SELECT * FROM users WHERE id = ?`, Args: []any{1}})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"name":"0042","active":true,"meta":{"tags":["a"]},"avatar":"yv4=","born":"2024-03-01"}]`, string(out))
+
+	out, err = ExecuteSqliteAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: ".tables"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"name":"users","type":"table"}]`, string(out))
+
+	out, err = ExecuteSqliteAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: ".schema us%"})
+	assert.NoError(t, err)
+	assert.Contains(t, string(out), `"sql":"CREATE TABLE users`)
+
+	_, err = ExecuteSqliteAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: ".dump"})
+	assert.Error(t, err)
+
+	cols, err := IntrospectSqlite(conn)
+	assert.NoError(t, err)
+	assert.Len(t, cols, 6)
+	assert.Equal(t, SqliteColumn{Database: "main", Table: "users", Column: "id"}, cols[0])
+
+	// Without mode=rwc a missing file is an error, not a new database.
+	_, err = ExecuteSqliteReadQuery(common.QueryMetadata{Conn: "sqlite://" + filepath.Join(t.TempDir(), "missing.db"), QueryLine: "SELECT 1"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,272 @@
+package adapters
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpanan/internal/common"
+	"strings"
+)
+
+// sqlserverAdminCmd is a parsed admin stage: `sp_help`, `EXEC
+// sp_help 'dbo.users'`, `sp_helpindex users`, `sp_who` or one of the
+// psql-style `\dt` / `\d <table>` shortcuts.
+type sqlserverAdminCmd struct {
+	// proc is the lower-cased procedure the stage stands for.
+	proc string
+	// object is the unquoted object name argument, if any.
+	object string
+	// tablesOnly narrows the sp_help listing to user tables (`\dt`).
+	tablesOnly bool
+}
+
+func parseSqlserverAdminCmd(query string) (sqlserverAdminCmd, bool) {
+	trimmed := strings.TrimSpace(query)
+	if strings.HasPrefix(trimmed, "\\") {
+		meta, err := parsePsqlMetaCommand(trimmed)
+		if err != nil {
+			return sqlserverAdminCmd{}, true
+		}
+		switch meta.name {
+		case "dt":
+			return sqlserverAdminCmd{proc: "sp_help", tablesOnly: true}, true
+		case "d":
+			return sqlserverAdminCmd{proc: "sp_help", object: meta.pattern}, true
+		}
+		// Unknown meta-commands are still admin; execution reports them.
+		return sqlserverAdminCmd{proc: "\\" + meta.name}, true
+	}
+
+	fields := strings.Fields(trimmed)
+	if len(fields) > 0 && (strings.EqualFold(fields[0], "exec") || strings.EqualFold(fields[0], "execute")) {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return sqlserverAdminCmd{}, false
+	}
+	proc := strings.ToLower(strings.TrimSuffix(fields[0], ";"))
+	switch proc {
+	case "sp_help", "sp_helpindex", "sp_who":
+	default:
+		return sqlserverAdminCmd{}, false
+	}
+
+	arg := strings.TrimSpace(strings.Join(fields[1:], " "))
+	arg = strings.TrimSuffix(arg, ";")
+	// `@objname = N'dbo.users'`
+	if name, value, ok := strings.Cut(arg, "="); ok && strings.HasPrefix(strings.TrimSpace(name), "@") {
+		arg = strings.TrimSpace(value)
+	}
+	arg = strings.TrimPrefix(arg, "N'")
+	arg = strings.Trim(arg, "'")
+	return sqlserverAdminCmd{proc: proc, object: arg}, true
+}
+
+// ExecuteSqlserverAdminCmd answers sp_help-style admin stages with
+// structured JSON:
+//
+//	sp_help / \d                 [{"schema", "name", "type"}] user objects
+//	\dt                          the same, user tables only
+//	sp_help <table> / \d <table> the column_definitions/indices/constraints
+//	                             shape of the Postgres admin command
+//	sp_helpindex <table>         [{"indexname", "indexdef"}]
+//	sp_who                       the server's rows as-is
+//
+// Object names may be schema-qualified and quoted: `[dbo].[users]`.
+// Results are not capped at MaxRowLimit.
+func ExecuteSqlserverAdminCmd(q common.QueryMetadata) ([]byte, error) {
+	cmd, ok := parseSqlserverAdminCmd(q.QueryLine)
+	if !ok {
+		return nil, fmt.Errorf("ExecuteSqlserverAdminCmd: invalid query format %s.", q.QueryLine)
+	}
+
+	db, err := openSqlserver(q.Conn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	switch {
+	case cmd.proc == "sp_help" && cmd.object == "":
+		return listSqlserverObjects(db, cmd.tablesOnly)
+	case cmd.proc == "sp_help":
+		return describeSqlserverTable(db, cmd.object)
+	case cmd.proc == "sp_helpindex":
+		rel, err := resolveSqlserverObject(db, cmd.object)
+		if err != nil {
+			return nil, err
+		}
+		indices, err := sqlserverIndexDefs(db, rel.objectID)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(indices)
+	case cmd.proc == "sp_who":
+		return querySqlserverRows(db, 0, "EXEC sp_who")
+	}
+	return nil, fmt.Errorf("ExecuteSqlserverAdminCmd: unsupported command %s.", cmd.proc)
+}
+
+const sqlserverObjectType = `CASE o.type
+    WHEN 'U' THEN 'table' WHEN 'V' THEN 'view'
+    WHEN 'P' THEN 'procedure' WHEN 'FN' THEN 'function'
+    WHEN 'IF' THEN 'function' WHEN 'TF' THEN 'function'
+    WHEN 'SN' THEN 'synonym' WHEN 'SO' THEN 'sequence'
+  END`
+
+func listSqlserverObjects(db *sql.DB, tablesOnly bool) ([]byte, error) {
+	types := "'U', 'V', 'P', 'FN', 'IF', 'TF', 'SN', 'SO'"
+	if tablesOnly {
+		types = "'U'"
+	}
+	return querySqlserverRows(db, 0, fmt.Sprintf(`SELECT s.name AS [schema], o.name AS name, %s AS type
+FROM sys.objects o
+JOIN sys.schemas s ON s.schema_id = o.schema_id
+WHERE o.is_ms_shipped = 0 AND o.type IN (%s)
+ORDER BY 1, 2`, sqlserverObjectType, types))
+}
+
+type sqlserverObject struct {
+	objectID int64
+	schema   string
+	name     string
+	typ      string
+}
+
+// resolveSqlserverObject finds an object the way sp_help does, through
+// OBJECT_ID, so unqualified names follow the user's default schema.
+func resolveSqlserverObject(db *sql.DB, name string) (sqlserverObject, error) {
+	var o sqlserverObject
+	err := db.QueryRow(fmt.Sprintf(`SELECT o.object_id, s.name, o.name, %s
+FROM sys.objects o
+JOIN sys.schemas s ON s.schema_id = o.schema_id
+WHERE o.object_id = OBJECT_ID(@p1)`, sqlserverObjectType), name).Scan(&o.objectID, &o.schema, &o.name, &o.typ)
+	if err == sql.ErrNoRows {
+		return o, fmt.Errorf("ExecuteSqlserverAdminCmd: did not find any object named %q.", name)
+	}
+	return o, err
+}
+
+func describeSqlserverTable(db *sql.DB, name string) ([]byte, error) {
+	rel, err := resolveSqlserverObject(db, name)
+	if err != nil {
+		return nil, err
+	}
+	d := relationDescription{Schema: rel.schema, Name: rel.name, Type: rel.typ}
+
+	columns, err := scanSqlserverRows(db, 0, `SELECT c.COLUMN_NAME AS column_name,
+  c.DATA_TYPE + CASE
+    WHEN c.CHARACTER_MAXIMUM_LENGTH = -1 THEN '(max)'
+    WHEN c.CHARACTER_MAXIMUM_LENGTH IS NOT NULL THEN '(' + CAST(c.CHARACTER_MAXIMUM_LENGTH AS varchar(10)) + ')'
+    WHEN c.DATA_TYPE IN ('decimal', 'numeric') THEN '(' + CAST(c.NUMERIC_PRECISION AS varchar(10)) + ',' + CAST(c.NUMERIC_SCALE AS varchar(10)) + ')'
+    ELSE '' END AS data_type,
+  c.IS_NULLABLE AS is_nullable,
+  c.COLUMN_DEFAULT AS column_default
+FROM INFORMATION_SCHEMA.COLUMNS c
+WHERE c.TABLE_SCHEMA = @p1 AND c.TABLE_NAME = @p2
+ORDER BY c.ORDINAL_POSITION`, rel.schema, rel.name)
+	if err != nil {
+		return nil, err
+	}
+	if d.ColumnDefinitions, err = rowDataObjects(columns); err != nil {
+		return nil, err
+	}
+
+	indices, err := sqlserverIndexDefs(db, rel.objectID)
+	if err != nil {
+		return nil, err
+	}
+	d.Indices = []json.RawMessage{}
+	for _, idx := range indices {
+		b, err := json.Marshal(idx)
+		if err != nil {
+			return nil, err
+		}
+		d.Indices = append(d.Indices, b)
+	}
+
+	constraints, err := scanSqlserverRows(db, 0, `SELECT tc.CONSTRAINT_NAME AS constraint_name,
+  tc.CONSTRAINT_TYPE AS constraint_type,
+  kcu.COLUMN_NAME AS column_name
+FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS tc
+LEFT JOIN INFORMATION_SCHEMA.KEY_COLUMN_USAGE kcu
+  ON kcu.CONSTRAINT_SCHEMA = tc.CONSTRAINT_SCHEMA
+  AND kcu.CONSTRAINT_NAME = tc.CONSTRAINT_NAME
+WHERE tc.TABLE_SCHEMA = @p1 AND tc.TABLE_NAME = @p2
+ORDER BY 1, kcu.ORDINAL_POSITION`, rel.schema, rel.name)
+	if err != nil {
+		return nil, err
+	}
+	if d.Constraints, err = rowDataObjects(constraints); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(d)
+}
+
+// sqlserverIndexDef is one entry of the indices list, named like the
+// Postgres pg_indexes columns.
+type sqlserverIndexDef struct {
+	IndexName string `json:"indexname"`
+	IndexDef  string `json:"indexdef"`
+}
+
+// sqlserverIndexDefs reads one row per index key column and folds them
+// into one definition per index, e.g.
+// "UNIQUE NONCLUSTERED INDEX [ix_email] ([email])". The folding happens
+// here rather than with STRING_AGG so older servers work too.
+func sqlserverIndexDefs(db *sql.DB, objectID int64) ([]sqlserverIndexDef, error) {
+	rows, err := scanSqlserverRows(db, 0, `SELECT i.name AS index_name, i.is_primary_key, i.is_unique,
+  i.type_desc, c.name AS column_name, ic.is_descending_key
+FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.object_id = @p1 AND i.type > 0 AND ic.is_included_column = 0
+ORDER BY i.name, ic.key_ordinal`, objectID)
+	if err != nil {
+		return nil, err
+	}
+	return foldSqlserverIndexRows(rows), nil
+}
+
+func foldSqlserverIndexRows(rows []common.RowData) []sqlserverIndexDef {
+	defs := []sqlserverIndexDef{}
+	var name, kind string
+	var parts []string
+	flush := func() {
+		if name == "" {
+			return
+		}
+		defs = append(defs, sqlserverIndexDef{
+			IndexName: name,
+			IndexDef:  fmt.Sprintf("%s (%s)", kind, strings.Join(parts, ", ")),
+		})
+	}
+	for _, r := range rows {
+		idx := fmt.Sprint(rowValue(r, "index_name"))
+		if idx != name {
+			flush()
+			name, parts = idx, nil
+			typeDesc := fmt.Sprint(rowValue(r, "type_desc"))
+			switch {
+			case rowValue(r, "is_primary_key") == true:
+				kind = "PRIMARY KEY " + typeDesc
+			case rowValue(r, "is_unique") == true:
+				kind = fmt.Sprintf("UNIQUE %s INDEX %s", typeDesc, quoteSqlserverIdent(name))
+			default:
+				kind = fmt.Sprintf("%s INDEX %s", typeDesc, quoteSqlserverIdent(name))
+			}
+		}
+		part := quoteSqlserverIdent(fmt.Sprint(rowValue(r, "column_name")))
+		if rowValue(r, "is_descending_key") == true {
+			part += " DESC"
+		}
+		parts = append(parts, part)
+	}
+	flush()
+	return defs
+}
+
+func quoteSqlserverIdent(s string) string {
+	return "[" + strings.ReplaceAll(s, "]", "]]") + "]"
+}
@@ -0,0 +1,420 @@
+package adapters
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"simpanan/internal/common"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryTypeSqlserver(t *testing.T) {
+	tests := []struct {
+		query string
+		want  common.QueryType
+	}{
+		{"SELECT TOP 5 * FROM users", common.Read},
+		{"with x as (select 1 as a) select * from x", common.Read},
+		{"INSERT INTO users (name) VALUES ('a')", common.Write},
+		{"update users set name = 'b'", common.Write},
+		{"MERGE INTO t USING s ON t.id = s.id WHEN MATCHED THEN DELETE;", common.Write},
+		{"TRUNCATE TABLE t", common.Write},
+		{"EXEC dbo.refresh_totals", common.Write},
+		{"sp_help", common.Admin},
+		{"EXEC sp_help 'dbo.users'", common.Admin},
+		{"execute sp_helpindex @objname = N'users'", common.Admin},
+		{"sp_who", common.Admin},
+		{"\\dt", common.Admin},
+		{"\\d users", common.Admin},
+		{"", common.Read},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assert.Equal(t, tt.want, QueryTypeSqlserver(tt.query))
+		})
+	}
+}
+
+func TestParseSqlserverAdminCmd(t *testing.T) {
+	tests := []struct {
+		query string
+		want  sqlserverAdminCmd
+	}{
+		{"sp_help", sqlserverAdminCmd{proc: "sp_help"}},
+		{"EXEC sp_help 'dbo.users';", sqlserverAdminCmd{proc: "sp_help", object: "dbo.users"}},
+		{"exec SP_HELP [sales].[orders]", sqlserverAdminCmd{proc: "sp_help", object: "[sales].[orders]"}},
+		{"sp_helpindex @objname = N'users'", sqlserverAdminCmd{proc: "sp_helpindex", object: "users"}},
+		{"\\dt", sqlserverAdminCmd{proc: "sp_help", tablesOnly: true}},
+		{"\\d+ dbo.users", sqlserverAdminCmd{proc: "sp_help", object: "dbo.users"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			got, ok := parseSqlserverAdminCmd(tt.query)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+
+	_, ok := parseSqlserverAdminCmd("EXEC dbo.refresh_totals")
+	assert.False(t, ok)
+}
+
+func TestApplySqlserverTop(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{"plain select", "SELECT * FROM users", "SELECT TOP (20) * FROM users"},
+		{"distinct", "select distinct name from users", "select distinct TOP (20) name from users"},
+		{"already limited", "SELECT TOP 5 * FROM users", "SELECT TOP 5 * FROM users"},
+		{"offset fetch", "SELECT * FROM users ORDER BY id OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY", "SELECT * FROM users ORDER BY id OFFSET 10 ROWS FETCH NEXT 5 ROWS ONLY"},
+		{"select into", "SELECT * INTO #tmp FROM users", "SELECT * INTO #tmp FROM users"},
+		{"union", "SELECT a FROM x UNION SELECT a FROM y", "SELECT a FROM x UNION SELECT a FROM y"},
+		{"cte", "WITH x AS (SELECT 1 AS a) SELECT * FROM x", "WITH x AS (SELECT 1 AS a) SELECT * FROM x"},
+		{"keyword in string", "SELECT 'top' AS word", "SELECT TOP (20) 'top' AS word"},
+		{"bracketed column", "SELECT [top] FROM t", "SELECT TOP (20) [top] FROM t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, applySqlserverTop(tt.query, 20))
+		})
+	}
+	assert.Equal(t, "SELECT 1", applySqlserverTop("SELECT 1", 0))
+}
+
+func TestConvertSqlserverValue(t *testing.T) {
+	at := time.Date(2024, 3, 1, 13, 45, 30, 120000000, time.FixedZone("", 2*3600))
+	guid := []byte{0x67, 0x45, 0x23, 0x01, 0xab, 0x89, 0xef, 0xcd, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef}
+	tests := []struct {
+		name     string
+		typeName string
+		in       any
+		want     any
+	}{
+		{"null", "INT", nil, nil},
+		{"int", "INT", int64(7), int64(7)},
+		{"decimal keeps digits", "DECIMAL", []byte("12.3400"), "12.3400"},
+		{"money keeps digits", "MONEY", []byte("1.5000"), "1.5000"},
+		{"guid", "UNIQUEIDENTIFIER", guid, "01234567-89AB-CDEF-0123-456789ABCDEF"},
+		{"varbinary is base64", "VARBINARY", []byte{0xca, 0xfe}, "yv4="},
+		{"date", "DATE", at, "2024-03-01"},
+		{"time", "TIME", at, "13:45:30.12"},
+		{"datetimeoffset", "DATETIMEOFFSET", at, "2024-03-01T13:45:30.12+02:00"},
+		{"datetime2 has no offset", "DATETIME2", at, "2024-03-01T13:45:30.12"},
+		{"real", "REAL", float64(float32(0.1)), 0.1},
+		{"nan", "FLOAT", math.NaN(), "NaN"},
+		{"string", "NVARCHAR", "héllo", "héllo"},
+		{"bit", "BIT", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, convertSqlserverValue(tt.typeName, tt.in))
+		})
+	}
+}
+
+func TestFoldSqlserverIndexRows(t *testing.T) {
+	row := func(index string, pk, unique bool, typeDesc, column string, desc bool) common.RowData {
+		return common.RowData{
+			{Key: "index_name", Value: index},
+			{Key: "is_primary_key", Value: pk},
+			{Key: "is_unique", Value: unique},
+			{Key: "type_desc", Value: typeDesc},
+			{Key: "column_name", Value: column},
+			{Key: "is_descending_key", Value: desc},
+		}
+	}
+	got := foldSqlserverIndexRows([]common.RowData{
+		row("ix_name", false, false, "NONCLUSTERED", "last", false),
+		row("ix_name", false, false, "NONCLUSTERED", "first", true),
+		row("pk_users", true, true, "CLUSTERED", "id", false),
+		row("uq_email", false, true, "NONCLUSTERED", "email", false),
+	})
+	assert.Equal(t, []sqlserverIndexDef{
+		{IndexName: "ix_name", IndexDef: "NONCLUSTERED INDEX [ix_name] ([last], [first] DESC)"},
+		{IndexName: "pk_users", IndexDef: "PRIMARY KEY CLUSTERED ([id])"},
+		{IndexName: "uq_email", IndexDef: "UNIQUE NONCLUSTERED INDEX [uq_email] ([email])"},
+	}, got)
+	assert.Equal(t, []sqlserverIndexDef{}, foldSqlserverIndexRows(nil))
+}
+
+func TestSqlserverReadAppliesTop(t *testing.T) {
+	fake := useFakeSqlserver(t, func(query string, args []driver.Value) *fakeSqlserverResult {
+		return &fakeSqlserverResult{
+			columns: []string{"id", "balance"},
+			types:   []string{"INT", "DECIMAL"},
+			rows:    [][]driver.Value{{int64(1), []byte("10.50")}},
+		}
+	})
+
+	out, err := ExecuteSqlserverReadQuery(common.QueryMetadata{
+		Conn:      "sqlserver://sa:pw@localhost?database=app",
+		QueryLine: "SELECT id, balance FROM accounts WHERE owner = @p1",
+		Args:      []any{"ann"},
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"id":1,"balance":"10.50"}]`, string(out))
+	assert.Equal(t, []string{"SELECT TOP (20) id, balance FROM accounts WHERE owner = @p1"}, fake.queries)
+	assert.Equal(t, []driver.Value{"ann"}, fake.args[0])
+}
+
+func TestSqlserverDescribeTable(t *testing.T) {
+	fake := useFakeSqlserver(t, func(query string, args []driver.Value) *fakeSqlserverResult {
+		switch {
+		case strings.Contains(query, "OBJECT_ID(@p1)"):
+			if args[0] != "users" {
+				return &fakeSqlserverResult{columns: []string{"object_id", "schema", "name", "type"}}
+			}
+			return &fakeSqlserverResult{
+				columns: []string{"object_id", "schema", "name", "type"},
+				types:   []string{"INT", "NVARCHAR", "NVARCHAR", "VARCHAR"},
+				rows:    [][]driver.Value{{int64(42), "dbo", "users", "table"}},
+			}
+		case strings.Contains(query, "INFORMATION_SCHEMA.COLUMNS"):
+			return &fakeSqlserverResult{
+				columns: []string{"column_name", "data_type", "is_nullable", "column_default"},
+				types:   []string{"NVARCHAR", "NVARCHAR", "VARCHAR", "NVARCHAR"},
+				rows: [][]driver.Value{
+					{"id", "int", "NO", nil},
+					{"email", "nvarchar(255)", "YES", nil},
+				},
+			}
+		case strings.Contains(query, "sys.indexes"):
+			return &fakeSqlserverResult{
+				columns: []string{"index_name", "is_primary_key", "is_unique", "type_desc", "column_name", "is_descending_key"},
+				types:   []string{"NVARCHAR", "BIT", "BIT", "NVARCHAR", "NVARCHAR", "BIT"},
+				rows:    [][]driver.Value{{"pk_users", true, true, "CLUSTERED", "id", false}},
+			}
+		case strings.Contains(query, "TABLE_CONSTRAINTS"):
+			return &fakeSqlserverResult{
+				columns: []string{"constraint_name", "constraint_type", "column_name"},
+				types:   []string{"NVARCHAR", "VARCHAR", "NVARCHAR"},
+				rows:    [][]driver.Value{{"pk_users", "PRIMARY KEY", "id"}},
+			}
+		}
+		return nil
+	})
+	conn := "sqlserver://sa:pw@localhost?database=app"
+
+	out, err := ExecuteSqlserverAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: "EXEC sp_help 'users'"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{
+		"schema": "dbo", "name": "users", "type": "table",
+		"column_definitions": [
+			{"column_name": "id", "data_type": "int", "is_nullable": "NO", "column_default": null},
+			{"column_name": "email", "data_type": "nvarchar(255)", "is_nullable": "YES", "column_default": null}
+		],
+		"indices": [{"indexname": "pk_users", "indexdef": "PRIMARY KEY CLUSTERED ([id])"}],
+		"constraints": [{"constraint_name": "pk_users", "constraint_type": "PRIMARY KEY", "column_name": "id"}]
+	}`, string(out))
+	assert.Equal(t, []driver.Value{"dbo", "users"}, fake.args[1])
+
+	out, err = ExecuteSqlserverAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: "sp_helpindex users"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"indexname": "pk_users", "indexdef": "PRIMARY KEY CLUSTERED ([id])"}]`, string(out))
+
+	_, err = ExecuteSqlserverAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: "sp_help missing"})
+	assert.ErrorContains(t, err, `did not find any object named "missing"`)
+}
+
+func TestSqlserverAdminReadsEveryRow(t *testing.T) {
+	n := common.GetConfig().MaxRowLimit + 5
+	var keyRows, objectRows [][]driver.Value
+	var keys []string
+	for i := 0; i < n; i++ {
+		keyRows = append(keyRows, []driver.Value{"ix_wide", false, false, "NONCLUSTERED", fmt.Sprintf("c%d", i), false})
+		objectRows = append(objectRows, []driver.Value{"dbo", fmt.Sprintf("t%d", i), "table"})
+		keys = append(keys, fmt.Sprintf("[c%d]", i))
+	}
+	useFakeSqlserver(t, func(query string, args []driver.Value) *fakeSqlserverResult {
+		switch {
+		case strings.Contains(query, "OBJECT_ID(@p1)"):
+			return &fakeSqlserverResult{
+				columns: []string{"object_id", "schema", "name", "type"},
+				types:   []string{"INT", "NVARCHAR", "NVARCHAR", "VARCHAR"},
+				rows:    [][]driver.Value{{int64(42), "dbo", "wide", "table"}},
+			}
+		case strings.Contains(query, "sys.indexes"):
+			return &fakeSqlserverResult{
+				columns: []string{"index_name", "is_primary_key", "is_unique", "type_desc", "column_name", "is_descending_key"},
+				types:   []string{"NVARCHAR", "BIT", "BIT", "NVARCHAR", "NVARCHAR", "BIT"},
+				rows:    keyRows,
+			}
+		case strings.Contains(query, "sys.objects"):
+			return &fakeSqlserverResult{
+				columns: []string{"schema", "name", "type"},
+				types:   []string{"NVARCHAR", "NVARCHAR", "VARCHAR"},
+				rows:    objectRows,
+			}
+		}
+		return nil
+	})
+	conn := "sqlserver://sa:pw@localhost?database=app"
+
+	out, err := ExecuteSqlserverAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: "sp_helpindex wide"})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `[{"indexname": "ix_wide", "indexdef": "NONCLUSTERED INDEX [ix_wide] (`+strings.Join(keys, ", ")+`)"}]`, string(out))
+
+	out, err = ExecuteSqlserverAdminCmd(common.QueryMetadata{Conn: conn, QueryLine: `\dt`})
+	assert.NoError(t, err)
+	var objects []map[string]any
+	assert.NoError(t, json.Unmarshal(out, &objects))
+	assert.Len(t, objects, n)
+}
+
+func TestIntrospectSqlserver(t *testing.T) {
+	fake := useFakeSqlserver(t, func(string, []driver.Value) *fakeSqlserverResult {
+		return &fakeSqlserverResult{
+			columns: []string{"TABLE_SCHEMA", "TABLE_NAME", "COLUMN_NAME"},
+			types:   []string{"NVARCHAR", "NVARCHAR", "NVARCHAR"},
+			rows:    [][]driver.Value{{"dbo", "users", "id"}, {"dbo", "users", "email"}, {"sales", "orders", "id"}},
+		}
+	})
+
+	cols, err := IntrospectSqlserver("sqlserver://sa:pw@localhost?database=app")
+	assert.NoError(t, err)
+	assert.Equal(t, []SqlserverColumn{
+		{Database: "dbo", Table: "users", Column: "id"},
+		{Database: "dbo", Table: "users", Column: "email"},
+		{Database: "sales", Table: "orders", Column: "id"},
+	}, cols)
+	assert.Contains(t, fake.queries[0], "INFORMATION_SCHEMA.COLUMNS")
+}
+
+// fakeSqlserverResult is the canned answer of the fake driver to one
+// query.
+type fakeSqlserverResult struct {
+	columns []string
+	types   []string
+	rows    [][]driver.Value
+}
+
+// fakeSqlserver is a database/sql driver standing in for go-mssqldb. It
+// records every query and answers with whatever respond returns.
+type fakeSqlserver struct {
+	respond func(query string, args []driver.Value) *fakeSqlserverResult
+	queries []string
+	args    [][]driver.Value
+}
+
+var fakeSqlserverDriver = &fakeSqlserverDispatch{}
+
+// fakeSqlserverDispatch is registered once; each test points it at its
+// own fakeSqlserver.
+type fakeSqlserverDispatch struct{ current *fakeSqlserver }
+
+func init() { sql.Register("sqlserver-fake", fakeSqlserverDriver) }
+
+func useFakeSqlserver(t *testing.T, respond func(string, []driver.Value) *fakeSqlserverResult) *fakeSqlserver {
+	t.Helper()
+	fake := &fakeSqlserver{respond: respond}
+	fakeSqlserverDriver.current = fake
+	prev := sqlserverDriver
+	sqlserverDriver = "sqlserver-fake"
+	t.Cleanup(func() {
+		sqlserverDriver = prev
+		fakeSqlserverDriver.current = nil
+	})
+	return fake
+}
+
+func (d *fakeSqlserverDispatch) Open(string) (driver.Conn, error) {
+	return fakeSqlserverConn{d.current}, nil
+}
+
+type fakeSqlserverConn struct{ fake *fakeSqlserver }
+
+func (c fakeSqlserverConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeSqlserverStmt{c.fake, query}, nil
+}
+func (fakeSqlserverConn) Close() error              { return nil }
+func (fakeSqlserverConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type fakeSqlserverStmt struct {
+	fake  *fakeSqlserver
+	query string
+}
+
+func (fakeSqlserverStmt) Close() error  { return nil }
+func (fakeSqlserverStmt) NumInput() int { return -1 }
+func (s fakeSqlserverStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.fake.queries = append(s.fake.queries, s.query)
+	s.fake.args = append(s.fake.args, args)
+	return driver.RowsAffected(1), nil
+}
+func (s fakeSqlserverStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.fake.queries = append(s.fake.queries, s.query)
+	s.fake.args = append(s.fake.args, args)
+	res := s.fake.respond(s.query, args)
+	if res == nil {
+		res = &fakeSqlserverResult{}
+	}
+	return &fakeSqlserverRows{res: res}, nil
+}
+
+type fakeSqlserverRows struct {
+	res *fakeSqlserverResult
+	pos int
+}
+
+func (r *fakeSqlserverRows) Columns() []string { return r.res.columns }
+func (r *fakeSqlserverRows) Close() error      { return nil }
+func (r *fakeSqlserverRows) ColumnTypeDatabaseTypeName(i int) string {
+	return r.res.types[i]
+}
+func (r *fakeSqlserverRows) Next(dest []driver.Value) error {
+	if r.pos == len(r.res.rows) {
+		return io.EOF
+	}
+	copy(dest, r.res.rows[r.pos])
+	r.pos++
+	return nil
+}
+
+func TestPlaceholderStyleSqlserver(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		payload  string
+		wantLine string
+		wantArgs []any
+	}{
+		{
+			name:     "scalar and array",
+			line:     "SELECT * FROM t WHERE id IN ({{.ids}}) AND name = N'{{.name}}'",
+			payload:  `{"ids": [1, 2], "name": "Zoë"}`,
+			wantLine: "SELECT * FROM t WHERE id IN (@p1, @p2) AND name = @p3",
+			wantArgs: []any{1, 2, "Zoë"},
+		},
+		{
+			name:     "quote in a comment is not a literal",
+			line:     "SELECT * FROM t -- don't\n WHERE id = {{.id}}",
+			payload:  `{"id": 1}`,
+			wantLine: "SELECT * FROM t -- don't\n WHERE id = @p1",
+			wantArgs: []any{1},
+		},
+		{
+			name:     "N prefix belongs to a word",
+			line:     "SELECT * FROM t WHERE x = IN'{{.v}}'",
+			payload:  `{"v": "a"}`,
+			wantLine: "SELECT * FROM t WHERE x = IN@p1",
+			wantArgs: []any{"a"},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			q := common.QueryMetadata{QueryLine: tc.line}
+			assert.NoError(t, common.PipeData(&q, []byte(tc.payload), PlaceholderStyleSqlserver))
+			assert.Equal(t, tc.wantLine, q.QueryLine)
+			assert.Equal(t, tc.wantArgs, q.Args)
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package adapters
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newStandIn starts an httptest server standing in for an HTTP backend.
+// Each request is read whole, turned into the backend's own record type
+// by record and kept in the returned slice for later assertions, then
+// answered by respond. The server is closed when the test ends.
+func newStandIn[R any](t *testing.T, record func(r *http.Request, body []byte) R, respond func(w http.ResponseWriter, r *http.Request, rec R)) (*httptest.Server, *[]R) {
+	t.Helper()
+	var seen []R
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec := record(r, body)
+		seen = append(seen, rec)
+		respond(w, r, rec)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &seen
+}
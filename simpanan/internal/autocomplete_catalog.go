@@ -29,7 +29,8 @@ const (
 
 // BuiltinCatalog is the static, ship-with-the-plugin completion knowledge
 // for one connection type. Contents are hand-maintained rather than
-// introspected from the backing database.
+// introspected from the backing database. Which of its lists are filled
+// picks the completion path of an adapter without a CompletionDialect.
 type BuiltinCatalog struct {
 	ConnectionType            common.ConnType
 	SqlKeywords               []string
@@ -39,21 +40,12 @@ type BuiltinCatalog struct {
 	JqOperators               []string
 }
 
-// GetBuiltinCatalog returns the static catalog for the given connection
-// type. Unknown types yield an empty catalog rather than an error so
-// callers can treat it uniformly.
+// GetBuiltinCatalog returns the static catalog registered by the
+// connection type's Adapter. Unknown types yield an empty catalog
+// rather than an error so callers can treat it uniformly.
 func GetBuiltinCatalog(ct common.ConnType) BuiltinCatalog {
-	switch ct {
-	case common.Postgres:
-		return postgresCatalog
-	case common.Mysql:
-		return mysqlCatalog
-	case common.Mongo:
-		return mongoCatalog
-	case common.Redis:
-		return redisCatalog
-	case common.Jq:
-		return jqCatalog
+	if adapter, ok := LookupAdapter(ct); ok {
+		return adapter.Catalog()
 	}
 	return BuiltinCatalog{ConnectionType: ct}
 }
@@ -81,7 +73,8 @@ var postgresCatalog = BuiltinCatalog{
 		"SCHEMA", "SEQUENCE", "MATERIALIZED",
 		"CONFLICT", "DO", "NOTHING",
 		// psql meta-commands — useful as completions for admin stages.
-		"\\dt", "\\d", "\\dn", "\\dv", "\\df",
+		"\\dt", "\\d", "\\d+", "\\dn", "\\dv", "\\dm", "\\di",
+		"\\df", "\\ds", "\\du", "\\l", "\\dx", "\\dT",
 	),
 }
 
@@ -92,9 +85,134 @@ var mysqlCatalog = BuiltinCatalog{
 		"DESCRIBE", "EXPLAIN", "USE",
 		"AUTO_INCREMENT", "UNSIGNED",
 		"REPLACE", "DUPLICATE", "KEY",
+		"INDEXES", "KEYS", "PROCESSLIST",
+		// psql-style shortcuts accepted by the MySQL admin path.
+		"\\dt", "\\d", "\\d+",
 	),
 }
 
+var sqliteCatalog = BuiltinCatalog{
+	ConnectionType: sqliteConnType,
+	SqlKeywords: append(sqlKeywordsCommon,
+		"PRAGMA", "VACUUM", "ATTACH", "DETACH", "REINDEX",
+		"AUTOINCREMENT", "WITHOUT", "ROWID", "STRICT", "GLOB",
+		"REPLACE", "CONFLICT", "RETURNING",
+		// sqlite3 shell dot-commands — useful as completions for admin stages.
+		".tables", ".schema",
+	),
+}
+
+var sqlserverCatalog = BuiltinCatalog{
+	ConnectionType: sqlserverConnType,
+	SqlKeywords: append(sqlKeywordsCommon,
+		"TOP", "PERCENT", "TIES", "FETCH", "NEXT", "ROWS", "ONLY",
+		"NOLOCK", "IDENTITY", "NVARCHAR", "UNIQUEIDENTIFIER", "DATETIME2",
+		"MERGE", "MATCHED", "OUTPUT", "INSERTED", "DELETED",
+		"EXEC", "EXECUTE", "DECLARE", "APPLY", "PIVOT", "UNPIVOT",
+		"GETDATE", "ISNULL", "NEWID", "CONVERT",
+		// System procedures and psql-style shortcuts accepted by the
+		// SQL Server admin path.
+		"sp_help", "sp_helpindex", "sp_who", "\\dt", "\\d",
+	),
+}
+
+// dynamodbCatalog lists PartiQL's keywords and functions next to the
+// shorthand commands, so both forms complete through the SQL path.
+var dynamodbCatalog = BuiltinCatalog{
+	ConnectionType: dynamodbConnType,
+	SqlKeywords: []string{
+		"SELECT", "FROM", "WHERE", "AND", "OR", "NOT", "IN", "IS",
+		"NULL", "MISSING", "BETWEEN", "ORDER", "BY", "ASC", "DESC",
+		"INSERT", "INTO", "VALUE", "UPDATE", "SET", "REMOVE", "DELETE",
+		"RETURNING", "MODIFIED", "OLD", "NEW", "ALL",
+		"EXISTS", "BEGINS_WITH", "CONTAINS", "SIZE", "ATTRIBUTE_TYPE",
+		"LIST_APPEND", "SET_ADD", "SET_DELETE",
+		"TRUE", "FALSE",
+		// Shorthand commands and their flags.
+		"scan", "query", "get-item", "list-tables", "describe-table",
+		"--index", "--limit", "--desc",
+	},
+}
+
+// cassandraCatalog is CQL's own keyword set rather than an extension of
+// sqlKeywordsCommon: CQL has no joins, subqueries or set operators.
+var cassandraCatalog = BuiltinCatalog{
+	ConnectionType: cassandraConnType,
+	SqlKeywords: []string{
+		"SELECT", "FROM", "WHERE", "AND", "IN", "CONTAINS", "KEY",
+		"ORDER", "BY", "ASC", "DESC", "LIMIT", "PER", "PARTITION",
+		"GROUP", "ALLOW", "FILTERING", "DISTINCT", "AS", "JSON",
+		"INSERT", "INTO", "VALUES", "UPDATE", "SET", "DELETE",
+		"IF", "EXISTS", "NOT", "USING", "TTL", "TIMESTAMP",
+		"BEGIN", "UNLOGGED", "BATCH", "APPLY", "TRUNCATE",
+		"CREATE", "ALTER", "DROP", "KEYSPACE", "TABLE", "INDEX",
+		"MATERIALIZED", "VIEW", "TYPE", "PRIMARY", "CLUSTERING",
+		"WITH", "REPLICATION", "NULL", "TRUE", "FALSE",
+		"COUNT", "MIN", "MAX", "SUM", "AVG", "WRITETIME", "TOKEN",
+		"NOW", "UUID", "TOTIMESTAMP", "TODATE",
+		// Admin commands answered from system_schema.
+		"DESCRIBE", "KEYSPACES", "TABLES",
+	},
+}
+
+var clickhouseCatalog = BuiltinCatalog{
+	ConnectionType: clickhouseConnType,
+	SqlKeywords: append(sqlKeywordsCommon,
+		"PREWHERE", "FINAL", "SAMPLE", "ARRAY", "GLOBAL", "ASOF",
+		"SETTINGS", "FORMAT", "ENGINE", "PARTITION", "TTL", "OPTIMIZE",
+		"SHOW", "DESCRIBE", "EXPLAIN", "DATABASES", "TABLES", "ROLLUP", "CUBE",
+		"TOTALS", "FILL",
+		// Functions, aggregate and otherwise.
+		"count", "countIf", "sum", "sumIf", "avg", "avgIf", "min", "max",
+		"uniq", "uniqExact", "uniqCombined", "any", "anyLast", "argMin", "argMax",
+		"groupArray", "groupUniqArray", "quantile", "quantiles", "median", "topK",
+		"toDate", "toDateTime", "toStartOfDay", "toStartOfHour", "toStartOfMonth",
+		"toStartOfInterval", "toYYYYMM", "now", "today", "yesterday", "dateDiff",
+		"formatDateTime", "parseDateTimeBestEffort",
+		"toString", "toInt64", "toUInt64", "toFloat64", "toDecimal64",
+		"arrayJoin", "arrayMap", "arrayFilter", "has", "length", "empty", "notEmpty",
+		"lower", "upper", "concat", "substring", "splitByChar", "replaceAll",
+		"match", "extract", "like", "position",
+		"JSONExtractString", "JSONExtractInt", "JSONExtractRaw",
+		"if", "multiIf", "coalesce", "ifNull", "round", "intDiv",
+		"cityHash64", "sipHash64",
+	),
+}
+
+// etcdCatalog and consulCatalog complete the KV commands through the
+// Redis command path: both are a command word followed by a key.
+var etcdCatalog = BuiltinCatalog{
+	ConnectionType: etcdConnType,
+	RedisCommands:  kvCommands,
+}
+
+var consulCatalog = BuiltinCatalog{
+	ConnectionType: consulConnType,
+	RedisCommands:  kvCommands,
+}
+
+var memcachedCatalog = BuiltinCatalog{
+	ConnectionType: memcachedConnType,
+	RedisCommands: []string{
+		"get", "gets", "set", "add", "replace", "append", "prepend", "cas",
+		"delete", "incr", "decr", "touch", "stats", "keys",
+		// stats groups
+		"items", "slabs", "settings", "cachedump",
+	},
+}
+
+// kafkaCatalog completes its commands and flags through the Redis
+// command path too.
+var kafkaCatalog = BuiltinCatalog{
+	ConnectionType: kafkaConnType,
+	RedisCommands: []string{
+		"consume", "produce", "list-topics", "list-partitions",
+		"--from-offset", "--partition", "--limit", "--key", "--header",
+	},
+}
+
+var kvCommands = []string{"get", "list", "put", "delete", "--prefix", "--limit"}
+
 var mongoCatalog = BuiltinCatalog{
 	ConnectionType: common.Mongo,
 	MongoCollectionOperations: []string{
@@ -108,7 +226,7 @@ var mongoCatalog = BuiltinCatalog{
 		"findOneAndUpdate", "findOneAndReplace", "findOneAndDelete",
 		"bulkWrite",
 		// Meta
-		"createIndex", "dropIndex", "getIndexes",
+		"createIndex", "dropIndex", "getIndexes", "stats",
 	},
 	MongoAggregationOperators: []string{
 		// Pipeline stages
@@ -27,6 +27,7 @@ const (
 
 // ContextClassification is the output of ClassifyContext. Fields beyond
 // Context are best-effort: SqlAliases is empty for non-SQL stages,
+// Scope is empty unless a completion dialect narrows the position, and
 // ConnectionLabel is empty when the cursor is not inside any stage.
 type ContextClassification struct {
 	Context         CompletionContext
@@ -34,6 +35,11 @@ type ContextClassification struct {
 	StageIndex      int
 	ConnectionLabel string
 	SqlAliases      map[string]string
+	// Scope is what a CompletionDialect narrows its suggestions to: the
+	// index a search stage's path names, the type and fields leading to
+	// the GraphQL selection set under the cursor, or the label bound to
+	// the Cypher variable whose property is being completed.
+	Scope []string
 }
 
 // stageHeaderRe matches the start of a stage line: literal '|', a
@@ -120,7 +126,7 @@ func ClassifyContext(bufferText string, cursorPos int) ContextClassification {
 }
 
 // classifyByConnLabel dispatches per the connection's type. Looks up
-// the type in the registry; on failure returns Unknown.
+// the type in the connection registry; on failure returns Unknown.
 func classifyByConnLabel(label, stageContent, prefix string) ContextClassification {
 	// jq placeholders are connection-type-agnostic: a {{ in any
 	// non-first stage opens a jq context.
@@ -137,21 +143,38 @@ func classifyByConnLabel(label, stageContent, prefix string) ContextClassificati
 	if !ok {
 		return ContextClassification{Context: CtxUnknown, Prefix: prefix}
 	}
+	if dialect, ok := completionDialectOf(ct); ok {
+		return dialect.ClassifyCompletion(stageContent, prefix)
+	}
 
-	switch ct {
-	case common.Postgres, common.Mysql:
-		out := classifySql(stageContent, prefix)
-		return out
-	case common.Mongo:
+	// Otherwise the completion dialect follows from what the adapter's
+	// catalog ships, so a new backend with SqlKeywords gets SQL
+	// classification without touching this dispatcher.
+	cat := GetBuiltinCatalog(ct)
+	switch {
+	case len(cat.SqlKeywords) > 0:
+		return classifySql(stageContent, prefix)
+	case len(cat.MongoCollectionOperations) > 0:
 		return classifyMongo(stageContent, prefix)
-	case common.Redis:
+	case len(cat.RedisCommands) > 0:
 		return ContextClassification{Context: CtxRedisCommandPrefix, Prefix: prefix}
-	case common.Jq:
+	case len(cat.JqOperators) > 0:
 		return ContextClassification{Context: CtxJqPlaceholder, Prefix: prefix}
 	}
 	return ContextClassification{Context: CtxUnknown, Prefix: prefix}
 }
 
+// completionDialectOf returns the CompletionDialect of the type's
+// adapter, if it brings one.
+func completionDialectOf(ct common.ConnType) (CompletionDialect, bool) {
+	adapter, ok := LookupAdapter(ct)
+	if !ok {
+		return nil, false
+	}
+	dialect, ok := adapter.(CompletionDialect)
+	return dialect, ok
+}
+
 // lookupConnTypeForLabel resolves a connection label to its type via
 // the on-disk registry. The reserved "jq" label is recognised without
 // a registry lookup.
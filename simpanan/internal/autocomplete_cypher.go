@@ -0,0 +1,243 @@
+package internal
+
+import (
+	"regexp"
+	"strings"
+)
+
+// The Cypher completion dialect, which neo4jAdapter brings through
+// CompletionDialect: keywords and procedures, node labels, relationship
+// types and property keys. A Neo4j cache holds node labels as
+// collections and relationship types as tables, each with its property
+// keys.
+
+const (
+	CtxCypherKeywordPrefix    CompletionContext = "cypher_keyword_prefix"
+	CtxCypherLabelExpected    CompletionContext = "cypher_label_expected"
+	CtxCypherRelTypeExpected  CompletionContext = "cypher_reltype_expected"
+	CtxCypherPropertyExpected CompletionContext = "cypher_property_expected"
+)
+
+const (
+	SuggestionCypherKeyword  SuggestionKind = "cypher_keyword"
+	SuggestionCypherLabel    SuggestionKind = "cypher_label"
+	SuggestionCypherRelType  SuggestionKind = "cypher_reltype"
+	SuggestionCypherProperty SuggestionKind = "cypher_property"
+)
+
+var cypherKeywords = []string{
+	"MATCH", "OPTIONAL", "WHERE", "RETURN", "WITH", "UNWIND", "AS",
+	"ORDER", "BY", "ASC", "DESC", "SKIP", "LIMIT", "DISTINCT",
+	"UNION", "ALL", "CALL", "YIELD", "CASE", "WHEN", "THEN", "ELSE", "END",
+	"AND", "OR", "XOR", "NOT", "IN", "IS", "NULL", "TRUE", "FALSE",
+	"STARTS", "ENDS", "CONTAINS", "EXISTS",
+	"CREATE", "MERGE", "ON", "SET", "DELETE", "DETACH", "REMOVE", "FOREACH",
+	"INDEX", "CONSTRAINT", "FOR", "REQUIRE", "UNIQUE", "DROP",
+	"SHOW", "DATABASES", "INDEXES", "CONSTRAINTS", "PROCEDURES", "FUNCTIONS",
+	"EXPLAIN", "PROFILE",
+	// Functions.
+	"count", "collect", "sum", "avg", "min", "max",
+	"id", "elementId", "labels", "type", "properties", "keys",
+	"nodes", "relationships", "length", "size", "head", "last", "tail",
+	"coalesce", "exists", "toString", "toInteger", "toFloat", "toLower", "toUpper",
+	"shortestPath", "allShortestPaths", "startNode", "endNode",
+	"date", "datetime", "timestamp", "duration", "point",
+	// Schema procedures.
+	"db.labels", "db.relationshipTypes", "db.propertyKeys",
+	"db.schema.visualization", "db.schema.nodeTypeProperties", "db.schema.relTypeProperties",
+}
+
+// cypherTrailingNameRe matches a label or type just written at the end
+// of a pattern so far: `(u:User` or `[:MEMBER_OF`.
+var cypherTrailingNameRe = regexp.MustCompile("[:|]\\s*`?([a-zA-Z_][a-zA-Z0-9_]*)`?\\s*$")
+
+// cypherCallRe matches a body that ends in CALL, where a procedure
+// name follows.
+var cypherCallRe = regexp.MustCompile(`(?i)\bCALL\s+$`)
+
+// classifyCypher reads the pattern open at the cursor. After `:` (or a
+// `|` alternative) a label is expected inside `(...)` and at the top
+// level (`WHERE n:`), a relationship type inside `[...]`. After `var.`,
+// and at a key position of a `{...}` map, a property is expected; the
+// label the pattern binds to that variable, or that the map belongs
+// to, is the Scope, so suggestions can narrow to its keys. Elsewhere,
+// a keyword, or a procedure after CALL.
+func classifyCypher(stageContent, prefix string) ContextClassification {
+	body := stageContent[:len(stageContent)-len(prefix)]
+	if cypherCallRe.MatchString(body) {
+		// A procedure name: `CALL db.schema.`.
+		return ContextClassification{Context: CtxCypherKeywordPrefix, Prefix: prefix}
+	}
+	if variable, _ := splitPrefix(prefix); variable != "" {
+		return ContextClassification{Context: CtxCypherPropertyExpected, Prefix: prefix, Scope: cypherScope(cypherBoundLabel(body, variable))}
+	}
+
+	// Offsets of the brackets still open before the word being typed.
+	var open []int
+	for i := 0; i < len(body); i++ {
+		switch c := body[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			end := i + 1
+			for end < len(body) && body[end] != c {
+				if body[end] == '\\' && c != '`' {
+					end++
+				}
+				end++
+			}
+			if end >= len(body) {
+				return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+			}
+			i = end
+		case strings.HasPrefix(body[i:], "//"):
+			nl := strings.IndexByte(body[i:], '\n')
+			if nl < 0 {
+				return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+			}
+			i += nl
+		case strings.HasPrefix(body[i:], "/*"):
+			end := strings.Index(body[i+2:], "*/")
+			if end < 0 {
+				return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+			}
+			i += end + 3
+		case c == '(' || c == '[' || c == '{':
+			open = append(open, i)
+		case c == ')' || c == ']' || c == '}':
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+		}
+	}
+
+	trimmed := strings.TrimRight(body, " \t\r\n")
+	var last, bracket byte
+	if trimmed != "" {
+		last = trimmed[len(trimmed)-1]
+	}
+	if len(open) > 0 {
+		bracket = body[open[len(open)-1]]
+	}
+	alternative := last == '|' && cypherTrailingNameRe.MatchString(trimmed[:len(trimmed)-1])
+	switch {
+	case bracket == '[' && (last == ':' || alternative):
+		return ContextClassification{Context: CtxCypherRelTypeExpected, Prefix: prefix}
+	case bracket != '{' && (last == ':' || alternative):
+		return ContextClassification{Context: CtxCypherLabelExpected, Prefix: prefix}
+	case bracket == '{' && (last == '{' || last == ','):
+		var label string
+		if m := cypherTrailingNameRe.FindStringSubmatch(body[:open[len(open)-1]]); m != nil {
+			label = m[1]
+		}
+		return ContextClassification{Context: CtxCypherPropertyExpected, Prefix: prefix, Scope: cypherScope(label)}
+	case bracket == '{':
+		// A map value is an expression; nothing specific to offer.
+		return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+	}
+	return ContextClassification{Context: CtxCypherKeywordPrefix, Prefix: prefix}
+}
+
+// cypherBoundLabel finds the first label or type a pattern gives
+// variable, as in `(u:User` or `[m:MEMBER_OF`.
+func cypherBoundLabel(body, variable string) string {
+	re := regexp.MustCompile(`[(\[]\s*` + regexp.QuoteMeta(variable) + "\\s*:\\s*`?([a-zA-Z_][a-zA-Z0-9_]*)")
+	if m := re.FindStringSubmatch(body); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// cypherScope is the Scope of a property position: the label its
+// variable or map is bound to, when the pattern names one.
+func cypherScope(label string) []string {
+	if label == "" {
+		return nil
+	}
+	return []string{label}
+}
+
+func suggestCypher(cc ContextClassification) []Suggestion {
+	_, completion := splitPrefix(cc.Prefix)
+	switch cc.Context {
+	case CtxCypherKeywordPrefix:
+		// Procedure names are dotted, so match the whole prefix.
+		return suggestCypherKeywords(cc.Prefix)
+	case CtxCypherLabelExpected:
+		return suggestCypherLabels(cc.ConnectionLabel, completion)
+	case CtxCypherRelTypeExpected:
+		return suggestCypherRelTypes(cc.ConnectionLabel, completion)
+	case CtxCypherPropertyExpected:
+		var cypherLabel string
+		if len(cc.Scope) > 0 {
+			cypherLabel = cc.Scope[0]
+		}
+		return suggestCypherProperties(cc.ConnectionLabel, cypherLabel, completion)
+	}
+	return nil
+}
+
+func suggestCypherKeywords(prefix string) []Suggestion {
+	return asSuggestions(filterByPrefix(cypherKeywords, prefix), SuggestionCypherKeyword)
+}
+
+func suggestCypherLabels(label, prefix string) []Suggestion {
+	cache, _ := EnsureSchemaCache(label)
+	if cache == nil {
+		return nil
+	}
+	var labels []string
+	for _, db := range cache.Databases {
+		for _, c := range db.Collections {
+			labels = append(labels, c.Name)
+		}
+	}
+	return asSuggestions(filterByPrefix(labels, prefix), SuggestionCypherLabel)
+}
+
+func suggestCypherRelTypes(label, prefix string) []Suggestion {
+	cache, _ := EnsureSchemaCache(label)
+	if cache == nil {
+		return nil
+	}
+	var types []string
+	for _, db := range cache.Databases {
+		for _, t := range db.Tables {
+			types = append(types, t.Name)
+		}
+	}
+	return asSuggestions(filterByPrefix(types, prefix), SuggestionCypherRelType)
+}
+
+// suggestCypherProperties offers the property keys of cypherLabel, a
+// node label or relationship type. When the label is not known, or not
+// cached, every cached property key is offered instead.
+func suggestCypherProperties(label, cypherLabel, prefix string) []Suggestion {
+	cache, _ := EnsureSchemaCache(label)
+	if cache == nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var all []string
+	add := func(keys []string) {
+		for _, k := range keys {
+			if _, ok := seen[k]; !ok {
+				seen[k] = struct{}{}
+				all = append(all, k)
+			}
+		}
+	}
+	for _, db := range cache.Databases {
+		for _, c := range db.Collections {
+			if cypherLabel != "" && c.Name == cypherLabel {
+				return asSuggestions(filterByPrefix(c.Fields, prefix), SuggestionCypherProperty)
+			}
+			add(c.Fields)
+		}
+		for _, t := range db.Tables {
+			if cypherLabel != "" && t.Name == cypherLabel {
+				return asSuggestions(filterByPrefix(t.Columns, prefix), SuggestionCypherProperty)
+			}
+			add(t.Columns)
+		}
+	}
+	return asSuggestions(filterByPrefix(all, prefix), SuggestionCypherProperty)
+}
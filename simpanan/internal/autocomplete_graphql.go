@@ -0,0 +1,189 @@
+package internal
+
+import "simpanan/internal/common"
+
+// The GraphQL completion dialect, which graphqlAdapter brings through
+// CompletionDialect: keywords outside any selection set, type names
+// after `on` and in variable definitions, and the fields of the type
+// selected under the cursor.
+
+const (
+	CtxGraphqlKeywordPrefix CompletionContext = "graphql_keyword_prefix"
+	CtxGraphqlTypeExpected  CompletionContext = "graphql_type_expected"
+	CtxGraphqlFieldExpected CompletionContext = "graphql_field_expected"
+)
+
+const (
+	SuggestionGraphqlKeyword SuggestionKind = "graphql_keyword"
+	SuggestionGraphqlType    SuggestionKind = "graphql_type"
+	SuggestionGraphqlField   SuggestionKind = "graphql_field"
+)
+
+var graphqlKeywords = []string{
+	"query", "mutation", "subscription", "fragment", "on",
+	"true", "false", "null",
+}
+
+// graphqlRootTypes are the conventional names of the operations' root
+// types, which selection sets at the top of a document belong to.
+var graphqlRootTypes = map[string]string{
+	"query":        "Query",
+	"mutation":     "Mutation",
+	"subscription": "Subscription",
+}
+
+// classifyGraphql follows the selection sets open at the cursor.
+// Inside one, the path from its root type down to the innermost field
+// is the Scope, so suggestions can offer that field's type's fields.
+// After `on`, and after `:` in variable definitions, a type name is
+// expected; elsewhere outside any selection set, a keyword.
+func classifyGraphql(stageContent, prefix string) ContextClassification {
+	toks := common.TokenizeGraphql(stageContent)
+	// The word being typed is what is completed, not context.
+	if n := len(toks); n > 0 && prefix != "" && toks[n-1].Kind == common.GraphqlName && toks[n-1].End() == len(stageContent) {
+		toks = toks[:n-1]
+	}
+
+	var stack [][]string
+	parens := 0
+	// next is what the next `{` opens: a type name when nextIsType,
+	// else a field of the enclosing selection set.
+	next, nextIsType := "", false
+	var prev common.GraphqlToken
+	for _, t := range toks {
+		punct := t.Kind == common.GraphqlPunct
+		switch {
+		case punct && t.Text == "(":
+			parens++
+		case punct && t.Text == ")":
+			parens--
+		case parens > 0:
+		case punct && t.Text == "{":
+			var path []string
+			switch {
+			case nextIsType:
+				path = []string{next}
+			case len(stack) == 0:
+				path = []string{graphqlRootTypes["query"]}
+			case next == "":
+				// `... @include(if: $x) {` keeps the enclosing type.
+				path = stack[len(stack)-1]
+			default:
+				path = append(append([]string(nil), stack[len(stack)-1]...), next)
+			}
+			stack = append(stack, path)
+			next, nextIsType = "", false
+		case punct && t.Text == "}":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			next, nextIsType = "", false
+		case punct && t.Text == "...":
+			next, nextIsType = "", false
+		case t.Kind == common.GraphqlName:
+			switch {
+			case prev.Kind == common.GraphqlPunct && prev.Text == "@":
+				// A directive name selects no field.
+			case prev.Kind == common.GraphqlName && prev.Text == "on":
+				next, nextIsType = t.Text, true
+			case t.Text == "on":
+			case len(stack) == 0:
+				if root, ok := graphqlRootTypes[t.Text]; ok {
+					next, nextIsType = root, true
+				}
+			case prev.Kind == common.GraphqlPunct && prev.Text == "...":
+				// A fragment spread selects no field.
+				next, nextIsType = "", false
+			default:
+				next, nextIsType = t.Text, false
+			}
+		}
+		prev = t
+	}
+
+	switch {
+	case prev.Kind == common.GraphqlName && prev.Text == "on",
+		parens > 0 && len(stack) == 0 && prev.Kind == common.GraphqlPunct && (prev.Text == ":" || prev.Text == "["):
+		return ContextClassification{Context: CtxGraphqlTypeExpected, Prefix: prefix}
+	case parens > 0:
+		return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+	case len(stack) == 0:
+		return ContextClassification{Context: CtxGraphqlKeywordPrefix, Prefix: prefix}
+	}
+	return ContextClassification{Context: CtxGraphqlFieldExpected, Prefix: prefix, Scope: stack[len(stack)-1]}
+}
+
+func suggestGraphql(cc ContextClassification) []Suggestion {
+	_, completion := splitPrefix(cc.Prefix)
+	switch cc.Context {
+	case CtxGraphqlKeywordPrefix:
+		return suggestGraphqlKeywords(completion)
+	case CtxGraphqlTypeExpected:
+		return suggestGraphqlTypes(cc.ConnectionLabel, completion)
+	case CtxGraphqlFieldExpected:
+		return suggestGraphqlFields(cc.ConnectionLabel, cc.Scope, completion)
+	}
+	return nil
+}
+
+// graphqlBuiltinScalars are offered wherever a type name is expected,
+// alongside the cached types.
+var graphqlBuiltinScalars = []string{"ID", "String", "Int", "Float", "Boolean"}
+
+func suggestGraphqlKeywords(prefix string) []Suggestion {
+	return asSuggestions(filterByPrefix(graphqlKeywords, prefix), SuggestionGraphqlKeyword)
+}
+
+func suggestGraphqlTypes(label, prefix string) []Suggestion {
+	types := append([]string(nil), graphqlBuiltinScalars...)
+	if cache, _ := EnsureSchemaCache(label); cache != nil {
+		for _, db := range cache.Databases {
+			for _, c := range db.Collections {
+				types = append(types, c.Name)
+			}
+		}
+	}
+	return asSuggestions(filterByPrefix(types, prefix), SuggestionGraphqlType)
+}
+
+// suggestGraphqlFields follows path from its type through each
+// selected field's type and offers the fields of the type it ends at.
+// A path the cache cannot follow (an unknown field, or a root type
+// not named Query/Mutation) offers every cached field instead.
+func suggestGraphqlFields(label string, path []string, prefix string) []Suggestion {
+	cache, _ := EnsureSchemaCache(label)
+	if cache == nil {
+		return nil
+	}
+	types := map[string]CollectionSchema{}
+	for _, db := range cache.Databases {
+		for _, c := range db.Collections {
+			types[c.Name] = c
+		}
+	}
+
+	if len(path) > 0 {
+		current, ok := types[path[0]]
+		for _, field := range path[1:] {
+			if !ok {
+				break
+			}
+			current, ok = types[current.FieldTypes[field]]
+		}
+		if ok {
+			return asSuggestions(filterByPrefix(current.Fields, prefix), SuggestionGraphqlField)
+		}
+	}
+
+	seen := map[string]struct{}{}
+	var fields []string
+	for _, c := range types {
+		for _, f := range c.Fields {
+			if _, ok := seen[f]; !ok {
+				seen[f] = struct{}{}
+				fields = append(fields, f)
+			}
+		}
+	}
+	return asSuggestions(filterByPrefix(fields, prefix), SuggestionGraphqlField)
+}
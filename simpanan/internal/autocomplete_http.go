@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"simpanan/internal/common"
+	"strings"
+)
+
+// The HTTP completion dialect, which httpAdapter brings through
+// CompletionDialect: the method is offered while the first word of a
+// stage is typed. Search stages share its context and suggestion kind.
+
+const CtxHttpMethodPrefix CompletionContext = "http_method_prefix"
+
+const SuggestionHttpMethod SuggestionKind = "http_method"
+
+var httpMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// classifyHttp offers the HTTP methods while the first word of a
+// generic HTTP stage is typed. Paths and bodies are free-form.
+func classifyHttp(stageContent, prefix string) ContextClassification {
+	stage := common.ParseHTTPStage(stageContent)
+	finished := strings.TrimRight(stageContent, " \t\r\n") != stageContent
+	typingMethod := stage.Method == "" && !strings.Contains(stage.Target, "/")
+
+	switch {
+	case stage.Body != "":
+	case stage.Target == "" && stage.Method == "",
+		stage.Target == "" && !finished,
+		typingMethod && !finished:
+		return ContextClassification{Context: CtxHttpMethodPrefix, Prefix: prefix}
+	}
+	return ContextClassification{Context: CtxUnknown, Prefix: prefix}
+}
+
+func suggestHttp(cc ContextClassification) []Suggestion {
+	if cc.Context != CtxHttpMethodPrefix {
+		return nil
+	}
+	_, completion := splitPrefix(cc.Prefix)
+	return suggestHttpMethods(httpMethods, completion)
+}
+
+func suggestHttpMethods(methods []string, prefix string) []Suggestion {
+	return asSuggestions(filterByPrefix(methods, prefix), SuggestionHttpMethod)
+}
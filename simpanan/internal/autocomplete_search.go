@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"simpanan/internal/common"
+	"strings"
+)
+
+// The search completion dialect, which elasticsearchAdapter brings
+// through CompletionDialect: the HTTP method, then the path's index
+// names and `_` endpoints, then the fields of the index in the body.
+
+const (
+	CtxSearchPathExpected  CompletionContext = "search_path_expected"
+	CtxSearchFieldExpected CompletionContext = "search_field_expected"
+)
+
+const (
+	SuggestionSearchIndex    SuggestionKind = "search_index"
+	SuggestionSearchEndpoint SuggestionKind = "search_endpoint"
+	SuggestionSearchField    SuggestionKind = "search_field"
+)
+
+var searchHttpMethods = []string{"GET", "POST", "PUT", "DELETE", "HEAD"}
+
+var searchEndpoints = []string{
+	// Reads
+	"_search", "_count", "_msearch", "_mget", "_explain",
+	"_field_caps", "_validate", "_analyze", "_source",
+	// Writes
+	"_doc", "_create", "_update", "_bulk",
+	"_update_by_query", "_delete_by_query", "_refresh",
+	// Admin, including the `_cat/<api>` names
+	"_cat", "_mapping", "_settings", "_aliases", "_cluster",
+	"indices", "health", "shards", "count", "aliases",
+}
+
+// classifySearch walks the request line of a search stage: the HTTP
+// method, then the path (index names and `_` endpoints), then the JSON
+// body, where the index's fields are what the user types. The index the
+// path names, if any, is the classification's Scope.
+func classifySearch(stageContent, prefix string) ContextClassification {
+	stage := common.ParseHTTPStage(stageContent)
+	// A trailing blank means the word before the cursor is finished.
+	finished := strings.TrimRight(stageContent, " \t\r\n") != stageContent
+	typingMethod := stage.Method == "" && !strings.ContainsAny(stage.Target, "/_")
+
+	switch {
+	case stage.Body != "" || (stage.Target != "" && finished):
+		cc := ContextClassification{Context: CtxSearchFieldExpected, Prefix: prefix}
+		index, _, _ := strings.Cut(strings.TrimPrefix(stage.Target, "/"), "/")
+		if index != "" && !strings.HasPrefix(index, "_") {
+			cc.Scope = []string{index}
+		}
+		return cc
+	case stage.Target == "" && stage.Method == "",
+		stage.Target == "" && !finished,
+		typingMethod && !finished:
+		return ContextClassification{Context: CtxHttpMethodPrefix, Prefix: prefix}
+	}
+	return ContextClassification{Context: CtxSearchPathExpected, Prefix: prefix}
+}
+
+func suggestSearch(cc ContextClassification) []Suggestion {
+	_, completion := splitPrefix(cc.Prefix)
+	switch cc.Context {
+	case CtxHttpMethodPrefix:
+		return suggestHttpMethods(searchHttpMethods, completion)
+	case CtxSearchPathExpected:
+		return suggestSearchPaths(cc.ConnectionLabel, completion)
+	case CtxSearchFieldExpected:
+		var index string
+		if len(cc.Scope) > 0 {
+			index = cc.Scope[0]
+		}
+		return suggestSearchFields(cc.ConnectionLabel, index, cc.Prefix)
+	}
+	return nil
+}
+
+// suggestSearchPaths offers the cached index names, then the
+// endpoints, for the path segment under the cursor.
+func suggestSearchPaths(label, prefix string) []Suggestion {
+	var out []Suggestion
+	if cache, _ := EnsureSchemaCache(label); cache != nil {
+		var indices []string
+		for _, db := range cache.Databases {
+			for _, c := range db.Collections {
+				indices = append(indices, c.Name)
+			}
+		}
+		out = asSuggestions(filterByPrefix(indices, prefix), SuggestionSearchIndex)
+	}
+	return append(out, asSuggestions(filterByPrefix(searchEndpoints, prefix), SuggestionSearchEndpoint)...)
+}
+
+// suggestSearchFields offers the field paths of the index the stage's
+// path names, or of every cached index when it names none the cache
+// knows (e.g. a `logs-*` pattern). Paths are
+// dotted (`address.city`), so the whole prefix filters them rather than
+// the part after its last dot.
+func suggestSearchFields(label, index, prefix string) []Suggestion {
+	cache, _ := EnsureSchemaCache(label)
+	if cache == nil {
+		return nil
+	}
+	seen := map[string]struct{}{}
+	var fields []string
+	for _, db := range cache.Databases {
+		for _, c := range db.Collections {
+			if index != "" && c.Name != index {
+				continue
+			}
+			for _, f := range c.Fields {
+				if _, ok := seen[f]; !ok {
+					seen[f] = struct{}{}
+					fields = append(fields, f)
+				}
+			}
+		}
+	}
+	if fields == nil && index != "" {
+		return suggestSearchFields(label, "", prefix)
+	}
+	return asSuggestions(filterByPrefix(fields, prefix), SuggestionSearchField)
+}
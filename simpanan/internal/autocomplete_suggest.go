@@ -24,6 +24,21 @@ func ComputeSuggestions(cc ContextClassification) []Suggestion {
 	switch cc.Context {
 	case CtxStageStart:
 		return suggestStageLabels(completion)
+	case CtxJqPlaceholder:
+		// Path suggestions are produced by ProbeJqPaths in a later
+		// milestone; M6 returns operators only.
+		return suggestJqOperators(completion)
+	}
+
+	// A backend with a completion dialect of its own answers every
+	// other context of its stages.
+	if ct, ok := lookupConnTypeForLabel(cc.ConnectionLabel); ok {
+		if dialect, ok := completionDialectOf(ct); ok {
+			return dialect.Suggest(cc)
+		}
+	}
+
+	switch cc.Context {
 	case CtxSqlKeywordPrefix:
 		return suggestSqlKeywords(cc.ConnectionLabel, completion)
 	case CtxSqlTableExpected:
@@ -39,11 +54,7 @@ func ComputeSuggestions(cc ContextClassification) []Suggestion {
 	case CtxMongoFieldExpected:
 		return suggestMongoFields(cc.ConnectionLabel, completion)
 	case CtxRedisCommandPrefix:
-		return suggestRedisCommands(completion)
-	case CtxJqPlaceholder:
-		// Path suggestions are produced by ProbeJqPaths in a later
-		// milestone; M6 returns operators only.
-		return suggestJqOperators(completion)
+		return suggestRedisCommands(cc.ConnectionLabel, completion)
 	}
 	return nil
 }
@@ -263,8 +274,14 @@ func suggestMongoFields(label, prefix string) []Suggestion {
 
 // ---- redis_command_prefix -----------------------------------------
 
-func suggestRedisCommands(prefix string) []Suggestion {
-	cat := GetBuiltinCatalog(common.Redis)
+// suggestRedisCommands offers the command words of the label's
+// catalog: Redis's, or the get/list/put/delete of the KV stores.
+func suggestRedisCommands(label, prefix string) []Suggestion {
+	ct, ok := lookupConnTypeForLabel(label)
+	if !ok {
+		ct = common.Redis
+	}
+	cat := GetBuiltinCatalog(ct)
 	return asSuggestions(filterByPrefix(cat.RedisCommands, prefix), SuggestionRedisCommand)
 }
 
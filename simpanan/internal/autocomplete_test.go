@@ -108,6 +108,11 @@ func TestClassifyContext(t *testing.T) {
 		{Key: "pg", URI: "postgres://h/db"},
 		{Key: "mg", URI: "mongodb://h/db"},
 		{Key: "rd", URI: "redis://h"},
+		{Key: "es", URI: "es://h:9200"},
+		{Key: "api", URI: "https://api.example.com/v1"},
+		{Key: "gql", URI: "graphql+https://api.example.com/graphql"},
+		{Key: "gr", URI: "neo4j://graph:7687/perms"},
+		{Key: "flags", URI: "etcd://localhost:2379"},
 	})
 
 	cases := []struct {
@@ -131,6 +136,30 @@ func TestClassifyContext(t *testing.T) {
 		{"after db.app.users.", "|mg> db.app.users.", 18, CtxMongoOperationExpected},
 		{"inside $match field pos", "|mg> db.app.users.aggregate([{$match: {", 39, CtxMongoFieldExpected},
 		{"redis command prefix", "|rd> G", 6, CtxRedisCommandPrefix},
+		{"kv command prefix", "|flags> ge", 10, CtxRedisCommandPrefix},
+		{"search method prefix", "|es> GE", 7, CtxHttpMethodPrefix},
+		{"search path after method", "|es> GET ", 9, CtxSearchPathExpected},
+		{"search endpoint segment", "|es> GET products/_se", 21, CtxSearchPathExpected},
+		{"search path without method", "|es> _cat/ind", 13, CtxSearchPathExpected},
+		{"search body", `|es> GET products/_search {"query": {"match": {`, 47, CtxSearchFieldExpected},
+		{"http method prefix", "|api> PO", 8, CtxHttpMethodPrefix},
+		{"http path is free-form", "|api> GET /us", 13, CtxUnknown},
+		{"http body is free-form", `|api> POST /orders {"s`, 22, CtxUnknown},
+		{"graphql operation keyword", "|gql> mut", 9, CtxGraphqlKeywordPrefix},
+		{"graphql field", "|gql> { user(id: 1) { na", 24, CtxGraphqlFieldExpected},
+		{"graphql argument", "|gql> { user(i", 14, CtxUnknown},
+		{"graphql type condition", "|gql> { node { ... on U", 23, CtxGraphqlTypeExpected},
+		{"graphql variable type", "|gql> query($id: ", 17, CtxGraphqlTypeExpected},
+		{"cypher clause keyword", "|gr> MATCH (u) RET", 18, CtxCypherKeywordPrefix},
+		{"cypher node label", "|gr> MATCH (u:Us", 16, CtxCypherLabelExpected},
+		{"cypher label alternative", "|gr> MATCH (u:User|Ad", 21, CtxCypherLabelExpected},
+		{"cypher label predicate", "|gr> MATCH (u) WHERE u:", 23, CtxCypherLabelExpected},
+		{"cypher relationship type", "|gr> MATCH (u)-[:MEM", 20, CtxCypherRelTypeExpected},
+		{"cypher property", "|gr> MATCH (u:User) RETURN u.na", 31, CtxCypherPropertyExpected},
+		{"cypher map key", "|gr> MATCH (u:User {", 20, CtxCypherPropertyExpected},
+		{"cypher map value", "|gr> MATCH (u:User {name: ", 26, CtxUnknown},
+		{"cypher string", "|gr> MATCH (u {name: 'a:", 24, CtxUnknown},
+		{"cypher procedure", "|gr> CALL db.sch", 16, CtxCypherKeywordPrefix},
 		{"jq placeholder in later stage", "|pg> SELECT 1\n|pg> SELECT {{.foo", 32, CtxJqPlaceholder},
 		{"explicit jq stage", "|jq> .", 6, CtxJqPlaceholder},
 		{"unknown label", "|other> SELECT", 14, CtxUnknown},
@@ -438,6 +467,176 @@ func TestComputeSuggestions_MongoFieldExpected(t *testing.T) {
 	}
 }
 
+func TestClassifyContext_SearchBodyNamesIndex(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "es", URI: "es://h:9200"}})
+
+	buf := "|es> POST /products/_search\n{\"query\": {\"term\": {\"ti"
+	got := ClassifyContext(buf, len(buf))
+	assert.Equal(t, CtxSearchFieldExpected, got.Context)
+	assert.Equal(t, []string{"products"}, got.Scope)
+	assert.Equal(t, "ti", got.Prefix)
+
+	buf = "|es> GET _search "
+	got = ClassifyContext(buf, len(buf))
+	assert.Equal(t, CtxSearchFieldExpected, got.Context)
+	assert.Empty(t, got.Scope)
+}
+
+func TestComputeSuggestions_Search(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "es", URI: "es://h:9200"}})
+	assert.NoError(t, SaveSchemaCache(&SchemaCache{
+		ConnectionLabel: "es",
+		PopulatedAt:     timePtr(time.Now()),
+		Databases: []DatabaseSchema{
+			{Name: "search-dev", Collections: []CollectionSchema{
+				{Name: "products", Fields: []string{"title", "title.keyword", "price"}},
+				{Name: "orders", Fields: []string{"total", "placed_at"}},
+			}},
+		},
+	}))
+
+	got := ComputeSuggestions(ContextClassification{Context: CtxHttpMethodPrefix, ConnectionLabel: "es", Prefix: "P"})
+	assert.Equal(t, []string{"POST", "PUT"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxSearchPathExpected, ConnectionLabel: "es", Prefix: "pro"})
+	assert.Equal(t, []Suggestion{{Text: "products", Kind: SuggestionSearchIndex}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxSearchPathExpected, ConnectionLabel: "es", Prefix: "_se"})
+	assert.Equal(t, []Suggestion{{Text: "_search", Kind: SuggestionSearchEndpoint}, {Text: "_settings", Kind: SuggestionSearchEndpoint}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxSearchFieldExpected, ConnectionLabel: "es", Scope: []string{"products"}, Prefix: "title."})
+	assert.Equal(t, []Suggestion{{Text: "title.keyword", Kind: SuggestionSearchField}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxSearchFieldExpected, ConnectionLabel: "es", Scope: []string{"logs-*"}, Prefix: "p"})
+	assert.Equal(t, []string{"placed_at", "price"}, suggestionTexts(got))
+}
+
+func TestClassifyContext_GraphqlPath(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "gql", URI: "graphql+https://api.example.com/graphql"}})
+
+	cases := []struct {
+		name string
+		buf  string
+		want []string
+	}{
+		{"bare selection set", "|gql> { us", []string{"Query"}},
+		{"nested with alias and args", "|gql> query Q($id: ID!) { me: user(id: $id) { posts(first: {{.n}}) { ti", []string{"Query", "user", "posts"}},
+		{"closed sibling", "|gql> mutation { addUser(name: \"a\") { id } other { ", []string{"Mutation", "other"}},
+		{"directive and spread", "|gql> { user { name @include(if: true) ...F  po", []string{"Query", "user"}},
+		{"inline fragment", "|gql> { node { ... on User { ", []string{"User"}},
+		{"fragment definition", "|gql> fragment F on User { posts { ", []string{"User", "posts"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ClassifyContext(tc.buf, len(tc.buf))
+			assert.Equal(t, CtxGraphqlFieldExpected, got.Context)
+			assert.Equal(t, tc.want, got.Scope)
+		})
+	}
+}
+
+func TestComputeSuggestions_Graphql(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "gql", URI: "graphql+https://api.example.com/graphql"}})
+	assert.NoError(t, SaveSchemaCache(&SchemaCache{
+		ConnectionLabel: "gql",
+		PopulatedAt:     timePtr(time.Now()),
+		Databases: []DatabaseSchema{
+			{Name: "api.example.com", Collections: []CollectionSchema{
+				{Name: "Query", Fields: []string{"user", "users"}, FieldTypes: map[string]string{"user": "User", "users": "User"}},
+				{Name: "User", Fields: []string{"id", "name", "posts"}, FieldTypes: map[string]string{"id": "ID", "name": "String", "posts": "Post"}},
+				{Name: "Post", Fields: []string{"title", "published"}, FieldTypes: map[string]string{"title": "String", "published": "Boolean"}},
+			}},
+		},
+	}))
+
+	got := ComputeSuggestions(ContextClassification{Context: CtxGraphqlKeywordPrefix, ConnectionLabel: "gql", Prefix: "m"})
+	assert.Equal(t, []Suggestion{{Text: "mutation", Kind: SuggestionGraphqlKeyword}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxGraphqlTypeExpected, ConnectionLabel: "gql", Prefix: "P"})
+	assert.Equal(t, []Suggestion{{Text: "Post", Kind: SuggestionGraphqlType}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxGraphqlFieldExpected, ConnectionLabel: "gql", Scope: []string{"Query", "user", "posts"}})
+	assert.Equal(t, []string{"published", "title"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxGraphqlFieldExpected, ConnectionLabel: "gql", Scope: []string{"Query"}, Prefix: "u"})
+	assert.Equal(t, []string{"user", "users"}, suggestionTexts(got))
+
+	// An unknown field falls back to every cached field.
+	got = ComputeSuggestions(ContextClassification{Context: CtxGraphqlFieldExpected, ConnectionLabel: "gql", Scope: []string{"Query", "nope"}, Prefix: "p"})
+	assert.Equal(t, []string{"posts", "published"}, suggestionTexts(got))
+}
+
+func TestClassifyContext_CypherLabel(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "gr", URI: "neo4j://graph:7687/perms"}})
+
+	cases := []struct {
+		name string
+		buf  string
+		want []string
+	}{
+		{"node variable", "|gr> MATCH (u:User)-[:MEMBER_OF]->(g) RETURN u.", []string{"User"}},
+		{"relationship variable", "|gr> MATCH (u)-[m:MEMBER_OF]->(g) WHERE m.ro", []string{"MEMBER_OF"}},
+		{"unbound variable", "|gr> MATCH (u:User)-[:MEMBER_OF]->(g) RETURN g.", nil},
+		{"map after label", "|gr> MATCH (u:User {name: 'a', ", []string{"User"}},
+		{"map after type", "|gr> MATCH (u)-[:MEMBER_OF {", []string{"MEMBER_OF"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ClassifyContext(tc.buf, len(tc.buf))
+			assert.Equal(t, CtxCypherPropertyExpected, got.Context)
+			assert.Equal(t, tc.want, got.Scope)
+		})
+	}
+}
+
+func TestComputeSuggestions_Cypher(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{{Key: "gr", URI: "neo4j://graph:7687/perms"}})
+	assert.NoError(t, SaveSchemaCache(&SchemaCache{
+		ConnectionLabel: "gr",
+		PopulatedAt:     timePtr(time.Now()),
+		Databases: []DatabaseSchema{
+			{Name: "perms",
+				Collections: []CollectionSchema{
+					{Name: "User", Fields: []string{"email", "name"}},
+					{Name: "Group", Fields: []string{"name", "owner"}},
+				},
+				Tables: []TableSchema{
+					{Name: "MEMBER_OF", Columns: []string{"role", "since"}},
+					{Name: "MANAGES", Columns: []string{}},
+				},
+			},
+		},
+	}))
+
+	got := ComputeSuggestions(ContextClassification{Context: CtxCypherKeywordPrefix, ConnectionLabel: "gr", Prefix: "MER"})
+	assert.Equal(t, []Suggestion{{Text: "MERGE", Kind: SuggestionCypherKeyword}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxCypherKeywordPrefix, ConnectionLabel: "gr", Prefix: "db.schema.n"})
+	assert.Equal(t, []string{"db.schema.nodeTypeProperties"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxCypherLabelExpected, ConnectionLabel: "gr", Prefix: "G"})
+	assert.Equal(t, []Suggestion{{Text: "Group", Kind: SuggestionCypherLabel}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxCypherRelTypeExpected, ConnectionLabel: "gr", Prefix: "M"})
+	assert.Equal(t, []string{"MANAGES", "MEMBER_OF"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxCypherPropertyExpected, ConnectionLabel: "gr", Prefix: "u.", Scope: []string{"User"}})
+	assert.Equal(t, []string{"email", "name"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxCypherPropertyExpected, ConnectionLabel: "gr", Prefix: "m.", Scope: []string{"MEMBER_OF"}})
+	assert.Equal(t, []Suggestion{{Text: "role", Kind: SuggestionCypherProperty}, {Text: "since", Kind: SuggestionCypherProperty}}, got)
+
+	// An unbound variable offers every property key.
+	got = ComputeSuggestions(ContextClassification{Context: CtxCypherPropertyExpected, ConnectionLabel: "gr", Prefix: "g."})
+	assert.Equal(t, []string{"email", "name", "owner", "role", "since"}, suggestionTexts(got))
+}
+
 func TestComputeSuggestions_RedisCommandPrefix(t *testing.T) {
 	cases := []struct {
 		name   string
@@ -457,6 +656,28 @@ func TestComputeSuggestions_RedisCommandPrefix(t *testing.T) {
 	}
 }
 
+func TestComputeSuggestions_KvCommands(t *testing.T) {
+	home := withTempHome(t)
+	seedConnections(t, home, []common.KeyURIPair{
+		{Key: "flags", URI: "etcd://localhost:2379"},
+		{Key: "svc", URI: "consul://localhost:8500"},
+		{Key: "cache", URI: "memcached://localhost:11211"},
+		{Key: "events", URI: "kafka://localhost:9092"},
+	})
+
+	got := ComputeSuggestions(ContextClassification{Context: CtxRedisCommandPrefix, ConnectionLabel: "flags", Prefix: "p"})
+	assert.Equal(t, []Suggestion{{Text: "put", Kind: SuggestionRedisCommand}}, got)
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxRedisCommandPrefix, ConnectionLabel: "cache", Prefix: "ge"})
+	assert.ElementsMatch(t, []string{"get", "gets"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxRedisCommandPrefix, ConnectionLabel: "events", Prefix: "list-"})
+	assert.ElementsMatch(t, []string{"list-topics", "list-partitions"}, suggestionTexts(got))
+
+	got = ComputeSuggestions(ContextClassification{Context: CtxRedisCommandPrefix, ConnectionLabel: "svc", Prefix: "--"})
+	assert.ElementsMatch(t, []string{"--prefix", "--limit"}, suggestionTexts(got))
+}
+
 func TestComputeSuggestions_JqPlaceholder(t *testing.T) {
 	got := ComputeSuggestions(ContextClassification{Context: CtxJqPlaceholder, Prefix: ""})
 	// M6 returns operators only; path-mining arrives in M7.
@@ -0,0 +1,165 @@
+package common
+
+import "strings"
+
+// GraphqlTokenKind tells the lexical classes of a GraphQL document apart.
+type GraphqlTokenKind int
+
+const (
+	// GraphqlPunct is one of `! & ( ) ... : = @ [ ] { | }`.
+	GraphqlPunct GraphqlTokenKind = iota
+	GraphqlName
+	// GraphqlVariable is `$name`, dollar included.
+	GraphqlVariable
+	GraphqlNumber
+	GraphqlString
+	// GraphqlPlaceholder is a `{{jq}}` placeholder that has not been
+	// piped yet, kept whole so its braces do not count as selections.
+	GraphqlPlaceholder
+)
+
+// GraphqlToken is one token of a GraphQL document and its byte offset.
+type GraphqlToken struct {
+	Kind   GraphqlTokenKind
+	Text   string
+	Offset int
+}
+
+// End is the offset just past the token.
+func (t GraphqlToken) End() int { return t.Offset + len(t.Text) }
+
+// TokenizeGraphql splits a GraphQL document into tokens, dropping
+// whitespace, commas and `#` comments. It never fails: an unterminated
+// string runs to the end of the document, so half-typed stages can be
+// tokenized for autocomplete.
+func TokenizeGraphql(doc string) []GraphqlToken {
+	var toks []GraphqlToken
+	for i := 0; i < len(doc); {
+		c := doc[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ',':
+			i++
+			continue
+		case c == '#':
+			for i < len(doc) && doc[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(doc[i:], "{{"):
+			end := strings.Index(doc[i:], "}}")
+			if end < 0 {
+				i = len(doc)
+			} else {
+				i += end + 2
+			}
+			toks = append(toks, GraphqlToken{GraphqlPlaceholder, doc[start:i], start})
+			continue
+		case strings.HasPrefix(doc[i:], "..."):
+			i += 3
+			toks = append(toks, GraphqlToken{GraphqlPunct, "...", start})
+			continue
+		case strings.HasPrefix(doc[i:], `"""`):
+			i += 3
+			for i < len(doc) && !strings.HasPrefix(doc[i:], `"""`) {
+				if strings.HasPrefix(doc[i:], `\"""`) {
+					i += 4
+					continue
+				}
+				i++
+			}
+			i = min(i+3, len(doc))
+			toks = append(toks, GraphqlToken{GraphqlString, doc[start:i], start})
+			continue
+		case c == '"':
+			i++
+			for i < len(doc) && doc[i] != '"' && doc[i] != '\n' {
+				if doc[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			i = min(i+1, len(doc))
+			toks = append(toks, GraphqlToken{GraphqlString, doc[start:i], start})
+			continue
+		case c == '$':
+			i++
+			for i < len(doc) && isGraphqlNameByte(doc[i]) {
+				i++
+			}
+			toks = append(toks, GraphqlToken{GraphqlVariable, doc[start:i], start})
+			continue
+		case isGraphqlNameByte(c) && !isDigit(c):
+			for i < len(doc) && isGraphqlNameByte(doc[i]) {
+				i++
+			}
+			toks = append(toks, GraphqlToken{GraphqlName, doc[start:i], start})
+			continue
+		case isDigit(c) || c == '-':
+			i++
+			for i < len(doc) && (isGraphqlNameByte(doc[i]) || doc[i] == '.' || doc[i] == '+' || doc[i] == '-') {
+				i++
+			}
+			toks = append(toks, GraphqlToken{GraphqlNumber, doc[start:i], start})
+			continue
+		}
+		i++
+		toks = append(toks, GraphqlToken{GraphqlPunct, doc[start:i], start})
+	}
+	return toks
+}
+
+// GraphqlOperation is one operation of a GraphQL document: its kind
+// ("query", "mutation" or "subscription") and the index of its first
+// token, the keyword or the `{` of a bare selection set.
+type GraphqlOperation struct {
+	Kind string
+	At   int
+}
+
+// GraphqlOperations lists the operations of a tokenized GraphQL
+// document in order. A bare selection set (`{ users { id } }`) is a
+// query; fragment definitions are skipped.
+func GraphqlOperations(toks []GraphqlToken) []GraphqlOperation {
+	var ops []GraphqlOperation
+	braces, parens := 0, 0
+	definitionStart := true
+	for i, t := range toks {
+		if t.Kind == GraphqlPunct {
+			switch t.Text {
+			case "(":
+				parens++
+			case ")":
+				parens--
+			case "{":
+				if braces == 0 && parens == 0 && definitionStart {
+					ops = append(ops, GraphqlOperation{"query", i})
+				}
+				if parens == 0 {
+					braces++
+					definitionStart = false
+				}
+			case "}":
+				if parens == 0 {
+					braces--
+					definitionStart = braces == 0
+				}
+			}
+			continue
+		}
+		if t.Kind == GraphqlName && braces == 0 && parens == 0 && definitionStart {
+			switch t.Text {
+			case "query", "mutation", "subscription":
+				ops = append(ops, GraphqlOperation{t.Text, i})
+			}
+			definitionStart = false
+		}
+	}
+	return ops
+}
+
+func isGraphqlNameByte(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
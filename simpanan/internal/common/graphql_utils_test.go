@@ -0,0 +1,44 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenizeGraphql(t *testing.T) {
+	toks := TokenizeGraphql("query($id: ID!) { # who\n  u: user(id: $id, tags: [\"a,b\"]) { ...F @skip(if: false) } note: {{.x}} }")
+	var texts []string
+	for _, tok := range toks {
+		texts = append(texts, tok.Text)
+	}
+	assert.Equal(t, []string{
+		"query", "(", "$id", ":", "ID", "!", ")", "{",
+		"u", ":", "user", "(", "id", ":", "$id", "tags", ":", "[", `"a,b"`, "]", ")",
+		"{", "...", "F", "@", "skip", "(", "if", ":", "false", ")", "}",
+		"note", ":", "{{.x}}", "}",
+	}, texts)
+	assert.Equal(t, GraphqlVariable, toks[2].Kind)
+	assert.Equal(t, GraphqlPlaceholder, toks[34].Kind)
+	assert.Equal(t, 6, toks[2].Offset)
+	assert.Equal(t, 9, toks[2].End())
+}
+
+func TestGraphqlOperations(t *testing.T) {
+	tests := []struct {
+		doc  string
+		want []GraphqlOperation
+	}{
+		{`{ users { id } }`, []GraphqlOperation{{"query", 0}}},
+		{`query Users($f: Filter = {a: 1}) { users(f: $f) { id } }`, []GraphqlOperation{{"query", 0}}},
+		{`mutation { addUser(name: "x") { id } }`, []GraphqlOperation{{"mutation", 0}}},
+		{`fragment F on User { id } mutation M { a { ...F } }`, []GraphqlOperation{{"mutation", 7}}},
+		{`query A { a } query B { b } subscription S { s }`, []GraphqlOperation{{"query", 0}, {"query", 5}, {"subscription", 10}}},
+		{`  # just a comment`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.doc, func(t *testing.T) {
+			assert.Equal(t, tt.want, GraphqlOperations(TokenizeGraphql(tt.doc)))
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package common
+
+import (
+	"strings"
+)
+
+// HTTPStage is a stage body written as an HTTP request line followed by
+// an optional payload, e.g. `GET products/_search {"query": ...}`.
+type HTTPStage struct {
+	// Method is upper-cased; empty when the stage starts with the
+	// target, which callers treat as GET.
+	Method string
+	Target string
+	Body   string
+}
+
+var httpMethods = map[string]bool{
+	"GET": true, "HEAD": true, "POST": true, "PUT": true,
+	"PATCH": true, "DELETE": true, "OPTIONS": true,
+}
+
+// IsHTTPMethod reports whether word is an HTTP method, in any case.
+func IsHTTPMethod(word string) bool {
+	return httpMethods[strings.ToUpper(word)]
+}
+
+// ParseHTTPStage splits a stage into method, request target and body.
+// The method is optional; the body is everything after the target,
+// trimmed.
+func ParseHTTPStage(line string) HTTPStage {
+	var s HTTPStage
+	i := 0
+	first := nextHTTPToken(line, &i)
+	if IsHTTPMethod(first) {
+		s.Method = strings.ToUpper(first)
+		s.Target = nextHTTPToken(line, &i)
+	} else {
+		s.Target = first
+	}
+	s.Body = strings.TrimSpace(line[i:])
+	return s
+}
+
+// HTTPBodyOffset is the byte offset at which the body of an HTTP-style
+// stage begins; placeholders before it sit in the request target.
+func HTTPBodyOffset(line string) int {
+	i := 0
+	if first := nextHTTPToken(line, &i); IsHTTPMethod(first) {
+		nextHTTPToken(line, &i)
+	}
+	return i
+}
+
+// nextHTTPToken returns the whitespace-delimited token starting at *i
+// and advances *i past it. A `{{...}}` placeholder is part of the
+// token even when its jq expression contains spaces.
+func nextHTTPToken(line string, i *int) string {
+	for *i < len(line) && isHTTPSpace(line[*i]) {
+		*i++
+	}
+	start := *i
+	for *i < len(line) && !isHTTPSpace(line[*i]) {
+		if strings.HasPrefix(line[*i:], "{{") {
+			if end := strings.Index(line[*i:], "}}"); end >= 0 {
+				*i += end + 2
+				continue
+			}
+		}
+		*i++
+	}
+	return line[start:*i]
+}
+
+func isHTTPSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
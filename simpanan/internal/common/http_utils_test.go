@@ -0,0 +1,25 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHTTPStage(t *testing.T) {
+	tests := []struct {
+		line string
+		want HTTPStage
+	}{
+		{`GET products/_search {"query": {"match_all": {}}}`, HTTPStage{Method: "GET", Target: "products/_search", Body: `{"query": {"match_all": {}}}`}},
+		{"post /orders\n  {\"a\": 1}\n", HTTPStage{Method: "POST", Target: "/orders", Body: `{"a": 1}`}},
+		{`_cat/indices`, HTTPStage{Target: "_cat/indices"}},
+		{`GET users/{{ .id }} `, HTTPStage{Method: "GET", Target: "users/{{ .id }}"}},
+		{``, HTTPStage{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			assert.Equal(t, tt.want, ParseHTTPStage(tt.line))
+		})
+	}
+}
@@ -1,46 +1,474 @@
 package common
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/big"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/itchyny/gojq"
 )
 
-func PipeData(q *QueryMetadata, pipedData []byte) error {
-	var input any
-	if err := json.Unmarshal(pipedData, &input); err != nil {
+// PlaceholderStyle renders `{{jq}}` results into a stage body. PipeData
+// tracks the body's quoted literals with the style's LiteralRules and
+// asks Render for the text of each placeholder. The styles below are
+// shared; a backend with its own dialect implements one next to its
+// adapter and returns it from PlaceholderStyler.
+type PlaceholderStyle interface {
+	LiteralRules() LiteralRules
+	// Render returns the text that replaces the placeholder at site,
+	// appending to q.Args when it binds value as a parameter. It may
+	// widen site over the quotes of a literal it replaces whole.
+	Render(q *QueryMetadata, value any, site *PlaceholderSite) (string, error)
+}
+
+// PlaceholderSite is where a placeholder sits in a stage body.
+type PlaceholderSite struct {
+	Line string
+	// Start and End delimit the text Render's result replaces; at
+	// first the `{{...}}` itself.
+	Start, End int
+	// Delim is the quote of the literal around the placeholder, 0
+	// outside literals; OpenedAt is the offset of that quote.
+	Delim    rune
+	OpenedAt int
+}
+
+// WholeLiteral reports whether the placeholder is the entire content of
+// a literal quoted with one of quotes, e.g. `'{{.name}}'`.
+func (s *PlaceholderSite) WholeLiteral(quotes string) bool {
+	return s.Delim != 0 && strings.ContainsRune(quotes, s.Delim) &&
+		s.OpenedAt == s.Start-1 &&
+		s.End < len(s.Line) && rune(s.Line[s.End]) == s.Delim
+}
+
+var (
+	// PlaceholderText splices the value into the query text: strings
+	// verbatim, everything else as JSON.
+	PlaceholderText PlaceholderStyle = textPlaceholders{}
+	// PlaceholderJSON renders the value as a JSON literal, for stages
+	// whose body is Extended JSON (Mongo).
+	PlaceholderJSON PlaceholderStyle = jsonPlaceholders{}
+	// PlaceholderRedis renders the value as a quoted Redis command
+	// token; arrays expand into one token per element.
+	PlaceholderRedis PlaceholderStyle = redisPlaceholders{}
+	// PlaceholderDollar binds the value as a Postgres-style `$n`
+	// parameter appended to QueryMetadata.Args.
+	PlaceholderDollar PlaceholderStyle = BindStyle{
+		Rules:    LiteralRules{DoubledEscapes: true, SQLComments: true, DollarQuotes: true, EscapeStrings: true},
+		Quotes:   "'",
+		Prefixes: "Ee",
+		Bind: func(q *QueryMetadata, value any, _ bool) string {
+			return ExpandParams(value, func(v any) string {
+				q.Args = append(q.Args, BindValue(v))
+				return fmt.Sprintf("$%d", len(q.Args))
+			})
+		},
+	}
+	// PlaceholderQuestion binds the value as a MySQL-style `?`
+	// parameter appended to QueryMetadata.Args.
+	PlaceholderQuestion PlaceholderStyle = BindStyle{
+		Rules:  LiteralRules{DoubledEscapes: true, BackslashEscapes: true, SQLComments: true, HashComments: true},
+		Quotes: `'"`,
+		Bind: func(q *QueryMetadata, value any, _ bool) string {
+			return ExpandParams(value, func(v any) string {
+				q.Args = append(q.Args, BindValue(v))
+				return "?"
+			})
+		},
+	}
+)
+
+// rawPlaceholderPrefix marks a placeholder that is always spliced as
+// plain text, whatever the stage's style: `{{raw: .table}}`.
+const rawPlaceholderPrefix = "raw:"
+
+var placeholderRe = regexp.MustCompile(`\{\{([^{}]+)\}\}`)
+
+// PipeData evaluates every `{{jq}}` placeholder in q.QueryLine against
+// the previous stage's JSON output and substitutes the results in the
+// given style.
+func PipeData(q *QueryMetadata, pipedData []byte, style PlaceholderStyle) error {
+	input, err := decodePipedJSON(pipedData)
+	if err != nil {
 		return fmt.Errorf("pipe data: previous result is not valid JSON: %w", err)
 	}
 
-	match := regexp.MustCompile(`\{\{([^{}]+)\}\}`).FindAllStringSubmatch(q.QueryLine, -1)
-	for _, m := range match {
-		captured := m[1]
-		query, err := gojq.Parse(captured)
+	line := q.QueryLine
+	matches := placeholderRe.FindAllStringSubmatchIndex(line, -1)
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var out strings.Builder
+	scan := literalScanner{LiteralRules: style.LiteralRules()}
+	copied := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		scan.feed(line[copied:start], copied)
+		if scan.commentEnd != "" {
+			// A placeholder in a comment is left as written: it binds
+			// nothing, and a rendered value could end the comment.
+			out.WriteString(line[copied:end])
+			copied = end
+			continue
+		}
+
+		expr := strings.TrimSpace(line[m[2]:m[3]])
+		raw := strings.HasPrefix(expr, rawPlaceholderPrefix)
+		if raw {
+			expr = strings.TrimPrefix(expr, rawPlaceholderPrefix)
+		}
+		value, err := evalPlaceholder(expr, input)
 		if err != nil {
 			return err
 		}
-		iter := query.Run(input)
 
-		var replacement any
-		for {
-			v, ok := iter.Next()
-			if !ok {
+		if raw {
+			out.WriteString(line[copied:start])
+			out.WriteString(RenderText(value))
+			copied = end
+			continue
+		}
+
+		site := PlaceholderSite{Line: line, Start: start, End: end, Delim: scan.delim, OpenedAt: scan.openedAt}
+		text, err := style.Render(q, value, &site)
+		if err != nil {
+			return err
+		}
+		if site.Start < copied || site.Start > start || site.End < end || site.End > len(line) {
+			return fmt.Errorf("pipe data: placeholder %s was rendered outside its own text", line[start:end])
+		}
+		out.WriteString(line[copied:site.Start])
+		out.WriteString(text)
+		if site.End > end {
+			// The style replaced the literal around the placeholder,
+			// closing quote included.
+			scan.delim = 0
+		}
+		copied = site.End
+	}
+	out.WriteString(line[copied:])
+	q.QueryLine = out.String()
+	return nil
+}
+
+// BindStyle binds placeholders as driver parameters. A placeholder
+// outside literals becomes the parameter marker; one that is the whole
+// of a literal replaces the literal, quotes included, so `'{{.name}}'`
+// reads naturally. Any other placeholder inside a literal is an error:
+// a parameter cannot sit inside a string.
+type BindStyle struct {
+	Rules LiteralRules
+	// Quotes lists the delimiters of the literals a placeholder may
+	// replace whole.
+	Quotes string
+	// Prefixes lists the letters that belong to a literal when they
+	// come right before its opening quote, e.g. T-SQL's N'...'.
+	Prefixes string
+	// Bind appends value to q.Args and returns the marker text that
+	// refers to it; quoted is true when the marker replaces a literal.
+	Bind func(q *QueryMetadata, value any, quoted bool) string
+}
+
+func (s BindStyle) LiteralRules() LiteralRules { return s.Rules }
+
+func (s BindStyle) Render(q *QueryMetadata, value any, site *PlaceholderSite) (string, error) {
+	if site.Delim == 0 {
+		return s.Bind(q, value, false), nil
+	}
+	placeholder := site.Line[site.Start:site.End]
+	if !site.WholeLiteral(s.Quotes) {
+		return "", fmt.Errorf("pipe data: placeholder %s sits inside a string literal; bind parameters must replace the whole literal, e.g. '%s', or use {{raw: ...}} to splice text", placeholder, placeholder)
+	}
+	site.Start = site.OpenedAt
+	if p := site.Start - 1; p >= 0 && strings.IndexByte(s.Prefixes, site.Line[p]) >= 0 &&
+		(p == 0 || !isWordByte(site.Line[p-1])) {
+		site.Start = p
+	}
+	site.End++
+	return s.Bind(q, value, true), nil
+}
+
+// ExpandParams renders value as parameter markers produced by marker,
+// which binds one value. Arrays expand into one parameter per element
+// (for `IN ({{.ids}})`); an empty array renders as NULL so the IN-list
+// stays valid and matches nothing.
+func ExpandParams(value any, marker func(v any) string) string {
+	values := []any{value}
+	if arr, ok := value.([]any); ok {
+		if len(arr) == 0 {
+			return "NULL"
+		}
+		values = arr
+	}
+	markers := make([]string, 0, len(values))
+	for _, v := range values {
+		markers = append(markers, marker(v))
+	}
+	return strings.Join(markers, ", ")
+}
+
+type textPlaceholders struct{}
+
+func (textPlaceholders) LiteralRules() LiteralRules { return LiteralRules{BackslashEscapes: true} }
+
+func (textPlaceholders) Render(_ *QueryMetadata, value any, _ *PlaceholderSite) (string, error) {
+	return RenderText(value), nil
+}
+
+type jsonPlaceholders struct{}
+
+func (jsonPlaceholders) LiteralRules() LiteralRules { return LiteralRules{BackslashEscapes: true} }
+
+func (jsonPlaceholders) Render(_ *QueryMetadata, value any, site *PlaceholderSite) (string, error) {
+	if site.Delim == 0 {
+		return MarshalLiteral(value), nil
+	}
+	return EscapeJSONStringContent(RenderText(value), site.Delim), nil
+}
+
+type redisPlaceholders struct{}
+
+func (redisPlaceholders) LiteralRules() LiteralRules { return LiteralRules{BackslashEscapes: true} }
+
+func (redisPlaceholders) Render(_ *QueryMetadata, value any, site *PlaceholderSite) (string, error) {
+	if site.Delim == 0 {
+		return redisTokens(value), nil
+	}
+	return escapeRedisQuoted(RenderText(value), site.Delim), nil
+}
+
+// decodePipedJSON reads the previous stage's result keeping integers
+// exact: whole numbers become int, or *big.Int past int64, the types
+// gojq computes with, rather than float64, which cannot hold IDs above
+// 2^53.
+func decodePipedJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after the top-level value")
+	}
+	return exactNumbers(v), nil
+}
+
+func exactNumbers(v any) any {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := strconv.ParseInt(val.String(), 10, 0); err == nil {
+			return int(i)
+		}
+		if i, ok := new(big.Int).SetString(val.String(), 10); ok {
+			return i
+		}
+		f, _ := val.Float64()
+		return f
+	case map[string]any:
+		for k, el := range val {
+			val[k] = exactNumbers(el)
+		}
+	case []any:
+		for i, el := range val {
+			val[i] = exactNumbers(el)
+		}
+	}
+	return v
+}
+
+// evalPlaceholder runs one jq expression and returns its last output,
+// or nil when the expression produces nothing.
+func evalPlaceholder(expr string, input any) (any, error) {
+	query, err := gojq.Parse(expr)
+	if err != nil {
+		return nil, err
+	}
+	iter := query.Run(input)
+
+	var replacement any
+	for {
+		v, ok := iter.Next()
+		if !ok {
+			break
+		}
+		if err, ok := v.(error); ok {
+			if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
 				break
 			}
-			if err, ok := v.(error); ok {
-				if err, ok := err.(*gojq.HaltError); ok && err.Value() == nil {
-					break
+			return nil, err
+		}
+		replacement = v
+	}
+	return replacement, nil
+}
+
+// RenderText is the textual form of a jq result: strings verbatim,
+// everything else (numbers, objects, arrays, null) as JSON.
+func RenderText(v any) string {
+	if s, ok := v.(string); ok {
+		return s
+	}
+	return MarshalLiteral(v)
+}
+
+// MarshalLiteral encodes a jq result as compact JSON without HTML
+// escaping, so `<`, `>` and `&` survive into query text unchanged.
+func MarshalLiteral(v any) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// EscapeJSONStringContent escapes s for insertion between the quotes of
+// an existing JSON (or JS-style single-quoted) string literal.
+func EscapeJSONStringContent(s string, delim rune) string {
+	quoted := MarshalLiteral(s)
+	content := quoted[1 : len(quoted)-1]
+	if delim == '\'' {
+		content = strings.ReplaceAll(content, `'`, `\'`)
+	}
+	return content
+}
+
+// redisTokens renders a value as one or more tokens that
+// tokenizeRedisCommand reads back verbatim. Arrays expand into one
+// token per element (for `DEL {{.keys}}`); use `tojson` in the jq
+// expression to pass an array as a single JSON token instead.
+func redisTokens(v any) string {
+	if arr, ok := v.([]any); ok {
+		tokens := make([]string, 0, len(arr))
+		for _, el := range arr {
+			tokens = append(tokens, `"`+escapeRedisQuoted(RenderText(el), '"')+`"`)
+		}
+		return strings.Join(tokens, " ")
+	}
+	return `"` + escapeRedisQuoted(RenderText(v), '"') + `"`
+}
+
+// escapeRedisQuoted escapes the characters tokenizeRedisCommand treats
+// specially inside a quoted token: the backslash and the delimiter.
+func escapeRedisQuoted(s string, delim rune) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, string(delim), `\`+string(delim))
+}
+
+// BindValue converts a jq result into something database/sql drivers
+// accept. Objects and nested arrays are passed as their JSON text so
+// they can land in json/jsonb columns.
+func BindValue(v any) any {
+	switch val := v.(type) {
+	case map[string]any, []any:
+		return MarshalLiteral(val)
+	case *big.Int:
+		return val.String()
+	}
+	return v
+}
+
+// LiteralRules describes the quoted literals and comments of a
+// stage's dialect.
+type LiteralRules struct {
+	// BackslashEscapes: `\x` inside a literal escapes x (MySQL, JSON,
+	// Redis).
+	BackslashEscapes bool
+	// DoubledEscapes: a doubled delimiter inside a literal is an
+	// escaped quote (SQL).
+	DoubledEscapes bool
+	// DoubleQuotesOnly: `'` does not open a literal (GraphQL).
+	DoubleQuotesOnly bool
+	// SQLComments: `-- ...` to the end of the line and `/* ... */`.
+	SQLComments bool
+	// HashComments: `# ...` to the end of the line (MySQL).
+	HashComments bool
+	// DollarQuotes: `$$...$$` and `$tag$...$tag$` bodies (Postgres).
+	DollarQuotes bool
+	// EscapeStrings: E'...' literals take backslash escapes even when
+	// plain ones do not (Postgres).
+	EscapeStrings bool
+}
+
+// literalScanner tracks whether a position in a stage body is inside a
+// quoted literal or a comment. It is fed the text between placeholders
+// only, so quotes inside a jq expression never affect the state.
+type literalScanner struct {
+	LiteralRules
+	// delim is the quote of the open literal, '$' for a dollar-quoted
+	// body closed by dollarTag.
+	delim     rune
+	dollarTag string
+	openedAt  int
+	// backslashes: the open literal takes backslash escapes.
+	backslashes bool
+	escaped     bool
+	// commentEnd closes the open comment: "\n" or "*/".
+	commentEnd string
+}
+
+var dollarTagRe = regexp.MustCompile(`^\$[A-Za-z_]*\$`)
+
+func (s *literalScanner) feed(chunk string, offset int) {
+	for i := 0; i < len(chunk); i++ {
+		c := chunk[i]
+		switch {
+		case s.commentEnd != "":
+			if strings.HasPrefix(chunk[i:], s.commentEnd) {
+				i += len(s.commentEnd) - 1
+				s.commentEnd = ""
+			}
+		case s.delim == '$':
+			if strings.HasPrefix(chunk[i:], s.dollarTag) {
+				i += len(s.dollarTag) - 1
+				s.delim = 0
+			}
+		case s.delim == 0:
+			switch {
+			case c == '"' || (c == '\'' && !s.DoubleQuotesOnly):
+				s.delim = rune(c)
+				s.openedAt = offset + i
+				s.backslashes = s.BackslashEscapes ||
+					(s.EscapeStrings && c == '\'' && i > 0 && (chunk[i-1] == 'E' || chunk[i-1] == 'e') &&
+						(i == 1 || !isWordByte(chunk[i-2])))
+			case s.SQLComments && strings.HasPrefix(chunk[i:], "--"):
+				s.commentEnd = "\n"
+				i++
+			case s.SQLComments && strings.HasPrefix(chunk[i:], "/*"):
+				s.commentEnd = "*/"
+				i++
+			case s.HashComments && c == '#':
+				s.commentEnd = "\n"
+			case s.DollarQuotes && c == '$':
+				if tag := dollarTagRe.FindString(chunk[i:]); tag != "" {
+					s.delim = '$'
+					s.dollarTag = tag
+					s.openedAt = offset + i
+					i += len(tag) - 1
 				}
-				return err
 			}
-			replacement = v
+		case s.escaped:
+			s.escaped = false
+		case s.backslashes && c == '\\':
+			s.escaped = true
+		case rune(c) == s.delim:
+			if s.DoubledEscapes && i+1 < len(chunk) && rune(chunk[i+1]) == s.delim {
+				i++
+			} else {
+				s.delim = 0
+			}
 		}
-
-		q.QueryLine = strings.Replace(q.QueryLine, m[0], fmt.Sprintf("%v", replacement), 1)
 	}
+}
 
-	return nil
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
 }
@@ -45,9 +45,291 @@ func TestPipeData(t *testing.T) {
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
-			err := PipeData(&test.qm, test.payload)
+			err := PipeData(&test.qm, test.payload, PlaceholderText)
 			assert.Equal(t, test.expectedError, err)
 			assert.Equal(t, test.expectedExecLine, test.qm.QueryLine)
 		})
 	}
 }
+
+func TestPipeDataBindsParameters(t *testing.T) {
+	tests := []struct {
+		name         string
+		style        PlaceholderStyle
+		line         string
+		payload      string
+		expectedLine string
+		expectedArgs []any
+	}{
+		{
+			name:         "postgres scalar",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t WHERE id = {{.id}}",
+			payload:      `{"id": 7}`,
+			expectedLine: "SELECT * FROM t WHERE id = $1",
+			expectedArgs: []any{7},
+		},
+		{
+			name:         "mysql scalar",
+			style:        PlaceholderQuestion,
+			line:         "SELECT * FROM t WHERE id = {{.id}}",
+			payload:      `{"id": 7}`,
+			expectedLine: "SELECT * FROM t WHERE id = ?",
+			expectedArgs: []any{7},
+		},
+		{
+			name:         "quote in value is a parameter, not SQL",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t WHERE name = {{.name}}",
+			payload:      `{"name": "O'Brien'; DROP TABLE t; --"}`,
+			expectedLine: "SELECT * FROM t WHERE name = $1",
+			expectedArgs: []any{"O'Brien'; DROP TABLE t; --"},
+		},
+		{
+			name:         "quoted placeholder absorbs its quotes",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t WHERE name = '{{.name}}' AND id = {{.id}}",
+			payload:      `{"name": "a'b", "id": 1}`,
+			expectedLine: "SELECT * FROM t WHERE name = $1 AND id = $2",
+			expectedArgs: []any{"a'b", 1},
+		},
+		{
+			name:         "mysql double-quoted string absorbs its quotes",
+			style:        PlaceholderQuestion,
+			line:         `SELECT * FROM t WHERE name = "{{.name}}"`,
+			payload:      `{"name": "x"}`,
+			expectedLine: "SELECT * FROM t WHERE name = ?",
+			expectedArgs: []any{"x"},
+		},
+		{
+			name:         "array expands for IN lists",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t WHERE a = {{.a}} AND id IN ({{.ids}})",
+			payload:      `{"a": "x", "ids": [1, 2, 3]}`,
+			expectedLine: "SELECT * FROM t WHERE a = $1 AND id IN ($2, $3, $4)",
+			expectedArgs: []any{"x", 1, 2, 3},
+		},
+		{
+			name:         "empty array renders NULL",
+			style:        PlaceholderQuestion,
+			line:         "SELECT * FROM t WHERE id IN ({{.ids}})",
+			payload:      `{"ids": []}`,
+			expectedLine: "SELECT * FROM t WHERE id IN (NULL)",
+			expectedArgs: nil,
+		},
+		{
+			name:         "object binds as JSON text",
+			style:        PlaceholderDollar,
+			line:         "INSERT INTO t (doc) VALUES ({{.doc}})",
+			payload:      `{"doc": {"a": 1}}`,
+			expectedLine: "INSERT INTO t (doc) VALUES ($1)",
+			expectedArgs: []any{`{"a":1}`},
+		},
+		{
+			name:         "quotes inside the jq expression do not open a literal",
+			style:        PlaceholderDollar,
+			line:         `SELECT {{.["it's"]}}, 'lit'`,
+			payload:      `{"it's": 1}`,
+			expectedLine: "SELECT $1, 'lit'",
+			expectedArgs: []any{1},
+		},
+		{
+			name:         "integers past 2^53 stay exact",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t WHERE id = {{.id}} AND score > {{.score}}",
+			payload:      `{"id": 9007199254740993, "score": 0.5}`,
+			expectedLine: "SELECT * FROM t WHERE id = $1 AND score > $2",
+			expectedArgs: []any{9007199254740993, 0.5},
+		},
+		{
+			name:         "integers past int64 bind as decimal text",
+			style:        PlaceholderQuestion,
+			line:         "SELECT * FROM t WHERE id = {{.id}}",
+			payload:      `{"id": 123456789012345678901234567890}`,
+			expectedLine: "SELECT * FROM t WHERE id = ?",
+			expectedArgs: []any{"123456789012345678901234567890"},
+		},
+		{
+			name:         "quote in a line comment is not a literal",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t -- don't\n WHERE id = {{.id}}",
+			payload:      `{"id": 1}`,
+			expectedLine: "SELECT * FROM t -- don't\n WHERE id = $1",
+			expectedArgs: []any{1},
+		},
+		{
+			name:         "quote in a block comment is not a literal",
+			style:        PlaceholderDollar,
+			line:         "SELECT * /* it's */ FROM t WHERE name = '{{.name}}'",
+			payload:      `{"name": "x"}`,
+			expectedLine: "SELECT * /* it's */ FROM t WHERE name = $1",
+			expectedArgs: []any{"x"},
+		},
+		{
+			name:         "mysql hash comment",
+			style:        PlaceholderQuestion,
+			line:         "SELECT * FROM t # don't\n WHERE id = {{.id}}",
+			payload:      `{"id": 1}`,
+			expectedLine: "SELECT * FROM t # don't\n WHERE id = ?",
+			expectedArgs: []any{1},
+		},
+		{
+			name:         "placeholder in a comment is left as written",
+			style:        PlaceholderDollar,
+			line:         "SELECT 1 -- was {{.id}}\n, {{.id}}",
+			payload:      `{"id": 1}`,
+			expectedLine: "SELECT 1 -- was {{.id}}\n, $1",
+			expectedArgs: []any{1},
+		},
+		{
+			name:         "quote in a dollar-quoted body is not a literal",
+			style:        PlaceholderDollar,
+			line:         "SELECT $$it's$$, $fn$ don't $fn$, {{.id}}",
+			payload:      `{"id": 1}`,
+			expectedLine: "SELECT $$it's$$, $fn$ don't $fn$, $1",
+			expectedArgs: []any{1},
+		},
+		{
+			name:         "escape string takes backslash escapes",
+			style:        PlaceholderDollar,
+			line:         `SELECT E'it\'s', {{.id}}`,
+			payload:      `{"id": 1}`,
+			expectedLine: `SELECT E'it\'s', $1`,
+			expectedArgs: []any{1},
+		},
+		{
+			name:         "escape string placeholder absorbs its prefix",
+			style:        PlaceholderDollar,
+			line:         "SELECT * FROM t WHERE name = E'{{.name}}' AND note = '{{.note}}'",
+			payload:      `{"name": "a", "note": "b"}`,
+			expectedLine: "SELECT * FROM t WHERE name = $1 AND note = $2",
+			expectedArgs: []any{"a", "b"},
+		},
+		{
+			name:         "escaped quote inside a literal is skipped",
+			style:        PlaceholderDollar,
+			line:         "SELECT 'it''s' || {{.x}}",
+			payload:      `{"x": "y"}`,
+			expectedLine: "SELECT 'it''s' || $1",
+			expectedArgs: []any{"y"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			qm := QueryMetadata{QueryLine: test.line}
+			err := PipeData(&qm, []byte(test.payload), test.style)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedLine, qm.QueryLine)
+			assert.Equal(t, test.expectedArgs, qm.Args)
+		})
+	}
+}
+
+func TestPipeDataRejectsPlaceholderInsideLongerLiteral(t *testing.T) {
+	qm := QueryMetadata{QueryLine: "SELECT * FROM t WHERE name LIKE '%{{.name}}%'"}
+	err := PipeData(&qm, []byte(`{"name": "x"}`), PlaceholderDollar)
+	assert.Error(t, err)
+}
+
+func TestPipeDataRejectsPlaceholderInsideDollarQuotedBody(t *testing.T) {
+	qm := QueryMetadata{QueryLine: "DO $$ BEGIN PERFORM {{.id}}; END $$"}
+	err := PipeData(&qm, []byte(`{"id": 1}`), PlaceholderDollar)
+	assert.Error(t, err)
+}
+
+func TestPipeDataRejectsTrailingData(t *testing.T) {
+	qm := QueryMetadata{QueryLine: "SELECT {{.id}}"}
+	err := PipeData(&qm, []byte(`{"id": 1} {"id": 2}`), PlaceholderDollar)
+	assert.Error(t, err)
+}
+
+func TestPipeDataRendersDialectLiterals(t *testing.T) {
+	tests := []struct {
+		name         string
+		style        PlaceholderStyle
+		line         string
+		payload      string
+		expectedLine string
+	}{
+		{
+			name:         "json string is quoted",
+			style:        PlaceholderJSON,
+			line:         `db.users.find({"name": {{.name}}})`,
+			payload:      `{"name": "Ann \"A\" <x>"}`,
+			expectedLine: `db.users.find({"name": "Ann \"A\" <x>"})`,
+		},
+		{
+			name:         "json object and array nest as JSON",
+			style:        PlaceholderJSON,
+			line:         `db.users.find({"a": {{.a}}, "ids": {"$in": {{.ids}}}})`,
+			payload:      `{"a": {"b": 1}, "ids": [1, "two"]}`,
+			expectedLine: `db.users.find({"a": {"b":1}, "ids": {"$in": [1,"two"]}})`,
+		},
+		{
+			name:         "json placeholder already inside quotes is escaped in place",
+			style:        PlaceholderJSON,
+			line:         `db.users.find({"user_id": "{{.id}}", "note": 'x{{.note}}'})`,
+			payload:      `{"id": 5, "note": "it's \"q\""}`,
+			expectedLine: `db.users.find({"user_id": "5", "note": 'xit\'s \"q\"'})`,
+		},
+		{
+			name:         "json null",
+			style:        PlaceholderJSON,
+			line:         `db.users.find({"deleted_at": {{.missing}}})`,
+			payload:      `{}`,
+			expectedLine: `db.users.find({"deleted_at": null})`,
+		},
+		{
+			name:         "redis string becomes one quoted token",
+			style:        PlaceholderRedis,
+			line:         `SET greeting {{.msg}}`,
+			payload:      `{"msg": "hello \"world\" \\o/"}`,
+			expectedLine: `SET greeting "hello \"world\" \\o/"`,
+		},
+		{
+			name:         "redis array expands to tokens",
+			style:        PlaceholderRedis,
+			line:         `DEL {{.keys}}`,
+			payload:      `{"keys": ["a b", "c"]}`,
+			expectedLine: `DEL "a b" "c"`,
+		},
+		{
+			name:         "redis placeholder inside single quotes",
+			style:        PlaceholderRedis,
+			line:         `GET 'user:{{.id}}'`,
+			payload:      `{"id": "o'k"}`,
+			expectedLine: `GET 'user:o\'k'`,
+		},
+		{
+			name:         "raw splices text in any style",
+			style:        PlaceholderDollar,
+			line:         `SELECT * FROM {{raw: .table}} WHERE id = {{.id}}`,
+			payload:      `{"table": "users", "id": 1}`,
+			expectedLine: `SELECT * FROM users WHERE id = $1`,
+		},
+		{
+			name:         "raw inside a json literal",
+			style:        PlaceholderJSON,
+			line:         `db.{{ raw: .coll }}.find({})`,
+			payload:      `{"coll": "orders"}`,
+			expectedLine: `db.orders.find({})`,
+		},
+		{
+			name:         "text renders objects as JSON, not Go syntax",
+			style:        PlaceholderText,
+			line:         `{{.a}}`,
+			payload:      `{"a": {"x": 1}}`,
+			expectedLine: `{"x":1}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			qm := QueryMetadata{QueryLine: test.line}
+			err := PipeData(&qm, []byte(test.payload), test.style)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedLine, qm.QueryLine)
+		})
+	}
+}
@@ -14,6 +14,9 @@ type (
 		Conn      string   `json:"conn"`
 		ConnType  ConnType `json:"conn_type"`
 		QueryLine string   `json:"query"`
+		// Args holds bind parameters produced by PipeData for adapters
+		// that take placeholders as driver arguments rather than text.
+		Args []any `json:"args,omitempty"`
 	}
 
 	ColumnValuePair struct {
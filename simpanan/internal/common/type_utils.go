@@ -11,27 +11,37 @@ func (kup KeyURIPair) String() string {
 	return fmt.Sprintf("%s → %s", kup.Key, kup.URI)
 }
 
+// connTypesByScheme maps a URI scheme to the connection type that
+// serves it. Populated by RegisterConnType, which the adapter registry
+// calls once per scheme an adapter claims.
+var connTypesByScheme = map[string]ConnType{}
+
+// RegisterConnType claims the given URI schemes for a connection type.
+// Re-registering a scheme for the same type is a no-op; claiming a
+// scheme already owned by another type is an error.
+func RegisterConnType(ct ConnType, schemes ...string) error {
+	for _, scheme := range schemes {
+		if owner, ok := connTypesByScheme[scheme]; ok && owner != ct {
+			return fmt.Errorf("scheme %q is already registered to %q", scheme, owner)
+		}
+	}
+	for _, scheme := range schemes {
+		connTypesByScheme[scheme] = ct
+	}
+	return nil
+}
+
 func (u URI) ConnType() (*ConnType, error) {
 	s := string(u)
 	idx := strings.Index(s, "://")
 	if idx < 0 {
 		return nil, errors.New("unknown connection type")
 	}
-	scheme := s[:idx]
-	switch scheme {
-	case "postgres", "postgresql":
-		return &Postgres, nil
-	case "mysql":
-		return &Mysql, nil
-	case "mongodb", "mongodb+srv":
-		return &Mongo, nil
-	case "redis", "rediss":
-		return &Redis, nil
-	case "jq":
-		return &Jq, nil
-	default:
+	ct, ok := connTypesByScheme[s[:idx]]
+	if !ok {
 		return nil, errors.New("unknown connection type")
 	}
+	return &ct, nil
 }
 
 func (kups KeyURIPairs) Map() map[string]string {
@@ -1,11 +1,24 @@
 package common
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// TestMain seeds the scheme registry the way the internal package's
+// adapter registrations do at start-up. The common package cannot
+// import those registrations without an import cycle.
+func TestMain(m *testing.M) {
+	RegisterConnType(Postgres, "postgres", "postgresql")
+	RegisterConnType(Mysql, "mysql")
+	RegisterConnType(Mongo, "mongodb", "mongodb+srv")
+	RegisterConnType(Redis, "redis", "rediss")
+	RegisterConnType(Jq, "jq")
+	os.Exit(m.Run())
+}
+
 func TestURIConnType(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -21,7 +34,7 @@ func TestURIConnType(t *testing.T) {
 		{"redis scheme", "redis://h:6379", &Redis, false},
 		{"rediss scheme", "rediss://h:6379", &Redis, false},
 		{"jq scheme", "jq://", &Jq, false},
-		{"unknown scheme", "http://h", nil, true},
+		{"unknown scheme", "ftp://h", nil, true},
 		{"missing scheme separator", "postgres", nil, true},
 		{"empty string", "", nil, true},
 		// Loose substring match used to accept any scheme containing
@@ -41,6 +54,15 @@ func TestURIConnType(t *testing.T) {
 	}
 }
 
+func TestRegisterConnTypeRejectsConflictingScheme(t *testing.T) {
+	assert.NoError(t, RegisterConnType(Postgres, "postgres"), "re-registering the same owner is a no-op")
+	assert.Error(t, RegisterConnType(Mysql, "postgres"))
+
+	got, err := URI("postgres://h/db").ConnType()
+	assert.NoError(t, err)
+	assert.Equal(t, &Postgres, got, "a rejected claim must not change the owner")
+}
+
 func TestSetConfigIgnoresMalformedOptions(t *testing.T) {
 	// No panic on options missing the '=' separator.
 	err := SetConfig([]string{"debug_mode", "=value", "max_row_limit=50"})
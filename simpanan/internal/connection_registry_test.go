@@ -66,7 +66,7 @@ func TestHandleAddConnectionRejectsEmptyLabel(t *testing.T) {
 func TestHandleAddConnectionRejectsUnrecognisedScheme(t *testing.T) {
 	home := withTempHome(t)
 	seedConnections(t, home, nil)
-	_, err := HandleAddConnection([]string{"bad>http://example.com"})
+	_, err := HandleAddConnection([]string{"bad>ftp://example.com"})
 	assert.Error(t, err)
 }
 
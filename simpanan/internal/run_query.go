@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
-	"simpanan/internal/adapters"
 	"simpanan/internal/common"
 	"strings"
 )
@@ -148,8 +147,9 @@ func parseQuery(a string, connMap map[string]string) (common.QueryMetadata, erro
 
 // validateChainedStagesAreReadOnly enforces the spec invariant of the
 // same name: in a pipeline, only the last stage may be a write or admin
-// operation; all preceding stages must be reads. jq stages are pure
-// transformers and are always read-compatible.
+// operation; all preceding stages must be reads. Classification is
+// delegated to each stage's Adapter; jq stages are pure transformers
+// and always classify as read.
 func validateChainedStagesAreReadOnly(queries []common.QueryMetadata) error {
 	if len(queries) < 2 {
 		return nil
@@ -158,21 +158,11 @@ func validateChainedStagesAreReadOnly(queries []common.QueryMetadata) error {
 		return fmt.Errorf("ChainedStagesAreReadOnly: stage %d is a non-terminal %s; only the last stage may be write or admin", i+1, qt)
 	}
 	for i, q := range queries[:len(queries)-1] {
-		var qt common.QueryType
-		switch q.ConnType {
-		case common.Jq:
-			continue
-		case common.Postgres:
-			qt = adapters.QueryTypePostgres(q.QueryLine)
-		case common.Mysql:
-			qt = adapters.QueryTypeMysql(q.QueryLine)
-		case common.Mongo:
-			qt = adapters.QueryTypeMongo(q.QueryLine)
-		case common.Redis:
-			qt = adapters.QueryTypeRedis(q.QueryLine)
-		default:
+		adapter, ok := LookupAdapter(q.ConnType)
+		if !ok {
 			continue
 		}
+		qt := adapter.QueryType(q.QueryLine)
 		if qt == common.Write || qt == common.Admin {
 			return reject(i, qt)
 		}
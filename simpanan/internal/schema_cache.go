@@ -42,6 +42,9 @@ type TableSchema struct {
 type CollectionSchema struct {
 	Name   string   `json:"name"`
 	Fields []string `json:"fields"`
+	// FieldTypes maps a field to the type it holds, for caches whose
+	// collections are types (GraphQL).
+	FieldTypes map[string]string `json:"field_types,omitempty"`
 }
 
 // mongoSampleSize is the number of documents sampled per Mongo
@@ -62,20 +65,20 @@ var systemMongoDatabases = map[string]struct{}{
 }
 
 // IntrospectSchema connects to the given URI and returns a fresh
-// SchemaCache. Redis and jq are ineligible by spec and return
-// (nil, nil) without an error so callers can treat them uniformly.
+// SchemaCache via the connection type's Adapter. Types whose adapter
+// does not implement SchemaIntrospector (redis, jq) are ineligible by
+// spec and return (nil, nil) without an error so callers can treat
+// them uniformly.
 func IntrospectSchema(label, uri string, ct common.ConnType) (*SchemaCache, error) {
-	switch ct {
-	case common.Postgres:
-		return introspectPostgres(label, uri)
-	case common.Mysql:
-		return introspectMysql(label, uri)
-	case common.Mongo:
-		return introspectMongo(label, uri)
-	case common.Redis, common.Jq:
+	adapter, ok := LookupAdapter(ct)
+	if !ok {
+		return nil, fmt.Errorf("IntrospectSchema: unsupported connection type %q", ct)
+	}
+	introspector, ok := adapter.(SchemaIntrospector)
+	if !ok {
 		return nil, nil
 	}
-	return nil, fmt.Errorf("IntrospectSchema: unsupported connection type %q", ct)
+	return introspector.Introspect(label, uri)
 }
 
 // sqlColumnRow is the row shape returned by the per-adapter
@@ -17,7 +17,8 @@ var introspectFn = IntrospectSchema
 // authoritative, and freshness is checked at every call.
 //
 // Behaviour:
-//   - Ineligible types (Redis, Jq, unregistered labels) return (nil, nil).
+//   - Ineligible types (adapters without a SchemaIntrospector, such as
+//     Redis and Jq, and unregistered labels) return (nil, nil).
 //   - A disk cache younger than AutocompleteConfig.SchemaRefreshInterval
 //     is returned without re-introspecting.
 //   - A missing or stale cache triggers introspection. On success the
@@ -33,7 +34,7 @@ func EnsureSchemaCache(label string) (*SchemaCache, error) {
 	if !ok {
 		return nil, nil
 	}
-	if ct == common.Redis || ct == common.Jq {
+	if !schemaCacheEligible(ct) {
 		return nil, nil
 	}
 
@@ -58,7 +59,7 @@ func EnsureSchemaCache(label string) (*SchemaCache, error) {
 // rule PopulateOnAddConnection is satisfied when the DB is available;
 // otherwise the cache is populated lazily at first completion request.
 func PopulateSchemaCacheForConnection(label, uri string, ct common.ConnType) {
-	if ct == common.Redis || ct == common.Jq {
+	if !schemaCacheEligible(ct) {
 		return
 	}
 	_, _ = populateSchemaCache(label, uri, ct, nil)
@@ -52,6 +52,26 @@ func TestURIConnTypeRecognisedSchemes(t *testing.T) {
 		{"redis", "redis://h:6379", common.Redis},
 		{"rediss", "rediss://h:6379", common.Redis},
 		{"jq", "jq://", common.Jq},
+		{"sqlite", "sqlite://data/app.db", sqliteConnType},
+		{"sqlserver", "sqlserver://sa:pw@localhost:1433?database=app", sqlserverConnType},
+		{"es", "es://localhost:9200", elasticsearchConnType},
+		{"opensearch", "opensearch://search:9200", elasticsearchConnType},
+		{"http", "http://localhost:8080/api", httpConnType},
+		{"https", "https://api.example.com/v1", httpConnType},
+		{"graphql+https", "graphql+https://api.example.com/graphql", graphqlConnType},
+		{"graphql+http", "graphql+http://localhost:4000/graphql", graphqlConnType},
+		{"dynamodb", "dynamodb://us-east-1?endpoint=http://localhost:8000", dynamodbConnType},
+		{"cassandra", "cassandra://app:pw@n1,n2:9042/telemetry", cassandraConnType},
+		{"scylla", "scylla://localhost/telemetry", cassandraConnType},
+		{"clickhouse", "clickhouse://app:pw@ch:8123/analytics", clickhouseConnType},
+		{"neo4j", "neo4j://app:pw@graph:7687/perms", neo4jConnType},
+		{"neo4j+s", "neo4j+s://graph.example.com", neo4jConnType},
+		{"bolt", "bolt://localhost:7687", neo4jConnType},
+		{"bolt+s", "bolt+s://graph.example.com:7687", neo4jConnType},
+		{"etcd", "etcd://e1:2379,e2:2379", etcdConnType},
+		{"consul", "consul://localhost:8500?dc=eu1", consulConnType},
+		{"memcached", "memcached://cache:11211", memcachedConnType},
+		{"kafka", "kafka://app:pw@b1:9092,b2:9092?tls=true", kafkaConnType},
 	}
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
@@ -66,8 +86,8 @@ func TestURIConnTypeRecognisedSchemes(t *testing.T) {
 
 func TestURIConnTypeRejectsUnknownSchemes(t *testing.T) {
 	cases := []string{
-		"http://h/db",
 		"ftp://h",
+		"gopher://h/db",
 		"no-scheme",
 		"",
 		"mariadb://h/db",
@@ -168,8 +188,10 @@ func TestQueryTypeMysqlRouting(t *testing.T) {
 		expected common.QueryType
 	}{
 		{"select is read", "SELECT 1", common.Read},
-		{"show tables is read (metadata)", "SHOW TABLES", common.Read},
-		{"describe is read", "DESCRIBE t", common.Read},
+		{"show databases is read", "SHOW DATABASES", common.Read},
+		{"show tables is admin", "SHOW TABLES", common.Admin},
+		{"describe is admin", "DESCRIBE t", common.Admin},
+		{"dt alias is admin", "\\dt", common.Admin},
 		{"insert is write", "INSERT INTO t VALUES (1)", common.Write},
 		{"update is write", "UPDATE t SET a=1", common.Write},
 		{"delete is write", "DELETE FROM t", common.Write},
@@ -205,6 +227,18 @@ func TestQueryTypeMongoRouting(t *testing.T) {
 		{"updateMany is write", "db.users.updateMany({}, {})", common.Write},
 		{"deleteOne is write", "db.users.deleteOne({})", common.Write},
 		{"deleteMany is write", "db.users.deleteMany({})", common.Write},
+		{"countDocuments is read", "db.users.countDocuments({})", common.Read},
+		{"getIndexes is read", "db.users.getIndexes()", common.Read},
+		{"stats is read", "db.users.stats()", common.Read},
+		{"replaceOne is write", "db.users.replaceOne({}, {})", common.Write},
+		{"findOneAndUpdate is write", "db.users.findOneAndUpdate({}, {})", common.Write},
+		{"findOneAndReplace is write", "db.users.findOneAndReplace({}, {})", common.Write},
+		{"findOneAndDelete is write", "db.users.findOneAndDelete({})", common.Write},
+		{"bulkWrite is write", "db.users.bulkWrite([])", common.Write},
+		{"createIndex is admin", "db.users.createIndex({a: 1})", common.Admin},
+		{"dropIndex is admin", "db.users.dropIndex('a_1')", common.Admin},
+		{"read-only runCommand is read", "db.runCommand({ping: 1})", common.Read},
+		{"other runCommand is admin", "db.runCommand({drop: 'users'})", common.Admin},
 		{"show collections is read", "show collections", common.Read},
 	}
 	for _, tc := range cases {
@@ -478,6 +478,39 @@ function schemeToConnType(scheme) {
 		case "redis":
 		case "rediss":
 			return "redis";
+		case "sqlite":
+			return "sqlite";
+		case "sqlserver":
+			return "sqlserver";
+		case "es":
+		case "opensearch":
+			return "elasticsearch";
+		case "http":
+		case "https":
+			return "http";
+		case "graphql+https":
+		case "graphql+http":
+			return "graphql";
+		case "dynamodb":
+			return "dynamodb";
+		case "cassandra":
+		case "scylla":
+			return "cassandra";
+		case "clickhouse":
+			return "clickhouse";
+		case "neo4j":
+		case "neo4j+s":
+		case "bolt":
+		case "bolt+s":
+			return "neo4j";
+		case "etcd":
+			return "etcd";
+		case "consul":
+			return "consul";
+		case "memcached":
+			return "memcached";
+		case "kafka":
+			return "kafka";
 	}
 	return null;
 }
@@ -485,7 +518,7 @@ function schemeToConnType(scheme) {
 function ingestConnections(conns) {
 	state.connTypes = new Map();
 	for (const c of conns) {
-		const m = (c.uri || "").match(/^([a-z+]+):\/\//i);
+		const m = (c.uri || "").match(/^([a-z0-9+]+):\/\//i);
 		const ct = m ? schemeToConnType(m[1].toLowerCase()) : null;
 		if (ct) state.connTypes.set(c.label, ct);
 	}
@@ -62,6 +62,28 @@ const MONGO_COLLECTION_OPS = new Set([
 	"bulkwrite", "createindex", "dropindex", "getindexes",
 ]);
 
+// Request-line methods of HTTP-style stages (Elasticsearch/OpenSearch).
+const HTTP_METHODS = new Set([
+	"get", "head", "post", "put", "patch", "delete", "options",
+]);
+
+const GRAPHQL_KEYWORDS = new Set([
+	"query", "mutation", "subscription", "fragment", "on",
+	"true", "false", "null",
+]);
+
+// Cypher clauses and operators; labels and property keys stay plain.
+const CYPHER_KEYWORDS = new Set([
+	"match", "optional", "where", "return", "with", "unwind", "as",
+	"order", "by", "asc", "desc", "skip", "limit", "distinct",
+	"union", "all", "call", "yield", "case", "when", "then", "else", "end",
+	"and", "or", "xor", "not", "in", "is", "null", "true", "false",
+	"starts", "ends", "contains", "exists",
+	"create", "merge", "on", "set", "delete", "detach", "remove", "foreach",
+	"index", "constraint", "for", "require", "unique", "drop", "show",
+	"explain", "profile",
+]);
+
 const JQ_FUNCS = new Set([
 	"select", "map", "map_values", "length", "keys", "keys_unsorted",
 	"values", "has", "in", "contains", "type", "not",
@@ -84,6 +106,11 @@ function langForLabel(label, connTypes) {
 	switch (ct) {
 		case "postgres":
 		case "mysql":
+		case "sqlite":
+		case "sqlserver":
+		case "dynamodb":
+		case "cassandra":
+		case "clickhouse":
 			return "sql";
 		case "mongo":
 			return "mongo";
@@ -91,6 +118,13 @@ function langForLabel(label, connTypes) {
 			return "redis";
 		case "jq":
 			return "jq";
+		case "elasticsearch":
+		case "http":
+			return "http";
+		case "graphql":
+			return "graphql";
+		case "neo4j":
+			return "cypher";
 	}
 	return null;
 }
@@ -104,6 +138,9 @@ function tokenForWord(word, lang) {
 		if (MONGO_COLLECTION_OPS.has(lower)) return "keyword";
 		if (word.startsWith("$")) return "propertyName"; // $match, $group, …
 	}
+	if (lang === "http" && HTTP_METHODS.has(lower)) return "keyword";
+	if (lang === "graphql" && GRAPHQL_KEYWORDS.has(word)) return "keyword";
+	if (lang === "cypher" && CYPHER_KEYWORDS.has(lower)) return "keyword";
 	if (lang === "jq" && JQ_FUNCS.has(lower)) return "keyword";
 	return null;
 }
@@ -210,7 +247,7 @@ function makeSimpParser(connTypesRef) {
 	};
 }
 
-// connTypesRef is { value: Map<label, "postgres"|"mysql"|"mongo"|"redis"|"jq"> }
+// connTypesRef is { value: Map<label, "postgres"|"mysql"|"sqlite"|"sqlserver"|"elasticsearch"|"http"|"graphql"|"dynamodb"|"cassandra"|"clickhouse"|"neo4j"|"etcd"|"consul"|"memcached"|"kafka"|"mongo"|"redis"|"jq"> }
 // passed as a live reference so the parser always sees the up-to-date
 // registry without needing to be reconstructed on every change.
 export function simpStreamLanguage(connTypesRef) {